@@ -2,24 +2,46 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"go-tasks-api/internal/config"
 	"go-tasks-api/internal/database"
+	"go-tasks-api/internal/encryption"
+	"go-tasks-api/internal/events"
 	"go-tasks-api/internal/handler"
+	"go-tasks-api/internal/httpcache"
+	"go-tasks-api/internal/jobs"
+	"go-tasks-api/internal/migrator"
+	"go-tasks-api/internal/outbox"
 	"go-tasks-api/internal/repository"
 	"go-tasks-api/internal/server"
+	"go-tasks-api/internal/utils"
+	"go-tasks-api/internal/webhook"
 
 	"github.com/rs/zerolog/log"
 )
 
 type Service struct {
-	taskHandler *handler.Task
+	handlers     server.Handlers
+	cache        httpcache.Policies
+	errorOptions utils.ErrorOptions
+	listener     *events.Listener
+	relay        *outbox.Relay
+	dispatcher   *webhook.Dispatcher
+	runner       *jobs.Runner
+	replicas     *database.Replicas
+	bus          events.Bus
+	// taskCache is the cache of the Postgres tasks, nil when disabled
+	taskCache repository.CachedTaskConnector
+	// taskLog is the repository of the file storage backend, it is closed once the server stopped
+	taskLog repository.FileTaskConnector
 }
 
 func main() {
@@ -34,26 +56,235 @@ func main() {
 func NewService(ctx context.Context) *Service {
 	cfg := config.LoadConfig()
 
-	db, err := database.NewConnection(cfg.DSN(), cfg.DatabaseMaxOpenConns)
+	errorOptions, err := cfg.ErrorOptions()
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid error response configuration")
+	}
+
+	cache := httpcache.Policies{
+		"/api/v1/tasks":      cfg.CacheControlTaskList,
+		"/api/v1/tasks/{id}": cfg.CacheControlTask,
+	}
+
+	cipher, err := cfg.Cipher()
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to load encryption keyring")
+	}
+
+	// events, webhooks, views and jobs are kept in Postgres, without it only tasks are served
+	switch cfg.StorageBackend {
+	case config.StorageMemory:
+		log.Warn().Msg("tasks are stored in memory and lost on exit, only the tasks routes are served")
+
+		return &Service{
+			handlers:     server.Handlers{Task: handler.NewTaskHandler(repository.NewMemoryTaskRepo(), database.NoopTransactor{})},
+			cache:        cache,
+			errorOptions: errorOptions,
+		}
+	case config.StorageFile:
+		taskLog, err := repository.OpenFileTaskRepo(cfg.FileStorage(), cipher)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to open task log")
+		}
+		log.Info().Str("path", cfg.StorageFilePath).Msg("tasks are stored in a file, only the tasks routes are served")
+
+		return &Service{
+			handlers:     server.Handlers{Task: handler.NewTaskHandler(taskLog, database.NoopTransactor{})},
+			cache:        cache,
+			errorOptions: errorOptions,
+			taskLog:      taskLog,
+		}
+	}
+
+	db, err := database.NewConnection(ctx, cfg.DSN(), cfg.DatabaseMaxOpenConns, cfg.DatabaseConnectTimeout)
 	if err != nil {
 		log.Fatal().Err(fmt.Errorf("failed to establish database connection: %w", err))
 	}
 
-	if err := database.MigrationVersionCheck(ctx, db, cfg.DatabaseMigrationTable, cfg.DatabaseMinVersion); err != nil {
-		log.Fatal().Err(fmt.Errorf("failed while checking database migration version: %w", err))
+	schemaRepo := repository.NewSchemaRepo(db, cfg.DatabaseMigrationTable)
+	expectedVersion := checkSchema(ctx, schemaRepo, cfg.SchemaWindow())
+
+	txConfig, err := cfg.TxConfig()
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid transaction configuration")
+	}
+
+	// without replica DSNs replicas stays nil and all reads go to the primary
+	var replicas *database.Replicas
+	if len(cfg.DatabaseReplicaDSNs) > 0 {
+		if replicas, err = database.OpenReplicas(cfg.DatabaseReplicaDSNs, cfg.DatabaseMaxOpenConns, cfg.ReplicaConfig()); err != nil {
+			log.Fatal().Err(err).Msg("failed to open read replicas")
+		}
 	}
 
-	taskRepo := repository.NewTaskRepo(db)
-	taskHandler := handler.NewTaskHandler(taskRepo)
+	// retries and the circuit breaker sit below the cache, which keeps serving hits while the
+	// database is down
+	taskRepo := repository.NewGuardedTaskRepo(repository.NewTaskRepo(db, cipher, replicas), database.NewGuard(cfg.RetryConfig()))
+	var taskCache repository.CachedTaskConnector
+	if cfg.TaskCacheSize > 0 {
+		taskCache = repository.NewCachedTaskRepo(taskRepo, cfg.TaskCache())
+		taskRepo = taskCache
+	}
+	eventRepo := repository.NewEventRepo(db, cipher)
+	webhookRepo := repository.NewWebhookRepo(db, cipher)
+	bus := events.NewBus()
+	recorder := events.NewRecorder(eventRepo, webhook.NewEnqueuer(webhookRepo))
+	listener := events.NewListener(cfg.DSN(), eventRepo, bus, events.ListenerConfig{
+		MinReconnectInterval: cfg.EventsListenerMinReconnect,
+		MaxReconnectInterval: cfg.EventsListenerMaxReconnect,
+		PingInterval:         cfg.EventsListenerPingInterval,
+	})
+
+	publisher, err := newPublisher(cfg, recorder, cipher)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to create outbox publisher")
+	}
+
+	relay := outbox.NewRelay(repository.NewOutboxRepo(db, cipher), publisher, outbox.Config{
+		PollInterval: cfg.OutboxPollInterval,
+		BatchSize:    cfg.OutboxBatchSize,
+		MaxAttempts:  cfg.OutboxMaxAttempts,
+		Lease:        cfg.OutboxLease,
+		BackoffBase:  cfg.OutboxBackoffBase,
+		BackoffMax:   cfg.OutboxBackoffMax,
+	})
+
+	dispatcher := webhook.NewDispatcher(webhookRepo, webhook.Config{
+		PollInterval: cfg.WebhookPollInterval,
+		BatchSize:    cfg.WebhookBatchSize,
+		Concurrency:  cfg.WebhookConcurrency,
+		Timeout:      cfg.WebhookTimeout,
+		MaxAttempts:  cfg.WebhookMaxAttempts,
+		BackoffBase:  cfg.WebhookBackoffBase,
+		BackoffMax:   cfg.WebhookBackoffMax,
+		DisableAfter: cfg.WebhookDisableAfter,
+	})
+
+	jobRepo := repository.NewJobRepo(db, cipher)
+	txManager := database.NewTxManager(db, txConfig)
+	jobKinds := jobs.TaskKinds(taskRepo, txManager)
+	runner := jobs.NewRunner(jobRepo, jobKinds, jobs.Config{
+		PollInterval:      cfg.JobsPollInterval,
+		Concurrency:       cfg.JobsConcurrency,
+		Lease:             cfg.JobsLease,
+		HeartbeatInterval: cfg.JobsHeartbeatInterval,
+		MaxAttempts:       cfg.JobsMaxAttempts,
+	})
 
 	return &Service{
-		taskHandler,
+		handlers: server.Handlers{
+			Task:    handler.NewTaskHandler(taskRepo, txManager),
+			Events:  handler.NewEventsHandler(eventRepo, bus, cfg.EventsHeartbeatInterval),
+			Webhook: handler.NewWebhookHandler(webhookRepo),
+			View:    handler.NewViewHandler(repository.NewViewRepo(db), taskRepo),
+			Job:     handler.NewJobHandler(jobRepo, jobKinds),
+			Admin:   handler.NewAdminHandler(schemaRepo, cfg.SchemaWindow(), expectedVersion),
+		},
+		cache:        cache,
+		errorOptions: errorOptions,
+		listener:     listener,
+		relay:        relay,
+		dispatcher:   dispatcher,
+		runner:       runner,
+		replicas:     replicas,
+		bus:          bus,
+		taskCache:    taskCache,
 	}
 }
 
+// checkSchema stops the service when the schema version of the database lies outside the window
+// and returns the version of the newest migration the service was built with
+func checkSchema(ctx context.Context, schemaRepo repository.SchemaConnector, window database.SchemaWindow) int64 {
+	expected, err := migrator.Latest(migrator.FS)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to read migrations")
+	}
+
+	current, err := schemaRepo.Version(ctx)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to read database schema version")
+	}
+
+	logger := log.With().Int64("current", current).Int64("expected", expected).
+		Int64("min", window.Min).Int64("max", window.Max).Logger()
+	if err := window.Check(current); err != nil {
+		logger.Fatal().Err(err).Msg("database schema version incompatible")
+	}
+
+	if current < expected {
+		logger.Warn().Msg("database schema version compatible, migrations of this build are pending")
+	} else {
+		logger.Info().Msg("database schema version compatible")
+	}
+
+	return expected
+}
+
+// newPublisher creates the outbox publisher of the configured destinations
+func newPublisher(cfg config.Config, recorder *events.Recorder, cipher encryption.Cipher) (outbox.Fanout, error) {
+	publishers := make(outbox.Fanout, 0, len(cfg.OutboxPublishers))
+	for _, name := range cfg.OutboxPublishers {
+		name = strings.TrimSpace(name)
+
+		var publisher outbox.Publisher
+		switch name {
+		case "events":
+			publisher = recorder
+		case "log":
+			publisher = outbox.LogPublisher{}
+		case "file":
+			p, err := outbox.NewFilePublisher(cfg.OutboxFilePath, cipher)
+			if err != nil {
+				return nil, err
+			}
+			publisher = p
+		case "http":
+			if cfg.OutboxHTTPURL == "" {
+				return nil, errors.New("OUTBOX_HTTP_URL is required by the http publisher")
+			}
+			publisher = outbox.NewHTTPPublisher(cfg.OutboxHTTPURL, cfg.OutboxHTTPTimeout)
+		default:
+			return nil, fmt.Errorf("unknown outbox publisher %q", name)
+		}
+		publishers = append(publishers, outbox.Destination{Name: name, Publisher: publisher})
+	}
+
+	return publishers, nil
+}
+
 // Run starts the service
 func (s *Service) Run(ctx context.Context) {
-	webServer := server.NewServer(s.taskHandler)
+	webServer := server.NewServer(s.handlers, s.cache, s.errorOptions, s.replicas)
+	go s.replicas.Run(ctx)
+
+	if s.taskLog != nil {
+		go s.taskLog.Run(ctx)
+	}
+
+	// the background workers need Postgres, the other storage backends run none of them
+	runnerDone := make(chan struct{})
+	if s.runner != nil {
+		go func() {
+			if err := s.listener.Run(ctx); err != nil {
+				log.Fatal().Err(err).Msg("events listener failed")
+			}
+		}()
+		go s.relay.Run(ctx)
+		go s.dispatcher.Run(ctx)
+
+		if s.taskCache != nil {
+			go events.Invalidate(ctx, s.bus, s.taskCache)
+		}
+
+		// running jobs are put back in the queue when the runner stops
+		go func() {
+			s.runner.Run(ctx)
+			close(runnerDone)
+		}()
+	} else {
+		close(runnerDone)
+	}
+
 	go func() {
 		if err := webServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatal().Err(err)
@@ -68,6 +299,22 @@ func (s *Service) Run(ctx context.Context) {
 		if err := webServer.Shutdown(shutdownCtx); err != nil {
 			log.Fatal().Err(err).Msg("server shutdown failed")
 		}
+
+		select {
+		case <-runnerDone:
+		case <-shutdownCtx.Done():
+			log.Warn().Msg("job runner did not stop in time, its jobs are run again once their lease expired")
+		}
+
+		if s.taskCache != nil {
+			log.Info().Interface("stats", s.taskCache.Stats()).Msg("task cache stopped")
+		}
+
+		if s.taskLog != nil {
+			if err := s.taskLog.Close(); err != nil {
+				log.Error().Err(err).Msg("failed to close task log")
+			}
+		}
 	}()
 
 	<-ctx.Done()
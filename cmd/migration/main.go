@@ -2,21 +2,229 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
 
 	"go-tasks-api/internal/config"
 	"go-tasks-api/internal/migrator"
 
+	"github.com/pressly/goose/v3"
 	"github.com/rs/zerolog/log"
 )
 
+const usage = `usage: migration [flags] [command]
+
+commands:
+  up               apply all pending migrations (default)
+  up-to VERSION    apply the pending migrations up to VERSION
+  down             roll back the latest applied migration
+  down-to VERSION  roll back the migrations applied after VERSION, 0 rolls back all
+  redo             roll back the latest applied migration and apply it again
+  status           list the migrations with their state
+  version          print the latest applied version
+  create NAME      write a new SQL migration to the migrations directory
+  lint             check the pending migrations for operations unsafe for zero-downtime deploys
+
+flags:
+`
+
 func main() {
-	log.Info().Msg("initiating migration")
+	dryRun := flag.Bool("dry-run", false, "print the SQL up, up-to, down and down-to would run without running it")
+	dir := flag.String("dir", migrator.Dir, "migrations directory create writes to")
+	all := flag.Bool("all", false, "lint all migrations instead of the pending ones, without a database")
+	src := flag.String("src", ".", "root of the Go sources lint searches for columns still referenced")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	command, args := "up", flag.Args()
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	// creating a migration needs no database
+	if command == "create" {
+		if len(args) != 1 {
+			log.Fatal().Msg("create takes the name of the migration")
+		}
+
+		path, err := migrator.Create(*dir, args[0], time.Now())
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to create migration")
+		}
+		log.Info().Str("path", path).Msg("migration created")
+
+		return
+	}
+
+	// linting all migrations needs no database either
+	if command == "lint" && *all {
+		if len(args) != 0 {
+			log.Fatal().Msg("lint takes no arguments")
+		}
+
+		if err := lint(migrator.FS, *src, 0); err != nil {
+			log.Fatal().Err(err).Msg("migration lint failed")
+		}
+
+		return
+	}
+
 	cfg := config.LoadConfig()
+	ctx := context.Background()
+
+	m, err := migrator.Open(ctx, cfg.DSN(), cfg.DatabaseConnectTimeout, cfg.DatabaseMigrationTable, migrator.FS)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to open migrations")
+	}
+	defer m.Close()
+
+	if err := run(ctx, m, command, args, *dryRun, *src); err != nil {
+		log.Fatal().Err(err).Str("command", command).Msg("migration failed")
+	}
+}
+
+// run runs a command on the database
+func run(ctx context.Context, m *migrator.Migrator, command string, args []string, dryRun bool, src string) error {
+	version := int64(-1)
+	switch command {
+	case "up-to", "down-to":
+		if len(args) != 1 {
+			return fmt.Errorf("%s takes the target version", command)
+		}
+
+		v, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil || v < 0 {
+			return fmt.Errorf("invalid version %q", args[0])
+		}
+		version = v
+	case "up", "down", "redo", "status", "version", "lint":
+		if len(args) != 0 {
+			return fmt.Errorf("%s takes no arguments", command)
+		}
+	default:
+		return fmt.Errorf("unknown command %q, run with -h for usage", command)
+	}
+
+	if dryRun {
+		switch command {
+		case "up", "up-to":
+			return printScripts(m.PendingUp(ctx, version))
+		case "down", "down-to":
+			return printScripts(m.PendingDown(ctx, version))
+		default:
+			return fmt.Errorf("-dry-run does not apply to %s", command)
+		}
+	}
+
+	switch command {
+	case "up":
+		return logResults(m.Up(ctx))
+	case "up-to":
+		return logResults(m.UpTo(ctx, version))
+	case "down":
+		result, err := m.Down(ctx)
+		if err != nil {
+			return err
+		}
 
-	// run migration
-	if err := migrator.Run(context.Background(), cfg.DSN(), cfg.DatabaseMigrationTable, migrator.FS); err != nil {
-		log.Fatal().Err(err).Msg("failed to apply migration")
+		return logResults([]*goose.MigrationResult{result}, nil)
+	case "down-to":
+		return logResults(m.DownTo(ctx, version))
+	case "redo":
+		return logResults(m.Redo(ctx))
+	case "status":
+		status, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tMIGRATION\tAPPLIED AT")
+		for _, s := range status {
+			appliedAt := "pending"
+			if s.State == goose.StateApplied {
+				appliedAt = s.AppliedAt.UTC().Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", s.Source.Version, s.Source.Path, appliedAt)
+		}
+
+		return w.Flush()
+	case "version":
+		v, err := m.Version(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Println(v)
+	case "lint":
+		v, err := m.Version(ctx)
+		if err != nil {
+			return err
+		}
+
+		return lint(migrator.FS, src, v)
+	}
+
+	return nil
+}
+
+// lint prints the findings of the migrations after version and fails when there is any
+func lint(migrationFS fs.FS, src string, version int64) error {
+	referenced, err := migrator.SourceReferences(src)
+	if err != nil {
+		return err
+	}
+
+	findings, err := migrator.Lint(migrationFS, migrator.LintOptions{Referenced: referenced, After: version})
+	if err != nil {
+		return err
+	}
+
+	for _, finding := range findings {
+		fmt.Println(finding)
+	}
+	if len(findings) > 0 {
+		return fmt.Errorf("%d unsafe operations found", len(findings))
+	}
+	log.Info().Int64("after", version).Msg("no unsafe operations found")
+
+	return nil
+}
+
+func logResults(results []*goose.MigrationResult, err error) error {
+	if err != nil {
+		return err
+	}
+
+	if len(results) == 0 {
+		log.Info().Msg("no migrations to run")
+	}
+	for _, result := range results {
+		log.Info().Msg(result.String())
+	}
+
+	return nil
+}
+
+// printScripts prints the scripts of a dry run to stdout
+func printScripts(scripts []migrator.Script, err error) error {
+	if err != nil {
+		return err
+	}
+
+	if len(scripts) == 0 {
+		log.Info().Msg("no migrations to run")
+	}
+	for _, script := range scripts {
+		fmt.Printf("-- %s\n%s\n\n", script.Path, script.SQL)
 	}
 
-	log.Info().Msg("migration successfully")
+	return nil
 }
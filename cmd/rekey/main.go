@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"flag"
+
+	"go-tasks-api/internal/config"
+	"go-tasks-api/internal/database"
+	"go-tasks-api/internal/encryption"
+	"go-tasks-api/internal/repository"
+
+	"github.com/rs/zerolog/log"
+)
+
+// rekey re-encrypts sensitive fields with the primary key of the keyring. Run it after
+// adding a new primary key and before removing the old key from the keyring file.
+func main() {
+	batchSize := flag.Int("batch-size", 500, "number of rows processed per batch")
+	flag.Parse()
+
+	log.Info().Msg("initiating re-encryption")
+	cfg := config.LoadConfig()
+
+	if cfg.EncryptionKeyringFile == "" {
+		log.Fatal().Msg("ENCRYPTION_KEYRING_FILE must be set to re-encrypt sensitive fields")
+	}
+
+	keyring, err := encryption.LoadKeyring(cfg.EncryptionKeyringFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to load encryption keyring")
+	}
+
+	db, err := database.NewConnection(context.Background(), cfg.DSN(), 1, cfg.DatabaseConnectTimeout)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to establish database connection")
+	}
+	defer db.Close()
+
+	rewritten, err := repository.Reencrypt(context.Background(), db, encryption.NewEnvelope(keyring), *batchSize)
+	if err != nil {
+		log.Fatal().Err(err).Int("rewritten", rewritten).Msg("failed to re-encrypt sensitive fields")
+	}
+
+	log.Info().
+		Int("rewritten", rewritten).
+		Str("primary_key_id", keyring.PrimaryKeyID()).
+		Msg("re-encryption successful")
+}
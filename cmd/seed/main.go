@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"time"
+
+	"go-tasks-api/internal/config"
+	"go-tasks-api/internal/database"
+	"go-tasks-api/internal/model"
+	"go-tasks-api/internal/repository"
+	"go-tasks-api/internal/seed"
+
+	"github.com/rs/zerolog/log"
+)
+
+// seed fills the task storage with the tasks of a fixture file or with synthetic tasks, for QA
+// and demo environments. Running it again leaves the tasks it created as they are.
+func main() {
+	file := flag.String("file", "", "YAML or JSON fixture file to load the tasks of")
+	count := flag.Int("count", 0, "number of synthetic tasks to generate instead of loading a fixture")
+	seedValue := flag.Uint64("seed", 1, "seed of the synthetic tasks, the same seed generates the same tasks")
+	flag.Parse()
+
+	if (*file == "") == (*count <= 0) {
+		log.Fatal().Msg("set either -file or -count")
+	}
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	var (
+		tasks []model.Task
+		err   error
+	)
+	if *file != "" {
+		if tasks, err = seed.Load(*file, now); err != nil {
+			log.Fatal().Err(err).Msg("failed to load fixture")
+		}
+	} else {
+		// the times of synthetic tasks are relative to the start of the day of the run
+		tasks = seed.Generate(*count, *seedValue, now.Truncate(24*time.Hour))
+	}
+
+	cfg := config.LoadConfig()
+
+	cipher, err := cfg.Cipher()
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to load encryption keyring")
+	}
+
+	var repo repository.TaskConnector
+	switch cfg.StorageBackend {
+	case config.StorageMemory:
+		log.Fatal().Msg("the memory storage backend loses the tasks on exit, seed postgres or file storage")
+	case config.StorageFile:
+		// the API keeps its own index of the log, records appended behind its back would be lost
+		// with its next compaction
+		taskLog, err := repository.OpenFileTaskRepo(cfg.FileStorage(), cipher)
+		if errors.Is(err, repository.ErrFileLocked) {
+			log.Fatal().Err(err).Msg("the task log is in use, stop the API before seeding")
+		}
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to open task log")
+		}
+		defer func() {
+			if err := taskLog.Close(); err != nil {
+				log.Error().Err(err).Msg("failed to close task log")
+			}
+		}()
+		repo = taskLog
+	default:
+		db, err := database.NewConnection(ctx, cfg.DSN(), 1, cfg.DatabaseConnectTimeout)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to establish database connection")
+		}
+		defer db.Close()
+		repo = repository.NewTaskRepo(db, cipher, nil)
+	}
+
+	result, err := seed.Seed(ctx, repo, tasks, now)
+	if err != nil {
+		log.Fatal().Err(err).Interface("result", result).Msg("failed to seed tasks")
+	}
+
+	log.Info().
+		Int("created", result.Created).
+		Int("updated", result.Updated).
+		Int("unchanged", result.Unchanged).
+		Int("skipped", result.Skipped).
+		Msg("seeding successful")
+}
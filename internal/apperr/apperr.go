@@ -0,0 +1,196 @@
+// Package apperr defines the errors the API reports to clients and maps them to HTTP responses
+package apperr
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-tasks-api/internal/utils"
+
+	"github.com/lib/pq"
+)
+
+// Kind classifies errors by how clients should react to them
+type Kind int
+
+const (
+	// Internal errors are unexpected failures, their details are not meant for clients
+	Internal Kind = iota
+	NotFound
+	Conflict
+	// Validation errors describe well-formed requests with invalid values
+	Validation
+	// Malformed errors describe request bodies that cannot be decoded
+	Malformed
+	Precondition
+	Forbidden
+	// Unavailable errors are transient, the request can be retried later
+	Unavailable
+	// TooLarge errors describe request bodies over the limit of the operation
+	TooLarge
+)
+
+// Status returns the HTTP status of the kind
+func (k Kind) Status() int {
+	switch k {
+	case NotFound:
+		return http.StatusNotFound
+	case Conflict:
+		return http.StatusConflict
+	case Validation, Malformed:
+		return http.StatusBadRequest
+	case Precondition:
+		return http.StatusPreconditionFailed
+	case Forbidden:
+		return http.StatusForbidden
+	case Unavailable:
+		return http.StatusServiceUnavailable
+	case TooLarge:
+		return http.StatusRequestEntityTooLarge
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Code returns the error code of the kind, used in error responses and problem types
+func (k Kind) Code() string {
+	switch k {
+	case NotFound:
+		return "not_found"
+	case Conflict:
+		return "conflict"
+	case Validation:
+		return "validation_error"
+	case Malformed:
+		return "bad_request"
+	case Precondition:
+		return "precondition_failed"
+	case Forbidden:
+		return "forbidden"
+	case Unavailable:
+		return "unavailable"
+	case TooLarge:
+		return "too_large"
+	default:
+		return "internal_error"
+	}
+}
+
+// message is the description of the kind sent instead of redacted details
+func (k Kind) message() string {
+	switch k {
+	case NotFound:
+		return "the resource does not exist"
+	case Conflict:
+		return "the request conflicts with the current state of the resource"
+	case Validation:
+		return "the request contains an invalid value"
+	case Malformed:
+		return "the request body cannot be decoded"
+	case Precondition:
+		return "a precondition of the request does not hold"
+	case Forbidden:
+		return "the request is not allowed"
+	case Unavailable:
+		return "the service is temporarily unavailable, retry later"
+	case TooLarge:
+		return "the request body is too large"
+	default:
+		return "an unexpected error occurred"
+	}
+}
+
+// Error is a domain error, its message is safe to send to clients while the wrapped error is not
+type Error struct {
+	Kind    Kind
+	Message string
+	Fields  []utils.FieldError
+	Err     error
+	// RetryAfter is the time after which an Unavailable request may succeed, 0 when unknown
+	RetryAfter time.Duration
+}
+
+// New creates a domain error of the given kind
+func New(kind Kind, message string, fields ...utils.FieldError) *Error {
+	return &Error{
+		Kind:    kind,
+		Message: message,
+		Fields:  fields,
+	}
+}
+
+// Wrap creates a domain error of the given kind caused by err
+func Wrap(kind Kind, message string, err error) *Error {
+	return &Error{
+		Kind:    kind,
+		Message: message,
+		Err:     err,
+	}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// KindOf returns the kind of the first domain error in the chain of err. Errors of the database
+// driver are classified by their cause, anything else is Internal.
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+
+	return driverKind(err)
+}
+
+func driverKind(err error) Kind {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return Unavailable
+	}
+
+	// the server closed the connection
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return Unavailable
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		code := string(pqErr.Code)
+		switch {
+		// connection exceptions, insufficient resources and operator intervention
+		case strings.HasPrefix(code, "08"), strings.HasPrefix(code, "53"), strings.HasPrefix(code, "57P"):
+			return Unavailable
+		// serialization failures and deadlocks succeed when retried
+		case code == "40001", code == "40P01":
+			return Unavailable
+		// unique and foreign key violations
+		case code == "23505", code == "23503":
+			return Conflict
+		// invalid text representation, such as a malformed UUID, and check violations
+		case code == "22P02", code == "23514":
+			return Validation
+		}
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return Unavailable
+	}
+
+	return Internal
+}
@@ -0,0 +1,114 @@
+package apperr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-tasks-api/internal/utils"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		kind Kind
+	}{
+		{"domain error", New(Conflict, "conflict"), Conflict},
+		{"wrapped domain error", fmt.Errorf("failed to get task: %w", New(NotFound, "no rows found")), NotFound},
+		{"unique violation", &pq.Error{Code: "23505"}, Conflict},
+		{"invalid uuid", fmt.Errorf("failed to query task: %w", &pq.Error{Code: "22P02"}), Validation},
+		{"connection failure", &pq.Error{Code: "08006"}, Unavailable},
+		{"serialization failure", &pq.Error{Code: "40001"}, Unavailable},
+		{"deadline", fmt.Errorf("failed to list tasks: %w", context.DeadlineExceeded), Unavailable},
+		{"closed connection", fmt.Errorf("failed to list tasks: %w", io.ErrUnexpectedEOF), Unavailable},
+		{"network", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, Unavailable},
+		{"other driver error", &pq.Error{Code: "42P01"}, Internal},
+		{"plain", errors.New("boom"), Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.kind, KindOf(tt.err))
+		})
+	}
+}
+
+func TestFromJSON(t *testing.T) {
+	decode := func(body string) *Error {
+		var v struct {
+			Title string `json:"title"`
+		}
+
+		return FromJSON(json.NewDecoder(strings.NewReader(body)).Decode(&v))
+	}
+
+	err := decode(`{"title": "a",}`)
+	require.Equal(t, Malformed, err.Kind)
+	require.Contains(t, err.Message, "byte offset 15")
+
+	err = decode(`{"title": 5}`)
+	require.Equal(t, `field "title" must be string, got number at byte offset 11`, err.Message)
+	require.Equal(t, []utils.FieldError{{Field: "title", Pointer: "/title", Message: "must be string, got number"}}, err.Fields)
+
+	require.Equal(t, "request body is empty", decode("").Message)
+	require.Equal(t, "request body ends in the middle of a JSON value", decode(`{"title"`).Message)
+}
+
+func serveError(redact bool, err error) map[string]any {
+	rec := httptest.NewRecorder()
+	handler := utils.ErrorOptions{RedactDetails: redact}.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Write(w, r, err, "failed to get task")
+	}))
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/tasks/1", nil))
+
+	var resp struct {
+		Errors []map[string]any `json:"errors"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+	resp.Errors[0]["http_status"] = rec.Code
+
+	return resp.Errors[0]
+}
+
+func TestWriteRedactsDriverErrors(t *testing.T) {
+	driverErr := fmt.Errorf("failed to query task: %w", &pq.Error{Code: "42P01", Message: `relation "tasks.tasks" does not exist`})
+
+	resp := serveError(true, driverErr)
+	require.Equal(t, http.StatusInternalServerError, resp["http_status"])
+	require.Equal(t, "internal_error", resp["code"])
+	require.Equal(t, "an unexpected error occurred", resp["detail"])
+	require.NotEmpty(t, resp["id"])
+
+	resp = serveError(false, driverErr)
+	require.Contains(t, resp["detail"], "does not exist")
+
+	// domain messages are safe to send, their causes are not
+	resp = serveError(true, Wrap(Unavailable, "database unavailable", driverErr))
+	require.Equal(t, http.StatusServiceUnavailable, resp["http_status"])
+	require.Equal(t, "database unavailable", resp["detail"])
+}
+
+func TestWriteSetsRetryAfter(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/tasks", nil)
+
+	w := httptest.NewRecorder()
+	Write(w, r, &Error{Kind: Unavailable, Message: "database unavailable", RetryAfter: 1500 * time.Millisecond}, "failed to list tasks")
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	require.Equal(t, "2", w.Header().Get("Retry-After"))
+
+	w = httptest.NewRecorder()
+	Write(w, r, New(Unavailable, "database unavailable"), "failed to list tasks")
+	require.Empty(t, w.Header().Get("Retry-After"))
+}
@@ -0,0 +1,104 @@
+package apperr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-tasks-api/internal/utils"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// Write writes the error response of err with the status and code of its kind, title describes
+// the failed operation. A domain error with a RetryAfter sets the Retry-After header. When the
+// request's utils.ErrorOptions redact details, clients only get the message of domain errors,
+// never the text of wrapped driver errors. The full error is logged with the error ID sent in
+// the response.
+func Write(w http.ResponseWriter, r *http.Request, err error, title string) {
+	kind := KindOf(err)
+	id := uuid.New().String()
+
+	var (
+		details = err.Error()
+		fields  []utils.FieldError
+		domain  *Error
+	)
+	isDomain := errors.As(err, &domain)
+	if isDomain {
+		fields = domain.Fields
+
+		if domain.RetryAfter > 0 {
+			// whole seconds, rounded up so clients do not come back early
+			seconds := (domain.RetryAfter + time.Second - 1) / time.Second
+			w.Header().Set("Retry-After", strconv.FormatInt(int64(seconds), 10))
+		}
+	}
+
+	if utils.ErrorOptionsFrom(r).RedactDetails {
+		if isDomain {
+			details = domain.Message
+		} else {
+			details = kind.message()
+		}
+	}
+
+	event := log.Debug()
+	if kind.Status() >= http.StatusInternalServerError {
+		event = log.Error()
+	}
+	event.Err(err).
+		Str("error_id", id).
+		Str("code", kind.Code()).
+		Str("method", r.Method).
+		Str("path", r.URL.Path).
+		Msg(title)
+
+	utils.WriteJSONError(w, r, kind.Status(), utils.ErrorDescription{
+		ID:      id,
+		Status:  kind.Status(),
+		Code:    kind.Code(),
+		Title:   title,
+		Details: details,
+	}, fields...)
+}
+
+// FromJSON describes a failure to decode a JSON request body, pointing at the byte offset of
+// the error where the decoder reports one
+func FromJSON(err error) *Error {
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return New(Malformed, "request body is empty")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return New(Malformed, "request body ends in the middle of a JSON value")
+	case errors.As(err, &syntaxErr):
+		return New(Malformed, fmt.Sprintf("malformed JSON at byte offset %d: %s", syntaxErr.Offset, syntaxErr))
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return New(Malformed, fmt.Sprintf("request body must be %s, got %s at byte offset %d",
+				typeErr.Type, typeErr.Value, typeErr.Offset))
+		}
+
+		message := fmt.Sprintf("must be %s, got %s", typeErr.Type, typeErr.Value)
+
+		return New(Malformed, fmt.Sprintf("field %q %s at byte offset %d", typeErr.Field, message, typeErr.Offset),
+			utils.FieldError{
+				Field:   typeErr.Field,
+				Pointer: "/" + strings.ReplaceAll(typeErr.Field, ".", "/"),
+				Message: message,
+			})
+	default:
+		return Wrap(Malformed, "malformed request body", err)
+	}
+}
@@ -2,6 +2,14 @@ package config
 
 import (
 	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go-tasks-api/internal/database"
+	"go-tasks-api/internal/encryption"
+	"go-tasks-api/internal/repository"
+	"go-tasks-api/internal/utils"
 
 	"github.com/caarlos0/env/v11"
 	"github.com/rs/zerolog/log"
@@ -10,15 +18,142 @@ import (
 type Config struct {
 	ServiceName string `env:"SERVICE_NAME,required"`
 
-	DatabaseHost           string `env:"DATABASE_HOST,required"`
-	DatabaseName           string `env:"DATABASE_NAME,required"`
-	DatabaseUserName       string `env:"DATABASE_USERNAME,required"`
-	DatabasePassword       string `env:"DATABASE_PASSWORD,required"`
-	DatabaseSSLMode        string `env:"DATABASE_SSL_MODE,required"`
-	DatabaseSSLRootCert    string `env:"DATABASE_SSL_ROOT_CERT,required"`
-	DatabaseMaxOpenConns   int    `env:"DATABASE_MAX_OPEN_CONNS,required"`
-	DatabaseMigrationTable string `env:"DATABASE_MIGRATION_TABLE,required"`
-	DatabaseMinVersion     int    `env:"DATABASE_MIN_VERSION,required"`
+	// StorageBackend is where tasks are stored, postgres, file or memory. The memory backend
+	// keeps tasks until the process exits, both it and the file backend serve the tasks routes only.
+	StorageBackend string `env:"STORAGE_BACKEND" envDefault:"postgres"`
+
+	// StorageFile* tune the file backend, an append-only log of task changes. With the always
+	// sync policy every change is on disk before it is acknowledged, with interval a crash loses
+	// the changes of the last interval and with never the operating system decides.
+	StorageFilePath            string        `env:"STORAGE_FILE_PATH" envDefault:"tasks.log"`
+	StorageFileSync            string        `env:"STORAGE_FILE_SYNC" envDefault:"always"`
+	StorageFileSyncInterval    time.Duration `env:"STORAGE_FILE_SYNC_INTERVAL" envDefault:"1s"`
+	StorageFileCompactInterval time.Duration `env:"STORAGE_FILE_COMPACT_INTERVAL" envDefault:"1m"`
+	StorageFileCompactMinStale int           `env:"STORAGE_FILE_COMPACT_MIN_STALE" envDefault:"1000"`
+
+	// Database* are required by the postgres storage backend
+	DatabaseHost           string `env:"DATABASE_HOST"`
+	DatabaseName           string `env:"DATABASE_NAME"`
+	DatabaseUserName       string `env:"DATABASE_USERNAME"`
+	DatabasePassword       string `env:"DATABASE_PASSWORD"`
+	DatabaseSSLMode        string `env:"DATABASE_SSL_MODE"`
+	DatabaseSSLRootCert    string `env:"DATABASE_SSL_ROOT_CERT"`
+	DatabaseMaxOpenConns   int    `env:"DATABASE_MAX_OPEN_CONNS"`
+	DatabaseMigrationTable string `env:"DATABASE_MIGRATION_TABLE"`
+
+	// DatabaseMinVersion and DatabaseMaxVersion bound the schema versions the service starts
+	// against, a maximum of 0 sets no upper bound
+	DatabaseMinVersion int64 `env:"DATABASE_MIN_VERSION"`
+	DatabaseMaxVersion int64 `env:"DATABASE_MAX_VERSION"`
+
+	// DatabaseReplicaDSNs are comma separated DSNs of read replicas, task reads are spread over
+	// those lagging at most DATABASE_REPLICA_MAX_LAG behind. Reads of a client go to the primary
+	// for DATABASE_READ_YOUR_WRITES_WINDOW after it wrote.
+	DatabaseReplicaDSNs          []string      `env:"DATABASE_REPLICA_DSNS" envSeparator:","`
+	DatabaseReplicaMaxLag        time.Duration `env:"DATABASE_REPLICA_MAX_LAG" envDefault:"5s"`
+	DatabaseReplicaCheckInterval time.Duration `env:"DATABASE_REPLICA_CHECK_INTERVAL" envDefault:"5s"`
+	DatabaseReadYourWritesWindow time.Duration `env:"DATABASE_READ_YOUR_WRITES_WINDOW" envDefault:"10s"`
+
+	// DatabaseTx* tune the transactions spanning several repository calls, those failing to
+	// serialize or deadlocking are run again
+	DatabaseTxIsolation   string        `env:"DATABASE_TX_ISOLATION" envDefault:"read committed"`
+	DatabaseTxMaxAttempts int           `env:"DATABASE_TX_MAX_ATTEMPTS" envDefault:"3"`
+	DatabaseTxRetryDelay  time.Duration `env:"DATABASE_TX_RETRY_DELAY" envDefault:"20ms"`
+
+	// DatabaseConnectTimeout is the time connecting on startup is retried while the database
+	// cannot be reached
+	DatabaseConnectTimeout time.Duration `env:"DATABASE_CONNECT_TIMEOUT" envDefault:"30s"`
+
+	// DatabaseRetry* tune the retries of task repository calls failing transiently, such as
+	// during a failover. DatabaseBreaker* tune the circuit breaker answering 503 while the
+	// database cannot be reached.
+	DatabaseRetryMaxAttempts int           `env:"DATABASE_RETRY_MAX_ATTEMPTS" envDefault:"3"`
+	DatabaseRetryBaseDelay   time.Duration `env:"DATABASE_RETRY_BASE_DELAY" envDefault:"50ms"`
+	DatabaseRetryMaxDelay    time.Duration `env:"DATABASE_RETRY_MAX_DELAY" envDefault:"1s"`
+	DatabaseBreakerThreshold int           `env:"DATABASE_BREAKER_THRESHOLD" envDefault:"5"`
+	DatabaseBreakerCooldown  time.Duration `env:"DATABASE_BREAKER_COOLDOWN" envDefault:"10s"`
+
+	// TaskCacheSize bounds the tasks and task lists of Postgres kept in memory, 0 disables the
+	// cache. Other instances see changes once TASK_CACHE_TTL passed, or at once when events are
+	// recorded.
+	TaskCacheSize int           `env:"TASK_CACHE_SIZE" envDefault:"0"`
+	TaskCacheTTL  time.Duration `env:"TASK_CACHE_TTL" envDefault:"5s"`
+
+	// CacheControl* are the Cache-Control values of conditional GET routes, no-cache lets
+	// clients keep responses but revalidate them with If-None-Match on every use
+	CacheControlTaskList string `env:"CACHE_CONTROL_TASK_LIST" envDefault:"private, no-cache"`
+	CacheControlTask     string `env:"CACHE_CONTROL_TASK" envDefault:"private, no-cache"`
+
+	// ErrorFormat is the default format of error responses, json or problem (RFC 9457 problem
+	// details), requests can select the other one with the Accept header
+	ErrorFormat string `env:"ERROR_FORMAT" envDefault:"json"`
+	// ProblemTypeBaseURI is prefixed to error codes to build the type URI of problem details
+	ProblemTypeBaseURI string `env:"PROBLEM_TYPE_BASE_URI" envDefault:"/problems/"`
+	// ErrorRedactDetails replaces the text of unexpected errors, such as database driver errors, in
+	// responses with a generic message, they are still logged with the error ID
+	ErrorRedactDetails bool `env:"ERROR_REDACT_DETAILS" envDefault:"true"`
+
+	EventsHeartbeatInterval time.Duration `env:"EVENTS_HEARTBEAT_INTERVAL" envDefault:"15s"`
+	// EventsListener* tune the LISTEN connection which brings events of all instances to the local bus
+	EventsListenerMinReconnect time.Duration `env:"EVENTS_LISTENER_MIN_RECONNECT" envDefault:"1s"`
+	EventsListenerMaxReconnect time.Duration `env:"EVENTS_LISTENER_MAX_RECONNECT" envDefault:"1m"`
+	EventsListenerPingInterval time.Duration `env:"EVENTS_LISTENER_PING_INTERVAL" envDefault:"90s"`
+
+	WebhookPollInterval time.Duration `env:"WEBHOOK_POLL_INTERVAL" envDefault:"1s"`
+	WebhookBatchSize    int           `env:"WEBHOOK_BATCH_SIZE" envDefault:"50"`
+	WebhookConcurrency  int           `env:"WEBHOOK_CONCURRENCY" envDefault:"8"`
+	WebhookTimeout      time.Duration `env:"WEBHOOK_TIMEOUT" envDefault:"10s"`
+	WebhookMaxAttempts  int           `env:"WEBHOOK_MAX_ATTEMPTS" envDefault:"10"`
+	WebhookBackoffBase  time.Duration `env:"WEBHOOK_BACKOFF_BASE" envDefault:"5s"`
+	WebhookBackoffMax   time.Duration `env:"WEBHOOK_BACKOFF_MAX" envDefault:"1h"`
+	WebhookDisableAfter int           `env:"WEBHOOK_DISABLE_AFTER" envDefault:"25"`
+
+	// Jobs* tune the workers of asynchronous jobs, a job whose worker sent no heartbeat for
+	// JOBS_LEASE is taken over by another worker
+	JobsPollInterval      time.Duration `env:"JOBS_POLL_INTERVAL" envDefault:"1s"`
+	JobsConcurrency       int           `env:"JOBS_CONCURRENCY" envDefault:"2"`
+	JobsLease             time.Duration `env:"JOBS_LEASE" envDefault:"1m"`
+	JobsHeartbeatInterval time.Duration `env:"JOBS_HEARTBEAT_INTERVAL" envDefault:"5s"`
+	JobsMaxAttempts       int           `env:"JOBS_MAX_ATTEMPTS" envDefault:"3"`
+
+	// OutboxPublishers lists where domain events are published: events (event log, SSE streams
+	// and webhooks), log, file and http
+	OutboxPublishers   []string      `env:"OUTBOX_PUBLISHERS" envSeparator:"," envDefault:"events"`
+	OutboxPollInterval time.Duration `env:"OUTBOX_POLL_INTERVAL" envDefault:"500ms"`
+	OutboxBatchSize    int           `env:"OUTBOX_BATCH_SIZE" envDefault:"100"`
+	OutboxFilePath     string        `env:"OUTBOX_FILE_PATH" envDefault:"outbox.ndjson"`
+	OutboxHTTPURL      string        `env:"OUTBOX_HTTP_URL"`
+	OutboxHTTPTimeout  time.Duration `env:"OUTBOX_HTTP_TIMEOUT" envDefault:"10s"`
+	// OutboxMaxAttempts bounds the attempts of a failing event, OutboxLease is the time a relay
+	// publishes a batch in before another relay claims its events again. A failing event is
+	// retried after an exponential backoff from OutboxBackoffBase up to OutboxBackoffMax.
+	OutboxMaxAttempts int           `env:"OUTBOX_MAX_ATTEMPTS" envDefault:"25"`
+	OutboxLease       time.Duration `env:"OUTBOX_LEASE" envDefault:"1m"`
+	OutboxBackoffBase time.Duration `env:"OUTBOX_BACKOFF_BASE" envDefault:"1s"`
+	OutboxBackoffMax  time.Duration `env:"OUTBOX_BACKOFF_MAX" envDefault:"5m"`
+
+	// EncryptionKeyringFile is the path to the keyring used to encrypt sensitive task fields,
+	// values are stored in plaintext when it is not set
+	EncryptionKeyringFile string `env:"ENCRYPTION_KEYRING_FILE"`
+}
+
+const (
+	StoragePostgres = "postgres"
+	StorageFile     = "file"
+	StorageMemory   = "memory"
+)
+
+// databaseEnv are the variables the postgres storage backend cannot do without
+var databaseEnv = []string{
+	"DATABASE_HOST",
+	"DATABASE_NAME",
+	"DATABASE_USERNAME",
+	"DATABASE_PASSWORD",
+	"DATABASE_SSL_MODE",
+	"DATABASE_SSL_ROOT_CERT",
+	"DATABASE_MAX_OPEN_CONNS",
+	"DATABASE_MIGRATION_TABLE",
+	"DATABASE_MIN_VERSION",
 }
 
 // LoadConfig loads configuration from environment variables
@@ -28,9 +163,127 @@ func LoadConfig() Config {
 		log.Fatal().Err(fmt.Errorf("failed to load config: %w", err))
 	}
 
+	if err := cfg.validateStorage(os.LookupEnv); err != nil {
+		log.Fatal().Err(err).Msg("failed to load config")
+	}
+
 	return cfg
 }
 
+// validateStorage checks that the variables of the storage backend are set
+func (c Config) validateStorage(lookup func(string) (string, bool)) error {
+	switch c.StorageBackend {
+	case StoragePostgres:
+		missing := make([]string, 0)
+		for _, name := range databaseEnv {
+			if _, ok := lookup(name); !ok {
+				missing = append(missing, name)
+			}
+		}
+
+		if len(missing) > 0 {
+			return fmt.Errorf("the postgres storage backend requires %s", strings.Join(missing, ", "))
+		}
+	case StorageFile, StorageMemory:
+	default:
+		return fmt.Errorf("unknown storage backend %q", c.StorageBackend)
+	}
+
+	return nil
+}
+
+// Cipher loads the keyring file and returns the cipher used for sensitive fields
+func (c Config) Cipher() (encryption.Cipher, error) {
+	if c.EncryptionKeyringFile == "" {
+		return encryption.NoopCipher{}, nil
+	}
+
+	keyring, err := encryption.LoadKeyring(c.EncryptionKeyringFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return encryption.NewEnvelope(keyring), nil
+}
+
+// TxConfig returns the configuration of database transactions
+func (c Config) TxConfig() (database.TxConfig, error) {
+	isolation, err := database.ParseIsolation(c.DatabaseTxIsolation)
+	if err != nil {
+		return database.TxConfig{}, err
+	}
+
+	return database.TxConfig{
+		Isolation:   isolation,
+		MaxAttempts: c.DatabaseTxMaxAttempts,
+		RetryDelay:  c.DatabaseTxRetryDelay,
+	}, nil
+}
+
+// RetryConfig returns the configuration of retries and the circuit breaker of the task repository
+func (c Config) RetryConfig() database.RetryConfig {
+	return database.RetryConfig{
+		MaxAttempts:      c.DatabaseRetryMaxAttempts,
+		BaseDelay:        c.DatabaseRetryBaseDelay,
+		MaxDelay:         c.DatabaseRetryMaxDelay,
+		BreakerThreshold: c.DatabaseBreakerThreshold,
+		BreakerCooldown:  c.DatabaseBreakerCooldown,
+	}
+}
+
+// SchemaWindow returns the schema versions the service supports
+func (c Config) SchemaWindow() database.SchemaWindow {
+	return database.SchemaWindow{
+		Min: c.DatabaseMinVersion,
+		Max: c.DatabaseMaxVersion,
+	}
+}
+
+// ReplicaConfig returns the configuration of the read replicas
+func (c Config) ReplicaConfig() database.ReplicaConfig {
+	return database.ReplicaConfig{
+		MaxLag:               c.DatabaseReplicaMaxLag,
+		CheckInterval:        c.DatabaseReplicaCheckInterval,
+		ReadYourWritesWindow: c.DatabaseReadYourWritesWindow,
+	}
+}
+
+// TaskCache returns the configuration of the task cache
+func (c Config) TaskCache() repository.CacheConfig {
+	return repository.CacheConfig{
+		Size: c.TaskCacheSize,
+		TTL:  c.TaskCacheTTL,
+	}
+}
+
+// FileStorage returns the configuration of the file storage backend
+func (c Config) FileStorage() repository.FileConfig {
+	return repository.FileConfig{
+		Path:            c.StorageFilePath,
+		Sync:            repository.FileSync(c.StorageFileSync),
+		SyncInterval:    c.StorageFileSyncInterval,
+		CompactInterval: c.StorageFileCompactInterval,
+		CompactMinStale: c.StorageFileCompactMinStale,
+	}
+}
+
+// ErrorOptions returns the options of error responses
+func (c Config) ErrorOptions() (utils.ErrorOptions, error) {
+	opts := utils.ErrorOptions{
+		ProblemTypeBaseURI: c.ProblemTypeBaseURI,
+		RedactDetails:      c.ErrorRedactDetails,
+	}
+	switch c.ErrorFormat {
+	case "json":
+	case "problem":
+		opts.ProblemDetails = true
+	default:
+		return utils.ErrorOptions{}, fmt.Errorf("unknown error format %q", c.ErrorFormat)
+	}
+
+	return opts, nil
+}
+
 // DSN constructs the Data Source Name for database connection
 func (c Config) DSN() string {
 	return fmt.Sprintf(
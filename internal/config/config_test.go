@@ -0,0 +1,23 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateStorage(t *testing.T) {
+	none := func(string) (string, bool) { return "", false }
+	all := func(string) (string, bool) { return "", true }
+
+	require.NoError(t, Config{StorageBackend: StorageMemory}.validateStorage(none))
+	require.NoError(t, Config{StorageBackend: StorageFile}.validateStorage(none))
+	require.NoError(t, Config{StorageBackend: StoragePostgres}.validateStorage(all))
+
+	err := Config{StorageBackend: StoragePostgres}.validateStorage(func(name string) (string, bool) {
+		return "", name != "DATABASE_HOST"
+	})
+	require.EqualError(t, err, "the postgres storage backend requires DATABASE_HOST")
+
+	require.Error(t, Config{StorageBackend: "sqlite"}.validateStorage(all))
+}
@@ -5,13 +5,17 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/rs/zerolog/log"
+	"github.com/sethvargo/go-retry"
 )
 
-// Establish a new database connection
-func NewConnection(dsn string, maxConnections int) (*sql.DB, error) {
+// Establish a new database connection. While the database cannot be reached, such as during a
+// failover or before its container started, connecting is retried for connectTimeout.
+func NewConnection(ctx context.Context, dsn string, maxConnections int, connectTimeout time.Duration) (*sql.DB, error) {
 	db, err := sql.Open("postgres", dsn)
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to create database connection")
@@ -19,29 +23,72 @@ func NewConnection(dsn string, maxConnections int) (*sql.DB, error) {
 
 	db.SetMaxOpenConns(maxConnections)
 
-	if err := db.PingContext(context.Background()); err != nil {
+	backoff := retry.WithMaxDuration(connectTimeout, retry.WithCappedDuration(5*time.Second, retry.NewExponential(100*time.Millisecond)))
+	err = retry.Do(ctx, backoff, func(ctx context.Context) error {
+		err := db.PingContext(ctx)
+		if err != nil && Classify(err) != Fatal {
+			log.Warn().Err(err).Msg("database unreachable, retrying")
+
+			return retry.RetryableError(err)
+		}
+
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
 	return db, nil
 }
 
-// Check database migration version against the minimum required version
-func MigrationVersionCheck(ctx context.Context, db *sql.DB, tableName string, minVersion int) error {
-	schemaVersionQuery := fmt.Sprintf(
-		`SELECT version_id FROM %s WHERE is_applied = true AND version_id >= %d;`,
-		tableName,
-		minVersion)
-
-	var res any
-	if err := db.QueryRowContext(ctx, schemaVersionQuery).Scan(&res); err != nil {
-		if err == sql.ErrNoRows {
-			return fmt.Errorf("%w: expected %s version to be >= %d", errors.New("version incompatible"), tableName, minVersion)
-		}
+// ErrSchemaIncompatible is the error of a schema version the service does not support
+var ErrSchemaIncompatible = errors.New("schema version incompatible")
+
+// SchemaWindow is the range of schema versions the service runs against. Min is the first
+// version with every table and column it uses, Max the last version keeping them, 0 sets no
+// upper bound.
+type SchemaWindow struct {
+	Min int64
+	Max int64
+}
 
-		return fmt.Errorf("error while checking version: %w", err)
+// Check returns an ErrSchemaIncompatible error when version lies outside the window
+func (w SchemaWindow) Check(version int64) error {
+	if version < w.Min {
+		return fmt.Errorf("%w: version %d is older than the minimum %d, apply the migrations first", ErrSchemaIncompatible, version, w.Min)
+	}
+	if w.Max > 0 && version > w.Max {
+		return fmt.Errorf("%w: version %d is newer than the maximum %d, deploy a newer service", ErrSchemaIncompatible, version, w.Max)
 	}
-	log.Info().Msg("database migration version check successful")
 
 	return nil
 }
+
+// SchemaVersion returns the latest migration version applied to the database, 0 when none is.
+// The version table is written by goose, a rolled back version is either deleted or recorded
+// again as not applied, so only the latest row of a version tells whether it is applied.
+func SchemaVersion(ctx context.Context, db *sql.DB, tableName string) (int64, error) {
+	query := `SELECT COALESCE(MAX(version_id), 0) FROM (
+		SELECT DISTINCT ON (version_id) version_id, is_applied FROM ` + QuoteTable(tableName) + `
+		ORDER BY version_id, id DESC
+	) versions WHERE is_applied`
+
+	var version int64
+	if err := db.QueryRowContext(ctx, query).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version from %s: %w", tableName, err)
+	}
+
+	return version, nil
+}
+
+// QuoteTable quotes a table name, optionally qualified by its schema, as identifiers
+func QuoteTable(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = pq.QuoteIdentifier(part)
+	}
+
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,42 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaVersion(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM "public"."goose_db_version"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(7))
+
+	version, err := SchemaVersion(context.Background(), db, "public.goose_db_version")
+	require.NoError(t, err)
+	require.Equal(t, int64(7), version)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestQuoteTable(t *testing.T) {
+	require.Equal(t, `"goose_db_version"`, QuoteTable("goose_db_version"))
+	require.Equal(t, `"tasks"."versions"`, QuoteTable("tasks.versions"))
+	require.Equal(t, `"x""; DROP TABLE tasks; --"`, QuoteTable(`x"; DROP TABLE tasks; --`))
+}
+
+func TestSchemaWindow(t *testing.T) {
+	window := SchemaWindow{Min: 3, Max: 5}
+
+	require.ErrorIs(t, window.Check(2), ErrSchemaIncompatible)
+	require.NoError(t, window.Check(3))
+	require.NoError(t, window.Check(5))
+	require.ErrorIs(t, window.Check(6), ErrSchemaIncompatible)
+
+	// without a maximum any newer schema is supported
+	require.NoError(t, SchemaWindow{Min: 3}.Check(100))
+}
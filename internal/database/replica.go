@@ -0,0 +1,277 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// PrimaryUntilCookie and PrimaryUntilHeader carry the Unix time in milliseconds until which
+	// the reads of a client go to the primary after it wrote
+	PrimaryUntilCookie = "primary_until"
+	PrimaryUntilHeader = "X-Primary-Until"
+)
+
+// primaryKey is the context key of reads which have to see the latest writes
+type primaryKey struct{}
+
+// WithPrimary makes the reads of the context go to the primary
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+func primaryOnly(ctx context.Context) bool {
+	only, _ := ctx.Value(primaryKey{}).(bool)
+
+	return only
+}
+
+// Fresh reports whether the reads of the context have to see the latest writes, because they
+// run in a transaction or go to the primary
+func Fresh(ctx context.Context) bool {
+	_, inTx := ctx.Value(txKey{}).(*txState)
+
+	return inTx || primaryOnly(ctx)
+}
+
+type ReplicaConfig struct {
+	// MaxLag is the replication lag up to which a replica serves reads
+	MaxLag time.Duration
+	// CheckInterval is the delay between health and lag checks of the replicas
+	CheckInterval time.Duration
+	// ReadYourWritesWindow is the time the reads of a client go to the primary after it wrote
+	ReadYourWritesWindow time.Duration
+}
+
+// PoolHealth is the state of a replica as of its last check
+type PoolHealth struct {
+	Name      string        `json:"name"`
+	Healthy   bool          `json:"healthy"`
+	Lag       time.Duration `json:"lag"`
+	Error     string        `json:"error,omitempty"`
+	CheckedAt time.Time     `json:"checked_at"`
+}
+
+// replica is the connection pool of a replica with its health
+type replica struct {
+	db *sql.DB
+
+	mu     sync.Mutex
+	health PoolHealth
+}
+
+func (r *replica) state() PoolHealth {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.health
+}
+
+// Replicas spreads reads over the read replicas which are healthy and close enough to the
+// primary, all other reads go to the primary. A nil Replicas reads from the primary only.
+type Replicas struct {
+	replicas []*replica
+	cfg      ReplicaConfig
+	// next is the round robin position over the replicas
+	next atomic.Uint64
+}
+
+// OpenReplicas creates the connection pools of the replicas, a replica is used once its first
+// check succeeded
+func OpenReplicas(dsns []string, maxConnections int, cfg ReplicaConfig) (*Replicas, error) {
+	dbs := make([]*sql.DB, 0, len(dsns))
+	for _, dsn := range dsns {
+		db, err := sql.Open("postgres", dsn)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open replica connection: %w", err)
+		}
+		db.SetMaxOpenConns(maxConnections)
+		dbs = append(dbs, db)
+	}
+
+	return NewReplicas(dbs, cfg), nil
+}
+
+// NewReplicas creates Replicas of open connection pools, no replica is used before Check
+func NewReplicas(dbs []*sql.DB, cfg ReplicaConfig) *Replicas {
+	replicas := make([]*replica, 0, len(dbs))
+	for i, db := range dbs {
+		// the DSN holds the password, replicas are named by position
+		replicas = append(replicas, &replica{db: db, health: PoolHealth{Name: "replica " + strconv.Itoa(i+1)}})
+	}
+
+	return &Replicas{
+		replicas: replicas,
+		cfg:      cfg,
+	}
+}
+
+// Reader returns the executor of reads: the transaction of the context, the primary when the
+// context requires it or no replica is usable, a replica otherwise
+func (r *Replicas) Reader(ctx context.Context, primary *sql.DB) Executor {
+	if r == nil || primaryOnly(ctx) || running(ctx, primary) != nil {
+		return Conn(ctx, primary)
+	}
+
+	n := uint64(len(r.replicas))
+	start := r.next.Add(1)
+	for i := range n {
+		rep := r.replicas[(start+i)%n]
+		if health := rep.state(); health.Healthy && health.Lag <= r.cfg.MaxLag {
+			return rep.db
+		}
+	}
+
+	return primary
+}
+
+// Run checks the replicas until the context is cancelled
+func (r *Replicas) Run(ctx context.Context) {
+	if r == nil || len(r.replicas) == 0 {
+		return
+	}
+
+	for {
+		r.Check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.cfg.CheckInterval):
+		}
+	}
+}
+
+// Check updates the health and lag of every replica
+func (r *Replicas) Check(ctx context.Context) {
+	// an idle primary sends no WAL, a replica which replayed all it received is not behind
+	lagSQL := `
+		SELECT CASE
+			WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+			ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+		END;
+	`
+
+	for _, rep := range r.replicas {
+		checkCtx, cancel := context.WithTimeout(ctx, r.cfg.CheckInterval)
+		var seconds float64
+		err := rep.db.QueryRowContext(checkCtx, lagSQL).Scan(&seconds)
+		cancel()
+
+		rep.mu.Lock()
+		was := rep.health
+		rep.health.CheckedAt = time.Now()
+		rep.health.Healthy = err == nil
+		rep.health.Error = ""
+		if err != nil {
+			rep.health.Error = err.Error()
+		} else {
+			rep.health.Lag = time.Duration(seconds * float64(time.Second))
+		}
+		now := rep.health
+		rep.mu.Unlock()
+
+		switch {
+		case was.Healthy && !now.Healthy:
+			log.Warn().Str("pool", now.Name).Str("error", now.Error).Msg("replica is unhealthy, reads go to the other pools")
+		case !was.Healthy && now.Healthy:
+			log.Info().Str("pool", now.Name).Dur("lag", now.Lag).Msg("replica is healthy")
+		case now.Healthy && (was.Lag <= r.cfg.MaxLag) != (now.Lag <= r.cfg.MaxLag):
+			log.Info().Str("pool", now.Name).Dur("lag", now.Lag).Bool("serving", now.Lag <= r.cfg.MaxLag).
+				Msg("replica lag crossed the maximum")
+		}
+	}
+}
+
+// Health returns the state of every replica
+func (r *Replicas) Health() []PoolHealth {
+	if r == nil {
+		return nil
+	}
+
+	health := make([]PoolHealth, 0, len(r.replicas))
+	for _, rep := range r.replicas {
+		health = append(health, rep.state())
+	}
+
+	return health
+}
+
+// Close closes the connection pools of the replicas
+func (r *Replicas) Close() error {
+	if r == nil {
+		return nil
+	}
+
+	for _, rep := range r.replicas {
+		if err := rep.db.Close(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Middleware sends the reads of a client to the primary for the read-your-writes window after
+// it wrote. Writes set a cookie and a header with the end of the window, a request carrying
+// either of them before that end reads from the primary.
+func (r *Replicas) Middleware(next http.Handler) http.Handler {
+	if r == nil || len(r.replicas) == 0 || r.cfg.ReadYourWritesWindow <= 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		now := time.Now()
+
+		switch req.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			if r.primaryUntil(req).After(now) {
+				req = req.WithContext(WithPrimary(req.Context()))
+			}
+		default:
+			until := now.Add(r.cfg.ReadYourWritesWindow)
+			value := strconv.FormatInt(until.UnixMilli(), 10)
+
+			w.Header().Set(PrimaryUntilHeader, value)
+			http.SetCookie(w, &http.Cookie{
+				Name:     PrimaryUntilCookie,
+				Value:    value,
+				Path:     "/",
+				Expires:  until,
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
+
+// primaryUntil returns the end of the read-your-writes window of the request. Clients cannot
+// extend it beyond one window from now.
+func (r *Replicas) primaryUntil(req *http.Request) time.Time {
+	value := req.Header.Get(PrimaryUntilHeader)
+	if cookie, err := req.Cookie(PrimaryUntilCookie); value == "" && err == nil {
+		value = cookie.Value
+	}
+
+	millis, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+
+	until := time.UnixMilli(millis)
+	if latest := time.Now().Add(r.cfg.ReadYourWritesWindow); until.After(latest) {
+		return latest
+	}
+
+	return until
+}
@@ -0,0 +1,180 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+)
+
+type replicaSuite struct {
+	suite.Suite
+	primary  *sql.DB
+	dbs      []*sql.DB
+	mocks    []sqlmock.Sqlmock
+	replicas *Replicas
+	ctx      context.Context
+}
+
+func TestReplicas(t *testing.T) {
+	suite.Run(t, new(replicaSuite))
+}
+
+func (s *replicaSuite) SetupTest() {
+	primary, _, err := sqlmock.New()
+	s.Require().NoError(err)
+	s.primary = primary
+
+	s.dbs, s.mocks = nil, nil
+	for range 2 {
+		db, mock, err := sqlmock.New()
+		s.Require().NoError(err)
+		s.dbs = append(s.dbs, db)
+		s.mocks = append(s.mocks, mock)
+	}
+
+	s.replicas = NewReplicas(s.dbs, ReplicaConfig{
+		MaxLag:               time.Second,
+		CheckInterval:        time.Second,
+		ReadYourWritesWindow: time.Minute,
+	})
+	s.ctx = context.Background()
+}
+
+func (s *replicaSuite) TearDownTest() {
+	for _, mock := range s.mocks {
+		s.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+// check runs a check in which the replicas report the given lags, a negative lag fails
+func (s *replicaSuite) check(lags ...float64) {
+	for i, lag := range lags {
+		if lag < 0 {
+			s.mocks[i].ExpectQuery("pg_last_xact_replay_timestamp").WillReturnError(errors.New("connection refused"))
+
+			continue
+		}
+		s.mocks[i].ExpectQuery("pg_last_xact_replay_timestamp").
+			WillReturnRows(sqlmock.NewRows([]string{"lag"}).AddRow(lag))
+	}
+
+	s.replicas.Check(s.ctx)
+}
+
+// readers returns the executors of a few reads
+func (s *replicaSuite) readers() map[Executor]bool {
+	readers := make(map[Executor]bool)
+	for range 4 {
+		readers[s.replicas.Reader(s.ctx, s.primary)] = true
+	}
+
+	return readers
+}
+
+func (s *replicaSuite) TestReadsFromPrimaryBeforeFirstCheck() {
+	s.Equal(map[Executor]bool{s.primary: true}, s.readers())
+}
+
+func (s *replicaSuite) TestSpreadsReadsOverReplicas() {
+	s.check(0, 0.5)
+
+	s.Equal(map[Executor]bool{s.dbs[0]: true, s.dbs[1]: true}, s.readers())
+}
+
+func (s *replicaSuite) TestSkipsLaggingAndUnhealthyReplicas() {
+	s.check(3, 0)
+	s.Equal(map[Executor]bool{s.dbs[1]: true}, s.readers())
+
+	s.check(3, -1)
+	s.Equal(map[Executor]bool{s.primary: true}, s.readers())
+
+	health := s.replicas.Health()
+	s.Equal("replica 1", health[0].Name)
+	s.True(health[0].Healthy)
+	s.Equal(3*time.Second, health[0].Lag)
+	s.False(health[1].Healthy)
+	s.Equal("connection refused", health[1].Error)
+}
+
+func (s *replicaSuite) TestReadsFromPrimaryWhenRequired() {
+	s.check(0, 0)
+
+	s.Equal(Executor(s.primary), s.replicas.Reader(WithPrimary(s.ctx), s.primary))
+
+	var nilReplicas *Replicas
+	s.Equal(Executor(s.primary), nilReplicas.Reader(s.ctx, s.primary))
+}
+
+func (s *replicaSuite) TestReadsInTransactionFromIt() {
+	s.check(0, 0)
+
+	db, mock, err := sqlmock.New()
+	s.Require().NoError(err)
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	err = NewTxManager(db, TxConfig{MaxAttempts: 1}).InTx(s.ctx, func(ctx context.Context) error {
+		_, ok := s.replicas.Reader(ctx, db).(*sql.Tx)
+		s.True(ok)
+
+		return nil
+	})
+	s.NoError(err)
+}
+
+// serve runs the request through the middleware and returns the response and whether the
+// handler had to read from the primary
+func (s *replicaSuite) serve(req *http.Request) (*httptest.ResponseRecorder, bool) {
+	var primary bool
+	handler := s.replicas.Middleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		primary = primaryOnly(r.Context())
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return rec, primary
+}
+
+func (s *replicaSuite) TestMiddlewareReadsYourWrites() {
+	rec, _ := s.serve(httptest.NewRequest(http.MethodPost, "/api/v1/tasks", nil))
+	s.NotEmpty(rec.Header().Get(PrimaryUntilHeader))
+	cookies := rec.Result().Cookies()
+	s.Require().Len(cookies, 1)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/tasks", nil)
+	req.AddCookie(cookies[0])
+	_, primary := s.serve(req)
+	s.True(primary)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/tasks", nil)
+	req.Header.Set(PrimaryUntilHeader, rec.Header().Get(PrimaryUntilHeader))
+	_, primary = s.serve(req)
+	s.True(primary)
+
+	_, primary = s.serve(httptest.NewRequest(http.MethodGet, "/api/v1/tasks", nil))
+	s.False(primary)
+}
+
+func (s *replicaSuite) TestMiddlewareIgnoresEndedWindow() {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/tasks", nil)
+	req.Header.Set(PrimaryUntilHeader, strconv.FormatInt(time.Now().Add(-time.Second).UnixMilli(), 10))
+
+	_, primary := s.serve(req)
+	s.False(primary)
+}
+
+func (s *replicaSuite) TestPrimaryUntilIsCappedToOneWindow() {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/tasks", nil)
+	req.Header.Set(PrimaryUntilHeader, strconv.FormatInt(time.Now().Add(time.Hour).UnixMilli(), 10))
+
+	s.WithinDuration(time.Now().Add(time.Minute), s.replicas.primaryUntil(req), time.Second)
+}
@@ -0,0 +1,271 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"go-tasks-api/internal/apperr"
+
+	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+	"github.com/sethvargo/go-retry"
+)
+
+// Class tells whether a failed database call may be run again
+type Class int
+
+const (
+	// Fatal errors fail again when the call is run again, such as constraint violations
+	Fatal Class = iota
+	// Rejected errors left the database unchanged: serialization failures, deadlocks and
+	// refused connections. The call may be run again.
+	Rejected
+	// Interrupted errors lost the connection while the call ran, such as a reset connection or
+	// a server shutting down. A read may be run again, a write may have been applied.
+	Interrupted
+)
+
+// ErrBreakerOpen is the cause of calls rejected while the database is considered down
+var ErrBreakerOpen = errors.New("database circuit breaker is open")
+
+// finalError is the error of a call which must not run again
+type finalError struct {
+	err error
+}
+
+func (e *finalError) Error() string {
+	return e.err.Error()
+}
+
+func (e *finalError) Unwrap() error {
+	return e.err
+}
+
+// Final marks the error of a call run by a Guard which must not run again, whatever its class
+func Final(err error) error {
+	return &finalError{err: err}
+}
+
+// Classify returns the class of an error of a database call
+func Classify(err error) Class {
+	// the context of the call ended, which tells nothing about the database
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return Fatal
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		// serialization failure, deadlock, cannot connect now, too many connections, unable to
+		// establish and rejected connection
+		case "40001", "40P01", "57P03", "53300", "08001", "08004":
+			return Rejected
+		// admin and crash shutdown
+		case "57P01", "57P02":
+			return Interrupted
+		}
+		if pqErr.Code.Class() == "08" {
+			return Interrupted
+		}
+
+		return Fatal
+	}
+
+	// database/sql returns ErrBadConn only when the statement was not sent
+	if errors.Is(err, driver.ErrBadConn) {
+		return Rejected
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return Rejected
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return Interrupted
+	}
+
+	return Fatal
+}
+
+// Retryable reports whether the call failed without changing the database and succeeds when
+// run again, such as a transaction failing to serialize
+func Retryable(err error) bool {
+	return Classify(err) == Rejected
+}
+
+// outage reports whether the error shows the database cannot be reached, unlike conflicts
+// between transactions
+func outage(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && (pqErr.Code == "40001" || pqErr.Code == "40P01") {
+		return false
+	}
+
+	return Classify(err) != Fatal
+}
+
+type RetryConfig struct {
+	// MaxAttempts bounds the runs of a call failing with a Rejected error, or of a read failing
+	// with an Interrupted one
+	MaxAttempts int
+	// BaseDelay is the delay before the second run, it doubles with every further run up to
+	// MaxDelay and gets a jitter of 20%
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// BreakerThreshold is the number of consecutive calls failing to reach the database which
+	// opens the breaker, 0 disables it
+	BreakerThreshold int
+	// BreakerCooldown is the time an open breaker rejects calls before letting one through
+	BreakerCooldown time.Duration
+}
+
+// Guard runs database calls, retrying those which failed transiently. After BreakerThreshold
+// calls in a row failed to reach the database its breaker opens, and calls fail at once with an
+// Unavailable error until the cooldown passed. A single call is then let through, the breaker
+// closes when it reaches the database and stays open for another cooldown otherwise.
+type Guard struct {
+	cfg RetryConfig
+
+	mu sync.Mutex
+	// failures are the consecutive calls which failed to reach the database
+	failures int
+	// openUntil is the end of the cooldown of the open breaker, zero when closed
+	openUntil time.Time
+	// probing is set while the call let through after the cooldown runs
+	probing bool
+}
+
+// NewGuard creates a new Guard with a closed breaker
+func NewGuard(cfg RetryConfig) *Guard {
+	cfg.BaseDelay = max(cfg.BaseDelay, time.Millisecond)
+	cfg.MaxDelay = max(cfg.MaxDelay, cfg.BaseDelay)
+
+	return &Guard{cfg: cfg}
+}
+
+// Read runs a call which does not change the database
+func (g *Guard) Read(ctx context.Context, fn func(ctx context.Context) error) error {
+	return g.run(ctx, fn, true)
+}
+
+// Write runs a call which changes the database, it is not run again after an Interrupted error
+func (g *Guard) Write(ctx context.Context, fn func(ctx context.Context) error) error {
+	return g.run(ctx, fn, false)
+}
+
+func (g *Guard) run(ctx context.Context, fn func(ctx context.Context) error, read bool) error {
+	probe, err := g.allow()
+	if err != nil {
+		return err
+	}
+
+	// a failed statement aborts the transaction, InTx runs it again as a whole
+	attempts := g.cfg.MaxAttempts
+	if _, inTx := ctx.Value(txKey{}).(*txState); inTx {
+		attempts = 1
+	}
+
+	backoff := retry.WithMaxRetries(uint64(max(attempts-1, 0)),
+		retry.WithJitterPercent(20, retry.WithCappedDuration(g.cfg.MaxDelay, retry.NewExponential(g.cfg.BaseDelay))))
+	err = retry.Do(ctx, backoff, func(ctx context.Context) error {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+
+		var final *finalError
+		if errors.As(err, &final) {
+			return final.err
+		}
+
+		if class := Classify(err); class == Rejected || (read && class == Interrupted) {
+			log.Debug().Err(err).Msg("retrying database call")
+
+			return retry.RetryableError(err)
+		}
+
+		return err
+	})
+
+	g.record(err, probe)
+
+	return err
+}
+
+// allow returns the error of a call rejected by the open breaker, and whether the call is let
+// through after the cooldown
+func (g *Guard) allow() (bool, error) {
+	if g.cfg.BreakerThreshold <= 0 {
+		return false, nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.openUntil.IsZero() {
+		return false, nil
+	}
+
+	wait := time.Until(g.openUntil)
+	if wait > 0 || g.probing {
+		return false, &apperr.Error{
+			Kind:       apperr.Unavailable,
+			Message:    "the database is unavailable, retry later",
+			Err:        ErrBreakerOpen,
+			RetryAfter: max(wait, time.Second),
+		}
+	}
+	g.probing = true
+
+	return true, nil
+}
+
+// record updates the breaker with the outcome of a call
+func (g *Guard) record(err error, probe bool) {
+	if g.cfg.BreakerThreshold <= 0 {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if probe {
+		g.probing = false
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		// the caller left, the call tells nothing about the database
+	case err != nil && outage(err):
+		g.failures++
+		if probe || (g.openUntil.IsZero() && g.failures >= g.cfg.BreakerThreshold) {
+			if !probe {
+				log.Error().Err(err).Int("failures", g.failures).Dur("cooldown", g.cfg.BreakerCooldown).
+					Msg("database unreachable, opening circuit breaker")
+			}
+			g.openUntil = time.Now().Add(g.cfg.BreakerCooldown)
+		}
+	default:
+		g.failures = 0
+		if !g.openUntil.IsZero() {
+			log.Info().Msg("database reachable again, closing circuit breaker")
+			g.openUntil = time.Time{}
+		}
+	}
+}
+
+// Open reports whether the breaker rejects calls
+func (g *Guard) Open() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return !g.openUntil.IsZero()
+}
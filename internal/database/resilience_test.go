@@ -0,0 +1,169 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"go-tasks-api/internal/apperr"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		class Class
+	}{
+		{"serialization failure", &pq.Error{Code: "40001"}, Rejected},
+		{"deadlock", fmt.Errorf("failed to update task: %w", &pq.Error{Code: "40P01"}), Rejected},
+		{"cannot connect now", &pq.Error{Code: "57P03"}, Rejected},
+		{"too many connections", &pq.Error{Code: "53300"}, Rejected},
+		{"bad connection", driver.ErrBadConn, Rejected},
+		{"refused", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, Rejected},
+		{"admin shutdown", &pq.Error{Code: "57P01"}, Interrupted},
+		{"connection failure", &pq.Error{Code: "08006"}, Interrupted},
+		{"reset", &net.OpError{Op: "read", Err: syscall.ECONNRESET}, Interrupted},
+		{"closed", io.ErrUnexpectedEOF, Interrupted},
+		{"unique violation", &pq.Error{Code: "23505"}, Fatal},
+		{"query canceled", &pq.Error{Code: "57014"}, Fatal},
+		{"deadline", context.DeadlineExceeded, Fatal},
+		{"plain", errors.New("boom"), Fatal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.class, Classify(tt.err))
+		})
+	}
+}
+
+func TestNewConnectionRetries(t *testing.T) {
+	start := time.Now()
+	_, err := NewConnection(context.Background(), "postgres://user@127.0.0.1:1/db?sslmode=disable", 1, 300*time.Millisecond)
+	require.Error(t, err)
+	require.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+}
+
+type guardSuite struct {
+	suite.Suite
+	guard *Guard
+	ctx   context.Context
+	// calls counts the runs of the calls
+	calls int
+}
+
+func TestGuard(t *testing.T) {
+	suite.Run(t, new(guardSuite))
+}
+
+func (s *guardSuite) SetupTest() {
+	s.guard = NewGuard(RetryConfig{
+		MaxAttempts:      3,
+		BaseDelay:        time.Millisecond,
+		MaxDelay:         time.Millisecond,
+		BreakerThreshold: 2,
+		BreakerCooldown:  20 * time.Millisecond,
+	})
+	s.ctx = context.Background()
+	s.calls = 0
+}
+
+// failing returns a call failing with the errors in turn, then succeeding
+func (s *guardSuite) failing(errs ...error) func(context.Context) error {
+	return func(context.Context) error {
+		s.calls++
+		if s.calls <= len(errs) {
+			return errs[s.calls-1]
+		}
+
+		return nil
+	}
+}
+
+func (s *guardSuite) TestRetriesRejectedCalls() {
+	s.NoError(s.guard.Write(s.ctx, s.failing(&pq.Error{Code: "40001"}, driver.ErrBadConn)))
+	s.Equal(3, s.calls)
+}
+
+func (s *guardSuite) TestRetriesInterruptedReadsOnly() {
+	interrupted := &pq.Error{Code: "57P01"}
+
+	s.NoError(s.guard.Read(s.ctx, s.failing(interrupted)))
+	s.Equal(2, s.calls)
+
+	s.calls = 0
+	s.ErrorIs(s.guard.Write(s.ctx, s.failing(interrupted)), interrupted)
+	s.Equal(1, s.calls)
+}
+
+func (s *guardSuite) TestGivesUpAfterMaxAttempts() {
+	refused := &pq.Error{Code: "57P03"}
+
+	s.ErrorIs(s.guard.Read(s.ctx, s.failing(refused, refused, refused)), refused)
+	s.Equal(3, s.calls)
+}
+
+func (s *guardSuite) TestDoesNotRetryFatalOrFinalErrors() {
+	s.Error(s.guard.Read(s.ctx, s.failing(&pq.Error{Code: "23505"})))
+	s.Equal(1, s.calls)
+
+	s.calls = 0
+	reset := &pq.Error{Code: "08006"}
+	s.ErrorIs(s.guard.Read(s.ctx, s.failing(Final(reset))), reset)
+	s.Equal(1, s.calls)
+}
+
+func (s *guardSuite) TestDoesNotRetryInTransaction() {
+	ctx := context.WithValue(s.ctx, txKey{}, &txState{})
+
+	s.Error(s.guard.Read(ctx, s.failing(&pq.Error{Code: "40001"})))
+	s.Equal(1, s.calls)
+}
+
+func (s *guardSuite) TestBreakerOpensAndRecovers() {
+	down := &pq.Error{Code: "57P03"}
+	for range 2 {
+		s.Error(s.guard.Read(s.ctx, s.failing(down, down, down)))
+		s.calls = 0
+	}
+	s.True(s.guard.Open())
+
+	err := s.guard.Read(s.ctx, s.failing())
+	s.ErrorIs(err, ErrBreakerOpen)
+	s.Equal(0, s.calls)
+
+	var domain *apperr.Error
+	s.Require().ErrorAs(err, &domain)
+	s.Equal(apperr.Unavailable, domain.Kind)
+	s.Equal(time.Second, domain.RetryAfter)
+
+	// after the cooldown one failing call opens the breaker again
+	time.Sleep(25 * time.Millisecond)
+	s.Error(s.guard.Read(s.ctx, s.failing(down, down, down)))
+	s.ErrorIs(s.guard.Read(s.ctx, s.failing()), ErrBreakerOpen)
+
+	time.Sleep(25 * time.Millisecond)
+	s.calls = 0
+	s.NoError(s.guard.Read(s.ctx, s.failing()))
+	s.False(s.guard.Open())
+}
+
+func (s *guardSuite) TestConflictsDoNotOpenBreaker() {
+	for range 3 {
+		s.Error(s.guard.Write(s.ctx, s.failing(&pq.Error{Code: "40001"}, &pq.Error{Code: "40001"}, &pq.Error{Code: "40P01"})))
+		s.calls = 0
+		s.Error(s.guard.Read(s.ctx, s.failing(context.Canceled)))
+		s.calls = 0
+	}
+	s.False(s.guard.Open())
+}
@@ -0,0 +1,231 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Executor runs statements, it is implemented by *sql.DB and *sql.Tx
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Transactor runs functions in a transaction
+type Transactor interface {
+	// InTx runs fn in a transaction carried by the context passed to fn, repository calls made
+	// with that context take part in it. The transaction is committed when fn returns nil and
+	// rolled back when it returns an error or panics. Within fn, InTx joins the running
+	// transaction and its options are ignored.
+	InTx(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error
+}
+
+// TxOption adjusts the options of a transaction
+type TxOption func(opts *sql.TxOptions)
+
+// WithIsolation runs the transaction at the given isolation level
+func WithIsolation(level sql.IsolationLevel) TxOption {
+	return func(opts *sql.TxOptions) {
+		opts.Isolation = level
+	}
+}
+
+// ReadOnly runs a transaction which cannot write
+func ReadOnly() TxOption {
+	return func(opts *sql.TxOptions) {
+		opts.ReadOnly = true
+	}
+}
+
+type TxConfig struct {
+	// Isolation is the isolation level of transactions started without WithIsolation
+	Isolation sql.IsolationLevel
+	// MaxAttempts bounds the runs of a transaction failing with a serialization failure or a deadlock
+	MaxAttempts int
+	// RetryDelay is the delay before the second run, it grows linearly with every further run
+	RetryDelay time.Duration
+}
+
+// txKey is the context key of the running transaction
+type txKey struct{}
+
+// txState is the running transaction of a database
+type txState struct {
+	db *sql.DB
+	tx *sql.Tx
+	// committed are the functions of OnCommit
+	committed []func()
+}
+
+// TxManager runs transactions on a database, retrying those which failed to serialize
+type TxManager struct {
+	db  *sql.DB
+	cfg TxConfig
+}
+
+// NewTxManager creates a new TxManager of the database
+func NewTxManager(db *sql.DB, cfg TxConfig) *TxManager {
+	return &TxManager{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+func (m *TxManager) InTx(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error {
+	if running(ctx, m.db) != nil {
+		return fn(ctx)
+	}
+
+	txOpts := &sql.TxOptions{Isolation: m.cfg.Isolation}
+	for _, opt := range opts {
+		opt(txOpts)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := m.run(ctx, txOpts, fn)
+		if err == nil || !Retryable(err) || attempt >= m.cfg.MaxAttempts {
+			return err
+		}
+
+		log.Debug().Err(err).Int("attempt", attempt).Msg("retrying transaction")
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt) * m.cfg.RetryDelay):
+		}
+	}
+}
+
+// run runs fn in one transaction
+func (m *TxManager) run(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context) error) error {
+	tx, err := m.db.BeginTx(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	committed := false
+	defer func() {
+		// also reached while panicking, the panic continues after the rollback
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	state := &txState{db: m.db, tx: tx}
+	if err := fn(context.WithValue(ctx, txKey{}, state)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	committed = true
+
+	for _, f := range state.committed {
+		f()
+	}
+
+	return nil
+}
+
+// OnCommit runs fn once the transaction carried by the context committed, or at once when the
+// context carries none. fn is dropped when the transaction is rolled back.
+func OnCommit(ctx context.Context, fn func()) {
+	state, ok := ctx.Value(txKey{}).(*txState)
+	if !ok {
+		fn()
+
+		return
+	}
+
+	state.committed = append(state.committed, fn)
+}
+
+// running returns the transaction of the database carried by the context
+func running(ctx context.Context, db *sql.DB) *sql.Tx {
+	state, ok := ctx.Value(txKey{}).(*txState)
+	if !ok || state.db != db {
+		return nil
+	}
+
+	return state.tx
+}
+
+// Conn returns the executor of statements on the database: the transaction carried by the
+// context, or the database itself when there is none
+func Conn(ctx context.Context, db *sql.DB) Executor {
+	if tx := running(ctx, db); tx != nil {
+		return tx
+	}
+
+	return db
+}
+
+// Tx is a transaction of a repository method, see Begin
+type Tx struct {
+	*sql.Tx
+	// joined is set when the transaction belongs to a Transactor, which commits or rolls it back
+	joined bool
+}
+
+// Begin begins the transaction of a repository method which runs several statements. Within
+// InTx it joins the running transaction, Commit and Rollback are then left to InTx.
+func Begin(ctx context.Context, db *sql.DB) (*Tx, error) {
+	if tx := running(ctx, db); tx != nil {
+		return &Tx{Tx: tx, joined: true}, nil
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tx{Tx: tx}, nil
+}
+
+func (t *Tx) Commit() error {
+	if t.joined {
+		return nil
+	}
+
+	return t.Tx.Commit()
+}
+
+func (t *Tx) Rollback() error {
+	if t.joined {
+		return nil
+	}
+
+	return t.Tx.Rollback()
+}
+
+// ParseIsolation parses an isolation level as written in SQL, such as "repeatable read"
+func ParseIsolation(s string) (sql.IsolationLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "default":
+		return sql.LevelDefault, nil
+	case "read committed":
+		return sql.LevelReadCommitted, nil
+	case "repeatable read":
+		return sql.LevelRepeatableRead, nil
+	case "serializable":
+		return sql.LevelSerializable, nil
+	default:
+		return sql.LevelDefault, fmt.Errorf("unknown isolation level %q", s)
+	}
+}
+
+// NoopTransactor runs functions without a transaction, for storage backends whose every
+// change is atomic on its own
+type NoopTransactor struct{}
+
+func (NoopTransactor) InTx(ctx context.Context, fn func(ctx context.Context) error, _ ...TxOption) error {
+	return fn(ctx)
+}
@@ -0,0 +1,207 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type txSuite struct {
+	suite.Suite
+	db      *sql.DB
+	mock    sqlmock.Sqlmock
+	manager *TxManager
+	ctx     context.Context
+}
+
+func TestTx(t *testing.T) {
+	suite.Run(t, new(txSuite))
+}
+
+func (s *txSuite) SetupTest() {
+	db, mock, err := sqlmock.New()
+	s.Require().NoError(err)
+
+	s.db = db
+	s.mock = mock
+	s.manager = NewTxManager(db, TxConfig{MaxAttempts: 3, RetryDelay: time.Millisecond})
+	s.ctx = context.Background()
+}
+
+func (s *txSuite) TearDownTest() {
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+// insert runs a statement the way repositories do, on the transaction of the context if any
+func (s *txSuite) insert(ctx context.Context) error {
+	_, err := Conn(ctx, s.db).ExecContext(ctx, "INSERT INTO t VALUES (1);")
+
+	return err
+}
+
+func (s *txSuite) TestCommits() {
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec("INSERT INTO t").WillReturnResult(sqlmock.NewResult(1, 1))
+	s.mock.ExpectCommit()
+
+	s.NoError(s.manager.InTx(s.ctx, s.insert))
+}
+
+func (s *txSuite) TestRollsBackOnError() {
+	failure := errors.New("failure")
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec("INSERT INTO t").WillReturnResult(sqlmock.NewResult(1, 1))
+	s.mock.ExpectRollback()
+
+	err := s.manager.InTx(s.ctx, func(ctx context.Context) error {
+		s.Require().NoError(s.insert(ctx))
+
+		return failure
+	})
+	s.ErrorIs(err, failure)
+}
+
+func (s *txSuite) TestRollsBackOnPanic() {
+	s.mock.ExpectBegin()
+	s.mock.ExpectRollback()
+
+	s.PanicsWithValue("boom", func() {
+		_ = s.manager.InTx(s.ctx, func(context.Context) error {
+			panic("boom")
+		})
+	})
+}
+
+func (s *txSuite) TestOnCommit() {
+	var ran []string
+
+	OnCommit(s.ctx, func() { ran = append(ran, "outside") })
+	s.Equal([]string{"outside"}, ran)
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectCommit()
+
+	s.NoError(s.manager.InTx(s.ctx, func(ctx context.Context) error {
+		OnCommit(ctx, func() { ran = append(ran, "committed") })
+		s.Len(ran, 1)
+
+		return nil
+	}))
+	s.Equal([]string{"outside", "committed"}, ran)
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectRollback()
+
+	s.Error(s.manager.InTx(s.ctx, func(ctx context.Context) error {
+		OnCommit(ctx, func() { ran = append(ran, "rolled back") })
+
+		return errors.New("failure")
+	}))
+	s.Len(ran, 2)
+}
+
+func (s *txSuite) TestNestedCallsJoin() {
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec("INSERT INTO t").WillReturnResult(sqlmock.NewResult(1, 1))
+	s.mock.ExpectExec("INSERT INTO t").WillReturnResult(sqlmock.NewResult(1, 1))
+	s.mock.ExpectCommit()
+
+	err := s.manager.InTx(s.ctx, func(ctx context.Context) error {
+		if err := s.manager.InTx(ctx, s.insert, WithIsolation(sql.LevelSerializable)); err != nil {
+			return err
+		}
+
+		// the transaction of a repository method is left to InTx
+		tx, err := Begin(ctx, s.db)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		if _, err := tx.ExecContext(ctx, "INSERT INTO t VALUES (1);"); err != nil {
+			return err
+		}
+
+		return tx.Commit()
+	})
+	s.NoError(err)
+}
+
+func (s *txSuite) TestBeginWithoutTransaction() {
+	s.mock.ExpectBegin()
+	s.mock.ExpectCommit()
+
+	tx, err := Begin(s.ctx, s.db)
+	s.Require().NoError(err)
+	s.NoError(tx.Commit())
+}
+
+func (s *txSuite) TestIgnoresTransactionOfOtherDatabase() {
+	other, otherMock, err := sqlmock.New()
+	s.Require().NoError(err)
+
+	otherMock.ExpectBegin()
+	otherMock.ExpectCommit()
+	s.mock.ExpectExec("INSERT INTO t").WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err = NewTxManager(other, TxConfig{MaxAttempts: 1}).InTx(s.ctx, s.insert)
+	s.NoError(err)
+	s.NoError(otherMock.ExpectationsWereMet())
+}
+
+func (s *txSuite) TestRetriesSerializationFailures() {
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec("INSERT INTO t").WillReturnError(&pq.Error{Code: "40001"})
+	s.mock.ExpectRollback()
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec("INSERT INTO t").WillReturnResult(sqlmock.NewResult(1, 1))
+	s.mock.ExpectCommit().WillReturnError(&pq.Error{Code: "40P01"})
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec("INSERT INTO t").WillReturnResult(sqlmock.NewResult(1, 1))
+	s.mock.ExpectCommit()
+
+	s.NoError(s.manager.InTx(s.ctx, s.insert))
+}
+
+func (s *txSuite) TestGivesUpAfterMaxAttempts() {
+	for range 3 {
+		s.mock.ExpectBegin()
+		s.mock.ExpectExec("INSERT INTO t").WillReturnError(&pq.Error{Code: "40001"})
+		s.mock.ExpectRollback()
+	}
+
+	err := s.manager.InTx(s.ctx, s.insert)
+	s.True(Retryable(err))
+}
+
+func (s *txSuite) TestDoesNotRetryOtherErrors() {
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec("INSERT INTO t").WillReturnError(&pq.Error{Code: "23505"})
+	s.mock.ExpectRollback()
+
+	s.Error(s.manager.InTx(s.ctx, s.insert))
+}
+
+func TestParseIsolation(t *testing.T) {
+	for text, level := range map[string]sql.IsolationLevel{
+		"":                sql.LevelDefault,
+		"read committed":  sql.LevelReadCommitted,
+		"Repeatable Read": sql.LevelRepeatableRead,
+		"serializable":    sql.LevelSerializable,
+	} {
+		parsed, err := ParseIsolation(text)
+		require.NoError(t, err)
+		require.Equal(t, level, parsed, text)
+	}
+
+	_, err := ParseIsolation("snapshot")
+	require.EqualError(t, err, `unknown isolation level "snapshot"`)
+}
@@ -0,0 +1,228 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// envelopePrefix marks a value produced by Envelope.Encrypt
+	envelopePrefix = "enc:v2:"
+	// legacyPrefix marks a value sealed before the additional data was bound, with the key ID only
+	legacyPrefix = "enc:v1:"
+	separator    = ":"
+)
+
+var ErrMalformedCiphertext = errors.New("malformed ciphertext")
+
+// Cipher encrypts and decrypts sensitive field values before they are stored. The additional
+// data names where the value is stored, see AdditionalData, a value only decrypts with the
+// additional data it was encrypted with so it cannot be copied to another row or column.
+type Cipher interface {
+	Encrypt(plaintext, additionalData string) (string, error)
+	Decrypt(value, additionalData string) (string, error)
+}
+
+// AdditionalData returns the additional data of a value stored in the column of the row with
+// the given ID
+func AdditionalData(table, column, id string) string {
+	return table + "." + column + "/" + id
+}
+
+// NoopCipher stores values as is, used when no keyring is configured
+type NoopCipher struct{}
+
+func (NoopCipher) Encrypt(plaintext, _ string) (string, error) {
+	return plaintext, nil
+}
+
+func (NoopCipher) Decrypt(value, _ string) (string, error) {
+	return value, nil
+}
+
+// Envelope implements AES-GCM envelope encryption: every value is sealed with a
+// fresh data key, which is in turn sealed with the primary key of the keyring.
+// The stored value has the form
+//
+//	enc:v2:<key id>:<base64 wrapped data key>:<base64 ciphertext>
+//
+// so it can be decrypted after the primary key has been rotated. The ciphertext is bound to
+// the key ID and the additional data of the value. Values of the enc:v1 form were bound to
+// the key ID only, they still decrypt and are rewritten by a rotation.
+type Envelope struct {
+	keyring *Keyring
+}
+
+// NewEnvelope creates a new envelope cipher backed by the given keyring
+func NewEnvelope(keyring *Keyring) *Envelope {
+	return &Envelope{
+		keyring: keyring,
+	}
+}
+
+func (e *Envelope) Encrypt(plaintext, additionalData string) (string, error) {
+	keyID := e.keyring.PrimaryKeyID()
+	kek, err := e.keyring.key(keyID)
+	if err != nil {
+		return "", err
+	}
+
+	dek := make([]byte, keySize)
+	if _, err := rand.Read(dek); err != nil {
+		return "", fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	wrapped, err := seal(kek, dek, []byte(keyID))
+	if err != nil {
+		return "", fmt.Errorf("failed to wrap data key: %w", err)
+	}
+
+	ciphertext, err := seal(dek, []byte(plaintext), []byte(keyID+separator+additionalData))
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt value: %w", err)
+	}
+
+	return envelopePrefix + strings.Join([]string{
+		keyID,
+		base64.RawStdEncoding.EncodeToString(wrapped),
+		base64.RawStdEncoding.EncodeToString(ciphertext),
+	}, separator), nil
+}
+
+// Decrypt opens a value produced by Encrypt with the same additional data. Values
+// without the envelope prefix were written before encryption was enabled and are
+// returned unchanged.
+func (e *Envelope) Decrypt(value, additionalData string) (string, error) {
+	sealed, legacy, ok := trimPrefix(value)
+	if !ok {
+		return value, nil
+	}
+
+	parts := strings.Split(sealed, separator)
+	if len(parts) != 3 {
+		return "", ErrMalformedCiphertext
+	}
+
+	keyID := parts[0]
+	bound := keyID + separator + additionalData
+	if legacy {
+		bound = keyID
+	}
+	kek, err := e.keyring.key(keyID)
+	if err != nil {
+		return "", err
+	}
+
+	wrapped, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrMalformedCiphertext, err)
+	}
+
+	ciphertext, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrMalformedCiphertext, err)
+	}
+
+	dek, err := open(kek, wrapped, []byte(keyID))
+	if err != nil {
+		return "", fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+
+	plaintext, err := open(dek, ciphertext, []byte(bound))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %w", err)
+	}
+
+	return string(plaintext), nil
+}
+
+// NeedsRotation reports whether the value is stored in plaintext, sealed without
+// its additional data or sealed with a key other than the current primary key
+func (e *Envelope) NeedsRotation(value string) bool {
+	if _, legacy, ok := trimPrefix(value); !ok || legacy {
+		return true
+	}
+
+	keyID, ok := KeyID(value)
+
+	return !ok || keyID != e.keyring.PrimaryKeyID()
+}
+
+// IsEncrypted reports whether the value was produced by Envelope.Encrypt
+func IsEncrypted(value string) bool {
+	_, _, ok := trimPrefix(value)
+
+	return ok
+}
+
+// KeyID returns the ID of the key an encrypted value was sealed with
+func KeyID(value string) (string, bool) {
+	sealed, _, ok := trimPrefix(value)
+	if !ok {
+		return "", false
+	}
+
+	keyID, _, found := strings.Cut(sealed, separator)
+
+	return keyID, found
+}
+
+// trimPrefix returns the value without its envelope prefix and whether it is of the legacy form
+func trimPrefix(value string) (string, bool, bool) {
+	if sealed, ok := strings.CutPrefix(value, envelopePrefix); ok {
+		return sealed, false, true
+	}
+
+	if sealed, ok := strings.CutPrefix(value, legacyPrefix); ok {
+		return sealed, true, true
+	}
+
+	return "", false, false
+}
+
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(key, sealed, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return nil, ErrMalformedCiphertext
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+
+	return gcm.Open(nil, nonce, ciphertext, additionalData)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func containsSeparator(s string) bool {
+	return strings.Contains(s, separator)
+}
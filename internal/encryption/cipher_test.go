@@ -0,0 +1,160 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testKeyring(t *testing.T, primary string, ids ...string) *Keyring {
+	t.Helper()
+
+	keys := make(map[string][]byte, len(ids))
+	for i, id := range ids {
+		keys[id] = bytes.Repeat([]byte{byte(i + 1)}, keySize)
+	}
+
+	keyring, err := NewKeyring(primary, keys)
+	require.NoError(t, err)
+
+	return keyring
+}
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	cipher := NewEnvelope(testKeyring(t, "k1", "k1"))
+
+	for _, plaintext := range []string{"", "customer data", "with: separators: inside"} {
+		encrypted, err := cipher.Encrypt(plaintext, "tasks.tasks.description/1")
+		require.NoError(t, err)
+		require.True(t, IsEncrypted(encrypted))
+		require.NotContains(t, encrypted, "customer")
+
+		keyID, ok := KeyID(encrypted)
+		require.True(t, ok)
+		require.Equal(t, "k1", keyID)
+
+		decrypted, err := cipher.Decrypt(encrypted, "tasks.tasks.description/1")
+		require.NoError(t, err)
+		require.Equal(t, plaintext, decrypted)
+	}
+}
+
+func TestEnvelopeUsesFreshDataKeys(t *testing.T) {
+	cipher := NewEnvelope(testKeyring(t, "k1", "k1"))
+
+	first, err := cipher.Encrypt("same", "ad")
+	require.NoError(t, err)
+	second, err := cipher.Encrypt("same", "ad")
+	require.NoError(t, err)
+
+	require.NotEqual(t, first, second)
+}
+
+func TestEnvelopeDecryptPlaintextPassthrough(t *testing.T) {
+	cipher := NewEnvelope(testKeyring(t, "k1", "k1"))
+
+	got, err := cipher.Decrypt("written before encryption", "ad")
+	require.NoError(t, err)
+	require.Equal(t, "written before encryption", got)
+}
+
+func TestEnvelopeRotation(t *testing.T) {
+	old := NewEnvelope(testKeyring(t, "k1", "k1"))
+	encrypted, err := old.Encrypt("secret", "ad")
+	require.NoError(t, err)
+
+	rotated := NewEnvelope(testKeyring(t, "k2", "k1", "k2"))
+	require.True(t, rotated.NeedsRotation(encrypted))
+	require.True(t, rotated.NeedsRotation("plaintext"))
+
+	decrypted, err := rotated.Decrypt(encrypted, "ad")
+	require.NoError(t, err)
+	require.Equal(t, "secret", decrypted)
+
+	reencrypted, err := rotated.Encrypt(decrypted, "ad")
+	require.NoError(t, err)
+	require.False(t, rotated.NeedsRotation(reencrypted))
+}
+
+func TestEnvelopeDecryptErrors(t *testing.T) {
+	cipher := NewEnvelope(testKeyring(t, "k1", "k1"))
+	encrypted, err := cipher.Encrypt("secret", "ad")
+	require.NoError(t, err)
+
+	_, err = NewEnvelope(testKeyring(t, "k2", "k2")).Decrypt(encrypted, "ad")
+	require.True(t, errors.Is(err, ErrUnknownKey))
+
+	_, err = cipher.Decrypt("enc:v2:k1:only-two", "ad")
+	require.True(t, errors.Is(err, ErrMalformedCiphertext))
+
+	// replace a character inside the ciphertext, away from the trailing padding bits
+	i := len(encrypted) - 10
+	replacement := "A"
+	if encrypted[i] == 'A' {
+		replacement = "B"
+	}
+	_, err = cipher.Decrypt(encrypted[:i]+replacement+encrypted[i+1:], "ad")
+	require.Error(t, err)
+}
+
+func TestEnvelopeBindsAdditionalData(t *testing.T) {
+	cipher := NewEnvelope(testKeyring(t, "k1", "k1"))
+	encrypted, err := cipher.Encrypt("secret", AdditionalData("tasks.tasks", "description", "1"))
+	require.NoError(t, err)
+
+	// copied to another row or another column
+	_, err = cipher.Decrypt(encrypted, AdditionalData("tasks.tasks", "description", "2"))
+	require.Error(t, err)
+	_, err = cipher.Decrypt(encrypted, AdditionalData("tasks.jobs", "params", "1"))
+	require.Error(t, err)
+}
+
+func TestEnvelopeDecryptLegacy(t *testing.T) {
+	keyring := testKeyring(t, "k1", "k1")
+	kek, err := keyring.key("k1")
+	require.NoError(t, err)
+
+	// sealed as before the additional data was bound
+	dek := bytes.Repeat([]byte{9}, keySize)
+	wrapped, err := seal(kek, dek, []byte("k1"))
+	require.NoError(t, err)
+	ciphertext, err := seal(dek, []byte("secret"), []byte("k1"))
+	require.NoError(t, err)
+	legacy := "enc:v1:k1:" + base64.RawStdEncoding.EncodeToString(wrapped) + ":" + base64.RawStdEncoding.EncodeToString(ciphertext)
+
+	cipher := NewEnvelope(keyring)
+	require.True(t, IsEncrypted(legacy))
+	require.True(t, cipher.NeedsRotation(legacy))
+
+	decrypted, err := cipher.Decrypt(legacy, "ad")
+	require.NoError(t, err)
+	require.Equal(t, "secret", decrypted)
+}
+
+func TestLoadKeyring(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, keySize))
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"primary_key_id":"2026-01","keys":{"2026-01":"`+key+`"}}`), 0o600))
+
+	keyring, err := LoadKeyring(path)
+	require.NoError(t, err)
+	require.Equal(t, "2026-01", keyring.PrimaryKeyID())
+}
+
+func TestNewKeyringValidation(t *testing.T) {
+	_, err := NewKeyring("missing", map[string][]byte{"k1": make([]byte, keySize)})
+	require.True(t, errors.Is(err, ErrUnknownKey))
+
+	_, err = NewKeyring("k1", map[string][]byte{"k1": make([]byte, 16)})
+	require.Error(t, err)
+	require.True(t, strings.Contains(err.Error(), "32 bytes"))
+
+	_, err = NewKeyring("k:1", map[string][]byte{"k:1": make([]byte, keySize)})
+	require.Error(t, err)
+}
@@ -0,0 +1,90 @@
+package encryption
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// keySize is the size in bytes of every key encryption key (AES-256)
+const keySize = 32
+
+var ErrUnknownKey = errors.New("unknown encryption key")
+
+// Keyring holds the key encryption keys, indexed by key ID, and the ID of the
+// primary key used for all new encryptions
+type Keyring struct {
+	primary string
+	keys    map[string][]byte
+}
+
+// keyringFile is the on-disk representation of a keyring
+type keyringFile struct {
+	PrimaryKeyID string            `json:"primary_key_id"`
+	Keys         map[string]string `json:"keys"`
+}
+
+// LoadKeyring reads a JSON keyring file of the form
+//
+//	{"primary_key_id": "2026-01", "keys": {"2026-01": "<base64 encoded 32 byte key>"}}
+func LoadKeyring(path string) (*Keyring, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring file: %w", err)
+	}
+
+	var file keyringFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse keyring file: %w", err)
+	}
+
+	keys := make(map[string][]byte, len(file.Keys))
+	for id, encoded := range file.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode key %q: %w", id, err)
+		}
+
+		keys[id] = key
+	}
+
+	return NewKeyring(file.PrimaryKeyID, keys)
+}
+
+// NewKeyring creates a keyring from raw keys, validating that the primary key exists
+func NewKeyring(primary string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[primary]; !ok {
+		return nil, fmt.Errorf("%w: primary key %q is not in the keyring", ErrUnknownKey, primary)
+	}
+
+	for id, key := range keys {
+		if id == "" || containsSeparator(id) {
+			return nil, fmt.Errorf("invalid key id %q", id)
+		}
+
+		if len(key) != keySize {
+			return nil, fmt.Errorf("key %q must be %d bytes, got %d", id, keySize, len(key))
+		}
+	}
+
+	return &Keyring{
+		primary: primary,
+		keys:    keys,
+	}, nil
+}
+
+// PrimaryKeyID returns the ID of the key used for new encryptions
+func (k *Keyring) PrimaryKeyID() string {
+	return k.primary
+}
+
+func (k *Keyring) key(id string) ([]byte, error) {
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, id)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,11 @@
+//go:generate go run github.com/dmarkham/enumer -type=DeliveryStatus -transform=lower --trimprefix DeliveryStatus_ -json -text -sql -output=delivery_status_enumer.go
+package enum
+
+type DeliveryStatus int
+
+//nolint:revive,stylecheck
+const (
+	DeliveryStatus_Pending DeliveryStatus = iota + 1
+	DeliveryStatus_Succeeded
+	DeliveryStatus_Failed
+)
@@ -0,0 +1,13 @@
+//go:generate go run github.com/dmarkham/enumer -type=JobStatus -transform=lower --trimprefix JobStatus_ -json -text -sql -output=job_status_enumer.go
+package enum
+
+type JobStatus int
+
+//nolint:revive,stylecheck
+const (
+	JobStatus_Queued JobStatus = iota + 1
+	JobStatus_Running
+	JobStatus_Succeeded
+	JobStatus_Failed
+	JobStatus_Cancelled
+)
@@ -0,0 +1,76 @@
+package events
+
+import (
+	"sync"
+
+	"go-tasks-api/internal/model"
+)
+
+//go:generate go run -mod=mod go.uber.org/mock/mockgen -package mocks -destination=./mocks/bus_mock.go -source=bus.go
+type Bus interface {
+	// Subscribe registers a subscriber with the given buffer size. The returned channel is
+	// closed when cancel is called, or when the subscriber falls behind and its buffer
+	// overflows, in which case it is expected to catch up from the event log.
+	Subscribe(buffer int) (<-chan model.Event, func())
+	// Publish delivers the event to all subscribers without blocking
+	Publish(event model.Event)
+}
+
+// localBus fans out published events to in-process subscribers
+type localBus struct {
+	mu          sync.RWMutex
+	subscribers map[*subscription]struct{}
+}
+
+type subscription struct {
+	ch   chan model.Event
+	once sync.Once
+}
+
+// NewBus creates an empty in-process event bus
+func NewBus() Bus {
+	return &localBus{
+		subscribers: make(map[*subscription]struct{}),
+	}
+}
+
+func (b *localBus) Subscribe(buffer int) (<-chan model.Event, func()) {
+	sub := &subscription{
+		ch: make(chan model.Event, buffer),
+	}
+
+	b.mu.Lock()
+	b.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+
+	return sub.ch, func() {
+		b.remove(sub)
+	}
+}
+
+func (b *localBus) Publish(event model.Event) {
+	b.mu.RLock()
+	overflowed := make([]*subscription, 0)
+	for sub := range b.subscribers {
+		select {
+		case sub.ch <- event:
+		default:
+			overflowed = append(overflowed, sub)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, sub := range overflowed {
+		b.remove(sub)
+	}
+}
+
+func (b *localBus) remove(sub *subscription) {
+	b.mu.Lock()
+	delete(b.subscribers, sub)
+	b.mu.Unlock()
+
+	sub.once.Do(func() {
+		close(sub.ch)
+	})
+}
@@ -0,0 +1,113 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-tasks-api/internal/enum"
+	"go-tasks-api/internal/model"
+	"go-tasks-api/internal/repository/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestBusFanOut(t *testing.T) {
+	bus := NewBus()
+	first, cancelFirst := bus.Subscribe(1)
+	second, cancelSecond := bus.Subscribe(1)
+	defer cancelSecond()
+
+	bus.Publish(model.Event{ID: 1})
+	require.Equal(t, int64(1), (<-first).ID)
+	require.Equal(t, int64(1), (<-second).ID)
+
+	cancelFirst()
+	_, ok := <-first
+	require.False(t, ok)
+
+	// cancelling twice is harmless
+	cancelFirst()
+}
+
+func TestBusDropsSlowSubscribers(t *testing.T) {
+	bus := NewBus()
+	slow, cancel := bus.Subscribe(1)
+	defer cancel()
+
+	bus.Publish(model.Event{ID: 1})
+	bus.Publish(model.Event{ID: 2})
+
+	require.Equal(t, int64(1), (<-slow).ID)
+	_, ok := <-slow
+	require.False(t, ok)
+}
+
+func TestRecorderAppends(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	eventRepo := mocks.NewMockEventConnector(ctrl)
+
+	task := model.Task{ID: uuid.New(), Title: "title", Status: enum.Status_Todo}
+	msg, err := model.NewTaskMessage(model.EventTaskCreated, task)
+	require.NoError(t, err)
+	msg.ID = 3
+
+	eventRepo.EXPECT().Append(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, event model.Event) (model.Event, error) {
+			require.Equal(t, model.EventTaskCreated, event.Type)
+			require.Equal(t, task.ID, event.TaskID)
+			require.Equal(t, enum.Status_Todo, event.Status)
+			require.Equal(t, int64(3), event.OutboxID)
+			require.Contains(t, string(event.Data), `"title":"title"`)
+			event.ID = 7
+
+			return event, nil
+		})
+
+	require.NoError(t, NewRecorder(eventRepo).Publish(context.Background(), msg))
+}
+
+func TestRecorderAppendFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	eventRepo := mocks.NewMockEventConnector(ctrl)
+
+	mockError := errors.New("db error")
+	eventRepo.EXPECT().Append(gomock.Any(), gomock.Any()).Return(model.Event{}, mockError)
+
+	err := NewRecorder(eventRepo).Publish(context.Background(), model.OutboxMessage{EventType: model.EventTaskDeleted, AggregateID: uuid.New()})
+	require.True(t, errors.Is(err, mockError))
+}
+
+// sinkFunc adapts a function to the Sink interface
+type sinkFunc func(ctx context.Context, event model.Event) error
+
+func (f sinkFunc) Handle(ctx context.Context, event model.Event) error {
+	return f(ctx, event)
+}
+
+func TestRecorderSinks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	eventRepo := mocks.NewMockEventConnector(ctrl)
+	eventRepo.EXPECT().Append(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, event model.Event) (model.Event, error) {
+			event.ID = 9
+
+			return event, nil
+		})
+
+	var handled []int64
+	mockError := errors.New("sink error")
+	failing := sinkFunc(func(_ context.Context, event model.Event) error { return mockError })
+	collecting := sinkFunc(func(_ context.Context, event model.Event) error {
+		handled = append(handled, event.ID)
+
+		return nil
+	})
+
+	// a failing sink does not prevent the next ones from running
+	err := NewRecorder(eventRepo, failing, collecting).Publish(context.Background(), model.OutboxMessage{EventType: model.EventTaskUpdated, AggregateID: uuid.New()})
+	require.ErrorIs(t, err, mockError)
+	require.Equal(t, []int64{9}, handled)
+}
@@ -0,0 +1,41 @@
+package events
+
+import (
+	"context"
+
+	"go-tasks-api/internal/model"
+	"go-tasks-api/internal/repository"
+
+	"github.com/rs/zerolog/log"
+)
+
+// invalidateBuffer is the number of events an invalidating subscriber may fall behind
+const invalidateBuffer = 256
+
+// Invalidate drops the tasks changed by any instance from the cache until the context is
+// cancelled. The whole cache is purged when the subscription falls behind and is renewed.
+func Invalidate(ctx context.Context, bus Bus, cache repository.CachedTaskConnector) {
+	for ctx.Err() == nil {
+		ch, cancel := bus.Subscribe(invalidateBuffer)
+		follow(ctx, ch, cache)
+		cancel()
+	}
+}
+
+// follow forgets the tasks of the events until the context is cancelled or the channel closed
+func follow(ctx context.Context, ch <-chan model.Event, cache repository.CachedTaskConnector) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-ch:
+			if !ok {
+				log.Warn().Msg("task cache fell behind the events, purging it")
+				cache.Purge()
+
+				return
+			}
+			cache.Forget(event.TaskID)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go-tasks-api/internal/model"
+	"go-tasks-api/internal/repository/mocks"
+
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+)
+
+func TestInvalidateForgetsChangedTasks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	cache := mocks.NewMockCachedTaskConnector(ctrl)
+	bus := NewBus()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	id := uuid.New()
+	forgotten := make(chan struct{})
+	var once sync.Once
+	cache.EXPECT().Forget(id).Do(func(uuid.UUID) { once.Do(func() { close(forgotten) }) }).MinTimes(1)
+
+	done := make(chan struct{})
+	go func() {
+		Invalidate(ctx, bus, cache)
+		close(done)
+	}()
+
+	// publish until the subscription is registered
+	for published := false; !published; {
+		bus.Publish(model.Event{ID: 1, TaskID: id})
+		select {
+		case <-forgotten:
+			published = true
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	cancel()
+	<-done
+}
+
+func TestInvalidatePurgesWhenBehind(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	cache := mocks.NewMockCachedTaskConnector(ctrl)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := make(chan model.Event)
+	close(ch)
+
+	cache.EXPECT().Purge()
+	follow(ctx, ch, cache)
+}
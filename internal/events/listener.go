@@ -0,0 +1,175 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go-tasks-api/internal/repository"
+
+	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+)
+
+// catchUpBatchSize is the number of events read from the event log per query after a reconnect
+const catchUpBatchSize = 500
+
+type ListenerConfig struct {
+	// MinReconnectInterval and MaxReconnectInterval bound the delay between reconnection attempts
+	MinReconnectInterval time.Duration
+	MaxReconnectInterval time.Duration
+	// PingInterval is the idle time after which the connection is checked
+	PingInterval time.Duration
+}
+
+// notificationSource is implemented by *pq.Listener
+type notificationSource interface {
+	NotificationChannel() <-chan *pq.Notification
+	Ping() error
+}
+
+// Listener publishes on the local bus the events appended by any instance, so SSE streams
+// and caches of every replica see all changes
+type Listener struct {
+	dsn       string
+	eventRepo repository.EventConnector
+	bus       Bus
+	cfg       ListenerConfig
+
+	// lastID is the highest event ID published or present when the listener started, events
+	// after it are fetched after a reconnect
+	lastID int64
+	// behind is set while events after lastID may be missing from the bus, they are caught up
+	// before the next notification is published
+	behind bool
+}
+
+// NewListener creates a new Listener of the events channel
+func NewListener(dsn string, eventRepo repository.EventConnector, bus Bus, cfg ListenerConfig) *Listener {
+	return &Listener{
+		dsn:       dsn,
+		eventRepo: eventRepo,
+		bus:       bus,
+		cfg:       cfg,
+	}
+}
+
+// Run listens for appended events until the context is cancelled. Lost connections are
+// re-established in the background, events appended in between are read from the event log.
+func (l *Listener) Run(ctx context.Context) error {
+	listener := pq.NewListener(l.dsn, l.cfg.MinReconnectInterval, l.cfg.MaxReconnectInterval, logListenerEvent)
+	defer listener.Close()
+
+	if err := listener.Listen(repository.EventsChannel); err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", repository.EventsChannel, err)
+	}
+
+	// read after listening, events appended in between are notified and those appended after a
+	// disconnect are caught up from this position
+	lastID, err := l.eventRepo.LastID(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read event log position: %w", err)
+	}
+	l.lastID = lastID
+
+	log.Info().Str("channel", repository.EventsChannel).Msg("events listener started")
+	l.listen(ctx, listener)
+	log.Info().Msg("events listener stopped")
+
+	return nil
+}
+
+func (l *Listener) listen(ctx context.Context, source notificationSource) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-source.NotificationChannel():
+			l.handle(ctx, n)
+		case <-time.After(l.cfg.PingInterval):
+			if l.behind {
+				l.catchUp(ctx)
+			}
+
+			// a broken connection is only noticed on use, the ping triggers the reconnect
+			go func() {
+				if err := source.Ping(); err != nil {
+					log.Warn().Err(err).Msg("events listener ping failed")
+				}
+			}()
+		}
+	}
+}
+
+// handle publishes the notified event, a nil notification signals a reconnect. While behind,
+// the notified event is left to the catch up so no earlier event is skipped.
+func (l *Listener) handle(ctx context.Context, n *pq.Notification) {
+	// events are notified once committed, catching up on the missing ones brings this one too
+	if n == nil || l.behind {
+		l.catchUp(ctx)
+
+		return
+	}
+
+	id, err := strconv.ParseInt(n.Extra, 10, 64)
+	if err != nil {
+		log.Warn().Str("payload", n.Extra).Msg("ignoring malformed event notification")
+
+		return
+	}
+
+	// appends commit in ID order, an event at or before lastID was published already
+	if id <= l.lastID {
+		return
+	}
+
+	event, err := l.eventRepo.Get(ctx, id)
+	if err != nil {
+		log.Error().Err(err).Int64("event_id", id).Msg("failed to fetch notified event")
+		l.catchUp(ctx)
+
+		return
+	}
+
+	l.bus.Publish(event)
+	l.lastID = event.ID
+}
+
+// catchUp publishes the events appended while the connection was lost or whose fetch failed,
+// the listener stays behind until it succeeds
+func (l *Listener) catchUp(ctx context.Context) {
+	l.behind = true
+	for {
+		batch, err := l.eventRepo.ListAfter(ctx, l.lastID, catchUpBatchSize)
+		if err != nil {
+			log.Error().Err(err).Int64("after_id", l.lastID).Msg("failed to catch up on events")
+
+			return
+		}
+
+		for _, event := range batch {
+			l.bus.Publish(event)
+			l.lastID = event.ID
+		}
+
+		if len(batch) < catchUpBatchSize {
+			l.behind = false
+
+			return
+		}
+	}
+}
+
+func logListenerEvent(event pq.ListenerEventType, err error) {
+	switch event {
+	case pq.ListenerEventConnected:
+		log.Debug().Msg("events listener connected")
+	case pq.ListenerEventDisconnected:
+		log.Warn().Err(err).Msg("events listener disconnected")
+	case pq.ListenerEventReconnected:
+		log.Info().Msg("events listener reconnected")
+	case pq.ListenerEventConnectionAttemptFailed:
+		log.Warn().Err(err).Msg("events listener connection attempt failed")
+	}
+}
@@ -0,0 +1,171 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	busmocks "go-tasks-api/internal/events/mocks"
+	"go-tasks-api/internal/model"
+	"go-tasks-api/internal/repository/mocks"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// fakeSource stands in for a *pq.Listener
+type fakeSource struct {
+	ch    chan *pq.Notification
+	pings chan struct{}
+}
+
+func newFakeSource() *fakeSource {
+	return &fakeSource{
+		ch:    make(chan *pq.Notification),
+		pings: make(chan struct{}, 1),
+	}
+}
+
+func (f *fakeSource) NotificationChannel() <-chan *pq.Notification {
+	return f.ch
+}
+
+func (f *fakeSource) Ping() error {
+	select {
+	case f.pings <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+func newTestListener(t *testing.T) (*Listener, *mocks.MockEventConnector, *busmocks.MockBus) {
+	ctrl := gomock.NewController(t)
+	eventRepo := mocks.NewMockEventConnector(ctrl)
+	bus := busmocks.NewMockBus(ctrl)
+
+	return NewListener("", eventRepo, bus, ListenerConfig{PingInterval: time.Hour}), eventRepo, bus
+}
+
+func TestListenerPublishesNotifiedEvent(t *testing.T) {
+	listener, eventRepo, bus := newTestListener(t)
+	event := model.Event{ID: 12, Type: model.EventTaskUpdated}
+
+	eventRepo.EXPECT().Get(gomock.Any(), int64(12)).Return(event, nil)
+	bus.EXPECT().Publish(event)
+
+	listener.handle(context.Background(), &pq.Notification{Channel: "task_events", Extra: "12"})
+	require.Equal(t, int64(12), listener.lastID)
+}
+
+func TestListenerIgnoresMalformedNotifications(t *testing.T) {
+	listener, _, _ := newTestListener(t)
+
+	listener.handle(context.Background(), &pq.Notification{Extra: "not-an-id"})
+	require.Zero(t, listener.lastID)
+}
+
+// TestListenerCatchesUpFailedFetches fails to fetch a notified event and to catch up on it, the
+// next notification publishes it before the events after it
+func TestListenerCatchesUpFailedFetches(t *testing.T) {
+	listener, eventRepo, bus := newTestListener(t)
+	listener.lastID = 5
+	source := newFakeSource()
+	dbErr := errors.New("db error")
+
+	published := make(chan model.Event, 3)
+	bus.EXPECT().Publish(gomock.Any()).Do(func(event model.Event) { published <- event }).Times(3)
+	gomock.InOrder(
+		eventRepo.EXPECT().Get(gomock.Any(), int64(6)).Return(model.Event{}, dbErr),
+		eventRepo.EXPECT().ListAfter(gomock.Any(), int64(5), catchUpBatchSize).Return(nil, dbErr),
+		eventRepo.EXPECT().ListAfter(gomock.Any(), int64(5), catchUpBatchSize).
+			Return([]model.Event{{ID: 6}, {ID: 7}}, nil),
+		eventRepo.EXPECT().Get(gomock.Any(), int64(8)).Return(model.Event{ID: 8}, nil),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		listener.listen(ctx, source)
+		close(done)
+	}()
+
+	source.ch <- &pq.Notification{Extra: "6"}
+	source.ch <- &pq.Notification{Extra: "7"}
+	source.ch <- &pq.Notification{Extra: "8"}
+
+	for _, id := range []int64{6, 7, 8} {
+		select {
+		case event := <-published:
+			require.Equal(t, id, event.ID)
+		case <-time.After(time.Second):
+			t.Fatalf("expected event %d to be published", id)
+		}
+	}
+
+	cancel()
+	<-done
+	require.Equal(t, int64(8), listener.lastID)
+	require.False(t, listener.behind)
+}
+
+func TestListenerCatchesUpAfterReconnect(t *testing.T) {
+	listener, eventRepo, bus := newTestListener(t)
+	listener.lastID = 5
+
+	eventRepo.EXPECT().ListAfter(gomock.Any(), int64(5), catchUpBatchSize).
+		Return([]model.Event{{ID: 6}, {ID: 8}}, nil)
+	gomock.InOrder(
+		bus.EXPECT().Publish(model.Event{ID: 6}),
+		bus.EXPECT().Publish(model.Event{ID: 8}),
+	)
+
+	// pq sends a nil notification once the connection was re-established
+	listener.handle(context.Background(), nil)
+	require.Equal(t, int64(8), listener.lastID)
+}
+
+// TestListenerCatchesUpBeforeFirstEvent reconnects before any event was notified, the events
+// appended to the empty log meanwhile are published
+func TestListenerCatchesUpBeforeFirstEvent(t *testing.T) {
+	listener, eventRepo, bus := newTestListener(t)
+
+	eventRepo.EXPECT().ListAfter(gomock.Any(), int64(0), catchUpBatchSize).Return([]model.Event{{ID: 1}}, nil)
+	bus.EXPECT().Publish(model.Event{ID: 1})
+
+	listener.handle(context.Background(), nil)
+	require.Equal(t, int64(1), listener.lastID)
+}
+
+func TestListenerSkipsPublishedEvents(t *testing.T) {
+	listener, _, _ := newTestListener(t)
+	listener.lastID = 8
+
+	// caught up after a reconnect before its notification arrived
+	listener.handle(context.Background(), &pq.Notification{Extra: "8"})
+	require.Equal(t, int64(8), listener.lastID)
+}
+
+func TestListenerPingsWhenIdle(t *testing.T) {
+	listener, _, _ := newTestListener(t)
+	listener.cfg.PingInterval = time.Millisecond
+	source := newFakeSource()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		listener.listen(ctx, source)
+		close(done)
+	}()
+
+	select {
+	case <-source.pings:
+	case <-time.After(time.Second):
+		t.Fatal("expected the idle connection to be pinged")
+	}
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,68 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: bus.go
+//
+// Generated by this command:
+//
+//	mockgen -package mocks -destination=./mocks/bus_mock.go -source=bus.go
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	model "go-tasks-api/internal/model"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockBus is a mock of Bus interface.
+type MockBus struct {
+	ctrl     *gomock.Controller
+	recorder *MockBusMockRecorder
+	isgomock struct{}
+}
+
+// MockBusMockRecorder is the mock recorder for MockBus.
+type MockBusMockRecorder struct {
+	mock *MockBus
+}
+
+// NewMockBus creates a new mock instance.
+func NewMockBus(ctrl *gomock.Controller) *MockBus {
+	mock := &MockBus{ctrl: ctrl}
+	mock.recorder = &MockBusMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBus) EXPECT() *MockBusMockRecorder {
+	return m.recorder
+}
+
+// Publish mocks base method.
+func (m *MockBus) Publish(event model.Event) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Publish", event)
+}
+
+// Publish indicates an expected call of Publish.
+func (mr *MockBusMockRecorder) Publish(event any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Publish", reflect.TypeOf((*MockBus)(nil).Publish), event)
+}
+
+// Subscribe mocks base method.
+func (m *MockBus) Subscribe(buffer int) (<-chan model.Event, func()) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Subscribe", buffer)
+	ret0, _ := ret[0].(<-chan model.Event)
+	ret1, _ := ret[1].(func())
+	return ret0, ret1
+}
+
+// Subscribe indicates an expected call of Subscribe.
+func (mr *MockBusMockRecorder) Subscribe(buffer any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Subscribe", reflect.TypeOf((*MockBus)(nil).Subscribe), buffer)
+}
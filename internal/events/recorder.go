@@ -0,0 +1,49 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go-tasks-api/internal/model"
+	"go-tasks-api/internal/repository"
+)
+
+// Sink receives every event after it was appended to the event log
+type Sink interface {
+	Handle(ctx context.Context, event model.Event) error
+}
+
+// Recorder is the outbox publisher feeding the event log and the sinks. Appending an event
+// notifies every instance, whose Listener publishes it on the local bus.
+type Recorder struct {
+	eventRepo repository.EventConnector
+	sinks     []Sink
+}
+
+// NewRecorder creates a Recorder which appends events to the event log, then hands them
+// to the sinks once they have an ID
+func NewRecorder(eventRepo repository.EventConnector, sinks ...Sink) *Recorder {
+	return &Recorder{
+		eventRepo: eventRepo,
+		sinks:     sinks,
+	}
+}
+
+// Publish records the outbox message as an event. A message published again after a relay
+// failure keeps the ID of its first append.
+func (r *Recorder) Publish(ctx context.Context, msg model.OutboxMessage) error {
+	event, err := r.eventRepo.Append(ctx, msg.Event())
+	if err != nil {
+		return fmt.Errorf("failed to append %s event: %w", msg.EventType, err)
+	}
+
+	errs := make([]error, 0)
+	for _, sink := range r.sinks {
+		if err := sink.Handle(ctx, event); err != nil {
+			errs = append(errs, fmt.Errorf("failed to handle %s event %d: %w", event.Type, event.ID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
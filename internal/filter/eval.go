@@ -0,0 +1,197 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Row returns the value of a field of the row an expression is evaluated against: a string, a
+// time.Time for Time fields, or nil for null
+type Row func(field string) any
+
+// truth is a value of the three-valued logic of SQL
+type truth int
+
+const (
+	unknown truth = iota
+	isFalse
+	isTrue
+)
+
+func truthOf(b bool) truth {
+	if b {
+		return isTrue
+	}
+
+	return isFalse
+}
+
+// Eval reports whether the row matches the expression, with the semantics of the SQL the
+// expression compiles to: comparisons with null are unknown, not keeps them unknown and rows
+// are only matched when the expression is true. Strings compare by bytes like the C collation.
+func Eval(expr Expr, fields Fields, row Row) (bool, error) {
+	t, err := eval(expr, fields, row)
+	if err != nil {
+		return false, err
+	}
+
+	return t == isTrue, nil
+}
+
+func eval(expr Expr, fields Fields, row Row) (truth, error) {
+	switch e := expr.(type) {
+	case And:
+		l, r, err := evalBoth(e.Left, e.Right, fields, row)
+		if err != nil {
+			return unknown, err
+		}
+
+		switch {
+		case l == isFalse || r == isFalse:
+			return isFalse, nil
+		case l == unknown || r == unknown:
+			return unknown, nil
+		default:
+			return isTrue, nil
+		}
+	case Or:
+		l, r, err := evalBoth(e.Left, e.Right, fields, row)
+		if err != nil {
+			return unknown, err
+		}
+
+		switch {
+		case l == isTrue || r == isTrue:
+			return isTrue, nil
+		case l == unknown || r == unknown:
+			return unknown, nil
+		default:
+			return isFalse, nil
+		}
+	case Not:
+		t, err := eval(e.Expr, fields, row)
+		if err != nil {
+			return unknown, err
+		}
+
+		switch t {
+		case isTrue:
+			return isFalse, nil
+		case isFalse:
+			return isTrue, nil
+		default:
+			return unknown, nil
+		}
+	case Comparison:
+		return evalComparison(e, fields, row)
+	default:
+		return unknown, fmt.Errorf("unsupported filter expression %T", expr)
+	}
+}
+
+func evalBoth(left, right Expr, fields Fields, row Row) (truth, truth, error) {
+	l, err := eval(left, fields, row)
+	if err != nil {
+		return unknown, unknown, err
+	}
+
+	r, err := eval(right, fields, row)
+	if err != nil {
+		return unknown, unknown, err
+	}
+
+	return l, r, nil
+}
+
+func evalComparison(e Comparison, fields Fields, row Row) (truth, error) {
+	field, ok := fields[e.Field]
+	if !ok {
+		return unknown, fmt.Errorf("unknown filter field %q", e.Field)
+	}
+
+	value := row(e.Field)
+
+	// IS NULL and IS NOT NULL
+	if e.Value == nil {
+		if e.Op == OpNe {
+			return truthOf(value != nil), nil
+		}
+
+		return truthOf(value == nil), nil
+	}
+
+	// IS DISTINCT FROM treats null as a value
+	if value == nil {
+		if e.Op == OpNe {
+			return isTrue, nil
+		}
+
+		return unknown, nil
+	}
+
+	if e.Op == OpContains {
+		s, ok := value.(string)
+		if !ok {
+			return unknown, fmt.Errorf("cannot apply contains to field %q", e.Field)
+		}
+
+		return truthOf(strings.Contains(strings.ToLower(s), strings.ToLower(e.Value.(string)))), nil
+	}
+
+	cmp, err := compare(field.Type, value, e.Value)
+	if err != nil {
+		return unknown, fmt.Errorf("failed to compare field %q: %w", e.Field, err)
+	}
+
+	switch e.Op {
+	case OpEq:
+		return truthOf(cmp == 0), nil
+	case OpNe:
+		return truthOf(cmp != 0), nil
+	case OpGt:
+		return truthOf(cmp > 0), nil
+	case OpGe:
+		return truthOf(cmp >= 0), nil
+	case OpLt:
+		return truthOf(cmp < 0), nil
+	case OpLe:
+		return truthOf(cmp <= 0), nil
+	default:
+		return unknown, fmt.Errorf("unsupported filter operator %q", e.Op)
+	}
+}
+
+// compare compares the value of a row with the value of a comparison
+func compare(typ Type, value, operand any) (int, error) {
+	switch typ {
+	case Time:
+		t, ok := value.(time.Time)
+		if !ok {
+			return 0, fmt.Errorf("expected a time, got %T", value)
+		}
+
+		return t.Compare(operand.(time.Time)), nil
+	case UUID:
+		v, err := uuid.Parse(fmt.Sprint(value))
+		if err != nil {
+			return 0, err
+		}
+
+		o, err := uuid.Parse(operand.(string))
+		if err != nil {
+			return 0, err
+		}
+
+		return strings.Compare(v.String(), o.String()), nil
+	default:
+		s, ok := value.(string)
+		if !ok {
+			return 0, fmt.Errorf("expected a string, got %T", value)
+		}
+
+		return strings.Compare(s, operand.(string)), nil
+	}
+}
@@ -0,0 +1,115 @@
+// Package filter parses filter expressions of list endpoints, such as
+// status eq 'todo' and (title contains 'invoice' or created_at gt 2026-01-01),
+// into an AST checked against a whitelist of fields, compiles them to SQL and evaluates them
+// against rows held in memory
+package filter
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// MaxLength bounds the length of filter expressions in bytes
+	MaxLength = 1024
+	// maxDepth bounds the nesting of parentheses and not
+	maxDepth = 32
+)
+
+// Op is a comparison operator
+type Op string
+
+const (
+	OpEq       Op = "eq"
+	OpNe       Op = "ne"
+	OpGt       Op = "gt"
+	OpGe       Op = "ge"
+	OpLt       Op = "lt"
+	OpLe       Op = "le"
+	OpContains Op = "contains"
+)
+
+// Type is the type of the values of a field
+type Type int
+
+const (
+	String Type = iota
+	// Enum fields are strings restricted to the values of the field
+	Enum
+	UUID
+	Time
+)
+
+// Field is a filterable field
+type Field struct {
+	// Column is the SQL expression the field compiles to
+	Column string
+	Type   Type
+	// Ops are the operators allowed on the field
+	Ops []Op
+	// Values are the allowed values of Enum fields
+	Values []string
+	// Nullable fields can be compared to null with eq and ne
+	Nullable bool
+}
+
+// Fields is the whitelist of filterable fields by name
+type Fields map[string]Field
+
+// Expr is a node of a filter expression
+type Expr interface {
+	expr()
+}
+
+// And matches when both sides match
+type And struct {
+	Left, Right Expr
+}
+
+// Or matches when either side matches
+type Or struct {
+	Left, Right Expr
+}
+
+// Not matches when the expression does not match
+type Not struct {
+	Expr Expr
+}
+
+// Comparison compares a field with a value, Value is a string, or a time.Time for Time fields,
+// and nil when compared to null
+type Comparison struct {
+	Field string
+	Op    Op
+	Value any
+}
+
+func (And) expr()        {}
+func (Or) expr()         {}
+func (Not) expr()        {}
+func (Comparison) expr() {}
+
+// Error points at the offending part of a filter expression
+type Error struct {
+	// Pos is the byte offset of the offending token
+	Pos     int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Message, e.Pos)
+}
+
+// parseTime accepts dates and RFC 3339 timestamps, dates are midnight UTC. Timestamps with an
+// offset are converted to UTC, the timestamps of tasks are stored and compared in UTC.
+func parseTime(s string) (time.Time, bool) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, true
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t.UTC(), true
+	}
+
+	return time.Time{}, false
+}
@@ -0,0 +1,167 @@
+package filter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testFields = Fields{
+	"title":  {Column: "title", Type: String, Ops: []Op{OpEq, OpNe, OpContains}},
+	"status": {Column: "status", Type: Enum, Ops: []Op{OpEq, OpNe}, Values: []string{"todo", "done"}},
+	"id":     {Column: "id", Type: UUID, Ops: []Op{OpEq}},
+	"created_at": {
+		Column: "created_at",
+		Type:   Time,
+		Ops:    []Op{OpEq, OpGt, OpLt},
+	},
+	"updated_at": {Column: "updated_at", Type: Time, Ops: []Op{OpEq, OpNe, OpGt}, Nullable: true},
+}
+
+func TestParse(t *testing.T) {
+	expr, err := Parse(`status eq 'todo' and (title contains 'invoice' or created_at gt 2026-01-01)`, testFields)
+	require.NoError(t, err)
+	require.Equal(t, And{
+		Left: Comparison{Field: "status", Op: OpEq, Value: "todo"},
+		Right: Or{
+			Left:  Comparison{Field: "title", Op: OpContains, Value: "invoice"},
+			Right: Comparison{Field: "created_at", Op: OpGt, Value: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		},
+	}, expr)
+}
+
+func TestParseTimeOffset(t *testing.T) {
+	expr, err := Parse(`updated_at eq 2026-01-01T12:30:00+02:00`, testFields)
+	require.NoError(t, err)
+	require.Equal(t, Comparison{Field: "updated_at", Op: OpEq, Value: time.Date(2026, 1, 1, 10, 30, 0, 0, time.UTC)}, expr)
+}
+
+func TestParsePrecedenceAndKeywords(t *testing.T) {
+	// and binds tighter than or, keywords and operators are case-insensitive
+	expr, err := Parse(`NOT title EQ 'it''s' OR status eq 'done' AND updated_at ne null`, testFields)
+	require.NoError(t, err)
+	require.Equal(t, Or{
+		Left: Not{Expr: Comparison{Field: "title", Op: OpEq, Value: "it's"}},
+		Right: And{
+			Left:  Comparison{Field: "status", Op: OpEq, Value: "done"},
+			Right: Comparison{Field: "updated_at", Op: OpNe, Value: nil},
+		},
+	}, expr)
+}
+
+func TestParseEmpty(t *testing.T) {
+	expr, err := Parse("  ", testFields)
+	require.NoError(t, err)
+	require.Nil(t, expr)
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input   string
+		pos     int
+		message string
+	}{
+		{`priority eq 'high'`, 0, "unknown field 'priority', filterable fields are created_at, id, status, title, updated_at"},
+		{`status gt 'todo'`, 7, "operator 'gt' is not supported on status, use one of eq, ne"},
+		{`status eq 'open'`, 10, "invalid value 'open', expected one of todo, done"},
+		{`title eq invoice`, 9, "unexpected 'invoice', expected a quoted string"},
+		{`created_at gt 2026-13-01`, 14, "invalid date '2026-13-01', expected YYYY-MM-DD or an RFC 3339 timestamp"},
+		{`id eq 'nope'`, 6, "invalid UUID 'nope'"},
+		{`created_at eq null`, 14, "null can only be compared with eq and ne on nullable fields"},
+		{`(title eq 'a'`, 13, "unexpected end of filter, expected ')'"},
+		{`title eq 'a' title eq 'b'`, 13, "unexpected 'title', expected 'and', 'or' or end of filter"},
+		{`title eq 'a`, 9, "unterminated string"},
+		{`title = 'a'`, 6, "unexpected character '='"},
+		{`title eq`, 8, "unexpected end of filter, expected a quoted string"},
+		{`and title eq 'a'`, 0, "unexpected 'and', expected a field"},
+		{`title eq 'a' and`, 16, "unexpected end of filter, expected a field"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := Parse(tt.input, testFields)
+
+			var fErr *Error
+			require.ErrorAs(t, err, &fErr)
+			require.Equal(t, tt.pos, fErr.Pos)
+			require.Contains(t, fErr.Message, tt.message)
+		})
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	deep := ""
+	for range maxDepth + 1 {
+		deep += "("
+	}
+	_, err := Parse(deep+"title eq 'a'", testFields)
+	require.ErrorContains(t, err, "nested too deeply")
+
+	long := "title eq '"
+	for len(long) <= MaxLength {
+		long += "a"
+	}
+	_, err = Parse(long+"'", testFields)
+	require.ErrorContains(t, err, "too long")
+}
+
+func TestSQL(t *testing.T) {
+	expr, err := Parse(`status eq 'todo' and not (title contains 'Invoice' or updated_at eq null) and created_at lt 2026-01-01`, testFields)
+	require.NoError(t, err)
+
+	cond, args, err := SQL(expr, testFields, []any{"owner"})
+	require.NoError(t, err)
+	require.Equal(t, "((status = $2 AND NOT ((strpos(lower(title), lower($3)) > 0 OR updated_at IS NULL))) AND created_at < $4)", cond)
+	require.Equal(t, []any{"owner", "todo", "Invoice", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}, args)
+}
+
+func TestEval(t *testing.T) {
+	updated := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	row := func(updatedAt any) Row {
+		return func(field string) any {
+			switch field {
+			case "title":
+				return "Pay the Invoice"
+			case "status":
+				return "todo"
+			case "id":
+				return "3b241101-e2bb-4255-8caf-4136c566a962"
+			case "created_at":
+				return time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+			default:
+				return updatedAt
+			}
+		}
+	}
+
+	tests := []struct {
+		filter    string
+		updatedAt any
+		want      bool
+	}{
+		{`title contains 'invoice' and status eq 'todo'`, nil, true},
+		{`id eq '3B241101-E2BB-4255-8CAF-4136C566A962'`, nil, true},
+		{`created_at lt 2026-01-01 or status eq 'done'`, nil, false},
+		{`updated_at eq null`, nil, true},
+		{`updated_at ne null`, updated, true},
+		// ne is IS DISTINCT FROM, null differs from any value
+		{`updated_at ne 2026-02-01`, nil, true},
+		// comparisons with null are unknown and stay unknown when negated
+		{`updated_at gt 2026-01-01`, nil, false},
+		{`not (updated_at gt 2026-01-01)`, nil, false},
+		{`not (updated_at gt 2026-01-01) or status eq 'todo'`, nil, true},
+		{`not (updated_at gt 2026-03-01)`, updated, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			expr, err := Parse(tt.filter, testFields)
+			require.NoError(t, err)
+
+			got, err := Eval(expr, testFields, row(tt.updatedAt))
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
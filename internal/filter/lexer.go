@@ -0,0 +1,104 @@
+package filter
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenLParen
+	tokenRParen
+	// tokenWord is an identifier, keyword, operator or unquoted value such as a date
+	tokenWord
+	// tokenString is a single-quoted value, quotes are escaped by doubling them
+	tokenString
+)
+
+type token struct {
+	kind tokenKind
+	// text is the word, or the unquoted value of strings
+	text string
+	// pos is the byte offset of the token in the expression
+	pos int
+}
+
+// describe returns the token as shown in error messages
+func (t token) describe() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of filter"
+	case tokenString:
+		return "'" + strings.ReplaceAll(t.text, "'", "''") + "'"
+	default:
+		return "'" + t.text + "'"
+	}
+}
+
+// lex splits the expression into tokens, the last one is always tokenEOF
+func lex(input string) ([]token, error) {
+	tokens := make([]token, 0)
+	for i := 0; i < len(input); {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+		case c == '\'':
+			tok, next, err := lexString(input, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, tok)
+			i = next
+		case isWordByte(c):
+			start := i
+			for i < len(input) && isWordByte(input[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: input[start:i], pos: start})
+		default:
+			r, _ := utf8.DecodeRuneInString(input[i:])
+
+			return nil, &Error{Pos: i, Message: "unexpected character '" + string(r) + "'"}
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(input)}), nil
+}
+
+func lexString(input string, start int) (token, int, error) {
+	var b strings.Builder
+	for i := start + 1; i < len(input); i++ {
+		if input[i] != '\'' {
+			b.WriteByte(input[i])
+
+			continue
+		}
+
+		if i+1 < len(input) && input[i+1] == '\'' {
+			b.WriteByte('\'')
+			i++
+
+			continue
+		}
+
+		return token{kind: tokenString, text: b.String(), pos: start}, i + 1, nil
+	}
+
+	return token{}, 0, &Error{Pos: start, Message: "unterminated string"}
+}
+
+// isWordByte reports whether c can be part of an unquoted word, which includes the characters
+// of dates and timestamps
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '_' || c == '-' || c == ':' || c == '.' || c == '+'
+}
@@ -0,0 +1,238 @@
+package filter
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Parse parses the expression, checking fields, operators and values against the whitelist.
+// An empty expression returns a nil Expr, which matches everything.
+func Parse(input string, fields Fields) (Expr, error) {
+	if strings.TrimSpace(input) == "" {
+		return nil, nil
+	}
+
+	if len(input) > MaxLength {
+		return nil, &Error{Pos: MaxLength, Message: "filter is too long"}
+	}
+
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens, fields: fields}
+	expr, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.errorf(tok, "unexpected %s, expected 'and', 'or' or end of filter", tok.describe())
+	}
+
+	return expr, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	fields Fields
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+
+	return tok
+}
+
+// keyword reports whether the next token is the keyword, keywords are case-insensitive
+func (p *parser) keyword(kw string) bool {
+	tok := p.peek()
+
+	return tok.kind == tokenWord && strings.EqualFold(tok.text, kw)
+}
+
+func (p *parser) errorf(tok token, format string, args ...any) *Error {
+	return &Error{Pos: tok.pos, Message: fmt.Sprintf(format, args...)}
+}
+
+// parseOr parses and-expressions separated by or, depth counts the enclosing groups
+func (p *parser) parseOr(depth int) (Expr, error) {
+	left, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword("or") {
+		p.next()
+		right, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = Or{Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd(depth int) (Expr, error) {
+	left, err := p.parseUnary(depth)
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword("and") {
+		p.next()
+		right, err := p.parseUnary(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = And{Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseUnary(depth int) (Expr, error) {
+	tok := p.peek()
+	if depth >= maxDepth {
+		return nil, p.errorf(tok, "filter is nested too deeply")
+	}
+
+	if p.keyword("not") {
+		p.next()
+		expr, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		return Not{Expr: expr}, nil
+	}
+
+	if tok.kind == tokenLParen {
+		p.next()
+		expr, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, p.errorf(closing, "unexpected %s, expected ')'", closing.describe())
+		}
+
+		return expr, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	name := p.next()
+	if name.kind != tokenWord || strings.EqualFold(name.text, "and") || strings.EqualFold(name.text, "or") {
+		return nil, p.errorf(name, "unexpected %s, expected a field", name.describe())
+	}
+
+	field, ok := p.fields[name.text]
+	if !ok {
+		return nil, p.errorf(name, "unknown field %s, filterable fields are %s", name.describe(), p.fieldNames())
+	}
+
+	opTok := p.next()
+	if opTok.kind != tokenWord {
+		return nil, p.errorf(opTok, "unexpected %s, expected an operator", opTok.describe())
+	}
+
+	op := Op(strings.ToLower(opTok.text))
+	if !slices.Contains(field.Ops, op) {
+		return nil, p.errorf(opTok, "operator %s is not supported on %s, use one of %s",
+			opTok.describe(), name.text, joinOps(field.Ops))
+	}
+
+	valueTok := p.next()
+	value, err := p.parseValue(field, op, valueTok)
+	if err != nil {
+		return nil, err
+	}
+
+	return Comparison{Field: name.text, Op: op, Value: value}, nil
+}
+
+func (p *parser) parseValue(field Field, op Op, tok token) (any, error) {
+	if tok.kind == tokenWord && strings.EqualFold(tok.text, "null") {
+		if !field.Nullable || (op != OpEq && op != OpNe) {
+			return nil, p.errorf(tok, "null can only be compared with eq and ne on nullable fields")
+		}
+
+		return nil, nil
+	}
+
+	switch field.Type {
+	case Time:
+		if tok.kind != tokenWord {
+			return nil, p.errorf(tok, "unexpected %s, expected a date or timestamp", tok.describe())
+		}
+
+		t, ok := parseTime(tok.text)
+		if !ok {
+			return nil, p.errorf(tok, "invalid date %s, expected YYYY-MM-DD or an RFC 3339 timestamp", tok.describe())
+		}
+
+		return t, nil
+	case UUID:
+		if tok.kind != tokenString {
+			return nil, p.errorf(tok, "unexpected %s, expected a quoted UUID", tok.describe())
+		}
+
+		if _, err := uuid.Parse(tok.text); err != nil {
+			return nil, p.errorf(tok, "invalid UUID %s", tok.describe())
+		}
+
+		return tok.text, nil
+	case Enum:
+		if tok.kind != tokenString {
+			return nil, p.errorf(tok, "unexpected %s, expected a quoted string", tok.describe())
+		}
+
+		if !slices.Contains(field.Values, tok.text) {
+			return nil, p.errorf(tok, "invalid value %s, expected one of %s", tok.describe(), strings.Join(field.Values, ", "))
+		}
+
+		return tok.text, nil
+	default:
+		if tok.kind != tokenString {
+			return nil, p.errorf(tok, "unexpected %s, expected a quoted string", tok.describe())
+		}
+
+		return tok.text, nil
+	}
+}
+
+func (p *parser) fieldNames() string {
+	names := make([]string, 0, len(p.fields))
+	for name := range p.fields {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return strings.Join(names, ", ")
+}
+
+func joinOps(ops []Op) string {
+	names := make([]string, 0, len(ops))
+	for _, op := range ops {
+		names = append(names, string(op))
+	}
+
+	return strings.Join(names, ", ")
+}
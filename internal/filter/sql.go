@@ -0,0 +1,95 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+)
+
+var sqlOps = map[Op]string{
+	OpEq: "=",
+	OpNe: "IS DISTINCT FROM",
+	OpGt: ">",
+	OpGe: ">=",
+	OpLt: "<",
+	OpLe: "<=",
+}
+
+// SQL compiles the expression to a parameterised SQL condition. Values are appended to args and
+// referenced by numbered placeholders, so the condition can follow other parameters of a query.
+func SQL(expr Expr, fields Fields, args []any) (string, []any, error) {
+	c := &compiler{fields: fields, args: args}
+	cond, err := c.compile(expr)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return cond, c.args, nil
+}
+
+type compiler struct {
+	fields Fields
+	args   []any
+}
+
+func (c *compiler) compile(expr Expr) (string, error) {
+	switch e := expr.(type) {
+	case And:
+		return c.binary(e.Left, "AND", e.Right)
+	case Or:
+		return c.binary(e.Left, "OR", e.Right)
+	case Not:
+		cond, err := c.compile(e.Expr)
+		if err != nil {
+			return "", err
+		}
+
+		return "NOT (" + cond + ")", nil
+	case Comparison:
+		return c.comparison(e)
+	default:
+		return "", fmt.Errorf("unsupported filter expression %T", expr)
+	}
+}
+
+func (c *compiler) binary(left Expr, op string, right Expr) (string, error) {
+	l, err := c.compile(left)
+	if err != nil {
+		return "", err
+	}
+
+	r, err := c.compile(right)
+	if err != nil {
+		return "", err
+	}
+
+	return "(" + l + " " + op + " " + r + ")", nil
+}
+
+func (c *compiler) comparison(e Comparison) (string, error) {
+	field, ok := c.fields[e.Field]
+	if !ok {
+		return "", fmt.Errorf("unknown filter field %q", e.Field)
+	}
+
+	if e.Value == nil {
+		if e.Op == OpNe {
+			return field.Column + " IS NOT NULL", nil
+		}
+
+		return field.Column + " IS NULL", nil
+	}
+
+	c.args = append(c.args, e.Value)
+	placeholder := "$" + strconv.Itoa(len(c.args))
+
+	if e.Op == OpContains {
+		return "strpos(lower(" + field.Column + "), lower(" + placeholder + ")) > 0", nil
+	}
+
+	op, ok := sqlOps[e.Op]
+	if !ok {
+		return "", fmt.Errorf("unsupported filter operator %q", e.Op)
+	}
+
+	return field.Column + " " + op + " " + placeholder, nil
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"net/http"
+
+	"go-tasks-api/internal/apperr"
+	"go-tasks-api/internal/database"
+	"go-tasks-api/internal/model"
+	"go-tasks-api/internal/repository"
+	"go-tasks-api/internal/utils"
+)
+
+type Admin struct {
+	schemaRepo repository.SchemaConnector
+	window     database.SchemaWindow
+	expected   int64
+}
+
+// NewAdminHandler creates a new Admin handler, window holds the schema versions the service
+// supports and expected the version of its newest migration
+func NewAdminHandler(s repository.SchemaConnector, window database.SchemaWindow, expected int64) *Admin {
+	return &Admin{
+		schemaRepo: s,
+		window:     window,
+		expected:   expected,
+	}
+}
+
+// Schema returns the schema version of the database next to the versions the service expects
+func (a *Admin) Schema(w http.ResponseWriter, r *http.Request) {
+	current, err := a.schemaRepo.Version(r.Context())
+	if err != nil {
+		apperr.Write(w, r, err, "failed to read schema version")
+
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, model.SchemaVersion{
+		Current:    current,
+		Expected:   a.expected,
+		Min:        a.window.Min,
+		Max:        a.window.Max,
+		Compatible: a.window.Check(current) == nil,
+		Pending:    current < a.expected,
+	})
+}
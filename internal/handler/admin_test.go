@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-tasks-api/internal/database"
+	"go-tasks-api/internal/model"
+	"go-tasks-api/internal/repository/mocks"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/mock/gomock"
+)
+
+type adminTestSuite struct {
+	suite.Suite
+	ctrl       *gomock.Controller
+	mockSchema *mocks.MockSchemaConnector
+	router     *chi.Mux
+	recoder    *httptest.ResponseRecorder
+}
+
+func TestAdminHandler(t *testing.T) {
+	suite.Run(t, new(adminTestSuite))
+}
+
+// Setup test suite
+func (s *adminTestSuite) SetupTest() {
+	s.ctrl = gomock.NewController(s.T())
+	s.mockSchema = mocks.NewMockSchemaConnector(s.ctrl)
+	s.recoder = httptest.NewRecorder()
+	s.router = chi.NewRouter()
+
+	connector := NewAdminHandler(s.mockSchema, database.SchemaWindow{Min: 5, Max: 8}, 8)
+	s.router.Get("/admin/schema", connector.Schema)
+}
+
+// Assert expectations
+func (s *adminTestSuite) TearDownTest() {
+	s.ctrl.Finish()
+}
+
+func (s *adminTestSuite) schema() model.SchemaVersion {
+	req, err := http.NewRequestWithContext(s.T().Context(), http.MethodGet, "/admin/schema", nil)
+	s.Require().NoError(err)
+
+	s.router.ServeHTTP(s.recoder, req)
+	s.Require().Equal(http.StatusOK, s.recoder.Code)
+
+	var version model.SchemaVersion
+	s.Require().NoError(json.Unmarshal(s.recoder.Body.Bytes(), &version))
+
+	return version
+}
+
+// Success: the database runs all migrations of the service
+//
+// Return: 200
+func (s *adminTestSuite) TestSchemaUpToDate() {
+	s.mockSchema.EXPECT().Version(gomock.Any()).Return(int64(8), nil)
+
+	s.Equal(model.SchemaVersion{Current: 8, Expected: 8, Min: 5, Max: 8, Compatible: true}, s.schema())
+}
+
+// Success: migrations of the service are pending, the schema is still supported
+//
+// Return: 200
+func (s *adminTestSuite) TestSchemaPending() {
+	s.mockSchema.EXPECT().Version(gomock.Any()).Return(int64(6), nil)
+
+	s.Equal(model.SchemaVersion{Current: 6, Expected: 8, Min: 5, Max: 8, Compatible: true, Pending: true}, s.schema())
+}
+
+// Success: the database was migrated beyond what the service supports
+//
+// Return: 200
+func (s *adminTestSuite) TestSchemaTooNew() {
+	s.mockSchema.EXPECT().Version(gomock.Any()).Return(int64(9), nil)
+
+	s.Equal(model.SchemaVersion{Current: 9, Expected: 8, Min: 5, Max: 8}, s.schema())
+}
+
+// Error: the version table cannot be read
+//
+// Return: 500
+func (s *adminTestSuite) TestSchemaFailed() {
+	s.mockSchema.EXPECT().Version(gomock.Any()).Return(int64(0), errors.New("connection refused"))
+
+	req, err := http.NewRequestWithContext(s.T().Context(), http.MethodGet, "/admin/schema", nil)
+	s.Require().NoError(err)
+
+	s.router.ServeHTTP(s.recoder, req)
+	s.Equal(http.StatusInternalServerError, s.recoder.Code)
+}
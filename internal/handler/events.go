@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go-tasks-api/internal/apperr"
+	"go-tasks-api/internal/enum"
+	"go-tasks-api/internal/events"
+	"go-tasks-api/internal/model"
+	"go-tasks-api/internal/repository"
+	"go-tasks-api/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// replayBatchSize is the number of events read from the event log per query on resumption
+	replayBatchSize = 500
+	// subscriberBuffer is the number of live events buffered per stream before it is dropped
+	subscriberBuffer = 256
+	// reconnectDelay is the retry delay advertised to clients, in milliseconds
+	reconnectDelay = 3000
+)
+
+type Events struct {
+	eventRepo repository.EventConnector
+	bus       events.Bus
+	heartbeat time.Duration
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// eventFilter narrows a stream down to a status and/or a single task
+type eventFilter struct {
+	status enum.StatusType
+	taskID uuid.UUID
+}
+
+// NewEventsHandler creates a new Events handler streaming from the bus and resuming from the event log
+func NewEventsHandler(e repository.EventConnector, b events.Bus, heartbeat time.Duration) *Events {
+	return &Events{
+		eventRepo: e,
+		bus:       b,
+		heartbeat: heartbeat,
+		done:      make(chan struct{}),
+	}
+}
+
+// Close ends all open streams, it is called when the server shuts down
+func (a *Events) Close() {
+	a.closeOnce.Do(func() {
+		close(a.done)
+	})
+}
+
+// Stream sends task events as Server-Sent Events. Clients resume after a disconnect by sending
+// the ID of the last received event in the Last-Event-ID header (or last_event_id query parameter).
+func (a *Events) Stream(w http.ResponseWriter, r *http.Request) {
+	filter, lastEventID, fErr := parseStreamParams(r)
+	if len(fErr) > 0 {
+		apperr.Write(w, r, apperr.New(apperr.Validation, "failed to validate request parameters", fErr...),
+			"failed to stream events")
+
+		return
+	}
+
+	// subscribe before replaying so no event is lost between the two
+	live, cancel := a.bus.Subscribe(subscriberBuffer)
+	defer cancel()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", reconnectDelay); err != nil {
+		return
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	// events up to replayed were sent from the event log
+	replayed := lastEventID
+	if lastEventID > 0 {
+		for {
+			batch, err := a.eventRepo.ListAfter(r.Context(), replayed, replayBatchSize)
+			if err != nil {
+				// headers are already sent, closing the stream makes the client reconnect
+				return
+			}
+
+			for _, event := range batch {
+				replayed = event.ID
+				if !filter.matches(event) {
+					continue
+				}
+
+				if err := writeEvent(w, event); err != nil {
+					return
+				}
+			}
+
+			if err := rc.Flush(); err != nil {
+				return
+			}
+
+			if len(batch) < replayBatchSize {
+				break
+			}
+		}
+	}
+
+	ticker := time.NewTicker(a.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-a.done:
+			return
+		case event, ok := <-live:
+			if !ok {
+				// the stream fell behind, the client resumes from the event log
+				return
+			}
+
+			if event.ID <= replayed || !filter.matches(event) {
+				continue
+			}
+
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func parseStreamParams(r *http.Request) (eventFilter, int64, []utils.FieldError) {
+	var (
+		filter eventFilter
+		errs   = make([]utils.FieldError, 0)
+		query  = r.URL.Query()
+	)
+
+	if s := query.Get("status"); s != "" {
+		status, err := enum.StatusTypeString(s)
+		if err != nil {
+			errs = append(errs, utils.FieldError{Field: "status", Message: "invalid status value: " + err.Error()})
+		}
+		filter.status = status
+	}
+
+	if id := query.Get("task_id"); id != "" {
+		taskID, err := uuid.Parse(id)
+		if err != nil {
+			errs = append(errs, utils.FieldError{Field: "task_id", Message: "must be a valid uuid"})
+		}
+		filter.taskID = taskID
+	}
+
+	raw := r.Header.Get("Last-Event-ID")
+	if raw == "" {
+		raw = query.Get("last_event_id")
+	}
+
+	var lastEventID int64
+	if raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id < 0 {
+			errs = append(errs, utils.FieldError{Field: "Last-Event-ID", Message: "must be a non-negative event id"})
+		}
+		lastEventID = id
+	}
+
+	return filter, lastEventID, errs
+}
+
+// matches reports whether the event passes the filter. Deleted events carry no status and
+// pass a status filter, so clients can drop the task from their view.
+func (f eventFilter) matches(event model.Event) bool {
+	if f.taskID != uuid.Nil && event.TaskID != f.taskID {
+		return false
+	}
+
+	if f.status.IsAStatusType() && event.Type != model.EventTaskDeleted && event.Status != f.status {
+		return false
+	}
+
+	return true
+}
+
+func writeEvent(w http.ResponseWriter, event model.Event) error {
+	_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+
+	return err
+}
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-tasks-api/internal/enum"
+	"go-tasks-api/internal/events"
+	"go-tasks-api/internal/model"
+	"go-tasks-api/internal/repository/mocks"
+	"go-tasks-api/internal/utils"
+
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/mock/gomock"
+)
+
+type eventsTestSuite struct {
+	suite.Suite
+	ctrl       *gomock.Controller
+	mockEvents *mocks.MockEventConnector
+	bus        events.Bus
+	handler    *Events
+	server     *httptest.Server
+}
+
+func TestEventsHandler(t *testing.T) {
+	suite.Run(t, new(eventsTestSuite))
+}
+
+func (s *eventsTestSuite) SetupTest() {
+	s.ctrl = gomock.NewController(s.T())
+	s.mockEvents = mocks.NewMockEventConnector(s.ctrl)
+	s.bus = events.NewBus()
+	s.handler = NewEventsHandler(s.mockEvents, s.bus, 50*time.Millisecond)
+	s.server = httptest.NewServer(http.HandlerFunc(s.handler.Stream))
+}
+
+func (s *eventsTestSuite) TearDownTest() {
+	s.handler.Close()
+	s.server.Close()
+	s.ctrl.Finish()
+}
+
+// open connects to the stream and returns a reader positioned after the retry advice,
+// at which point the stream is subscribed to the bus
+func (s *eventsTestSuite) open(query string, lastEventID string) (*bufio.Reader, *http.Response) {
+	ctx, cancel := context.WithTimeout(s.T().Context(), 5*time.Second)
+	s.T().Cleanup(cancel)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.server.URL+"?"+query, nil)
+	s.Require().NoError(err)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	s.Require().NoError(err)
+	s.T().Cleanup(func() { resp.Body.Close() })
+
+	reader := bufio.NewReader(resp.Body)
+	if resp.StatusCode == http.StatusOK {
+		s.Equal("retry: 3000", s.readMessage(reader))
+	}
+
+	return reader, resp
+}
+
+// readMessage reads the next message, skipping heartbeats, and returns its lines joined by "|"
+func (s *eventsTestSuite) readMessage(reader *bufio.Reader) string {
+	for {
+		lines := make([]string, 0)
+		for {
+			line, err := reader.ReadString('\n')
+			s.Require().NoError(err)
+
+			line = strings.TrimSuffix(line, "\n")
+			if line == "" {
+				break
+			}
+			lines = append(lines, line)
+		}
+
+		if len(lines) == 1 && strings.HasPrefix(lines[0], ":") {
+			continue
+		}
+
+		return strings.Join(lines, "|")
+	}
+}
+
+// Success: Live events are streamed and filtered by status
+//
+// Return: 200
+func (s *eventsTestSuite) TestStreamLiveEvents() {
+	reader, resp := s.open("status=done", "")
+	s.Equal("text/event-stream", resp.Header.Get("Content-Type"))
+
+	todo := utils.GetMockUUID()
+	done := utils.GetMockUUID()
+	s.bus.Publish(model.Event{ID: 1, Type: model.EventTaskCreated, TaskID: todo, Status: enum.Status_Todo, Data: []byte(`{"id":"todo"}`)})
+	s.bus.Publish(model.Event{ID: 2, Type: model.EventTaskUpdated, TaskID: done, Status: enum.Status_Done, Data: []byte(`{"id":"done"}`)})
+	s.bus.Publish(model.Event{ID: 3, Type: model.EventTaskDeleted, TaskID: todo, Data: []byte(`{"id":"deleted"}`)})
+
+	s.Equal(`id: 2|event: task.updated|data: {"id":"done"}`, s.readMessage(reader))
+	s.Equal(`id: 3|event: task.deleted|data: {"id":"deleted"}`, s.readMessage(reader))
+}
+
+// Success: Events after Last-Event-ID are replayed from the event log before live events
+//
+// Return: 200
+func (s *eventsTestSuite) TestStreamResumesFromEventLog() {
+	taskID := utils.GetMockUUID()
+	other := utils.GetMockUUID()
+
+	s.mockEvents.EXPECT().ListAfter(gomock.Any(), int64(10), replayBatchSize).Return([]model.Event{
+		{ID: 11, Type: model.EventTaskCreated, TaskID: taskID, Status: enum.Status_Todo, Data: []byte(`{"n":11}`)},
+		{ID: 12, Type: model.EventTaskCreated, TaskID: other, Status: enum.Status_Todo, Data: []byte(`{"n":12}`)},
+	}, nil)
+
+	reader, _ := s.open("task_id="+taskID.String(), "10")
+	s.Equal(`id: 11|event: task.created|data: {"n":11}`, s.readMessage(reader))
+
+	// already replayed
+	s.bus.Publish(model.Event{ID: 11, Type: model.EventTaskCreated, TaskID: taskID, Data: []byte(`{"n":11}`)})
+	s.bus.Publish(model.Event{ID: 13, Type: model.EventTaskUpdated, TaskID: taskID, Data: []byte(`{"n":13}`)})
+
+	s.Equal(`id: 13|event: task.updated|data: {"n":13}`, s.readMessage(reader))
+}
+
+// Success: Heartbeats are sent while idle
+//
+// Return: 200
+func (s *eventsTestSuite) TestStreamHeartbeat() {
+	reader, _ := s.open("", "")
+
+	line, err := reader.ReadString('\n')
+	s.Require().NoError(err)
+	s.Equal(": heartbeat\n", line)
+}
+
+// BadRequest: Invalid filters and Last-Event-ID
+//
+// Return: 400
+func (s *eventsTestSuite) TestStreamBadRequest() {
+	_, resp := s.open("status=doing&task_id=abc", "x")
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+}
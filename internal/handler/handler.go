@@ -1,11 +1,30 @@
 package handler
 
+import (
+	"encoding/json"
+	"net/http"
+
+	"go-tasks-api/internal/apperr"
+)
+
 const (
-	internalError   = "internal_error"
-	validationError = "validation_error"
-	badRequest      = "bad_request"
-	notFound        = "not_found"
+	notAcceptable = "not_acceptable"
 
 	failedToCreateTask = "failed to create task"
 	taskNotFound       = "task not found"
+
+	failedToCreateWebhook = "failed to create webhook"
 )
+
+// errEmptyID is returned for routes matched without an id path param
+var errEmptyID = apperr.New(apperr.NotFound, "path param 'id' cannot be empty")
+
+// decode reads the JSON request body into v, malformed bodies are reported with the byte
+// offset of the error
+func decode(r *http.Request, v any) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return apperr.FromJSON(err)
+	}
+
+	return nil
+}
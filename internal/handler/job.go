@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-tasks-api/internal/apperr"
+	"go-tasks-api/internal/enum"
+	"go-tasks-api/internal/jobs"
+	"go-tasks-api/internal/model"
+	"go-tasks-api/internal/repository"
+	"go-tasks-api/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	failedToCreateJob = "failed to create job"
+	jobNotFound       = "job not found"
+)
+
+// resultExtensions are the file name extensions of job results by media type, the system
+// MIME tables may not know all of them
+var resultExtensions = map[string]string{
+	utils.MediaTypeJSON:   ".json",
+	utils.MediaTypeCSV:    ".csv",
+	utils.MediaTypeNDJSON: ".ndjson",
+}
+
+type Job struct {
+	jobRepo repository.JobConnector
+	kinds   jobs.Registry
+}
+
+// NewJobHandler creates a new Job handler accepting jobs of the given kinds
+func NewJobHandler(j repository.JobConnector, kinds jobs.Registry) *Job {
+	return &Job{
+		jobRepo: j,
+		kinds:   kinds,
+	}
+}
+
+// Create queues a job and answers 202 with its location, workers pick it up in the background
+func (a *Job) Create(w http.ResponseWriter, r *http.Request) {
+	var req model.JobCreateRequest
+	if err := decode(r, &req); err != nil {
+		apperr.Write(w, r, err, "failed to decode request body")
+
+		return
+	}
+
+	if err := a.validate(req); err != nil {
+		apperr.Write(w, r, err, failedToCreateJob)
+
+		return
+	}
+
+	job := model.Job{
+		ID:        uuid.New(),
+		Type:      req.Type,
+		Params:    req.Params,
+		Status:    enum.JobStatus_Queued,
+		CreatedAt: time.Now(),
+	}
+	if len(job.Params) == 0 {
+		job.Params = []byte("{}")
+	}
+
+	if err := a.jobRepo.Create(r.Context(), job); err != nil {
+		apperr.Write(w, r, err, failedToCreateJob)
+
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/jobs/"+job.ID.String())
+	utils.WriteJSON(w, http.StatusAccepted, job)
+}
+
+func (a *Job) Get(w http.ResponseWriter, r *http.Request) {
+	job, ok := a.get(w, r)
+	if !ok {
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, job)
+}
+
+// Cancel cancels a queued job right away, running jobs are stopped by their worker and get 202
+func (a *Job) Cancel(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		apperr.Write(w, r, errEmptyID, jobNotFound)
+
+		return
+	}
+
+	job, err := a.jobRepo.Cancel(r.Context(), id)
+	if err != nil {
+		apperr.Write(w, r, err, "failed to cancel job")
+
+		return
+	}
+
+	status := http.StatusOK
+	if !job.Finished() {
+		status = http.StatusAccepted
+	}
+
+	utils.WriteJSON(w, status, job)
+}
+
+// Result downloads the output of a succeeded job
+func (a *Job) Result(w http.ResponseWriter, r *http.Request) {
+	job, ok := a.get(w, r)
+	if !ok {
+		return
+	}
+
+	if job.Status != enum.JobStatus_Succeeded {
+		apperr.Write(w, r, apperr.New(apperr.Conflict, fmt.Sprintf("job is %s, only succeeded jobs have a result", job.Status)), "failed to get job result")
+
+		return
+	}
+
+	result, err := a.jobRepo.GetResult(r.Context(), job.ID.String())
+	if err != nil {
+		apperr.Write(w, r, err, "failed to get job result")
+
+		return
+	}
+
+	filename := "job-" + job.ID.String()
+	if mediaType, _, err := mime.ParseMediaType(result.ContentType); err == nil {
+		filename += resultExtensions[mediaType]
+	}
+
+	w.Header().Set("Content-Type", result.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(result.Data)
+}
+
+func (a *Job) get(w http.ResponseWriter, r *http.Request) (model.Job, bool) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		apperr.Write(w, r, errEmptyID, jobNotFound)
+
+		return model.Job{}, false
+	}
+
+	job, err := a.jobRepo.Get(r.Context(), id)
+	if err != nil {
+		apperr.Write(w, r, err, "failed to get job")
+
+		return model.Job{}, false
+	}
+
+	return job, true
+}
+
+// validate checks the request and its params against the kind of the job
+func (a *Job) validate(req model.JobCreateRequest) error {
+	vErr := req.Validate()
+	if len(vErr) > 0 {
+		return apperr.New(apperr.Validation, "failed to validate request body", vErr...)
+	}
+
+	kind, ok := a.kinds[req.Type]
+	if !ok {
+		return apperr.New(apperr.Validation, "failed to validate request body", utils.FieldError{
+			Field:   "type",
+			Pointer: "/type",
+			Message: fmt.Sprintf("unknown job type %q, types are %s", req.Type, strings.Join(a.kinds.Types(), ", ")),
+		})
+	}
+
+	vErr = kind.Validate(req.Params)
+	for i := range vErr {
+		vErr[i].Field = strings.TrimSuffix("params."+vErr[i].Field, ".params")
+		vErr[i].Pointer = "/params" + vErr[i].Pointer
+	}
+	if len(vErr) > 0 {
+		return apperr.New(apperr.Validation, "failed to validate params", vErr...)
+	}
+
+	return nil
+}
@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-tasks-api/internal/database"
+	"go-tasks-api/internal/enum"
+	"go-tasks-api/internal/jobs"
+	"go-tasks-api/internal/model"
+	"go-tasks-api/internal/repository"
+	"go-tasks-api/internal/repository/mocks"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/mock/gomock"
+)
+
+type jobTestSuite struct {
+	suite.Suite
+	ctrl      *gomock.Controller
+	connector *Job
+	mockJobs  *mocks.MockJobConnector
+	router    *chi.Mux
+	recoder   *httptest.ResponseRecorder
+}
+
+func TestJobHandler(t *testing.T) {
+	suite.Run(t, new(jobTestSuite))
+}
+
+// Setup test suite
+func (s *jobTestSuite) SetupTest() {
+	s.ctrl = gomock.NewController(s.T())
+	s.mockJobs = mocks.NewMockJobConnector(s.ctrl)
+
+	s.connector = NewJobHandler(s.mockJobs, jobs.TaskKinds(mocks.NewMockTaskConnector(s.ctrl), database.NoopTransactor{}))
+	s.recoder = httptest.NewRecorder()
+	s.router = chi.NewRouter()
+
+	s.router.Post("/jobs", s.connector.Create)
+	s.router.Get("/jobs/{id}", s.connector.Get)
+	s.router.Post("/jobs/{id}/cancel", s.connector.Cancel)
+	s.router.Get("/jobs/{id}/result", s.connector.Result)
+}
+
+// Assert expectations
+func (s *jobTestSuite) TearDownTest() {
+	s.ctrl.Finish()
+}
+
+// Accepted: An export job was queued
+//
+// Return: 202
+func (s *jobTestSuite) TestCreateJobAccepted() {
+	req, err := http.NewRequestWithContext(s.T().Context(), http.MethodPost, "/jobs",
+		strings.NewReader(`{"type": "tasks.export", "params": {"filter": "status eq 'todo'", "format": "csv"}}`))
+	s.Require().NoError(err)
+
+	var created model.Job
+	s.mockJobs.EXPECT().Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, job model.Job) error {
+			created = job
+			s.Equal(enum.JobStatus_Queued, job.Status)
+			s.Equal(jobs.TypeExportTasks, job.Type)
+
+			return nil
+		})
+
+	s.router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusAccepted, s.recoder.Code)
+	s.Equal("/api/v1/jobs/"+created.ID.String(), s.recoder.Header().Get("Location"))
+	s.NotContains(s.recoder.Body.String(), "params")
+}
+
+// BadRequest: The params do not fit the job type
+//
+// Return: 400
+func (s *jobTestSuite) TestCreateJobInvalidParams() {
+	req, err := http.NewRequestWithContext(s.T().Context(), http.MethodPost, "/jobs",
+		strings.NewReader(`{"type": "tasks.update", "params": {"status": "archived"}}`))
+	s.Require().NoError(err)
+
+	s.router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusBadRequest, s.recoder.Code)
+	s.Regexp(`"pointer":"/params/status"`, s.recoder.Body.String())
+}
+
+// BadRequest: The job type is unknown
+//
+// Return: 400
+func (s *jobTestSuite) TestCreateJobUnknownType() {
+	req, err := http.NewRequestWithContext(s.T().Context(), http.MethodPost, "/jobs", strings.NewReader(`{"type": "tasks.purge"}`))
+	s.Require().NoError(err)
+
+	s.router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusBadRequest, s.recoder.Code)
+	s.Regexp("types are tasks.export, tasks.import, tasks.update", s.recoder.Body.String())
+}
+
+// Accepted: Cancelling a running job is left to its worker
+//
+// Return: 202
+func (s *jobTestSuite) TestCancelRunningJob() {
+	id := uuid.New()
+	req, err := http.NewRequestWithContext(s.T().Context(), http.MethodPost, "/jobs/"+id.String()+"/cancel", nil)
+	s.Require().NoError(err)
+
+	s.mockJobs.EXPECT().Cancel(gomock.Any(), id.String()).
+		Return(model.Job{ID: id, Status: enum.JobStatus_Running, CancelRequested: true}, nil)
+
+	s.router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusAccepted, s.recoder.Code)
+}
+
+// Conflict: Finished jobs cannot be cancelled
+//
+// Return: 409
+func (s *jobTestSuite) TestCancelFinishedJob() {
+	id := uuid.New()
+	req, err := http.NewRequestWithContext(s.T().Context(), http.MethodPost, "/jobs/"+id.String()+"/cancel", nil)
+	s.Require().NoError(err)
+
+	s.mockJobs.EXPECT().Cancel(gomock.Any(), id.String()).Return(model.Job{}, repository.ErrJobFinished)
+
+	s.router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusConflict, s.recoder.Code)
+}
+
+// Success: The result of a succeeded export is downloaded
+//
+// Return: 200
+func (s *jobTestSuite) TestDownloadResult() {
+	id := uuid.New()
+	req, err := http.NewRequestWithContext(s.T().Context(), http.MethodGet, "/jobs/"+id.String()+"/result", nil)
+	s.Require().NoError(err)
+
+	s.mockJobs.EXPECT().Get(gomock.Any(), id.String()).Return(model.Job{ID: id, Status: enum.JobStatus_Succeeded}, nil)
+	s.mockJobs.EXPECT().GetResult(gomock.Any(), id.String()).
+		Return(model.JobResult{ContentType: "text/csv; charset=utf-8", Data: []byte("id\n")}, nil)
+
+	s.router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusOK, s.recoder.Code)
+	s.Equal("text/csv; charset=utf-8", s.recoder.Header().Get("Content-Type"))
+	s.Equal(`attachment; filename=job-`+id.String()+`.csv`, s.recoder.Header().Get("Content-Disposition"))
+	s.Equal("id\n", s.recoder.Body.String())
+}
+
+// Conflict: Running jobs have no result yet
+//
+// Return: 409
+func (s *jobTestSuite) TestDownloadResultOfRunningJob() {
+	id := uuid.New()
+	req, err := http.NewRequestWithContext(s.T().Context(), http.MethodGet, "/jobs/"+id.String()+"/result", nil)
+	s.Require().NoError(err)
+
+	s.mockJobs.EXPECT().Get(gomock.Any(), id.String()).Return(model.Job{ID: id, Status: enum.JobStatus_Running}, nil)
+
+	s.router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusConflict, s.recoder.Code)
+	s.Regexp("job is running", s.recoder.Body.String())
+}
@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"go-tasks-api/internal/utils"
+)
+
+// negotiate picks the encoder of the response from the Accept header, it writes a 406 when none
+// of the supported media types is acceptable
+func negotiate(w http.ResponseWriter, r *http.Request) (string, utils.Encoder, bool) {
+	w.Header().Add("Vary", "Accept")
+
+	mediaType, enc, ok := utils.Negotiate(r)
+	if !ok {
+		supported := make([]string, 0, len(utils.Encoders))
+		for _, e := range utils.Encoders {
+			supported = append(supported, e.MediaType)
+		}
+
+		utils.WriteJSONError(w, r, http.StatusNotAcceptable, utils.ErrorDescription{
+			Status:  http.StatusNotAcceptable,
+			Code:    notAcceptable,
+			Title:   "media type not acceptable",
+			Details: "supported media types are " + strings.Join(supported, ", "),
+		})
+
+		return "", nil, false
+	}
+
+	return mediaType, enc, true
+}
@@ -1,69 +1,163 @@
 package handler
 
 import (
-	"encoding/json"
-	"errors"
+	"context"
+	"database/sql"
 	"net/http"
+	"strings"
 	"time"
 
+	"go-tasks-api/internal/apperr"
+	"go-tasks-api/internal/database"
 	"go-tasks-api/internal/enum"
+	"go-tasks-api/internal/httpcache"
 	"go-tasks-api/internal/model"
 	"go-tasks-api/internal/repository"
 	"go-tasks-api/internal/utils"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
 )
 
+// streamFlushRows is the number of rows written between flushes of streamed lists
+const streamFlushRows = 100
+
 type Task struct {
 	taskRepo repository.TaskConnector
+	tx       database.Transactor
 }
 
-// NewTaskHandler creates a new Task handler
-func NewTaskHandler(t repository.TaskConnector) *Task {
+// NewTaskHandler creates a new Task handler, tx runs the repository calls which have to see
+// the same state of a task
+func NewTaskHandler(t repository.TaskConnector, tx database.Transactor) *Task {
 	return &Task{
 		taskRepo: t,
+		tx:       tx,
 	}
 }
 
 func (a *Task) List(w http.ResponseWriter, r *http.Request) {
-	tasks, err := a.taskRepo.List(r.Context())
+	mediaType, enc, ok := negotiate(w, r)
+	if !ok {
+		return
+	}
+
+	opts, err := listOptions(r)
 	if err != nil {
-		utils.WriteJSONError(w, http.StatusInternalServerError, utils.ErrorDescription{
-			Status:  http.StatusInternalServerError,
-			Code:    internalError,
-			Title:   "failed to list tasks",
-			Details: err.Error(),
-		})
+		apperr.Write(w, r, err, "failed to list tasks")
 
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusOK, tasks)
+	writeTasks(w, r, a.taskRepo, mediaType, enc, opts)
+}
+
+// writeTasks writes the tasks selected by the options in the negotiated media type, NDJSON
+// is streamed
+func writeTasks(w http.ResponseWriter, r *http.Request, taskRepo repository.TaskConnector, mediaType string, enc utils.Encoder, opts repository.ListOptions) {
+	if mediaType == utils.MediaTypeNDJSON {
+		streamTasks(w, r, taskRepo, enc, opts)
+
+		return
+	}
+
+	tasks, err := taskRepo.List(r.Context(), opts)
+	if err != nil {
+		apperr.Write(w, r, err, "failed to list tasks")
+
+		return
+	}
+
+	utils.WriteEncoded(w, enc, http.StatusOK, model.Project(tasks, opts.Fields))
+}
+
+// streamTasks writes the tasks as they are read from the database, flushing every streamFlushRows rows
+func streamTasks(w http.ResponseWriter, r *http.Request, taskRepo repository.TaskConnector, enc utils.Encoder, opts repository.ListOptions) {
+	rc := http.NewResponseController(w)
+	written := 0
+
+	err := taskRepo.Stream(r.Context(), opts, func(task model.Task) error {
+		// the status is only sent with the first row, so failing queries still get an error response
+		if written == 0 {
+			w.Header().Set("Content-Type", enc.ContentType())
+			w.WriteHeader(http.StatusOK)
+		}
+
+		var row any = task
+		if len(opts.Fields) > 0 {
+			row = model.TaskProjection{Task: task, Fields: opts.Fields}
+		}
+		if err := enc.Encode(w, row); err != nil {
+			return err
+		}
+
+		written++
+		if written%streamFlushRows == 0 {
+			return rc.Flush()
+		}
+
+		return nil
+	})
+	if err != nil {
+		if written == 0 {
+			apperr.Write(w, r, err, "failed to list tasks")
+
+			return
+		}
+
+		// the status was already sent, the client sees a truncated stream
+		log.Error().Err(err).Int("rows", written).Msg("failed to stream tasks")
+
+		return
+	}
+
+	if written == 0 {
+		w.Header().Set("Content-Type", enc.ContentType())
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// listOptions reads the filter, sort and fields query parameters of list requests, sort and
+// fields are comma separated
+func listOptions(r *http.Request) (repository.ListOptions, error) {
+	query := r.URL.Query()
+	opts, vErr := repository.NewListOptions(model.ViewDefinition{
+		Filter: query.Get("filter"),
+		Sort:   splitList(query.Get("sort")),
+		Fields: splitList(query.Get("fields")),
+	})
+	if len(vErr) > 0 {
+		for i := range vErr {
+			vErr[i].Pointer = "/query/" + vErr[i].Field
+		}
+
+		return repository.ListOptions{}, apperr.New(apperr.Validation, "invalid list parameters", vErr...)
+	}
+
+	return opts, nil
+}
+
+// splitList splits a comma separated query parameter, an empty parameter has no elements
+func splitList(param string) []string {
+	if param == "" {
+		return nil
+	}
+
+	return strings.Split(param, ",")
 }
 
 func (a *Task) Create(w http.ResponseWriter, r *http.Request) {
 	var req model.TaskCreateRequest
-	err := json.NewDecoder(r.Body).Decode(&req)
-	if err != nil {
-		utils.WriteJSONError(w, http.StatusInternalServerError, utils.ErrorDescription{
-			Status:  http.StatusInternalServerError,
-			Code:    internalError,
-			Title:   "failed to decode request body",
-			Details: err.Error(),
-		})
+	if err := decode(r, &req); err != nil {
+		apperr.Write(w, r, err, "failed to decode request body")
 
 		return
 	}
 
 	vErr := req.Validate()
 	if len(vErr) > 0 {
-		utils.WriteJSONError(w, http.StatusBadRequest, utils.ErrorDescription{
-			Code:    validationError,
-			Status:  http.StatusBadRequest,
-			Title:   failedToCreateTask,
-			Details: "failed to validate request body",
-		}, vErr...)
+		apperr.Write(w, r, apperr.New(apperr.Validation, "failed to validate request body", vErr...), failedToCreateTask)
 
 		return
 	}
@@ -72,15 +166,11 @@ func (a *Task) Create(w http.ResponseWriter, r *http.Request) {
 		ID:          uuid.New(),
 		Title:       utils.TrimString(req.Title),
 		Description: utils.TrimString(req.Description),
+		Status:      enum.Status_Todo,
 		CreatedAt:   time.Now(),
 	}
-	if err := a.taskRepo.Create(r.Context(), task); err != nil {
-		utils.WriteJSONError(w, http.StatusInternalServerError, utils.ErrorDescription{
-			Status:  http.StatusInternalServerError,
-			Code:    internalError,
-			Title:   failedToCreateTask,
-			Details: err.Error(),
-		})
+	if _, err := a.taskRepo.Create(r.Context(), task); err != nil {
+		apperr.Write(w, r, err, failedToCreateTask)
 
 		return
 	}
@@ -90,125 +180,83 @@ func (a *Task) Create(w http.ResponseWriter, r *http.Request) {
 		Title:       task.Title,
 		Description: task.Description,
 		CreatedAt:   task.CreatedAt,
-		Status:      enum.Status_Todo,
+		Status:      task.Status,
 	})
 }
 
 func (a *Task) Get(w http.ResponseWriter, r *http.Request) {
+	_, enc, ok := negotiate(w, r)
+	if !ok {
+		return
+	}
+
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		utils.WriteJSONError(w, http.StatusNotFound, utils.ErrorDescription{
-			Status:  http.StatusNotFound,
-			Code:    notFound,
-			Title:   taskNotFound,
-			Details: "path param 'id' cannot be empty",
-		})
+		apperr.Write(w, r, errEmptyID, taskNotFound)
 
 		return
 	}
 	task, err := a.taskRepo.Get(r.Context(), id)
 	if err != nil {
-		if errors.Is(err, repository.ErrNoRows) {
-			utils.WriteJSONError(w, http.StatusNotFound, utils.ErrorDescription{
-				Status:  http.StatusNotFound,
-				Code:    notFound,
-				Title:   taskNotFound,
-				Details: err.Error(),
-			})
-
-			return
-		}
-
-		utils.WriteJSONError(w, http.StatusInternalServerError, utils.ErrorDescription{
-			Status:  http.StatusInternalServerError,
-			Code:    internalError,
-			Title:   "failed to get task",
-			Details: err.Error(),
-		})
+		apperr.Write(w, r, err, "failed to get task")
 
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusOK, task)
+	// lets clients revalidate with If-Modified-Since, see httpcache.Policies.Middleware
+	lastModified := task.CreatedAt
+	if task.UpdatedAt != nil {
+		lastModified = *task.UpdatedAt
+	}
+	httpcache.SetLastModified(w, lastModified)
+
+	utils.WriteEncoded(w, enc, http.StatusOK, task)
 }
 
 func (a *Task) Update(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		utils.WriteJSONError(w, http.StatusNotFound, utils.ErrorDescription{
-			Status:  http.StatusNotFound,
-			Code:    notFound,
-			Title:   taskNotFound,
-			Details: "path param 'id' cannot be empty",
-		})
+		apperr.Write(w, r, errEmptyID, taskNotFound)
 
 		return
 	}
 
 	var req model.TaskUpdateRequest
-	err := json.NewDecoder(r.Body).Decode(&req)
-	if err != nil {
-		utils.WriteJSONError(w, http.StatusInternalServerError, utils.ErrorDescription{
-			Status:  http.StatusInternalServerError,
-			Code:    internalError,
-			Title:   "failed to decode request body",
-			Details: err.Error(),
-		})
+	if err := decode(r, &req); err != nil {
+		apperr.Write(w, r, err, "failed to decode request body")
 
 		return
 	}
 
 	vErr := req.Validate()
 	if len(vErr) > 0 {
-		utils.WriteJSONError(w, http.StatusBadRequest, utils.ErrorDescription{
-			Code:    validationError,
-			Status:  http.StatusBadRequest,
-			Title:   failedToCreateTask,
-			Details: "failed to validate request body",
-		}, vErr...)
+		apperr.Write(w, r, apperr.New(apperr.Validation, "failed to validate request body", vErr...), failedToCreateTask)
 
 		return
 	}
 
-	task, err := a.taskRepo.Get(r.Context(), id)
-	if err != nil {
-		if errors.Is(err, repository.ErrNoRows) {
-			utils.WriteJSONError(w, http.StatusNotFound, utils.ErrorDescription{
-				Status:  http.StatusNotFound,
-				Code:    notFound,
-				Title:   taskNotFound,
-				Details: err.Error(),
-			})
-
-			return
+	// a concurrent update of the task between both calls fails to serialize and is retried
+	var task model.Task
+	err := a.tx.InTx(r.Context(), func(ctx context.Context) error {
+		var err error
+		if task, err = a.taskRepo.Get(ctx, id); err != nil {
+			return err
 		}
 
-		utils.WriteJSONError(w, http.StatusInternalServerError, utils.ErrorDescription{
-			Status:  http.StatusInternalServerError,
-			Code:    internalError,
-			Title:   "failed to get task",
-			Details: err.Error(),
-		})
-
-		return
-	}
+		task.Title = utils.TrimString(req.Title)
+		task.Description = utils.TrimString(req.Description)
+		// ignore error as it is already validated
+		t, _ := enum.StatusTypeString(req.Status)
+		task.Status = t
+		now := time.Now()
+		task.UpdatedAt = &now
 
-	task.Title = utils.TrimString(req.Title)
-	task.Description = utils.TrimString(req.Description)
-	// ignore error as it is already validated
-	t, _ := enum.StatusTypeString(req.Status)
-	task.Status = t
-	now := time.Now()
-	task.UpdatedAt = &now
+		task, err = a.taskRepo.Update(ctx, task)
 
-	task, err = a.taskRepo.Update(r.Context(), task)
+		return err
+	}, database.WithIsolation(sql.LevelRepeatableRead))
 	if err != nil {
-		utils.WriteJSONError(w, http.StatusInternalServerError, utils.ErrorDescription{
-			Status:  http.StatusInternalServerError,
-			Code:    internalError,
-			Title:   "failed to update task",
-			Details: err.Error(),
-		})
+		apperr.Write(w, r, err, "failed to update task")
 
 		return
 	}
@@ -219,35 +267,14 @@ func (a *Task) Update(w http.ResponseWriter, r *http.Request) {
 func (a *Task) Delete(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		utils.WriteJSONError(w, http.StatusNotFound, utils.ErrorDescription{
-			Status:  http.StatusNotFound,
-			Code:    notFound,
-			Title:   taskNotFound,
-			Details: "path param 'id' cannot be empty",
-		})
+		apperr.Write(w, r, errEmptyID, taskNotFound)
 
 		return
 	}
 
 	err := a.taskRepo.Delete(r.Context(), id)
 	if err != nil {
-		if errors.Is(err, repository.ErrNoRows) {
-			utils.WriteJSONError(w, http.StatusNotFound, utils.ErrorDescription{
-				Status:  http.StatusNotFound,
-				Code:    notFound,
-				Title:   taskNotFound,
-				Details: err.Error(),
-			})
-
-			return
-		}
-
-		utils.WriteJSONError(w, http.StatusInternalServerError, utils.ErrorDescription{
-			Status:  http.StatusInternalServerError,
-			Code:    internalError,
-			Title:   "failed to delete task",
-			Details: err.Error(),
-		})
+		apperr.Write(w, r, err, "failed to delete task")
 
 		return
 	}
@@ -2,16 +2,20 @@ package handler
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
 
+	"go-tasks-api/internal/database"
 	"go-tasks-api/internal/enum"
+	"go-tasks-api/internal/filter"
 	"go-tasks-api/internal/model"
 	"go-tasks-api/internal/repository"
 	"go-tasks-api/internal/repository/mocks"
@@ -40,7 +44,7 @@ func (s *taskTestSuite) SetupTest() {
 	s.ctrl = gomock.NewController(s.T())
 	s.mockTasks = mocks.NewMockTaskConnector(s.ctrl)
 
-	s.connector = NewTaskHandler(s.mockTasks)
+	s.connector = NewTaskHandler(s.mockTasks, database.NoopTransactor{})
 	s.recoder = httptest.NewRecorder()
 	s.router = chi.NewRouter()
 
@@ -70,13 +74,13 @@ func (s *taskTestSuite) TestCreateTaskSuccess() {
 	defer req.Body.Close()
 
 	s.mockTasks.EXPECT().Create(gomock.Any(), gomock.Any()).
-		DoAndReturn(func(ctx context.Context, a model.Task) error {
+		DoAndReturn(func(ctx context.Context, a model.Task) (bool, error) {
 			// validate fields
-			if a.Title != "test title" {
-				return errors.New("incorrect params")
+			if a.Title != "test title" || a.Status != enum.Status_Todo {
+				return false, errors.New("incorrect params")
 			}
 
-			return nil
+			return true, nil
 		})
 
 	s.router.ServeHTTP(s.recoder, req)
@@ -104,6 +108,24 @@ func (s *taskTestSuite) TestTaskBadRequestDocumentNumber() {
 	s.Regexp("title", string(resBody))
 }
 
+// BadRequest: Request body is not valid JSON
+//
+// Returns: 400
+func (s *taskTestSuite) TestCreateTaskMalformedBody() {
+	req, err := http.NewRequestWithContext(s.T().Context(), http.MethodPost, "/tasks",
+		strings.NewReader(`{ "title": "test title", }`))
+	s.Require().NoError(err)
+	defer req.Body.Close()
+
+	s.router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusBadRequest, s.recoder.Code)
+	resBody, err := io.ReadAll(s.recoder.Body)
+	s.NoError(err)
+	s.Regexp("bad_request", string(resBody))
+	s.Regexp("byte offset 26", string(resBody))
+}
+
 // InternalServerError: Task creation failed at database
 //
 // Return: 500
@@ -120,13 +142,13 @@ func (s *taskTestSuite) TestTaskCreateFailed() {
 	defer req.Body.Close()
 
 	s.mockTasks.EXPECT().Create(gomock.Any(), gomock.Any()).
-		DoAndReturn(func(ctx context.Context, a model.Task) error {
+		DoAndReturn(func(ctx context.Context, a model.Task) (bool, error) {
 			// validate fields
 			if a.Title != "test title" {
-				return errors.New("incorrect params")
+				return false, errors.New("incorrect params")
 			}
 
-			return mockDBError
+			return false, mockDBError
 		})
 
 	s.router.ServeHTTP(s.recoder, req)
@@ -166,6 +188,7 @@ func (s *taskTestSuite) TestGetTaskSuccess() {
 	s.NoError(err)
 
 	s.JSONEq(string(expectedJson), string(resBody))
+	s.Equal(expected.CreatedAt.UTC().Format(http.TimeFormat), s.recoder.Header().Get("Last-Modified"))
 }
 
 // InternalServerError: Failed to get task by ID
@@ -247,7 +270,7 @@ func (s *taskTestSuite) TestListTasksSuccess() {
 		},
 	}
 
-	s.mockTasks.EXPECT().List(gomock.Any()).Return(expected, nil)
+	s.mockTasks.EXPECT().List(gomock.Any(), repository.ListOptions{}).Return(expected, nil)
 
 	s.router.ServeHTTP(s.recoder, req)
 
@@ -271,7 +294,7 @@ func (s *taskTestSuite) TestListTasksFailure() {
 
 	mockDBError := errors.New("some-db-error")
 
-	s.mockTasks.EXPECT().List(gomock.Any()).Return(nil, mockDBError)
+	s.mockTasks.EXPECT().List(gomock.Any(), repository.ListOptions{}).Return(nil, mockDBError)
 
 	s.router.ServeHTTP(s.recoder, req)
 
@@ -283,6 +306,167 @@ func (s *taskTestSuite) TestListTasksFailure() {
 	s.Regexp("internal_error", string(resBody))
 }
 
+// Success: List tasks matching a filter
+//
+// Return: 200
+func (s *taskTestSuite) TestListTasksFiltered() {
+	req, err := http.NewRequestWithContext(s.T().Context(), http.MethodGet,
+		"/tasks?filter="+url.QueryEscape(`status eq 'todo' and title contains 'invoice'`), nil)
+	s.Require().NoError(err)
+
+	s.mockTasks.EXPECT().List(gomock.Any(), repository.ListOptions{
+		Filter: filter.And{
+			Left:  filter.Comparison{Field: "status", Op: filter.OpEq, Value: "todo"},
+			Right: filter.Comparison{Field: "title", Op: filter.OpContains, Value: "invoice"},
+		},
+	}).Return([]model.Task{}, nil)
+
+	s.router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusOK, s.recoder.Code)
+}
+
+// BadRequest: Filter refers to a field that cannot be filtered
+//
+// Return: 400
+func (s *taskTestSuite) TestListTasksInvalidFilter() {
+	req, err := http.NewRequestWithContext(s.T().Context(), http.MethodGet,
+		"/tasks?filter="+url.QueryEscape(`status eq 'todo' and description eq 'x'`), nil)
+	s.Require().NoError(err)
+
+	s.router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusBadRequest, s.recoder.Code)
+	s.Regexp("validation_error", s.recoder.Body.String())
+	s.Regexp(`unknown field 'description'.* at position 21`, s.recoder.Body.String())
+	s.Regexp("/query/filter", s.recoder.Body.String())
+}
+
+// Success: List tasks as CSV
+//
+// Return: 200
+func (s *taskTestSuite) TestListTasksCSV() {
+	req, err := http.NewRequestWithContext(s.T().Context(), http.MethodGet, "/tasks", nil)
+	s.Require().NoError(err)
+	req.Header.Set("Accept", "text/csv, application/json;q=0.5")
+
+	task := model.Task{
+		ID:          utils.GetMockUUID(),
+		Title:       "title, with comma",
+		Description: "test description",
+		Status:      enum.Status_Todo,
+		CreatedAt:   time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	s.mockTasks.EXPECT().List(gomock.Any(), repository.ListOptions{}).Return([]model.Task{task}, nil)
+
+	s.router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusOK, s.recoder.Code)
+	s.Equal("text/csv; charset=utf-8", s.recoder.Header().Get("Content-Type"))
+	s.Equal("Accept", s.recoder.Header().Get("Vary"))
+	s.Equal("id,title,status,description,created_at,updated_at\n"+
+		task.ID.String()+`,"title, with comma",todo,test description,2025-03-01T12:00:00Z,`+"\n",
+		s.recoder.Body.String())
+}
+
+// Success: Get task as YAML
+//
+// Return: 200
+func (s *taskTestSuite) TestGetTaskYAML() {
+	taskID := utils.GetMockUUID()
+	req, err := http.NewRequestWithContext(s.T().Context(), http.MethodGet, "/tasks/"+taskID.String(), nil)
+	s.Require().NoError(err)
+	req.Header.Set("Accept", "application/yaml")
+
+	s.mockTasks.EXPECT().Get(gomock.Any(), taskID.String()).Return(model.Task{
+		ID:          taskID,
+		Title:       "true",
+		Description: "test description",
+		Status:      enum.Status_Todo,
+		CreatedAt:   time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+	}, nil)
+
+	s.router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusOK, s.recoder.Code)
+	s.Equal("application/yaml; charset=utf-8", s.recoder.Header().Get("Content-Type"))
+	s.Equal("id: "+taskID.String()+"\n"+
+		"title: \"true\"\n"+
+		"status: todo\n"+
+		"description: test description\n"+
+		"created_at: \"2025-03-01T12:00:00Z\"\n",
+		s.recoder.Body.String())
+}
+
+// Success: List tasks as NDJSON, streamed from the database cursor
+//
+// Return: 200
+func (s *taskTestSuite) TestListTasksNDJSON() {
+	req, err := http.NewRequestWithContext(s.T().Context(), http.MethodGet, "/tasks", nil)
+	s.Require().NoError(err)
+	req.Header.Set("Accept", "application/x-ndjson")
+
+	tasks := make([]model.Task, streamFlushRows+1)
+	for i := range tasks {
+		tasks[i] = model.Task{ID: utils.GetMockUUID(), Title: "test title", Status: enum.Status_Todo}
+	}
+
+	s.mockTasks.EXPECT().Stream(gomock.Any(), repository.ListOptions{}, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ repository.ListOptions, fn func(model.Task) error) error {
+			for _, task := range tasks {
+				if err := fn(task); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+
+	s.router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusOK, s.recoder.Code)
+	s.Equal("application/x-ndjson", s.recoder.Header().Get("Content-Type"))
+	s.True(s.recoder.Flushed)
+
+	lines := strings.Split(strings.TrimSuffix(s.recoder.Body.String(), "\n"), "\n")
+	s.Len(lines, len(tasks))
+
+	var first model.Task
+	s.NoError(json.Unmarshal([]byte(lines[0]), &first))
+	s.Equal(tasks[0].ID, first.ID)
+}
+
+// InternalServerError: Streaming tasks failed before the first row
+//
+// Return: 500
+func (s *taskTestSuite) TestListTasksNDJSONFailure() {
+	req, err := http.NewRequestWithContext(s.T().Context(), http.MethodGet, "/tasks", nil)
+	s.Require().NoError(err)
+	req.Header.Set("Accept", "application/x-ndjson")
+
+	s.mockTasks.EXPECT().Stream(gomock.Any(), repository.ListOptions{}, gomock.Any()).Return(errors.New("some-db-error"))
+
+	s.router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusInternalServerError, s.recoder.Code)
+	s.Regexp("internal_error", s.recoder.Body.String())
+}
+
+// NotAcceptable: No supported media type is accepted
+//
+// Return: 406
+func (s *taskTestSuite) TestListTasksNotAcceptable() {
+	req, err := http.NewRequestWithContext(s.T().Context(), http.MethodGet, "/tasks", nil)
+	s.Require().NoError(err)
+	req.Header.Set("Accept", "application/xml, application/json;q=0")
+
+	s.router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusNotAcceptable, s.recoder.Code)
+	s.Regexp("not_acceptable", s.recoder.Body.String())
+}
+
 // UpdateTaskSuccess: Update task successfully
 //
 // Return: 200
@@ -332,6 +516,56 @@ func (s *taskTestSuite) TestUpdateTaskSuccess() {
 	s.JSONEq(string(expectedJson), string(resBody))
 }
 
+// txMarker marks the context of the transaction of txRecorder
+type txMarker struct{}
+
+// txRecorder runs functions in a fake transaction and records its options
+type txRecorder struct {
+	opts sql.TxOptions
+}
+
+func (t *txRecorder) InTx(ctx context.Context, fn func(ctx context.Context) error, opts ...database.TxOption) error {
+	for _, opt := range opts {
+		opt(&t.opts)
+	}
+
+	return fn(context.WithValue(ctx, txMarker{}, true))
+}
+
+// Success: the task is read and updated in one repeatable read transaction
+//
+// Return: 200
+func (s *taskTestSuite) TestUpdateTaskInTransaction() {
+	tx := &txRecorder{}
+	router := chi.NewRouter()
+	router.Put("/tasks/{id}", NewTaskHandler(s.mockTasks, tx).Update)
+
+	taskID := utils.GetMockUUID()
+	req := httptest.NewRequestWithContext(s.T().Context(), http.MethodPut, "/tasks/"+taskID.String(),
+		strings.NewReader(`{"title": "updated title", "status": "done"}`))
+
+	inTx := func(ctx context.Context) {
+		s.Equal(true, ctx.Value(txMarker{}))
+	}
+	s.mockTasks.EXPECT().Get(gomock.Any(), taskID.String()).
+		DoAndReturn(func(ctx context.Context, _ string) (model.Task, error) {
+			inTx(ctx)
+
+			return model.Task{ID: taskID, Title: "title"}, nil
+		})
+	s.mockTasks.EXPECT().Update(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, task model.Task) (model.Task, error) {
+			inTx(ctx)
+
+			return task, nil
+		})
+
+	router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusOK, s.recoder.Code)
+	s.Equal(sql.LevelRepeatableRead, tx.opts.Isolation)
+}
+
 // UpdateTaskFailureNotFound: Update task failure, task not found
 //
 // Return: 404
@@ -450,3 +684,35 @@ func (s *taskTestSuite) TestDeleteTaskFailureInternalError() {
 
 	s.Regexp("internal_error", string(resBody))
 }
+
+// Success: List tasks sorted and restricted to the selected fields
+//
+// Return: 200
+func (s *taskTestSuite) TestListTasksSortedFields() {
+	req, err := http.NewRequestWithContext(s.T().Context(), http.MethodGet, "/tasks?sort=-created_at,title&fields=title,id", nil)
+	s.Require().NoError(err)
+	id := utils.GetMockUUID()
+
+	s.mockTasks.EXPECT().List(gomock.Any(), repository.ListOptions{
+		Sort:   []repository.SortKey{{Field: "created_at", Desc: true}, {Field: "title"}},
+		Fields: []string{"title", "id"},
+	}).Return([]model.Task{{ID: id, Title: "title"}}, nil)
+
+	s.router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusOK, s.recoder.Code)
+	s.JSONEq(`[{"id":"`+id.String()+`","title":"title"}]`, s.recoder.Body.String())
+}
+
+// BadRequest: Fields names a field tasks do not have
+//
+// Return: 400
+func (s *taskTestSuite) TestListTasksUnknownField() {
+	req, err := http.NewRequestWithContext(s.T().Context(), http.MethodGet, "/tasks?fields=id,owner", nil)
+	s.Require().NoError(err)
+
+	s.router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusBadRequest, s.recoder.Code)
+	s.Regexp("/query/fields", s.recoder.Body.String())
+}
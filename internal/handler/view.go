@@ -0,0 +1,268 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"go-tasks-api/internal/apperr"
+	"go-tasks-api/internal/model"
+	"go-tasks-api/internal/repository"
+	"go-tasks-api/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	// userIDHeader identifies the user making the request
+	userIDHeader = "X-User-ID"
+	// defaultViewID addresses the default view of the user in place of a view ID
+	defaultViewID = "default"
+
+	failedToCreateView = "failed to create view"
+	viewNotFound       = "view not found"
+)
+
+type View struct {
+	viewRepo repository.ViewConnector
+	taskRepo repository.TaskConnector
+}
+
+// NewViewHandler creates a new View handler, views are run against the task repository
+func NewViewHandler(v repository.ViewConnector, t repository.TaskConnector) *View {
+	return &View{
+		viewRepo: v,
+		taskRepo: t,
+	}
+}
+
+func (a *View) List(w http.ResponseWriter, r *http.Request) {
+	user, err := userID(r)
+	if err != nil {
+		apperr.Write(w, r, err, "failed to list views")
+
+		return
+	}
+
+	views, err := a.viewRepo.ListFor(r.Context(), user)
+	if err != nil {
+		apperr.Write(w, r, err, "failed to list views")
+
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, views)
+}
+
+func (a *View) Create(w http.ResponseWriter, r *http.Request) {
+	user, err := userID(r)
+	if err != nil {
+		apperr.Write(w, r, err, failedToCreateView)
+
+		return
+	}
+
+	var req model.ViewCreateRequest
+	if err := decode(r, &req); err != nil {
+		apperr.Write(w, r, err, "failed to decode request body")
+
+		return
+	}
+
+	if err := validateView(req); err != nil {
+		apperr.Write(w, r, err, failedToCreateView)
+
+		return
+	}
+
+	view := model.View{
+		ID:             uuid.New(),
+		OwnerID:        user,
+		Name:           strings.TrimSpace(req.Name),
+		ViewDefinition: req.ViewDefinition,
+		SharedWith:     sharedWith(req.SharedWith, user),
+		Default:        req.Default,
+		CreatedAt:      time.Now(),
+	}
+	if err := a.viewRepo.Create(r.Context(), view); err != nil {
+		apperr.Write(w, r, err, failedToCreateView)
+
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, view)
+}
+
+func (a *View) Get(w http.ResponseWriter, r *http.Request) {
+	view, ok := a.get(w, r, false)
+	if !ok {
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, view)
+}
+
+func (a *View) Update(w http.ResponseWriter, r *http.Request) {
+	var req model.ViewUpdateRequest
+	if err := decode(r, &req); err != nil {
+		apperr.Write(w, r, err, "failed to decode request body")
+
+		return
+	}
+
+	if err := validateView(req); err != nil {
+		apperr.Write(w, r, err, "failed to update view")
+
+		return
+	}
+
+	view, ok := a.get(w, r, true)
+	if !ok {
+		return
+	}
+
+	view.Name = strings.TrimSpace(req.Name)
+	view.ViewDefinition = req.ViewDefinition
+	view.SharedWith = sharedWith(req.SharedWith, view.OwnerID)
+	view.Default = req.Default
+	now := time.Now()
+	view.UpdatedAt = &now
+
+	view, err := a.viewRepo.Update(r.Context(), view)
+	if err != nil {
+		apperr.Write(w, r, err, "failed to update view")
+
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, view)
+}
+
+func (a *View) Delete(w http.ResponseWriter, r *http.Request) {
+	view, ok := a.get(w, r, true)
+	if !ok {
+		return
+	}
+
+	if err := a.viewRepo.Delete(r.Context(), view.ID.String()); err != nil {
+		apperr.Write(w, r, err, "failed to delete view")
+
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusNoContent, nil)
+}
+
+// Tasks lists the tasks of the view, like the task list with the filter, sort and fields of the view
+func (a *View) Tasks(w http.ResponseWriter, r *http.Request) {
+	mediaType, enc, ok := negotiate(w, r)
+	if !ok {
+		return
+	}
+
+	view, ok := a.get(w, r, false)
+	if !ok {
+		return
+	}
+
+	opts, vErr := repository.NewListOptions(view.ViewDefinition)
+	if len(vErr) > 0 {
+		// definitions are validated when saved, only a change of the task fields invalidates them
+		apperr.Write(w, r, apperr.New(apperr.Conflict, "the view definition is no longer valid", vErr...), "failed to run view")
+
+		return
+	}
+
+	writeTasks(w, r, a.taskRepo, mediaType, enc, opts)
+}
+
+// get loads the view of the id path param, the id default loads the default view of the user.
+// Views shared with the user can only be read, modify requires ownership.
+func (a *View) get(w http.ResponseWriter, r *http.Request, modify bool) (model.View, bool) {
+	user, err := userID(r)
+	if err != nil {
+		apperr.Write(w, r, err, viewNotFound)
+
+		return model.View{}, false
+	}
+
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		apperr.Write(w, r, errEmptyID, viewNotFound)
+
+		return model.View{}, false
+	}
+
+	var view model.View
+	if id == defaultViewID && !modify {
+		view, err = a.viewRepo.GetDefault(r.Context(), user)
+	} else {
+		view, err = a.viewRepo.Get(r.Context(), id)
+	}
+	if err != nil {
+		apperr.Write(w, r, err, "failed to get view")
+
+		return model.View{}, false
+	}
+
+	switch {
+	case view.OwnerID == user:
+		return view, true
+	case !slices.Contains(view.SharedWith, user):
+		// views of other users are not disclosed
+		apperr.Write(w, r, repository.ErrNoRows, "failed to get view")
+	case modify:
+		apperr.Write(w, r, apperr.New(apperr.Forbidden, "only the owner can modify the view"), "failed to modify view")
+	default:
+		return view, true
+	}
+
+	return model.View{}, false
+}
+
+// userID reads the user making the request from the X-User-ID header
+func userID(r *http.Request) (string, error) {
+	user := strings.TrimSpace(r.Header.Get(userIDHeader))
+	if user == "" {
+		return "", apperr.New(apperr.Validation, "missing user", utils.FieldError{
+			Field:   userIDHeader,
+			Pointer: "/header/" + userIDHeader,
+			Message: "header parameter is required",
+		})
+	}
+
+	return user, nil
+}
+
+// validateView checks the request and its definition against the filterable, sortable and
+// selectable task fields
+func validateView(req model.ViewCreateRequest) error {
+	vErr := req.Validate()
+
+	_, defErr := repository.NewListOptions(req.ViewDefinition)
+	for _, fErr := range defErr {
+		fErr.Pointer = "/" + fErr.Field
+		vErr = append(vErr, fErr)
+	}
+
+	if len(vErr) > 0 {
+		return apperr.New(apperr.Validation, "failed to validate request body", vErr...)
+	}
+
+	return nil
+}
+
+// sharedWith returns the users a view is shared with without duplicates and its owner
+func sharedWith(users []string, owner string) []string {
+	shared := make([]string, 0, len(users))
+	for _, user := range users {
+		user = strings.TrimSpace(user)
+		if user != owner && !slices.Contains(shared, user) {
+			shared = append(shared, user)
+		}
+	}
+
+	return shared
+}
@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-tasks-api/internal/filter"
+	"go-tasks-api/internal/model"
+	"go-tasks-api/internal/repository"
+	"go-tasks-api/internal/repository/mocks"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/mock/gomock"
+)
+
+type viewTestSuite struct {
+	suite.Suite
+	ctrl      *gomock.Controller
+	connector *View
+	mockViews *mocks.MockViewConnector
+	mockTasks *mocks.MockTaskConnector
+	router    *chi.Mux
+	recoder   *httptest.ResponseRecorder
+}
+
+func TestViewHandler(t *testing.T) {
+	suite.Run(t, new(viewTestSuite))
+}
+
+// Setup test suite
+func (s *viewTestSuite) SetupTest() {
+	s.ctrl = gomock.NewController(s.T())
+	s.mockViews = mocks.NewMockViewConnector(s.ctrl)
+	s.mockTasks = mocks.NewMockTaskConnector(s.ctrl)
+
+	s.connector = NewViewHandler(s.mockViews, s.mockTasks)
+	s.recoder = httptest.NewRecorder()
+	s.router = chi.NewRouter()
+
+	s.router.Post("/views", s.connector.Create)
+	s.router.Get("/views", s.connector.List)
+	s.router.Get("/views/{id}", s.connector.Get)
+	s.router.Put("/views/{id}", s.connector.Update)
+	s.router.Delete("/views/{id}", s.connector.Delete)
+	s.router.Get("/views/{id}/tasks", s.connector.Tasks)
+}
+
+// Assert expectations
+func (s *viewTestSuite) TearDownTest() {
+	s.ctrl.Finish()
+}
+
+func (s *viewTestSuite) request(method, target, user, body string) *http.Request {
+	req, err := http.NewRequestWithContext(s.T().Context(), method, target, strings.NewReader(body))
+	s.Require().NoError(err)
+	if user != "" {
+		req.Header.Set(userIDHeader, user)
+	}
+
+	return req
+}
+
+// Success: A view was created for the user, the owner is not in its shares
+//
+// Return: 201
+func (s *viewTestSuite) TestCreateViewSuccess() {
+	req := s.request(http.MethodPost, "/views", "alice",
+		`{"name": " open ", "filter": "status eq 'todo'", "sort": ["-created_at"], "shared_with": ["bob", "alice", "bob"], "default": true}`)
+
+	s.mockViews.EXPECT().Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ any, view model.View) error {
+			s.Equal("alice", view.OwnerID)
+			s.Equal("open", view.Name)
+			s.Equal([]string{"bob"}, view.SharedWith)
+			s.True(view.Default)
+
+			return nil
+		})
+
+	s.router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusCreated, s.recoder.Code)
+}
+
+// BadRequest: The definition sorts by a field that cannot be sorted
+//
+// Return: 400
+func (s *viewTestSuite) TestCreateViewInvalidDefinition() {
+	req := s.request(http.MethodPost, "/views", "alice", `{"name": "open", "sort": ["description"]}`)
+
+	s.router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusBadRequest, s.recoder.Code)
+	s.Regexp(`cannot sort by \\"description\\"`, s.recoder.Body.String())
+	s.Regexp(`"pointer":"/sort"`, s.recoder.Body.String())
+}
+
+// BadRequest: The request does not identify the user
+//
+// Return: 400
+func (s *viewTestSuite) TestListViewsMissingUser() {
+	req := s.request(http.MethodGet, "/views", "", "")
+
+	s.router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusBadRequest, s.recoder.Code)
+	s.Regexp("/header/X-User-ID", s.recoder.Body.String())
+}
+
+// NotFound: Views of other users are hidden
+//
+// Return: 404
+func (s *viewTestSuite) TestGetViewOfOtherUser() {
+	id := uuid.New()
+	req := s.request(http.MethodGet, "/views/"+id.String(), "carol", "")
+
+	s.mockViews.EXPECT().Get(gomock.Any(), id.String()).
+		Return(model.View{ID: id, OwnerID: "alice", SharedWith: []string{"bob"}}, nil)
+
+	s.router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusNotFound, s.recoder.Code)
+}
+
+// Forbidden: Users a view is shared with cannot delete it
+//
+// Return: 403
+func (s *viewTestSuite) TestDeleteSharedView() {
+	id := uuid.New()
+	req := s.request(http.MethodDelete, "/views/"+id.String(), "bob", "")
+
+	s.mockViews.EXPECT().Get(gomock.Any(), id.String()).
+		Return(model.View{ID: id, OwnerID: "alice", SharedWith: []string{"bob"}}, nil)
+
+	s.router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusForbidden, s.recoder.Code)
+	s.Regexp("forbidden", s.recoder.Body.String())
+}
+
+// Success: The owner updates the view
+//
+// Return: 200
+func (s *viewTestSuite) TestUpdateViewSuccess() {
+	id := uuid.New()
+	req := s.request(http.MethodPut, "/views/"+id.String(), "alice", `{"name": "renamed", "fields": ["id"]}`)
+
+	s.mockViews.EXPECT().Get(gomock.Any(), id.String()).
+		Return(model.View{ID: id, OwnerID: "alice", Name: "open"}, nil)
+	s.mockViews.EXPECT().Update(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ any, view model.View) (model.View, error) {
+			s.Equal("renamed", view.Name)
+			s.Equal([]string{"id"}, view.Fields)
+			s.NotNil(view.UpdatedAt)
+
+			return view, nil
+		})
+
+	s.router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusOK, s.recoder.Code)
+}
+
+// Success: A shared default view is run with its filter, sort and fields
+//
+// Return: 200
+func (s *viewTestSuite) TestRunDefaultView() {
+	req := s.request(http.MethodGet, "/views/default/tasks", "bob", "")
+
+	s.mockViews.EXPECT().GetDefault(gomock.Any(), "bob").
+		Return(model.View{
+			ID:      uuid.New(),
+			OwnerID: "bob",
+			ViewDefinition: model.ViewDefinition{
+				Filter: "status eq 'done'",
+				Sort:   []string{"-updated_at"},
+				Fields: []string{"title"},
+			},
+		}, nil)
+	s.mockTasks.EXPECT().List(gomock.Any(), repository.ListOptions{
+		Filter: filter.Comparison{Field: "status", Op: filter.OpEq, Value: "done"},
+		Sort:   []repository.SortKey{{Field: "updated_at", Desc: true}},
+		Fields: []string{"title"},
+	}).Return([]model.Task{{ID: uuid.New(), Title: "title"}}, nil)
+
+	s.router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusOK, s.recoder.Code)
+
+	var got []map[string]any
+	s.Require().NoError(json.Unmarshal(s.recoder.Body.Bytes(), &got))
+	s.Equal([]map[string]any{{"title": "title"}}, got)
+}
+
+// NotFound: The user has no default view
+//
+// Return: 404
+func (s *viewTestSuite) TestRunDefaultViewNotFound() {
+	req := s.request(http.MethodGet, "/views/default/tasks", "bob", "")
+
+	s.mockViews.EXPECT().GetDefault(gomock.Any(), "bob").Return(model.View{}, repository.ErrNoRows)
+
+	s.router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusNotFound, s.recoder.Code)
+}
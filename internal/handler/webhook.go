@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"go-tasks-api/internal/apperr"
+	"go-tasks-api/internal/model"
+	"go-tasks-api/internal/repository"
+	"go-tasks-api/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// deliveryLogLimit is the number of most recent deliveries returned by the delivery log
+const deliveryLogLimit = 100
+
+type Webhook struct {
+	webhookRepo repository.WebhookConnector
+}
+
+// NewWebhookHandler creates a new Webhook handler
+func NewWebhookHandler(w repository.WebhookConnector) *Webhook {
+	return &Webhook{
+		webhookRepo: w,
+	}
+}
+
+func (a *Webhook) List(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := a.webhookRepo.List(r.Context())
+	if err != nil {
+		apperr.Write(w, r, err, "failed to list webhooks")
+
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, webhooks)
+}
+
+func (a *Webhook) Create(w http.ResponseWriter, r *http.Request) {
+	var req model.WebhookCreateRequest
+	if err := decode(r, &req); err != nil {
+		apperr.Write(w, r, err, "failed to decode request body")
+
+		return
+	}
+
+	vErr := req.Validate()
+	if len(vErr) > 0 {
+		apperr.Write(w, r, apperr.New(apperr.Validation, "failed to validate request body", vErr...), failedToCreateWebhook)
+
+		return
+	}
+
+	webhook := model.Webhook{
+		ID:         uuid.New(),
+		URL:        req.URL,
+		Secret:     req.Secret,
+		EventTypes: req.EventTypes,
+		Enabled:    true,
+		CreatedAt:  time.Now(),
+	}
+	if err := a.webhookRepo.Create(r.Context(), webhook); err != nil {
+		apperr.Write(w, r, err, failedToCreateWebhook)
+
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, webhook)
+}
+
+func (a *Webhook) Get(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := a.get(w, r)
+	if !ok {
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, webhook)
+}
+
+func (a *Webhook) Update(w http.ResponseWriter, r *http.Request) {
+	var req model.WebhookUpdateRequest
+	if err := decode(r, &req); err != nil {
+		apperr.Write(w, r, err, "failed to decode request body")
+
+		return
+	}
+
+	vErr := req.Validate()
+	if len(vErr) > 0 {
+		apperr.Write(w, r, apperr.New(apperr.Validation, "failed to validate request body", vErr...), "failed to update webhook")
+
+		return
+	}
+
+	webhook, ok := a.get(w, r)
+	if !ok {
+		return
+	}
+
+	if req.Enabled && !webhook.Enabled {
+		webhook.ConsecutiveFailures = 0
+	}
+	webhook.URL = req.URL
+	if req.Secret != "" {
+		webhook.Secret = req.Secret
+	}
+	webhook.EventTypes = req.EventTypes
+	webhook.Enabled = req.Enabled
+	now := time.Now()
+	webhook.UpdatedAt = &now
+
+	webhook, err := a.webhookRepo.Update(r.Context(), webhook)
+	if err != nil {
+		apperr.Write(w, r, err, "failed to update webhook")
+
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, webhook)
+}
+
+func (a *Webhook) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := a.webhookRepo.Delete(r.Context(), chi.URLParam(r, "id")); err != nil {
+		apperr.Write(w, r, err, "failed to delete webhook")
+
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusNoContent, nil)
+}
+
+// Deliveries returns the most recent deliveries of the webhook
+func (a *Webhook) Deliveries(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := a.get(w, r)
+	if !ok {
+		return
+	}
+
+	deliveries, err := a.webhookRepo.ListDeliveries(r.Context(), webhook.ID.String(), deliveryLogLimit)
+	if err != nil {
+		apperr.Write(w, r, err, "failed to list webhook deliveries")
+
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, deliveries)
+}
+
+// Redeliver schedules a new delivery of the same event to the webhook
+func (a *Webhook) Redeliver(w http.ResponseWriter, r *http.Request) {
+	delivery, err := a.webhookRepo.Redeliver(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "deliveryID"))
+	if err != nil {
+		if errors.Is(err, repository.ErrNoRows) {
+			err = apperr.Wrap(apperr.NotFound, "delivery not found", err)
+		}
+		apperr.Write(w, r, err, "failed to redeliver webhook delivery")
+
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusAccepted, delivery)
+}
+
+// get loads the webhook of the id path param, writing the error response when it fails
+func (a *Webhook) get(w http.ResponseWriter, r *http.Request) (model.Webhook, bool) {
+	webhook, err := a.webhookRepo.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		apperr.Write(w, r, err, "failed to get webhook")
+
+		return model.Webhook{}, false
+	}
+
+	return webhook, true
+}
@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-tasks-api/internal/enum"
+	"go-tasks-api/internal/model"
+	"go-tasks-api/internal/repository"
+	"go-tasks-api/internal/repository/mocks"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/mock/gomock"
+)
+
+type webhookTestSuite struct {
+	suite.Suite
+	ctrl         *gomock.Controller
+	connector    *Webhook
+	mockWebhooks *mocks.MockWebhookConnector
+	router       *chi.Mux
+	recoder      *httptest.ResponseRecorder
+}
+
+func TestWebhookHandler(t *testing.T) {
+	suite.Run(t, new(webhookTestSuite))
+}
+
+// Setup test suite
+func (s *webhookTestSuite) SetupTest() {
+	s.ctrl = gomock.NewController(s.T())
+	s.mockWebhooks = mocks.NewMockWebhookConnector(s.ctrl)
+
+	s.connector = NewWebhookHandler(s.mockWebhooks)
+	s.recoder = httptest.NewRecorder()
+	s.router = chi.NewRouter()
+
+	s.router.Post("/webhooks", s.connector.Create)
+	s.router.Get("/webhooks/{id}", s.connector.Get)
+	s.router.Put("/webhooks/{id}", s.connector.Update)
+	s.router.Delete("/webhooks/{id}", s.connector.Delete)
+	s.router.Get("/webhooks/{id}/deliveries", s.connector.Deliveries)
+	s.router.Post("/webhooks/{id}/deliveries/{deliveryID}/redeliver", s.connector.Redeliver)
+}
+
+// Assert expectations
+func (s *webhookTestSuite) TearDownTest() {
+	s.ctrl.Finish()
+}
+
+// Success: A webhook was created, the secret is not returned
+//
+// Return: 201
+func (s *webhookTestSuite) TestCreateWebhookSuccess() {
+	req, err := http.NewRequestWithContext(s.T().Context(), http.MethodPost, "/webhooks",
+		strings.NewReader(`{
+			"url": "https://example.com/hook",
+			"secret": "0123456789abcdef",
+			"event_types": ["task.created"]
+		}`))
+	s.Require().NoError(err)
+	defer req.Body.Close()
+
+	s.mockWebhooks.EXPECT().Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, a model.Webhook) error {
+			s.Equal("https://example.com/hook", a.URL)
+			s.Equal("0123456789abcdef", a.Secret)
+			s.True(a.Enabled)
+
+			return nil
+		})
+
+	s.router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusCreated, s.recoder.Code)
+	resBody, err := io.ReadAll(s.recoder.Body)
+	s.NoError(err)
+	s.Contains(string(resBody), `"enabled":true`)
+	s.NotContains(string(resBody), "0123456789abcdef")
+}
+
+// BadRequest: Relative URL, short secret and unknown event type
+//
+// Return: 400
+func (s *webhookTestSuite) TestCreateWebhookBadRequest() {
+	req, err := http.NewRequestWithContext(s.T().Context(), http.MethodPost, "/webhooks",
+		strings.NewReader(`{"url": "/hook", "secret": "short", "event_types": ["task.archived"]}`))
+	s.Require().NoError(err)
+	defer req.Body.Close()
+
+	s.router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusBadRequest, s.recoder.Code)
+	resBody, err := io.ReadAll(s.recoder.Body)
+	s.NoError(err)
+	s.Contains(string(resBody), `"url"`)
+	s.Contains(string(resBody), `"secret"`)
+	s.Contains(string(resBody), "task.archived")
+}
+
+// NotFound: Webhook does not exist
+//
+// Return: 404
+func (s *webhookTestSuite) TestGetWebhookNotFound() {
+	id := uuid.New().String()
+	req, err := http.NewRequestWithContext(s.T().Context(), http.MethodGet, "/webhooks/"+id, nil)
+	s.Require().NoError(err)
+
+	s.mockWebhooks.EXPECT().Get(gomock.Any(), id).Return(model.Webhook{}, repository.ErrNoRows)
+
+	s.router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusNotFound, s.recoder.Code)
+}
+
+// Success: Enabling a disabled webhook resets its failures and keeps the secret
+//
+// Return: 200
+func (s *webhookTestSuite) TestUpdateWebhookReenabled() {
+	existing := model.Webhook{
+		ID:                  uuid.New(),
+		URL:                 "https://example.com/hook",
+		Secret:              "0123456789abcdef",
+		EventTypes:          []string{model.EventTaskCreated},
+		ConsecutiveFailures: 25,
+	}
+	req, err := http.NewRequestWithContext(s.T().Context(), http.MethodPut, "/webhooks/"+existing.ID.String(),
+		strings.NewReader(`{"url": "https://example.com/v2", "event_types": ["task.deleted"], "enabled": true}`))
+	s.Require().NoError(err)
+	defer req.Body.Close()
+
+	s.mockWebhooks.EXPECT().Get(gomock.Any(), existing.ID.String()).Return(existing, nil)
+	s.mockWebhooks.EXPECT().Update(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, a model.Webhook) (model.Webhook, error) {
+			s.Equal("https://example.com/v2", a.URL)
+			s.Equal("0123456789abcdef", a.Secret)
+			s.Equal([]string{model.EventTaskDeleted}, a.EventTypes)
+			s.True(a.Enabled)
+			s.Zero(a.ConsecutiveFailures)
+			s.NotNil(a.UpdatedAt)
+
+			return a, nil
+		})
+
+	s.router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusOK, s.recoder.Code)
+}
+
+// Success: A webhook was deleted
+//
+// Return: 204
+func (s *webhookTestSuite) TestDeleteWebhookSuccess() {
+	id := uuid.New().String()
+	req, err := http.NewRequestWithContext(s.T().Context(), http.MethodDelete, "/webhooks/"+id, nil)
+	s.Require().NoError(err)
+
+	s.mockWebhooks.EXPECT().Delete(gomock.Any(), id).Return(nil)
+
+	s.router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusNoContent, s.recoder.Code)
+}
+
+// Success: The delivery log of the webhook
+//
+// Return: 200
+func (s *webhookTestSuite) TestDeliveriesSuccess() {
+	webhook := model.Webhook{ID: uuid.New()}
+	req, err := http.NewRequestWithContext(s.T().Context(), http.MethodGet, "/webhooks/"+webhook.ID.String()+"/deliveries", nil)
+	s.Require().NoError(err)
+
+	s.mockWebhooks.EXPECT().Get(gomock.Any(), webhook.ID.String()).Return(webhook, nil)
+	s.mockWebhooks.EXPECT().ListDeliveries(gomock.Any(), webhook.ID.String(), deliveryLogLimit).
+		Return([]model.WebhookDelivery{{ID: uuid.New(), Status: enum.DeliveryStatus_Failed, Attempts: 10}}, nil)
+
+	s.router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusOK, s.recoder.Code)
+	s.Contains(s.recoder.Body.String(), `"status":"failed"`)
+}
+
+// Success: A new delivery of the same event was scheduled
+//
+// Return: 202
+func (s *webhookTestSuite) TestRedeliverSuccess() {
+	webhookID, deliveryID := uuid.New().String(), uuid.New().String()
+	req, err := http.NewRequestWithContext(s.T().Context(), http.MethodPost,
+		"/webhooks/"+webhookID+"/deliveries/"+deliveryID+"/redeliver", nil)
+	s.Require().NoError(err)
+
+	s.mockWebhooks.EXPECT().Redeliver(gomock.Any(), webhookID, deliveryID).
+		Return(model.WebhookDelivery{ID: uuid.New(), Status: enum.DeliveryStatus_Pending}, nil)
+
+	s.router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusAccepted, s.recoder.Code)
+	s.Contains(s.recoder.Body.String(), `"status":"pending"`)
+}
+
+// NotFound: The delivery does not belong to the webhook
+//
+// Return: 404
+func (s *webhookTestSuite) TestRedeliverNotFound() {
+	webhookID, deliveryID := uuid.New().String(), uuid.New().String()
+	req, err := http.NewRequestWithContext(s.T().Context(), http.MethodPost,
+		"/webhooks/"+webhookID+"/deliveries/"+deliveryID+"/redeliver", nil)
+	s.Require().NoError(err)
+
+	s.mockWebhooks.EXPECT().Redeliver(gomock.Any(), webhookID, deliveryID).
+		Return(model.WebhookDelivery{}, repository.ErrNoRows)
+
+	s.router.ServeHTTP(s.recoder, req)
+
+	s.Equal(http.StatusNotFound, s.recoder.Code)
+	s.Contains(s.recoder.Body.String(), "delivery not found")
+}
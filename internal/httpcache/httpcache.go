@@ -0,0 +1,174 @@
+package httpcache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Policies maps route patterns, such as /api/v1/tasks/{id}, to the Cache-Control value of their responses
+type Policies map[string]string
+
+// Middleware makes GET routes conditional. Successful responses get a weak ETag computed from
+// the body, unless the handler set one from the row version, and the Cache-Control of their
+// route. Requests whose If-None-Match, or else If-Modified-Since against the Last-Modified set
+// by the handler, still matches get a 304 without body. Responses the handler flushes are
+// streamed as they are written and are neither tagged nor conditional.
+func (p Policies) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		if rec.streaming {
+			return
+		}
+
+		if rec.status != http.StatusOK {
+			w.WriteHeader(rec.status)
+			_, _ = w.Write(rec.body.Bytes())
+
+			return
+		}
+
+		header := w.Header()
+		if policy, ok := p[routePattern(r)]; ok && header.Get("Cache-Control") == "" {
+			header.Set("Cache-Control", policy)
+		}
+
+		etag := header.Get("ETag")
+		if etag == "" {
+			etag = WeakETag(rec.body.Bytes())
+			header.Set("ETag", etag)
+		}
+
+		if notModified(r, etag, header.Get("Last-Modified")) {
+			header.Del("Content-Type")
+			header.Del("Content-Length")
+			w.WriteHeader(http.StatusNotModified)
+
+			return
+		}
+
+		w.WriteHeader(rec.status)
+		_, _ = w.Write(rec.body.Bytes())
+	})
+}
+
+// WeakETag returns a weak entity tag of the body
+func WeakETag(body []byte) string {
+	sum := sha256.Sum256(body)
+
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// SetLastModified sets the Last-Modified header, the time is truncated to the second precision
+// of HTTP dates
+func SetLastModified(w http.ResponseWriter, t time.Time) {
+	w.Header().Set("Last-Modified", t.UTC().Truncate(time.Second).Format(http.TimeFormat))
+}
+
+// notModified evaluates the preconditions of RFC 9110, If-None-Match takes precedence over If-Modified-Since.
+// Both dates have second precision, a change in the same second as the copy of the client only shows in
+// the ETag, which clients send along.
+func notModified(r *http.Request, etag, lastModified string) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return matchesETag(inm, etag)
+	}
+
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || lastModified == "" {
+		return false
+	}
+
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+
+	modified, err := http.ParseTime(lastModified)
+	if err != nil {
+		return false
+	}
+
+	return modified.Truncate(time.Second).Compare(since.Truncate(time.Second)) <= 0
+}
+
+// matchesETag compares the If-None-Match list with the weak comparison function
+func matchesETag(list, etag string) bool {
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func routePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return ""
+	}
+
+	pattern := rctx.RoutePattern()
+	if len(pattern) > 1 {
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+
+	return pattern
+}
+
+// recorder buffers the response so its ETag can be computed before anything is sent
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+	// streaming is set on the first flush, from then on writes go straight to the client
+	streaming bool
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.wroteHeader {
+		return
+	}
+	r.status = status
+	r.wroteHeader = true
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	if r.streaming {
+		return r.ResponseWriter.Write(b)
+	}
+
+	return r.body.Write(b)
+}
+
+// Flush sends what was buffered so far and switches the recorder to streaming
+func (r *recorder) Flush() {
+	if !r.streaming {
+		r.streaming = true
+		r.ResponseWriter.WriteHeader(r.status)
+		_, _ = r.ResponseWriter.Write(r.body.Bytes())
+		r.body.Reset()
+	}
+
+	_ = http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
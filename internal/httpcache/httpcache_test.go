@@ -0,0 +1,134 @@
+package httpcache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+// modified has the microsecond precision of the timestamps of tasks
+var modified = time.Date(2025, 3, 1, 12, 0, 0, 250_000_000, time.UTC)
+
+func testRouter() *chi.Mux {
+	router := chi.NewRouter()
+	router.Route("/tasks", func(r chi.Router) {
+		r.Use(Policies{"/tasks": "private, no-cache", "/tasks/{id}": "private, max-age=60"}.Middleware)
+
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`[{"id":"1"}]`))
+		})
+		r.Get("/stream", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("{\"id\":\"1\"}\n"))
+			_ = http.NewResponseController(w).Flush()
+			_, _ = w.Write([]byte("{\"id\":\"2\"}\n"))
+		})
+		r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+			if chi.URLParam(r, "id") == "missing" {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"errors":[]}`))
+
+				return
+			}
+
+			SetLastModified(w, modified)
+			_, _ = w.Write([]byte(`{"id":"1"}`))
+		})
+		r.Put("/{id}", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"id":"1"}`))
+		})
+	})
+
+	return router
+}
+
+func serve(t *testing.T, method, target string, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, target, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	testRouter().ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestETagAndCacheControl(t *testing.T) {
+	rec := serve(t, http.MethodGet, "/tasks", nil)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, WeakETag([]byte(`[{"id":"1"}]`)), rec.Header().Get("ETag"))
+	require.Equal(t, "private, no-cache", rec.Header().Get("Cache-Control"))
+	require.Equal(t, `[{"id":"1"}]`, rec.Body.String())
+
+	rec = serve(t, http.MethodGet, "/tasks/1", nil)
+	require.Equal(t, "private, max-age=60", rec.Header().Get("Cache-Control"))
+	require.Equal(t, "Sat, 01 Mar 2025 12:00:00 GMT", rec.Header().Get("Last-Modified"))
+}
+
+func TestIfNoneMatch(t *testing.T) {
+	etag := serve(t, http.MethodGet, "/tasks", nil).Header().Get("ETag")
+
+	rec := serve(t, http.MethodGet, "/tasks", http.Header{"If-None-Match": {`"other", ` + etag}})
+	require.Equal(t, http.StatusNotModified, rec.Code)
+	require.Empty(t, rec.Body.String())
+	require.Equal(t, etag, rec.Header().Get("ETag"))
+	require.Empty(t, rec.Header().Get("Content-Type"))
+
+	// weak comparison ignores the W/ prefix
+	rec = serve(t, http.MethodGet, "/tasks", http.Header{"If-None-Match": {etag[2:]}})
+	require.Equal(t, http.StatusNotModified, rec.Code)
+
+	rec = serve(t, http.MethodGet, "/tasks", http.Header{"If-None-Match": {`W/"stale"`}})
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestIfModifiedSince(t *testing.T) {
+	rec := serve(t, http.MethodGet, "/tasks/1", http.Header{"If-Modified-Since": {modified.Format(http.TimeFormat)}})
+	require.Equal(t, http.StatusNotModified, rec.Code)
+
+	rec = serve(t, http.MethodGet, "/tasks/1", http.Header{"If-Modified-Since": {modified.Add(-time.Second).Format(http.TimeFormat)}})
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	// the header date is the Last-Modified of the copy, the fraction of the second is lost in both
+	rec = serve(t, http.MethodGet, "/tasks/1", http.Header{"If-Modified-Since": {"Sat, 01 Mar 2025 12:00:00 GMT"}})
+	require.Equal(t, http.StatusNotModified, rec.Code)
+	rec = serve(t, http.MethodGet, "/tasks/1", http.Header{"If-Modified-Since": {"Sat, 01 Mar 2025 11:59:59 GMT"}})
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	// If-None-Match takes precedence
+	rec = serve(t, http.MethodGet, "/tasks/1", http.Header{
+		"If-Modified-Since": {modified.Format(http.TimeFormat)},
+		"If-None-Match":     {`W/"stale"`},
+	})
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	// lists have no Last-Modified, If-Modified-Since alone never matches
+	rec = serve(t, http.MethodGet, "/tasks", http.Header{"If-Modified-Since": {time.Now().UTC().Format(http.TimeFormat)}})
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestErrorsAndWritesAreNotCached(t *testing.T) {
+	rec := serve(t, http.MethodGet, "/tasks/missing", http.Header{"If-None-Match": {"*"}})
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Empty(t, rec.Header().Get("ETag"))
+	require.Equal(t, `{"errors":[]}`, rec.Body.String())
+
+	rec = serve(t, http.MethodPut, "/tasks/1", http.Header{"If-None-Match": {"*"}})
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Empty(t, rec.Header().Get("ETag"))
+}
+
+func TestFlushedResponsesAreStreamed(t *testing.T) {
+	rec := serve(t, http.MethodGet, "/tasks/stream", http.Header{"If-None-Match": {"*"}})
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.True(t, rec.Flushed)
+	require.Empty(t, rec.Header().Get("ETag"))
+	require.Equal(t, "{\"id\":\"1\"}\n{\"id\":\"2\"}\n", rec.Body.String())
+}
@@ -0,0 +1,44 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+
+	"go-tasks-api/internal/model"
+	"go-tasks-api/internal/utils"
+)
+
+// ProgressFunc reports that done of total units of work are complete
+type ProgressFunc func(done, total int)
+
+// Kind is a type of job
+type Kind interface {
+	// Validate checks the params of a new job, errors are located by JSON Pointers relative to the params
+	Validate(params json.RawMessage) []utils.FieldError
+	// Run executes the job and returns its result. It stops when the context is cancelled, and
+	// a job interrupted by a crash is run again from the start, so Run must be safe to repeat.
+	Run(ctx context.Context, job model.Job, progress ProgressFunc) (*model.JobResult, error)
+}
+
+// Registry maps job types to their kind
+type Registry map[string]Kind
+
+// Types returns the registered job types in order
+func (r Registry) Types() []string {
+	return slices.Sorted(maps.Keys(r))
+}
+
+// decodeParams decodes the params of a job into v, missing params decode as an empty object
+func decodeParams(params json.RawMessage, v any) []utils.FieldError {
+	if len(params) == 0 || string(params) == "null" {
+		return nil
+	}
+
+	if err := json.Unmarshal(params, v); err != nil {
+		return []utils.FieldError{{Field: "params", Pointer: "", Message: "invalid params: " + err.Error()}}
+	}
+
+	return nil
+}
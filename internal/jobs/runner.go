@@ -0,0 +1,226 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-tasks-api/internal/enum"
+	"go-tasks-api/internal/model"
+	"go-tasks-api/internal/repository"
+	"go-tasks-api/internal/utils"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// maxErrorLength bounds the error message stored per job
+const maxErrorLength = 512
+
+type Config struct {
+	// PollInterval is the delay between two claims when no job was queued
+	PollInterval time.Duration
+	// Concurrency is the number of jobs run in parallel
+	Concurrency int
+	// Lease is how long a claimed job stays with its worker without a heartbeat, jobs of
+	// crashed workers are claimed again once it expired
+	Lease time.Duration
+	// HeartbeatInterval is the delay between two heartbeats, which store the progress and
+	// pick up cancellations
+	HeartbeatInterval time.Duration
+	// MaxAttempts is the number of claims after which a job which never completed is failed
+	MaxAttempts int
+}
+
+// Runner executes queued jobs with a pool of workers
+type Runner struct {
+	jobRepo repository.JobConnector
+	kinds   Registry
+	cfg     Config
+}
+
+// NewRunner creates a new Runner of the given job kinds
+func NewRunner(jobRepo repository.JobConnector, kinds Registry, cfg Config) *Runner {
+	return &Runner{
+		jobRepo: jobRepo,
+		kinds:   kinds,
+		cfg:     cfg,
+	}
+}
+
+// Run executes jobs until the context is cancelled, jobs still running then are put back in the queue
+func (r *Runner) Run(ctx context.Context) {
+	log.Info().Int("concurrency", r.cfg.Concurrency).Msg("job runner started")
+
+	var wg sync.WaitGroup
+	for range max(r.cfg.Concurrency, 1) {
+		wg.Go(func() {
+			r.work(ctx)
+		})
+	}
+	wg.Wait()
+
+	log.Info().Msg("job runner stopped")
+}
+
+func (r *Runner) work(ctx context.Context) {
+	for {
+		claimed, err := r.RunNext(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to run job")
+		}
+
+		// keep going while jobs are queued
+		if err == nil && claimed {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.cfg.PollInterval):
+		}
+	}
+}
+
+// RunNext claims one job and executes it, it reports whether a job was claimed
+func (r *Runner) RunNext(ctx context.Context) (bool, error) {
+	job, err := r.jobRepo.Claim(ctx, r.cfg.Lease)
+	if err != nil {
+		if errors.Is(err, repository.ErrNoRows) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	logger := log.With().
+		Str("job_id", job.ID.String()).
+		Str("job_type", job.Type).
+		Int("attempt", job.Attempts).
+		Logger()
+
+	outcome := model.JobOutcome{JobID: job.ID, Attempt: job.Attempts, Status: enum.JobStatus_Failed}
+	kind, ok := r.kinds[job.Type]
+	switch {
+	case job.CancelRequested:
+		// the cancellation was requested while the previous worker of the job was gone
+		outcome.Status = enum.JobStatus_Cancelled
+	case !ok:
+		outcome.Error = errorMessage(fmt.Errorf("unknown job type %q", job.Type))
+	case job.Attempts > r.cfg.MaxAttempts:
+		outcome.Error = errorMessage(fmt.Errorf("abandoned after %d attempts which did not complete", r.cfg.MaxAttempts))
+	default:
+		if outcome, ok = r.execute(ctx, job, kind, logger); !ok {
+			return true, nil
+		}
+	}
+
+	// the outcome is stored even when the runner is stopping
+	if err := r.jobRepo.Complete(context.WithoutCancel(ctx), outcome); err != nil {
+		if errors.Is(err, repository.ErrNoRows) {
+			logger.Warn().Msg("job lease lost before completion, the outcome is discarded")
+
+			return true, nil
+		}
+
+		return true, fmt.Errorf("failed to complete job %s: %w", job.ID, err)
+	}
+
+	if outcome.Status == enum.JobStatus_Failed {
+		logger.Warn().Str("error", *outcome.Error).Msg("job failed")
+	} else {
+		logger.Info().Str("status", outcome.Status.String()).Msg("job completed")
+	}
+
+	return true, nil
+}
+
+// execute runs the job while a heartbeat keeps its lease. It reports false when the job was
+// released because the runner is stopping or the lease was lost.
+func (r *Runner) execute(ctx context.Context, job model.Job, kind Kind, logger zerolog.Logger) (model.JobOutcome, bool) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		progress  atomic.Int64
+		cancelled atomic.Bool
+		lost      atomic.Bool
+	)
+	progress.Store(int64(job.Progress))
+
+	heartbeat := make(chan struct{})
+	go func() {
+		defer close(heartbeat)
+
+		ticker := time.NewTicker(r.cfg.HeartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-jobCtx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			beat := job
+			beat.Progress = int(progress.Load())
+			cancelRequested, err := r.jobRepo.Heartbeat(jobCtx, beat, r.cfg.Lease)
+			switch {
+			case errors.Is(err, repository.ErrNoRows):
+				lost.Store(true)
+				cancel()
+			case err != nil:
+				logger.Warn().Err(err).Msg("failed to store job heartbeat")
+			case cancelRequested:
+				cancelled.Store(true)
+				cancel()
+			}
+		}
+	}()
+
+	logger.Info().Msg("job started")
+	result, err := kind.Run(jobCtx, job, func(done, total int) {
+		if total > 0 {
+			// 100 is kept for completed jobs
+			progress.Store(int64(min(done*100/total, 99)))
+		}
+	})
+	cancel()
+	<-heartbeat
+
+	outcome := model.JobOutcome{JobID: job.ID, Attempt: job.Attempts}
+	switch {
+	case lost.Load():
+		logger.Warn().Msg("job lease lost, another worker took over the job")
+
+		return outcome, false
+	case cancelled.Load():
+		outcome.Status = enum.JobStatus_Cancelled
+	case err == nil:
+		outcome.Status = enum.JobStatus_Succeeded
+		outcome.Result = result
+	case ctx.Err() != nil:
+		job.Progress = int(progress.Load())
+		if err := r.jobRepo.Release(context.WithoutCancel(ctx), job); err != nil {
+			logger.Error().Err(err).Msg("failed to release job, it is run again once its lease expired")
+		}
+
+		return outcome, false
+	default:
+		outcome.Status = enum.JobStatus_Failed
+		outcome.Error = errorMessage(err)
+	}
+
+	return outcome, true
+}
+
+func errorMessage(err error) *string {
+	// error is a TEXT column, a split rune would fail the write of the result
+	msg := utils.TruncateString(err.Error(), maxErrorLength)
+
+	return &msg
+}
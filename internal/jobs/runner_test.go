@@ -0,0 +1,178 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"go-tasks-api/internal/enum"
+	"go-tasks-api/internal/model"
+	"go-tasks-api/internal/repository"
+	"go-tasks-api/internal/repository/mocks"
+	"go-tasks-api/internal/utils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// kindFunc runs jobs with a function
+type kindFunc func(ctx context.Context, job model.Job, progress ProgressFunc) (*model.JobResult, error)
+
+func (f kindFunc) Validate(json.RawMessage) []utils.FieldError {
+	return nil
+}
+
+func (f kindFunc) Run(ctx context.Context, job model.Job, progress ProgressFunc) (*model.JobResult, error) {
+	return f(ctx, job, progress)
+}
+
+var testConfig = Config{
+	Lease:             time.Minute,
+	HeartbeatInterval: time.Millisecond,
+	MaxAttempts:       3,
+}
+
+func newTestRunner(t *testing.T, kind Kind) (*Runner, *mocks.MockJobConnector) {
+	ctrl := gomock.NewController(t)
+	jobRepo := mocks.NewMockJobConnector(ctrl)
+
+	return NewRunner(jobRepo, Registry{"test": kind}, testConfig), jobRepo
+}
+
+func testJob() model.Job {
+	return model.Job{ID: uuid.New(), Type: "test", Status: enum.JobStatus_Running, Attempts: 1}
+}
+
+func TestRunNextStoresResult(t *testing.T) {
+	result := &model.JobResult{ContentType: "application/json", Data: []byte(`{}`)}
+	runner, jobRepo := newTestRunner(t, kindFunc(func(_ context.Context, _ model.Job, progress ProgressFunc) (*model.JobResult, error) {
+		progress(1, 2)
+
+		return result, nil
+	}))
+	job := testJob()
+
+	jobRepo.EXPECT().Claim(gomock.Any(), time.Minute).Return(job, nil)
+	jobRepo.EXPECT().Heartbeat(gomock.Any(), gomock.Any(), time.Minute).Return(false, nil).AnyTimes()
+	jobRepo.EXPECT().Complete(gomock.Any(), model.JobOutcome{
+		JobID:   job.ID,
+		Attempt: 1,
+		Status:  enum.JobStatus_Succeeded,
+		Result:  result,
+	})
+
+	claimed, err := runner.RunNext(context.Background())
+	require.NoError(t, err)
+	require.True(t, claimed)
+}
+
+func TestRunNextNothingQueued(t *testing.T) {
+	runner, jobRepo := newTestRunner(t, nil)
+
+	jobRepo.EXPECT().Claim(gomock.Any(), time.Minute).Return(model.Job{}, repository.ErrNoRows)
+
+	claimed, err := runner.RunNext(context.Background())
+	require.NoError(t, err)
+	require.False(t, claimed)
+}
+
+func TestRunNextStoresFailure(t *testing.T) {
+	runner, jobRepo := newTestRunner(t, kindFunc(func(context.Context, model.Job, ProgressFunc) (*model.JobResult, error) {
+		return nil, errors.New("boom")
+	}))
+	job := testJob()
+
+	jobRepo.EXPECT().Claim(gomock.Any(), time.Minute).Return(job, nil)
+	jobRepo.EXPECT().Heartbeat(gomock.Any(), gomock.Any(), time.Minute).Return(false, nil).AnyTimes()
+	jobRepo.EXPECT().Complete(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, outcome model.JobOutcome) error {
+			require.Equal(t, enum.JobStatus_Failed, outcome.Status)
+			require.Equal(t, "boom", *outcome.Error)
+
+			return nil
+		})
+
+	_, err := runner.RunNext(context.Background())
+	require.NoError(t, err)
+}
+
+func TestErrorMessageKeepsRunes(t *testing.T) {
+	// the cut at maxErrorLength falls into the two bytes of é
+	msg := errorMessage(errors.New(strings.Repeat("a", maxErrorLength-1) + "é task title"))
+	require.True(t, utf8.ValidString(*msg))
+	require.Equal(t, strings.Repeat("a", maxErrorLength-1), *msg)
+}
+
+func TestRunNextCancelsOnHeartbeat(t *testing.T) {
+	runner, jobRepo := newTestRunner(t, kindFunc(func(ctx context.Context, _ model.Job, progress ProgressFunc) (*model.JobResult, error) {
+		progress(1, 4)
+		<-ctx.Done()
+
+		return nil, ctx.Err()
+	}))
+	job := testJob()
+
+	jobRepo.EXPECT().Claim(gomock.Any(), time.Minute).Return(job, nil)
+	jobRepo.EXPECT().Heartbeat(gomock.Any(), gomock.Any(), time.Minute).
+		DoAndReturn(func(_ context.Context, beat model.Job, _ time.Duration) (bool, error) {
+			require.Equal(t, 25, beat.Progress)
+
+			return true, nil
+		})
+	jobRepo.EXPECT().Complete(gomock.Any(), model.JobOutcome{JobID: job.ID, Attempt: 1, Status: enum.JobStatus_Cancelled})
+
+	_, err := runner.RunNext(context.Background())
+	require.NoError(t, err)
+}
+
+func TestRunNextReleasesOnShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	runner, jobRepo := newTestRunner(t, kindFunc(func(ctx context.Context, _ model.Job, progress ProgressFunc) (*model.JobResult, error) {
+		progress(1, 2)
+		cancel()
+
+		return nil, ctx.Err()
+	}))
+	job := testJob()
+
+	jobRepo.EXPECT().Claim(gomock.Any(), time.Minute).Return(job, nil)
+	jobRepo.EXPECT().Heartbeat(gomock.Any(), gomock.Any(), time.Minute).Return(false, nil).AnyTimes()
+	jobRepo.EXPECT().Release(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, released model.Job) error {
+			require.NoError(t, ctx.Err())
+			require.Equal(t, 50, released.Progress)
+
+			return nil
+		})
+
+	claimed, err := runner.RunNext(ctx)
+	require.NoError(t, err)
+	require.True(t, claimed)
+}
+
+func TestRunNextAbandonsRepeatedlyInterruptedJob(t *testing.T) {
+	runner, jobRepo := newTestRunner(t, kindFunc(func(context.Context, model.Job, ProgressFunc) (*model.JobResult, error) {
+		t.Fatal("job must not run again")
+
+		return nil, nil
+	}))
+	job := testJob()
+	job.Attempts = 4
+
+	jobRepo.EXPECT().Claim(gomock.Any(), time.Minute).Return(job, nil)
+	jobRepo.EXPECT().Complete(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, outcome model.JobOutcome) error {
+			require.Equal(t, enum.JobStatus_Failed, outcome.Status)
+			require.Contains(t, *outcome.Error, "abandoned after 3 attempts")
+
+			return nil
+		})
+
+	_, err := runner.RunNext(context.Background())
+	require.NoError(t, err)
+}
@@ -0,0 +1,360 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go-tasks-api/internal/apperr"
+	"go-tasks-api/internal/database"
+	"go-tasks-api/internal/enum"
+	"go-tasks-api/internal/filter"
+	"go-tasks-api/internal/model"
+	"go-tasks-api/internal/repository"
+	"go-tasks-api/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+const (
+	TypeExportTasks = "tasks.export"
+	TypeImportTasks = "tasks.import"
+	TypeUpdateTasks = "tasks.update"
+
+	// maxImportTasks bounds the tasks created by one import job
+	maxImportTasks = 10000
+)
+
+// TaskKinds returns the job types working on tasks, tx runs the changes of a task which read it
+// first
+func TaskKinds(taskRepo repository.TaskConnector, tx database.Transactor) Registry {
+	return Registry{
+		TypeExportTasks: ExportTasks{taskRepo: taskRepo},
+		TypeImportTasks: ImportTasks{taskRepo: taskRepo},
+		TypeUpdateTasks: UpdateTasks{taskRepo: taskRepo, tx: tx, now: time.Now},
+	}
+}
+
+// ExportTasks writes the tasks selected like a saved view as CSV or NDJSON
+type ExportTasks struct {
+	taskRepo repository.TaskConnector
+}
+
+type exportParams struct {
+	model.ViewDefinition
+	// Format is csv or ndjson, the default
+	Format string `json:"format"`
+}
+
+func (k ExportTasks) Validate(params json.RawMessage) []utils.FieldError {
+	_, _, vErr := k.parse(params)
+
+	return vErr
+}
+
+func (k ExportTasks) parse(params json.RawMessage) (exportParams, repository.ListOptions, []utils.FieldError) {
+	var p exportParams
+	if vErr := decodeParams(params, &p); len(vErr) > 0 {
+		return p, repository.ListOptions{}, vErr
+	}
+
+	opts, vErr := repository.NewListOptions(p.ViewDefinition)
+	for i := range vErr {
+		vErr[i].Pointer = "/" + vErr[i].Field
+	}
+
+	switch p.Format {
+	case "":
+		p.Format = "ndjson"
+	case "csv", "ndjson":
+	default:
+		vErr = append(vErr, utils.FieldError{Field: "format", Pointer: "/format", Message: "must be csv or ndjson"})
+	}
+
+	return p, opts, vErr
+}
+
+func (k ExportTasks) Run(ctx context.Context, job model.Job, progress ProgressFunc) (*model.JobResult, error) {
+	p, opts, vErr := k.parse(job.Params)
+	if len(vErr) > 0 {
+		return nil, apperr.New(apperr.Validation, "invalid params", vErr...)
+	}
+
+	// the count only drives the progress, the tasks are read from a cursor so only the export is
+	// held in memory
+	total := 0
+	err := k.taskRepo.Stream(ctx, repository.ListOptions{Filter: opts.Filter, Fields: []string{"id"}}, func(model.Task) error {
+		total++
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tasks: %w", err)
+	}
+
+	var buf bytes.Buffer
+	w, contentType, err := newExportWriter(&buf, p.Format, opts.Fields)
+	if err != nil {
+		return nil, err
+	}
+
+	done := 0
+	err = k.taskRepo.Stream(ctx, opts, func(task model.Task) error {
+		if err := w.write(task); err != nil {
+			return fmt.Errorf("failed to write task %s: %w", task.ID, err)
+		}
+		done++
+		progress(done, max(total, done))
+
+		return ctx.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := w.flush(); err != nil {
+		return nil, fmt.Errorf("failed to write export: %w", err)
+	}
+
+	return &model.JobResult{ContentType: contentType, Data: buf.Bytes()}, nil
+}
+
+// exportWriter writes exported tasks one at a time
+type exportWriter struct {
+	write func(task model.Task) error
+	flush func() error
+}
+
+// newExportWriter returns the writer of the format and the content type of its output, fields
+// restricts the tasks to the selected fields
+func newExportWriter(buf *bytes.Buffer, format string, fields []string) (exportWriter, string, error) {
+	row := func(task model.Task) utils.CSVRecorder {
+		if len(fields) == 0 {
+			return task
+		}
+
+		return model.TaskProjection{Task: task, Fields: fields}
+	}
+
+	if format == "csv" {
+		cw := csv.NewWriter(buf)
+		if err := cw.Write(row(model.Task{}).CSVHeader()); err != nil {
+			return exportWriter{}, "", err
+		}
+
+		return exportWriter{
+			write: func(task model.Task) error { return cw.Write(row(task).CSVRecord()) },
+			flush: func() error { cw.Flush(); return cw.Error() },
+		}, utils.CSVEncoder{}.ContentType(), nil
+	}
+
+	enc := json.NewEncoder(buf)
+
+	return exportWriter{
+		write: func(task model.Task) error { return enc.Encode(row(task)) },
+		flush: func() error { return nil },
+	}, utils.NDJSONEncoder{}.ContentType(), nil
+}
+
+// ImportTasks creates tasks from a list of task create requests
+type ImportTasks struct {
+	taskRepo repository.TaskConnector
+}
+
+type importParams struct {
+	Tasks []model.TaskCreateRequest `json:"tasks"`
+}
+
+// importSummary is the result of import jobs
+type importSummary struct {
+	Created int `json:"created"`
+	// Skipped counts the tasks created by an earlier, interrupted attempt of the job
+	Skipped int `json:"skipped"`
+}
+
+func (k ImportTasks) Validate(params json.RawMessage) []utils.FieldError {
+	var p importParams
+	if vErr := decodeParams(params, &p); len(vErr) > 0 {
+		return vErr
+	}
+
+	if len(p.Tasks) == 0 || len(p.Tasks) > maxImportTasks {
+		return []utils.FieldError{{Field: "tasks", Pointer: "/tasks", Message: "must hold between 1 and 10000 tasks"}}
+	}
+
+	vErr := make([]utils.FieldError, 0)
+	for i, task := range p.Tasks {
+		for _, fErr := range task.Validate() {
+			vErr = append(vErr, utils.FieldError{
+				Field:   fmt.Sprintf("tasks[%d].%s", i, fErr.Field),
+				Pointer: fmt.Sprintf("/tasks/%d/%s", i, fErr.Field),
+				Message: fErr.Message,
+			})
+		}
+	}
+
+	return vErr
+}
+
+func (k ImportTasks) Run(ctx context.Context, job model.Job, progress ProgressFunc) (*model.JobResult, error) {
+	var p importParams
+	if err := json.Unmarshal(job.Params, &p); err != nil {
+		return nil, fmt.Errorf("failed to decode params: %w", err)
+	}
+
+	var summary importSummary
+	for i, req := range p.Tasks {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// IDs derived from the job make a repeated attempt skip the tasks it already created
+		task := model.Task{
+			ID:          uuid.NewSHA1(job.ID, []byte(strconv.Itoa(i))),
+			Title:       utils.TrimString(req.Title),
+			Description: utils.TrimString(req.Description),
+			Status:      enum.Status_Todo,
+			CreatedAt:   time.Now(),
+		}
+
+		// Create keeps existing tasks, also those deleted since an earlier attempt created them
+		created, err := k.taskRepo.Create(ctx, task)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import task %d: %w", i, err)
+		}
+		if created {
+			summary.Created++
+		} else {
+			summary.Skipped++
+		}
+		progress(i+1, len(p.Tasks))
+	}
+
+	return jsonResult(summary)
+}
+
+// UpdateTasks sets the status of the tasks matching a filter, leaving their other fields as
+// they are
+type UpdateTasks struct {
+	taskRepo repository.TaskConnector
+	tx       database.Transactor
+	now      func() time.Time
+}
+
+type updateParams struct {
+	// Filter selects the updated tasks, all tasks when empty
+	Filter string `json:"filter"`
+	Status string `json:"status"`
+}
+
+// updateSummary is the result of update jobs
+type updateSummary struct {
+	Matched int `json:"matched"`
+	Updated int `json:"updated"`
+}
+
+func (k UpdateTasks) Validate(params json.RawMessage) []utils.FieldError {
+	_, _, vErr := k.parse(params)
+
+	return vErr
+}
+
+func (k UpdateTasks) parse(params json.RawMessage) (filter.Expr, enum.StatusType, []utils.FieldError) {
+	var p updateParams
+	if vErr := decodeParams(params, &p); len(vErr) > 0 {
+		return nil, 0, vErr
+	}
+
+	vErr := make([]utils.FieldError, 0)
+	expr, err := filter.Parse(p.Filter, repository.TaskFilterFields)
+	if err != nil {
+		vErr = append(vErr, utils.FieldError{Field: "filter", Pointer: "/filter", Message: err.Error()})
+	}
+
+	status, err := enum.StatusTypeString(p.Status)
+	if err != nil {
+		vErr = append(vErr, utils.FieldError{Field: "status", Pointer: "/status", Message: "must be one of todo, done"})
+	}
+
+	return expr, status, vErr
+}
+
+func (k UpdateTasks) Run(ctx context.Context, job model.Job, progress ProgressFunc) (*model.JobResult, error) {
+	expr, status, vErr := k.parse(job.Params)
+	if len(vErr) > 0 {
+		return nil, apperr.New(apperr.Validation, "invalid params", vErr...)
+	}
+
+	// a cached or replicated list may miss the latest changes of the tasks
+	tasks, err := k.taskRepo.List(database.WithPrimary(ctx), repository.ListOptions{Filter: expr, Fields: []string{"id", "status"}})
+	if err != nil {
+		return nil, err
+	}
+
+	summary := updateSummary{Matched: len(tasks)}
+	for i, task := range tasks {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// tasks updated by an interrupted attempt already have the status
+		if task.Status != status {
+			updated, err := k.update(ctx, task.ID.String(), status)
+			switch {
+			case err == nil:
+				if updated {
+					summary.Updated++
+				}
+			case errors.Is(err, repository.ErrNoRows):
+				// deleted since it was listed
+			default:
+				return nil, fmt.Errorf("failed to update task %s: %w", task.ID, err)
+			}
+		}
+		progress(i+1, len(tasks))
+	}
+
+	return jsonResult(summary)
+}
+
+// update sets the status of the task as read in the transaction, so the title and description
+// changed since the task was listed are kept. It reports false when the task has the status.
+func (k UpdateTasks) update(ctx context.Context, id string, status enum.StatusType) (bool, error) {
+	// a concurrent update of the task between both calls fails to serialize and is retried
+	var updated bool
+	err := k.tx.InTx(ctx, func(ctx context.Context) error {
+		updated = false
+
+		task, err := k.taskRepo.Get(ctx, id)
+		if err != nil || task.Status == status {
+			return err
+		}
+
+		now := k.now()
+		task.Status = status
+		task.UpdatedAt = &now
+		if _, err := k.taskRepo.Update(ctx, task); err != nil {
+			return err
+		}
+		updated = true
+
+		return nil
+	}, database.WithIsolation(sql.LevelRepeatableRead))
+
+	return updated, err
+}
+
+func jsonResult(v any) (*model.JobResult, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	return &model.JobResult{ContentType: utils.JSONEncoder{}.ContentType(), Data: data}, nil
+}
@@ -0,0 +1,198 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"go-tasks-api/internal/database"
+	"go-tasks-api/internal/enum"
+	"go-tasks-api/internal/model"
+	"go-tasks-api/internal/openapi"
+	"go-tasks-api/internal/repository"
+	"go-tasks-api/internal/repository/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func noProgress(int, int) {}
+
+func TestExportTasksCSV(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	taskRepo := mocks.NewMockTaskConnector(ctrl)
+	id := uuid.MustParse("3b241101-e2bb-4255-8caf-4136c566a962")
+
+	stream := func(_ context.Context, _ repository.ListOptions, fn func(model.Task) error) error {
+		return fn(model.Task{ID: id, Title: "a, b"})
+	}
+	gomock.InOrder(
+		taskRepo.EXPECT().Stream(gomock.Any(), repository.ListOptions{Fields: []string{"id"}}, gomock.Any()).
+			DoAndReturn(stream),
+		taskRepo.EXPECT().Stream(gomock.Any(), repository.ListOptions{
+			Sort:   []repository.SortKey{{Field: "title"}},
+			Fields: []string{"id", "title"},
+		}, gomock.Any()).DoAndReturn(stream),
+	)
+
+	var done [][2]int
+	job := model.Job{Params: json.RawMessage(`{"sort": ["title"], "fields": ["id", "title"], "format": "csv"}`)}
+	result, err := ExportTasks{taskRepo: taskRepo}.Run(context.Background(), job, func(n, total int) {
+		done = append(done, [2]int{n, total})
+	})
+	require.NoError(t, err)
+	require.Equal(t, [][2]int{{1, 1}}, done)
+	require.Equal(t, "text/csv; charset=utf-8", result.ContentType)
+	require.Equal(t, "id,title\n3b241101-e2bb-4255-8caf-4136c566a962,\"a, b\"\n", string(result.Data))
+}
+
+func TestExportTasksValidate(t *testing.T) {
+	vErr := ExportTasks{}.Validate(json.RawMessage(`{"fields": ["owner"], "format": "xml"}`))
+	require.Len(t, vErr, 2)
+	require.Equal(t, "/fields", vErr[0].Pointer)
+	require.Equal(t, "/format", vErr[1].Pointer)
+}
+
+func TestImportTasksSkipsTasksOfEarlierAttempt(t *testing.T) {
+	taskRepo := repository.NewMemoryTaskRepo()
+	job := model.Job{ID: uuid.New(), Params: json.RawMessage(`{"tasks": [{"title": "first"}, {"title": "second"}]}`)}
+
+	// the interrupted attempt created the first task
+	first := model.Task{ID: uuid.NewSHA1(job.ID, []byte("0")), Title: "first", CreatedAt: time.Now()}
+	_, err := taskRepo.Create(context.Background(), first)
+	require.NoError(t, err)
+
+	var done []int
+	result, err := ImportTasks{taskRepo: taskRepo}.Run(context.Background(), job, func(n, _ int) { done = append(done, n) })
+	require.NoError(t, err)
+	require.JSONEq(t, `{"created": 1, "skipped": 1}`, string(result.Data))
+	require.Equal(t, []int{1, 2}, done)
+
+	tasks, err := taskRepo.List(context.Background(), repository.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+}
+
+// TestImportTasksSkipsDeletedTasks imports a task again after it was deleted, the deleted task
+// keeps its ID so it is neither restored nor counted as created
+func TestImportTasksSkipsDeletedTasks(t *testing.T) {
+	ctx := context.Background()
+	taskRepo := repository.NewMemoryTaskRepo()
+	job := model.Job{ID: uuid.New(), Params: json.RawMessage(`{"tasks": [{"title": "first"}, {"title": "second"}]}`)}
+
+	first := model.Task{ID: uuid.NewSHA1(job.ID, []byte("0")), Title: "first", CreatedAt: time.Now()}
+	_, err := taskRepo.Create(ctx, first)
+	require.NoError(t, err)
+	require.NoError(t, taskRepo.Delete(ctx, first.ID.String()))
+
+	result, err := ImportTasks{taskRepo: taskRepo}.Run(ctx, job, noProgress)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"created": 1, "skipped": 1}`, string(result.Data))
+
+	tasks, err := taskRepo.List(ctx, repository.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	require.Equal(t, "second", tasks[0].Title)
+}
+
+func TestImportTasksValidate(t *testing.T) {
+	vErr := ImportTasks{}.Validate(json.RawMessage(`{"tasks": [{"title": "ok"}, {"title": ""}]}`))
+	require.Len(t, vErr, 1)
+	require.Equal(t, "tasks[1].title", vErr[0].Field)
+	require.Equal(t, "/tasks/1/title", vErr[0].Pointer)
+
+	require.Len(t, ImportTasks{}.Validate(nil), 1)
+}
+
+// TestImportFitsBodyLimit checks the request body limit of jobs leaves room for the largest import
+// of tasks with a full title and a description of 4 KiB
+func TestImportFitsBodyLimit(t *testing.T) {
+	tasks := make([]model.TaskCreateRequest, maxImportTasks)
+	for i := range tasks {
+		tasks[i] = model.TaskCreateRequest{Title: strings.Repeat("t", 255), Description: strings.Repeat("d", 4<<10)}
+	}
+	body, err := json.Marshal(map[string]any{"type": TypeImportTasks, "params": importParams{Tasks: tasks}})
+	require.NoError(t, err)
+
+	op := openapi.MustLoad().Paths["/api/v1/jobs"].Operation(http.MethodPost)
+	require.LessOrEqual(t, int64(len(body)), op.RequestBody.MaxBytes)
+}
+
+func TestUpdateTasksSkipsTasksWithStatus(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	taskRepo := mocks.NewMockTaskConnector(ctrl)
+	now := time.Now()
+	todo := model.Task{ID: uuid.New(), Status: enum.Status_Todo}
+
+	taskRepo.EXPECT().List(gomock.Any(), gomock.Any()).
+		Return([]model.Task{todo, {ID: uuid.New(), Status: enum.Status_Done}}, nil)
+	taskRepo.EXPECT().Get(gomock.Any(), todo.ID.String()).
+		Return(model.Task{ID: todo.ID, Title: "renamed since listed", Status: enum.Status_Todo}, nil)
+	taskRepo.EXPECT().Update(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, task model.Task) (model.Task, error) {
+			require.Equal(t, todo.ID, task.ID)
+			require.Equal(t, "renamed since listed", task.Title)
+			require.Equal(t, enum.Status_Done, task.Status)
+			require.Equal(t, &now, task.UpdatedAt)
+
+			return task, nil
+		})
+
+	kind := UpdateTasks{taskRepo: taskRepo, tx: database.NoopTransactor{}, now: func() time.Time { return now }}
+	result, err := kind.Run(context.Background(), model.Job{Params: json.RawMessage(`{"filter": "title contains 'x'", "status": "done"}`)}, noProgress)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"matched": 2, "updated": 1}`, string(result.Data))
+}
+
+// TestUpdateTasksKeepsConcurrentEdits changes a task between the list and the update, only the
+// status is written over it
+func TestUpdateTasksKeepsConcurrentEdits(t *testing.T) {
+	ctx := context.Background()
+	taskRepo := repository.NewMemoryTaskRepo()
+	task := model.Task{ID: uuid.New(), Title: "draft", CreatedAt: time.Now()}
+	_, err := taskRepo.Create(ctx, task)
+	require.NoError(t, err)
+
+	listing := &listHook{TaskConnector: taskRepo, after: func() {
+		edited := task
+		edited.Title = "final"
+		edited.Description = "edited meanwhile"
+		edited.Status = enum.Status_Todo
+		_, err := taskRepo.Update(ctx, edited)
+		require.NoError(t, err)
+	}}
+
+	kind := UpdateTasks{taskRepo: listing, tx: database.NoopTransactor{}, now: time.Now}
+	_, err = kind.Run(ctx, model.Job{Params: json.RawMessage(`{"status": "done"}`)}, noProgress)
+	require.NoError(t, err)
+
+	got, err := taskRepo.Get(ctx, task.ID.String())
+	require.NoError(t, err)
+	require.Equal(t, "final", got.Title)
+	require.Equal(t, "edited meanwhile", got.Description)
+	require.Equal(t, enum.Status_Done, got.Status)
+}
+
+// listHook runs after once List returned
+type listHook struct {
+	repository.TaskConnector
+	after func()
+}
+
+func (h *listHook) List(ctx context.Context, opts repository.ListOptions) ([]model.Task, error) {
+	tasks, err := h.TaskConnector.List(ctx, opts)
+	h.after()
+
+	return tasks, err
+}
+
+func TestUpdateTasksValidate(t *testing.T) {
+	vErr := UpdateTasks{}.Validate(json.RawMessage(`{"filter": "title eq", "status": "archived"}`))
+	require.Len(t, vErr, 2)
+	require.Equal(t, "filter", vErr[0].Field)
+	require.Equal(t, "status", vErr[1].Field)
+}
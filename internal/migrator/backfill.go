@@ -0,0 +1,168 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultBatchSize     = 1000
+	defaultProgressEvery = 10 * time.Second
+)
+
+// Backfill changes the rows of a table in batches, each committed in its own transaction
+// together with a checkpoint. Run in the up function of a Go migration registered with RunDB,
+// an interrupted backfill resumes after the last committed batch when the migration runs again.
+type Backfill struct {
+	// Name identifies the checkpoint of the backfill, it must not change once the backfill ran
+	Name string
+	// BatchSize is the maximum number of rows of a batch, 1000 by default
+	BatchSize int
+	// Pause is the delay between two batches, leaving the database to the queries of the service
+	Pause time.Duration
+	// ProgressEvery is the interval between progress logs, 10s by default
+	ProgressEvery time.Duration
+	// Count returns the number of rows to backfill, only used to log the progress. Optional.
+	Count func(ctx context.Context, db *sql.DB) (int64, error)
+	// Batch processes at most limit rows after the key after in key order, after is empty for
+	// the first batch. It returns the key of the last row it processed and the number of rows,
+	// the backfill ends with the first batch of less than limit rows.
+	Batch func(ctx context.Context, tx *sql.Tx, after string, limit int) (last string, n int, err error)
+}
+
+// Run runs the batches left of the backfill, it returns at once when it finished before
+func (b Backfill) Run(ctx context.Context, db *sql.DB) error {
+	if b.Name == "" || b.Batch == nil {
+		return errors.New("a backfill needs a name and a batch function")
+	}
+	if b.BatchSize <= 0 {
+		b.BatchSize = defaultBatchSize
+	}
+	if b.ProgressEvery <= 0 {
+		b.ProgressEvery = defaultProgressEvery
+	}
+
+	_, err := db.ExecContext(ctx, `INSERT INTO tasks.backfills (name) VALUES ($1) ON CONFLICT (name) DO NOTHING`, b.Name)
+	if err != nil {
+		return fmt.Errorf("failed to create checkpoint of backfill %s: %w", b.Name, err)
+	}
+
+	var (
+		after    string
+		rows     int64
+		finished sql.NullTime
+	)
+	err = db.QueryRowContext(ctx, `SELECT last_key, processed, finished_at FROM tasks.backfills WHERE name = $1`, b.Name).
+		Scan(&after, &rows, &finished)
+	if err != nil {
+		return fmt.Errorf("failed to read checkpoint of backfill %s: %w", b.Name, err)
+	}
+
+	logger := log.With().Str("backfill", b.Name).Logger()
+	if finished.Valid {
+		logger.Info().Int64("rows", rows).Time("finished_at", finished.Time).Msg("backfill already finished")
+
+		return nil
+	}
+
+	total := int64(-1)
+	if b.Count != nil {
+		if total, err = b.Count(ctx, db); err != nil {
+			return fmt.Errorf("failed to count rows of backfill %s: %w", b.Name, err)
+		}
+	}
+	logger.Info().Int64("rows", rows).Int64("total", total).Str("after", after).Msg("backfill started")
+
+	start, lastLog, done := time.Now(), time.Now(), int64(0)
+	for {
+		last, n, err := b.batch(ctx, db, after)
+		if err != nil {
+			return err
+		}
+		after = last
+		rows += int64(n)
+		done += int64(n)
+
+		if n < b.BatchSize {
+			break
+		}
+
+		if time.Since(lastLog) >= b.ProgressEvery {
+			lastLog = time.Now()
+
+			event := logger.Info().Int64("rows", rows).Str("after", after).
+				Float64("rows_per_second", float64(done)/time.Since(start).Seconds())
+			if total > 0 {
+				event = event.Int64("total", total).Float64("percent", min(100, float64(rows)*100/float64(total)))
+			}
+			event.Msg("backfill progress")
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("backfill %s interrupted after %q: %w", b.Name, after, ctx.Err())
+		case <-time.After(b.Pause):
+		}
+	}
+
+	logger.Info().Int64("rows", rows).Dur("duration", time.Since(start)).Msg("backfill finished")
+
+	return nil
+}
+
+// batch runs a batch after the key after and moves the checkpoint in the same transaction. The
+// checkpoint row is locked, so a batch interrupted at any point is run again as a whole.
+func (b Backfill) batch(ctx context.Context, db *sql.DB, after string) (string, int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to begin batch of backfill %s: %w", b.Name, err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	_, err = tx.ExecContext(ctx, `SELECT 1 FROM tasks.backfills WHERE name = $1 FOR UPDATE`, b.Name)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to lock checkpoint of backfill %s: %w", b.Name, err)
+	}
+
+	last, n, err := b.Batch(ctx, tx, after, b.BatchSize)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to run batch of backfill %s after %q: %w", b.Name, after, err)
+	}
+	if n > 0 && last == "" {
+		return "", 0, fmt.Errorf("batch of backfill %s returned no key for %d rows", b.Name, n)
+	}
+	if n == 0 {
+		last = after
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE tasks.backfills
+		SET last_key = $2, processed = processed + $3, updated_at = CURRENT_TIMESTAMP,
+			finished_at = CASE WHEN $4 THEN CURRENT_TIMESTAMP END
+		WHERE name = $1`, b.Name, last, n, n < b.BatchSize)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to save checkpoint of backfill %s: %w", b.Name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", 0, fmt.Errorf("failed to commit batch of backfill %s: %w", b.Name, err)
+	}
+
+	return last, n, nil
+}
+
+// Reset removes the checkpoint of the backfill, so it starts over when run again. Call it in the
+// down function of the migration running the backfill.
+func (b Backfill) Reset(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM tasks.backfills WHERE name = $1`, b.Name); err != nil {
+		return fmt.Errorf("failed to reset backfill %s: %w", b.Name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+)
+
+type backfillSuite struct {
+	suite.Suite
+
+	db   *sql.DB
+	mock sqlmock.Sqlmock
+}
+
+func TestBackfill(t *testing.T) {
+	suite.Run(t, new(backfillSuite))
+}
+
+func (s *backfillSuite) SetupTest() {
+	var err error
+	s.db, s.mock, err = sqlmock.New()
+	s.Require().NoError(err)
+}
+
+func (s *backfillSuite) TearDownTest() {
+	s.Require().NoError(s.mock.ExpectationsWereMet())
+	s.db.Close()
+}
+
+// expectCheckpoint expects the checkpoint of the summaries backfill to be read
+func (s *backfillSuite) expectCheckpoint(after string, rows int64, finished any) {
+	s.mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO tasks.backfills (name) VALUES ($1) ON CONFLICT (name) DO NOTHING`)).
+		WithArgs("summaries").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectQuery(regexp.QuoteMeta(`SELECT last_key, processed, finished_at FROM tasks.backfills WHERE name = $1`)).
+		WithArgs("summaries").
+		WillReturnRows(sqlmock.NewRows([]string{"last_key", "processed", "finished_at"}).AddRow(after, rows, finished))
+}
+
+// expectBatch expects a batch moving the checkpoint to last
+func (s *backfillSuite) expectBatch(last string, n int, finished bool) {
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta(`SELECT 1 FROM tasks.backfills WHERE name = $1 FOR UPDATE`)).
+		WithArgs("summaries").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectExec(`UPDATE tasks.backfills`).
+		WithArgs("summaries", last, n, finished).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectCommit()
+}
+
+// keys is a backfill over the keys 1 to 5 recording the after keys of its batches
+func keys(afters *[]string) Backfill {
+	return Backfill{
+		Name:      "summaries",
+		BatchSize: 2,
+		Pause:     time.Millisecond,
+		Batch: func(ctx context.Context, tx *sql.Tx, after string, limit int) (string, int, error) {
+			*afters = append(*afters, after)
+
+			start := 1
+			if after != "" {
+				_, _ = fmt.Sscan(after, &start)
+				start++
+			}
+
+			n := max(0, min(limit, 5-start+1))
+			if n == 0 {
+				return "", 0, nil
+			}
+
+			return fmt.Sprint(start + n - 1), n, nil
+		},
+	}
+}
+
+func (s *backfillSuite) TestRunsBatchesUntilShortOne() {
+	s.expectCheckpoint("", 0, nil)
+	s.expectBatch("2", 2, false)
+	s.expectBatch("4", 2, false)
+	s.expectBatch("5", 1, true)
+
+	var afters []string
+	s.Require().NoError(keys(&afters).Run(context.Background(), s.db))
+	s.Require().Equal([]string{"", "2", "4"}, afters)
+}
+
+func (s *backfillSuite) TestResumesAfterCheckpoint() {
+	s.expectCheckpoint("4", 4, nil)
+	s.expectBatch("5", 1, true)
+
+	var afters []string
+	s.Require().NoError(keys(&afters).Run(context.Background(), s.db))
+	s.Require().Equal([]string{"4"}, afters)
+}
+
+func (s *backfillSuite) TestEmptyBatchKeepsCheckpoint() {
+	s.expectCheckpoint("5", 5, nil)
+	s.expectBatch("5", 0, true)
+
+	var afters []string
+	s.Require().NoError(keys(&afters).Run(context.Background(), s.db))
+}
+
+func (s *backfillSuite) TestSkipsFinishedBackfill() {
+	s.expectCheckpoint("5", 5, time.Now())
+
+	var afters []string
+	s.Require().NoError(keys(&afters).Run(context.Background(), s.db))
+	s.Require().Empty(afters)
+}
+
+func (s *backfillSuite) TestFailedBatchKeepsCheckpoint() {
+	s.expectCheckpoint("", 0, nil)
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta(`SELECT 1 FROM tasks.backfills WHERE name = $1 FOR UPDATE`)).
+		WithArgs("summaries").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectRollback()
+
+	backfill := Backfill{
+		Name: "summaries",
+		Batch: func(ctx context.Context, tx *sql.Tx, after string, limit int) (string, int, error) {
+			return "", 0, errors.New("boom")
+		},
+	}
+	s.Require().ErrorContains(backfill.Run(context.Background(), s.db), "boom")
+}
+
+func (s *backfillSuite) TestStopsDuringPause() {
+	s.expectCheckpoint("", 0, nil)
+	s.expectBatch("2", 2, false)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	var afters []string
+	backfill := keys(&afters)
+	backfill.Pause = time.Hour
+
+	s.Require().ErrorIs(backfill.Run(ctx, s.db), context.DeadlineExceeded)
+	s.Require().Equal([]string{""}, afters)
+}
+
+func (s *backfillSuite) TestReset() {
+	s.mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM tasks.backfills WHERE name = $1`)).
+		WithArgs("summaries").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	s.Require().NoError(Backfill{Name: "summaries"}.Reset(context.Background(), s.db))
+}
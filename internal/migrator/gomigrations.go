@@ -0,0 +1,33 @@
+package migrator
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/pressly/goose/v3"
+)
+
+// goMigrations are the migrations written in Go, applied in version order together with the
+// SQL files. They are registered by the init functions of the files of this package, named
+// after their version like the SQL files, such as 000009_task_summaries.go.
+var goMigrations = map[int64]*goose.Migration{}
+
+// register adds a Go migration. Set RunTx of up and down to run in a transaction, RunDB to run
+// without one, such as a Backfill committing batch by batch. A nil down cannot be rolled back
+// beyond recording the version.
+func register(version int64, up, down *goose.GoFunc) {
+	if _, ok := goMigrations[version]; ok {
+		panic(fmt.Sprintf("migrator: Go migration %d registered twice", version))
+	}
+
+	goMigrations[version] = goose.NewGoMigration(version, up, down)
+}
+
+// registered returns the Go migrations in version order
+func registered() []*goose.Migration {
+	return slices.SortedFunc(maps.Values(goMigrations), func(a, b *goose.Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+}
@@ -0,0 +1,424 @@
+package migrator
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Finding is an operation of a migration which is unsafe while the previous version of the
+// service still runs against the database
+type Finding struct {
+	Path string
+	Line int
+	Rule string
+	// Message tells why the operation is unsafe and how to do it safely
+	Message string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s:%d: %s: %s", f.Path, f.Line, f.Rule, f.Message)
+}
+
+type LintOptions struct {
+	// Referenced reports whether the code still uses a column of a table, see SourceReferences.
+	// Nil considers every column referenced.
+	Referenced func(table, column string) bool
+	// After skips the migrations up to and including this version, such as applied ones
+	After int64
+}
+
+// statement is a statement of the Up section of a migration
+type statement struct {
+	line int
+	sql  string
+	// ignored are the rules disabled with a "-- lint:ignore rule" comment before the statement
+	ignored []string
+}
+
+var (
+	createTableRe  = regexp.MustCompile(`^CREATE (?:UNLOGGED )?TABLE (?:IF NOT EXISTS )?([^\s(]+)`)
+	createIndexRe  = regexp.MustCompile(`^CREATE (?:UNIQUE )?INDEX (CONCURRENTLY )?(?:IF NOT EXISTS )?(?:\S+ )?ON (?:ONLY )?([^\s(]+)`)
+	alterTableRe   = regexp.MustCompile(`^ALTER TABLE (?:IF EXISTS )?(?:ONLY )?(\S+) (.+)$`)
+	addColumnRe    = regexp.MustCompile(`^ADD (?:COLUMN )?(?:IF NOT EXISTS )?(\S+) (.+)$`)
+	dropColumnRe   = regexp.MustCompile(`^DROP (?:COLUMN )?(?:IF EXISTS )?(\S+)`)
+	renameColumnRe = regexp.MustCompile(`^RENAME (?:COLUMN )?(\S+) TO (\S+)$`)
+	alterTypeRe    = regexp.MustCompile(`^ALTER (?:COLUMN )?(\S+) (?:SET DATA )?TYPE `)
+	setNotNullRe   = regexp.MustCompile(`^ALTER (?:COLUMN )?(\S+) SET NOT NULL$`)
+	validatedRe    = regexp.MustCompile(`\b(?:FOREIGN KEY|REFERENCES|CHECK)\b`)
+	dmlRe          = regexp.MustCompile(`^(?:WITH .* )?(UPDATE|DELETE FROM) (?:ONLY )?([^\s(]+)`)
+	volatileRe     = regexp.MustCompile(`\bDEFAULT .*\b(?:RANDOM|GEN_RANDOM_UUID|UUID_GENERATE_V[14]|CLOCK_TIMESTAMP|TIMEOFDAY|NEXTVAL)\s*\(|\b(?:SMALL|BIG)?SERIAL\b`)
+	constraintRe   = regexp.MustCompile(`^(?:CONSTRAINT|PRIMARY|UNIQUE|FOREIGN|CHECK|EXCLUDE)\b`)
+	ignoreRe       = regexp.MustCompile(`^--\s*lint:ignore\s+(.+)$`)
+)
+
+// Lint checks the SQL migrations of the migrations directory of migrationFS
+func Lint(migrationFS fs.FS, opts LintOptions) ([]Finding, error) {
+	entries, err := fs.ReadDir(migrationFS, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+
+	findings := make([]Finding, 0)
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		var version int64
+		if _, err := fmt.Sscanf(entry.Name(), "%d_", &version); err != nil {
+			return nil, fmt.Errorf("migration %s has no version", entry.Name())
+		}
+		if version <= opts.After {
+			continue
+		}
+
+		content, err := fs.ReadFile(migrationFS, path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+		findings = append(findings, LintFile(entry.Name(), string(content), opts)...)
+	}
+
+	return findings, nil
+}
+
+// LintFile checks the Up section of a SQL migration and that it can be rolled back. Tables
+// created by the migration itself are new and unused, operations on them are not checked.
+func LintFile(name, content string, opts LintOptions) []Finding {
+	referenced := opts.Referenced
+	if referenced == nil {
+		referenced = func(string, string) bool { return true }
+	}
+
+	findings := make([]Finding, 0)
+	report := func(stmt statement, rule, message string) {
+		if !slices.Contains(stmt.ignored, rule) {
+			findings = append(findings, Finding{Path: name, Line: stmt.line, Rule: rule, Message: message})
+		}
+	}
+
+	if !strings.Contains(content, "-- +goose Down") {
+		findings = append(findings, Finding{Path: name, Line: 1, Rule: "missing-down",
+			Message: "the migration has no -- +goose Down section and cannot be rolled back"})
+	} else if Section(content, false) == "" {
+		findings = append(findings, Finding{Path: name, Line: 1, Rule: "missing-down",
+			Message: "the -- +goose Down section is empty, write the statements rolling the migration back"})
+	}
+
+	noTransaction := strings.Contains(content, "-- +goose NO TRANSACTION")
+	created := map[string]bool{}
+
+	for _, stmt := range upStatements(content) {
+		if m := createTableRe.FindStringSubmatch(stmt.sql); m != nil {
+			created[tableName(m[1])] = true
+
+			continue
+		}
+
+		if m := createIndexRe.FindStringSubmatch(stmt.sql); m != nil {
+			switch {
+			case created[tableName(m[2])]:
+			case m[1] == "":
+				report(stmt, "index-not-concurrent",
+					"CREATE INDEX blocks writes to "+m[2]+" until it is built, use CREATE INDEX CONCURRENTLY")
+			case !noTransaction:
+				report(stmt, "concurrently-in-transaction",
+					"CREATE INDEX CONCURRENTLY fails in a transaction, add -- +goose NO TRANSACTION")
+			}
+
+			continue
+		}
+
+		if strings.HasPrefix(stmt.sql, "DROP INDEX ") {
+			if !strings.HasPrefix(stmt.sql, "DROP INDEX CONCURRENTLY ") {
+				report(stmt, "index-not-concurrent", "DROP INDEX blocks reads and writes of its table, use DROP INDEX CONCURRENTLY")
+			} else if !noTransaction {
+				report(stmt, "concurrently-in-transaction", "DROP INDEX CONCURRENTLY fails in a transaction, add -- +goose NO TRANSACTION")
+			}
+
+			continue
+		}
+
+		if strings.HasPrefix(stmt.sql, "VACUUM FULL") || strings.HasPrefix(stmt.sql, "CLUSTER") {
+			report(stmt, "table-rewrite", "the table is rewritten under an exclusive lock, blocking reads and writes")
+
+			continue
+		}
+
+		if m := dmlRe.FindStringSubmatch(stmt.sql); m != nil {
+			if !created[tableName(m[2])] {
+				report(stmt, "unbatched-update", fmt.Sprintf(
+					"%s on %s holds the locks of every row it touches until the migration commits, run it in batches with a Backfill from a Go migration",
+					strings.TrimSuffix(m[1], " FROM"), tableName(m[2])))
+			}
+
+			continue
+		}
+
+		m := alterTableRe.FindStringSubmatch(stmt.sql)
+		if m == nil || created[tableName(m[1])] {
+			continue
+		}
+		table := tableName(m[1])
+
+		for _, action := range splitActions(m[2]) {
+			lintAlter(stmt, table, action, referenced, report)
+		}
+	}
+
+	return findings
+}
+
+// lintAlter checks an action of ALTER TABLE on an existing table
+func lintAlter(stmt statement, table, action string, referenced func(table, column string) bool, report func(statement, string, string)) {
+	switch {
+	case strings.HasPrefix(action, "ADD ") && !constraintRe.MatchString(strings.TrimPrefix(action, "ADD ")):
+		m := addColumnRe.FindStringSubmatch(action)
+		if m == nil {
+			return
+		}
+
+		if strings.Contains(m[2], "NOT NULL") && !strings.Contains(m[2], "DEFAULT") {
+			report(stmt, "not-null-without-default", fmt.Sprintf(
+				"adding %s.%s as NOT NULL without DEFAULT fails on existing rows and breaks inserts of the running version, add a DEFAULT",
+				table, columnName(m[1])))
+		}
+		if volatileRe.MatchString(m[2]) {
+			report(stmt, "table-rewrite", fmt.Sprintf(
+				"the volatile default of %s.%s rewrites the table under an exclusive lock, add the column without it and backfill",
+				table, columnName(m[1])))
+		}
+	case strings.HasPrefix(action, "ADD ") && validatedRe.MatchString(action) && !strings.HasSuffix(action, " NOT VALID"):
+		report(stmt, "constraint-not-valid", fmt.Sprintf(
+			"the constraint is checked against every row of %s while writes are blocked, add it NOT VALID and VALIDATE CONSTRAINT in a later migration",
+			table))
+	case strings.HasPrefix(action, "DROP ") && !strings.HasPrefix(action, "DROP CONSTRAINT "):
+		m := dropColumnRe.FindStringSubmatch(action)
+		if m == nil {
+			return
+		}
+
+		if column := columnName(m[1]); referenced(table, column) {
+			report(stmt, "column-drop", fmt.Sprintf(
+				"%s.%s is still referenced by the code, release code no longer using it before dropping it", table, column))
+		}
+	case strings.HasPrefix(action, "RENAME TO "):
+		report(stmt, "table-rename", fmt.Sprintf(
+			"the running version still uses %s, create the new table and move to it over several releases", table))
+	case strings.HasPrefix(action, "RENAME ") && !strings.HasPrefix(action, "RENAME CONSTRAINT "):
+		m := renameColumnRe.FindStringSubmatch(action)
+		if m == nil {
+			return
+		}
+
+		if column := columnName(m[1]); referenced(table, column) {
+			report(stmt, "column-rename", fmt.Sprintf(
+				"%s.%s is still referenced by the code, add %s, backfill it and drop %s once unused",
+				table, column, columnName(m[2]), column))
+		}
+	case setNotNullRe.MatchString(action):
+		column := columnName(setNotNullRe.FindStringSubmatch(action)[1])
+		report(stmt, "set-not-null", fmt.Sprintf(
+			"SET NOT NULL scans %s under an exclusive lock, add CHECK (%s IS NOT NULL) NOT VALID, validate it and set NOT NULL in a later migration",
+			table, column))
+	case alterTypeRe.MatchString(action):
+		m := alterTypeRe.FindStringSubmatch(action)
+		report(stmt, "table-rewrite", fmt.Sprintf(
+			"changing the type of %s.%s rewrites the table under an exclusive lock, add a new column and backfill it",
+			table, columnName(m[1])))
+	}
+}
+
+// upStatements splits the Up section of a migration into statements, normalized to single
+// spaces with upper case keywords. Comments are removed, string literals are kept.
+func upStatements(content string) []statement {
+	var (
+		statements []statement
+		current    strings.Builder
+		start      int
+		ignored    []string
+		up         bool
+		// quote is the open quote, ' or $$
+		quote string
+	)
+
+	for i, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if annotation, ok := strings.CutPrefix(trimmed, "-- +goose "); ok && quote == "" {
+			switch strings.Fields(annotation)[0] {
+			case "Up":
+				up = true
+			case "Down":
+				up = false
+			}
+
+			continue
+		}
+		if !up {
+			continue
+		}
+
+		if m := ignoreRe.FindStringSubmatch(trimmed); m != nil && quote == "" {
+			for _, rule := range strings.Split(m[1], ",") {
+				ignored = append(ignored, strings.TrimSpace(rule))
+			}
+
+			continue
+		}
+
+		for j := 0; j < len(line); j++ {
+			c := line[j]
+			switch {
+			case quote == "" && strings.HasPrefix(line[j:], "--"):
+				j = len(line)
+
+				continue
+			case quote == "" && c == '\'':
+				quote = "'"
+			case quote == "" && strings.HasPrefix(line[j:], "$$"):
+				quote = "$$"
+				current.WriteString("$")
+				j++
+			case quote == "'" && c == '\'':
+				quote = ""
+			case quote == "$$" && strings.HasPrefix(line[j:], "$$"):
+				quote = ""
+				current.WriteString("$")
+				j++
+			case quote == "" && c == ';':
+				if sql := normalize(current.String()); sql != "" {
+					statements = append(statements, statement{line: start, sql: sql, ignored: ignored})
+				}
+				current.Reset()
+				ignored = nil
+
+				continue
+			}
+
+			if current.Len() == 0 && c != ' ' && c != '\t' {
+				start = i + 1
+			}
+			if current.Len() > 0 || (c != ' ' && c != '\t') {
+				current.WriteByte(c)
+			}
+		}
+		if current.Len() > 0 {
+			current.WriteByte('\n')
+		}
+	}
+
+	if sql := normalize(current.String()); sql != "" {
+		statements = append(statements, statement{line: start, sql: sql, ignored: ignored})
+	}
+
+	return statements
+}
+
+// normalize collapses white space and upper cases the statement outside string literals
+func normalize(sql string) string {
+	var b strings.Builder
+
+	quoted := false
+	for _, field := range strings.Fields(sql) {
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+
+		if !quoted && strings.Count(field, "'")%2 == 0 {
+			b.WriteString(strings.ToUpper(field))
+		} else {
+			b.WriteString(field)
+		}
+		if strings.Count(field, "'")%2 == 1 {
+			quoted = !quoted
+		}
+	}
+
+	return b.String()
+}
+
+// splitActions splits the actions of ALTER TABLE at the commas outside parentheses
+func splitActions(actions string) []string {
+	var (
+		split []string
+		depth int
+		start int
+	)
+
+	for i, c := range actions {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				split = append(split, strings.TrimSpace(actions[start:i]))
+				start = i + 1
+			}
+		}
+	}
+
+	return append(split, strings.TrimSpace(actions[start:]))
+}
+
+func tableName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, `"`, ""))
+}
+
+func columnName(name string) string {
+	return strings.ToLower(strings.Trim(name, `"`))
+}
+
+// SourceReferences returns a Referenced function searching the Go files under root, outside
+// tests, vendor and the migrations. A column counts as referenced when a file names both the
+// table and the column.
+func SourceReferences(root string) (func(table, column string) bool, error) {
+	sources := make([]string, 0)
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if name := d.Name(); name == "vendor" || name == "migrator" || (strings.HasPrefix(name, ".") && p != root) {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if !strings.HasSuffix(p, ".go") || strings.HasSuffix(p, "_test.go") {
+			return nil
+		}
+
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		sources = append(sources, strings.ToLower(string(content)))
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sources: %w", err)
+	}
+
+	return func(table, column string) bool {
+		columnRe := regexp.MustCompile(`\b` + regexp.QuoteMeta(column) + `\b`)
+
+		for _, source := range sources {
+			if strings.Contains(source, table) && columnRe.MatchString(source) {
+				return true
+			}
+		}
+
+		return false
+	}, nil
+}
@@ -0,0 +1,95 @@
+package migrator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestMigrationsAreSafe fails CI on migrations unsafe for zero-downtime deploys, disable a rule
+// for a statement with a "-- lint:ignore rule" comment above it where it is safe
+func TestMigrationsAreSafe(t *testing.T) {
+	referenced, err := SourceReferences("../..")
+	require.NoError(t, err)
+
+	findings, err := Lint(FS, LintOptions{Referenced: referenced})
+	require.NoError(t, err)
+
+	for _, finding := range findings {
+		t.Error(finding)
+	}
+}
+
+func TestLintFile(t *testing.T) {
+	const down = "\n-- +goose Down\nSELECT 1;\n"
+
+	tests := []struct {
+		name  string
+		up    string
+		rules []string
+	}{
+		{"new table", "CREATE TABLE tasks.tags (id UUID NOT NULL);\nCREATE INDEX tags_idx ON tasks.tags (id);", nil},
+		{"nullable column", "ALTER TABLE tasks.tasks ADD COLUMN priority INT DEFAULT NULL;", nil},
+		{"not null with default", "ALTER TABLE tasks.tasks ADD COLUMN priority INT NOT NULL DEFAULT 0;", nil},
+		{"not null without default", "ALTER TABLE tasks.tasks ADD COLUMN priority INT NOT NULL;", []string{"not-null-without-default"}},
+		{"index", "create index tasks_title_idx on tasks.tasks (title);", []string{"index-not-concurrent"}},
+		{"concurrent index in transaction", "CREATE INDEX CONCURRENTLY tasks_title_idx ON tasks.tasks (title);", []string{"concurrently-in-transaction"}},
+		{"drop index", "DROP INDEX tasks.tasks_title_idx;", []string{"index-not-concurrent"}},
+		{"drop referenced column", "ALTER TABLE tasks.tasks DROP COLUMN title;", []string{"column-drop"}},
+		{"drop unused column", "ALTER TABLE tasks.tasks DROP COLUMN legacy;", nil},
+		{"rename column", "ALTER TABLE tasks.tasks RENAME COLUMN title TO name;", []string{"column-rename"}},
+		{"rename table", "ALTER TABLE tasks.tasks RENAME TO todos;", []string{"table-rename"}},
+		{"change type", "ALTER TABLE tasks.tasks ALTER COLUMN title TYPE VARCHAR(200);", []string{"table-rewrite"}},
+		{"volatile default", "ALTER TABLE tasks.tasks ADD COLUMN token UUID DEFAULT gen_random_uuid();", []string{"table-rewrite"}},
+		{"several actions", "ALTER TABLE tasks.tasks ADD COLUMN a INT NOT NULL, DROP COLUMN title;", []string{"not-null-without-default", "column-drop"}},
+		{"ignored", "-- lint:ignore index-not-concurrent\nCREATE INDEX tasks_title_idx ON tasks.tasks (title);", nil},
+		{"keywords in strings", "INSERT INTO tasks.tasks (title) VALUES ('ALTER TABLE x DROP COLUMN title; --');", nil},
+		{"set not null", "ALTER TABLE tasks.tasks ALTER COLUMN title SET NOT NULL;", []string{"set-not-null"}},
+		{"drop not null", "ALTER TABLE tasks.tasks ALTER COLUMN title DROP NOT NULL;", nil},
+		{"foreign key", "ALTER TABLE tasks.tasks ADD CONSTRAINT tasks_view_fk FOREIGN KEY (view_id) REFERENCES tasks.views (id);", []string{"constraint-not-valid"}},
+		{"foreign key not valid", "ALTER TABLE tasks.tasks ADD CONSTRAINT tasks_view_fk FOREIGN KEY (view_id) REFERENCES tasks.views (id) NOT VALID;", nil},
+		{"check", "ALTER TABLE tasks.tasks ADD CHECK (title <> '');", []string{"constraint-not-valid"}},
+		{"check not valid", "ALTER TABLE tasks.tasks ADD CONSTRAINT tasks_title_check CHECK (title IS NOT NULL) NOT VALID;", nil},
+		{"validate constraint", "ALTER TABLE tasks.tasks VALIDATE CONSTRAINT tasks_title_check;", nil},
+		{"update", "UPDATE tasks.tasks SET title = 'ALTER TABLE x DROP COLUMN title; --';", []string{"unbatched-update"}},
+		{"delete", "DELETE FROM tasks.tasks WHERE deleted_at IS NOT NULL;", []string{"unbatched-update"}},
+		{"update of new table", "CREATE TABLE tasks.tags (id UUID);\nUPDATE tasks.tags SET id = NULL;", nil},
+		{"update in cte", "WITH gone AS (DELETE FROM tasks.views RETURNING id) UPDATE tasks.tasks SET title = '';", []string{"unbatched-update"}},
+	}
+
+	// only title is used by the code
+	referenced := func(table, column string) bool {
+		return table == "tasks.tasks" && column == "title"
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := LintFile("1_test.sql", "-- +goose Up\n"+tt.up+down, LintOptions{Referenced: referenced})
+
+			rules := make([]string, 0)
+			for _, finding := range findings {
+				rules = append(rules, finding.Rule)
+			}
+			require.ElementsMatch(t, tt.rules, rules)
+		})
+	}
+}
+
+func TestLintFileRequiresDown(t *testing.T) {
+	findings := LintFile("1_test.sql", "-- +goose Up\nSELECT 1;\n", LintOptions{})
+	require.Len(t, findings, 1)
+	require.Equal(t, "missing-down", findings[0].Rule)
+
+	findings = LintFile("1_test.sql", "-- +goose Up\nSELECT 1;\n-- +goose Down\n", LintOptions{})
+	require.Len(t, findings, 1)
+	require.Equal(t, "missing-down", findings[0].Rule)
+}
+
+func TestLintFileReportsLines(t *testing.T) {
+	content := "-- +goose NO TRANSACTION\n-- +goose Up\nSELECT 1;\n\nCREATE INDEX\n    tasks_title_idx ON tasks.tasks (title);\n-- +goose Down\nSELECT 1;\n"
+
+	findings := LintFile("1_test.sql", content, LintOptions{})
+	require.Len(t, findings, 1)
+	require.Equal(t, 5, findings[0].Line)
+	require.Equal(t, "1_test.sql:5: index-not-concurrent: CREATE INDEX blocks writes to TASKS.TASKS until it is built, use CREATE INDEX CONCURRENTLY", findings[0].String())
+}
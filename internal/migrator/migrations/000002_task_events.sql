@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE tasks.events (
+    id BIGSERIAL PRIMARY KEY,
+    type TEXT NOT NULL,
+    task_id UUID NOT NULL,
+    status TEXT DEFAULT NULL,
+    payload TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX events_created_at_idx ON tasks.events (created_at);
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS tasks.events;
+
+-- +goose StatementEnd
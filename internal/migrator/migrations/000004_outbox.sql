@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE tasks.outbox (
+    id BIGSERIAL PRIMARY KEY,
+    aggregate_type TEXT NOT NULL,
+    aggregate_id UUID NOT NULL,
+    event_type TEXT NOT NULL,
+    status TEXT DEFAULT NULL,
+    payload TEXT NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT DEFAULT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    sent_at TIMESTAMP DEFAULT NULL
+);
+
+CREATE INDEX outbox_unsent_idx ON tasks.outbox (aggregate_id, id) WHERE sent_at IS NULL;
+
+ALTER TABLE tasks.events ADD COLUMN outbox_id BIGINT DEFAULT NULL;
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE tasks.events DROP COLUMN IF EXISTS outbox_id;
+DROP TABLE IF EXISTS tasks.outbox;
+
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+-- +goose StatementBegin
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS events_outbox_id_idx ON tasks.events (outbox_id);
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX CONCURRENTLY IF EXISTS tasks.events_outbox_id_idx;
+
+-- +goose StatementEnd
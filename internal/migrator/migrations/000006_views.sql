@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE tasks.views (
+    id UUID PRIMARY KEY,
+    owner_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    definition JSONB NOT NULL,
+    shared_with TEXT[] NOT NULL DEFAULT '{}',
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT NULL
+);
+
+CREATE UNIQUE INDEX views_owner_name_idx ON tasks.views (owner_id, name);
+-- at most one default view per owner
+CREATE UNIQUE INDEX views_owner_default_idx ON tasks.views (owner_id) WHERE is_default;
+CREATE INDEX views_shared_with_idx ON tasks.views USING GIN (shared_with);
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS tasks.views;
+
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE tasks.jobs (
+    id UUID PRIMARY KEY,
+    type TEXT NOT NULL,
+    params TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'queued',
+    progress INT NOT NULL DEFAULT 0,
+    attempts INT NOT NULL DEFAULT 0,
+    cancel_requested BOOLEAN NOT NULL DEFAULT FALSE,
+    locked_until TIMESTAMP DEFAULT NULL,
+    error TEXT DEFAULT NULL,
+    result TEXT DEFAULT NULL,
+    result_type TEXT DEFAULT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP DEFAULT NULL,
+    finished_at TIMESTAMP DEFAULT NULL
+);
+
+-- queued jobs and running jobs whose lease may expire are the only ones claimed
+CREATE INDEX jobs_claim_idx ON tasks.jobs (created_at) WHERE status IN ('queued', 'running');
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS tasks.jobs;
+
+-- +goose StatementEnd
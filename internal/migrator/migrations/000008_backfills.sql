@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- checkpoints of the batched backfills of the Go migrations, a backfill resumes after last_key
+CREATE TABLE tasks.backfills (
+    name TEXT PRIMARY KEY,
+    last_key TEXT NOT NULL DEFAULT '',
+    processed BIGINT NOT NULL DEFAULT 0,
+    started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP DEFAULT NULL
+);
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS tasks.backfills;
+
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+-- deliveries created by a redelivery point at the delivery they repeat, an event gets a single
+-- delivery per webhook otherwise. Without a transaction the column is committed at once, so its
+-- exclusive lock is not held while the update below scans the table.
+ALTER TABLE tasks.webhook_deliveries ADD COLUMN IF NOT EXISTS redelivery_of UUID DEFAULT NULL;
+
+-- the relay enqueued the same event again when another publisher failed, the copies are kept as
+-- redeliveries of the first delivery. Only these copies are locked, they are few and no longer
+-- written, so a single statement is safe.
+-- lint:ignore unbatched-update
+UPDATE tasks.webhook_deliveries d
+SET redelivery_of = original.id
+FROM (
+    SELECT DISTINCT ON (webhook_id, event_id) id, webhook_id, event_id
+    FROM tasks.webhook_deliveries
+    ORDER BY webhook_id, event_id, created_at, id
+) original
+WHERE d.webhook_id = original.webhook_id AND d.event_id = original.event_id AND d.id <> original.id
+  AND d.redelivery_of IS NULL;
+
+-- +goose Down
+ALTER TABLE tasks.webhook_deliveries DROP COLUMN IF EXISTS redelivery_of;
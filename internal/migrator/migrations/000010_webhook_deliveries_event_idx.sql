@@ -0,0 +1,12 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+-- +goose StatementBegin
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS webhook_deliveries_event_idx
+    ON tasks.webhook_deliveries (webhook_id, event_id) WHERE redelivery_of IS NULL;
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX CONCURRENTLY IF EXISTS tasks.webhook_deliveries_event_idx;
+
+-- +goose StatementEnd
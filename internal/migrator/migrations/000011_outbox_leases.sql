@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- the relay leases the messages it publishes instead of locking them for the whole publish,
+-- published_to are the publishers which took a message whose publish failed on another one
+ALTER TABLE tasks.outbox ADD COLUMN locked_until TIMESTAMP DEFAULT NULL;
+ALTER TABLE tasks.outbox ADD COLUMN published_to TEXT[] NOT NULL DEFAULT '{}';
+-- a message failing OUTBOX_MAX_ATTEMPTS times is dead, it no longer holds back its aggregate
+ALTER TABLE tasks.outbox ADD COLUMN dead_at TIMESTAMP DEFAULT NULL;
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE tasks.outbox DROP COLUMN IF EXISTS dead_at;
+ALTER TABLE tasks.outbox DROP COLUMN IF EXISTS published_to;
+ALTER TABLE tasks.outbox DROP COLUMN IF EXISTS locked_until;
+
+-- +goose StatementEnd
@@ -1,39 +1,393 @@
 package migrator
 
 import (
+	"bufio"
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
 
 	"go-tasks-api/internal/database"
 
 	_ "github.com/lib/pq"
 	"github.com/pressly/goose/v3"
+	goosedb "github.com/pressly/goose/v3/database"
+	"github.com/pressly/goose/v3/lock"
 )
 
-func Run(ctx context.Context, dsn string, migrationTable string, migrationFS fs.FS) error {
-	db, err := database.NewConnection(dsn, 1)
+// Dir is the directory of the migrations in FS and in the repository
+const Dir = "internal/migrator/migrations"
+
+// poolSize is the number of connections of a migrator: the advisory lock holds one of them for
+// the whole run, goose runs the migrations on another and Go migrations run without a
+// transaction take a third from the pool
+const poolSize = 3
+
+// lockRetries and lockRetryInterval bound the wait for the advisory lock to an hour, the time a
+// long backfill of another migrator may take
+const (
+	lockRetries       = 720
+	lockRetryInterval = 5 * time.Second
+)
+
+// Migrator applies and rolls back the migrations of a database. Commands changing the schema
+// hold a Postgres advisory lock, a second migrator waits up to an hour for the first one to
+// finish and fails after.
+type Migrator struct {
+	db       *sql.DB
+	provider *goose.Provider
+	// unlocked runs the migrations of commands taking the lock themselves, such as Redo
+	unlocked *goose.Provider
+	locker   lock.SessionLocker
+	fsys     fs.FS
+}
+
+// Script is the SQL a migration runs in one direction
+type Script struct {
+	Version int64
+	Path    string
+	SQL     string
+}
+
+// Run applies all pending migrations
+func Run(ctx context.Context, dsn string, connectTimeout time.Duration, migrationTable string, migrationFS fs.FS) error {
+	m, err := Open(ctx, dsn, connectTimeout, migrationTable, migrationFS)
 	if err != nil {
-		return fmt.Errorf("failed to establish db connection: %w", err)
+		return err
 	}
+	defer m.Close()
 
-	if err := goose.SetDialect("postgres"); err != nil {
-		return fmt.Errorf(
-			"unable to apply sql dialect to migrations package %q: %w",
-			"postgres",
-			err,
-		)
+	if _, err := m.Up(ctx); err != nil {
+		return err
 	}
 
-	goose.SetTableName(migrationTable)
-	goose.SetLogger(&logger{})
-	goose.SetVerbose(true)
+	return nil
+}
+
+// Open connects to the database and reads the migrations of the migrations directory of
+// migrationFS, versions are recorded in migrationTable
+func Open(ctx context.Context, dsn string, connectTimeout time.Duration, migrationTable string, migrationFS fs.FS) (*Migrator, error) {
+	db, err := database.NewConnection(ctx, dsn, poolSize, connectTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to establish db connection: %w", err)
+	}
 
-	goose.SetBaseFS(migrationFS)
+	m, err := New(db, migrationTable, migrationFS)
+	if err != nil {
+		_ = db.Close()
 
-	if err := goose.UpContext(ctx, db, "migrations"); err != nil {
-		return fmt.Errorf("unable to apply database migrations: %w", err)
+		return nil, err
 	}
 
-	return nil
+	return m, nil
+}
+
+// New creates a Migrator of the migrations directory of migrationFS and the registered Go
+// migrations on the database
+func New(db *sql.DB, migrationTable string, migrationFS fs.FS) (*Migrator, error) {
+	fsys, err := fs.Sub(migrationFS, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open migrations directory: %w", err)
+	}
+
+	store, err := goosedb.NewStore(goosedb.DialectPostgres, migrationTable)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migration store: %w", err)
+	}
+
+	locker, err := lock.NewPostgresSessionLocker(lock.WithLockTimeout(uint64(lockRetryInterval/time.Second), lockRetries))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migration lock: %w", err)
+	}
+
+	options := []goose.ProviderOption{
+		goose.WithStore(store),
+		goose.WithGoMigrations(registered()...),
+		goose.WithDisableGlobalRegistry(true),
+		goose.WithLogger(&logger{}),
+		goose.WithVerbose(true),
+	}
+
+	provider, err := goose.NewProvider(goose.DialectCustom, db, fsys, append(options, goose.WithSessionLocker(locker))...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	unlocked, err := goose.NewProvider(goose.DialectCustom, db, fsys, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	return &Migrator{
+		db:       db,
+		provider: provider,
+		unlocked: unlocked,
+		locker:   locker,
+		fsys:     fsys,
+	}, nil
+}
+
+// Latest returns the version of the newest migration of the migrations directory of migrationFS
+// and the Go migrations, the schema version the service was built for
+func Latest(migrationFS fs.FS) (int64, error) {
+	names, err := fs.Glob(migrationFS, "migrations/*.sql")
+	if err != nil {
+		return 0, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	var latest int64
+	for _, name := range names {
+		version, err := goose.NumericComponent(name)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read version of migration %s: %w", name, err)
+		}
+		latest = max(latest, version)
+	}
+	for version := range goMigrations {
+		latest = max(latest, version)
+	}
+
+	return latest, nil
+}
+
+// Close closes the database connection
+func (m *Migrator) Close() error {
+	return m.db.Close()
+}
+
+// Up applies all pending migrations
+func (m *Migrator) Up(ctx context.Context) ([]*goose.MigrationResult, error) {
+	results, err := m.provider.Up(ctx)
+	if err != nil {
+		return results, fmt.Errorf("unable to apply database migrations: %w", err)
+	}
+
+	return results, nil
+}
+
+// UpTo applies the pending migrations up to and including version
+func (m *Migrator) UpTo(ctx context.Context, version int64) ([]*goose.MigrationResult, error) {
+	results, err := m.provider.UpTo(ctx, version)
+	if err != nil {
+		return results, fmt.Errorf("unable to apply database migrations up to %d: %w", version, err)
+	}
+
+	return results, nil
+}
+
+// Down rolls back the latest applied migration
+func (m *Migrator) Down(ctx context.Context) (*goose.MigrationResult, error) {
+	result, err := m.provider.Down(ctx)
+	if err != nil {
+		return result, fmt.Errorf("unable to roll back database migration: %w", err)
+	}
+
+	return result, nil
+}
+
+// DownTo rolls back the applied migrations after version, 0 rolls back all of them
+func (m *Migrator) DownTo(ctx context.Context, version int64) ([]*goose.MigrationResult, error) {
+	results, err := m.provider.DownTo(ctx, version)
+	if err != nil {
+		return results, fmt.Errorf("unable to roll back database migrations to %d: %w", version, err)
+	}
+
+	return results, nil
+}
+
+// Redo rolls back the latest applied migration and applies it again, both under one lock so no
+// other migrator runs in between
+func (m *Migrator) Redo(ctx context.Context) (results []*goose.MigrationResult, err error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if err := m.locker.SessionLock(ctx, conn); err != nil {
+		return nil, fmt.Errorf("failed to lock migrations: %w", err)
+	}
+	defer func() {
+		// the context may be cancelled, the lock is released anyway
+		if uErr := m.locker.SessionUnlock(context.WithoutCancel(ctx), conn); uErr != nil && err == nil {
+			err = fmt.Errorf("failed to unlock migrations: %w", uErr)
+		}
+	}()
+
+	down, err := m.unlocked.Down(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to roll back database migration: %w", err)
+	}
+
+	up, err := m.unlocked.ApplyVersion(ctx, down.Source.Version, true)
+	if err != nil {
+		return []*goose.MigrationResult{down, up}, fmt.Errorf("unable to apply database migration %d again: %w", down.Source.Version, err)
+	}
+
+	return []*goose.MigrationResult{down, up}, nil
+}
+
+// Status returns the state of every migration
+func (m *Migrator) Status(ctx context.Context) ([]*goose.MigrationStatus, error) {
+	status, err := m.provider.Status(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read migration status: %w", err)
+	}
+
+	return status, nil
+}
+
+// Version returns the latest applied version, 0 when none is
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	version, err := m.provider.GetDBVersion(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("unable to read database version: %w", err)
+	}
+
+	return version, nil
+}
+
+// PendingUp returns the scripts Up or UpTo would run, in order. A negative version stands for
+// all pending migrations.
+func (m *Migrator) PendingUp(ctx context.Context, version int64) ([]Script, error) {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	scripts := make([]Script, 0)
+	for _, s := range status {
+		if s.State != goose.StatePending || (version >= 0 && s.Source.Version > version) {
+			continue
+		}
+
+		script, err := m.script(s.Source, true)
+		if err != nil {
+			return nil, err
+		}
+		scripts = append(scripts, script)
+	}
+
+	return scripts, nil
+}
+
+// PendingDown returns the scripts DownTo would run, in order. A negative version stands for the
+// latest applied migration only, like Down.
+func (m *Migrator) PendingDown(ctx context.Context, version int64) ([]Script, error) {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	scripts := make([]Script, 0)
+	for _, s := range slices.Backward(status) {
+		if s.State != goose.StateApplied || (version >= 0 && s.Source.Version <= version) {
+			continue
+		}
+
+		script, err := m.script(s.Source, false)
+		if err != nil {
+			return nil, err
+		}
+		scripts = append(scripts, script)
+
+		if version < 0 {
+			break
+		}
+	}
+
+	return scripts, nil
+}
+
+// script reads the SQL of the source in the given direction
+func (m *Migrator) script(source *goose.Source, up bool) (Script, error) {
+	if source.Type == goose.TypeGo {
+		return Script{
+			Version: source.Version,
+			Path:    source.Path,
+			SQL:     fmt.Sprintf("-- Go migration %d, its statements are only known when it runs", source.Version),
+		}, nil
+	}
+
+	content, err := fs.ReadFile(m.fsys, source.Path)
+	if err != nil {
+		return Script{}, fmt.Errorf("failed to read migration %s: %w", source.Path, err)
+	}
+
+	return Script{
+		Version: source.Version,
+		Path:    source.Path,
+		SQL:     Section(string(content), up),
+	}, nil
+}
+
+// Section returns the Up or Down section of a goose SQL migration without its annotations
+func Section(content string, up bool) string {
+	var (
+		b       strings.Builder
+		current string
+	)
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if annotation, ok := strings.CutPrefix(strings.TrimSpace(line), "-- +goose "); ok {
+			switch strings.Fields(annotation)[0] {
+			case "Up":
+				current = "Up"
+			case "Down":
+				current = "Down"
+			}
+
+			continue
+		}
+
+		if (up && current == "Up") || (!up && current == "Down") {
+			b.WriteString(line + "\n")
+		}
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+var nameChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Create writes the skeleton of a new SQL migration to dir, versioned by the creation time
+func Create(dir, name string, now time.Time) (string, error) {
+	name = strings.Trim(nameChars.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return "", errors.New("the migration name must contain a letter or digit")
+	}
+
+	path := filepath.Join(dir, now.UTC().Format("20060102150405")+"_"+name+".sql")
+	skeleton := `-- +goose Up
+-- +goose StatementBegin
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+
+-- +goose StatementEnd
+`
+
+	// O_EXCL keeps a migration created in the same second
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return "", fmt.Errorf("failed to create migration: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(skeleton); err != nil {
+		return "", fmt.Errorf("failed to write migration: %w", err)
+	}
+
+	return path, nil
 }
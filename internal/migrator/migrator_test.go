@@ -0,0 +1,190 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pressly/goose/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewReadsEmbeddedMigrations(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	m, err := New(db, "goose_db_version", FS)
+	require.NoError(t, err)
+
+	sources := m.provider.ListSources()
+	require.NotEmpty(t, sources)
+	require.Equal(t, int64(1), sources[0].Version)
+}
+
+func TestSection(t *testing.T) {
+	content := `-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE t (id INT);
+-- +goose StatementEnd
+-- +goose Down
+DROP TABLE t;
+`
+
+	require.Equal(t, "CREATE TABLE t (id INT);", Section(content, true))
+	require.Equal(t, "DROP TABLE t;", Section(content, false))
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+
+	path, err := Create(dir, "Add task Priority", now)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, "20240506070809_add_task_priority.sql"), path)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "-- +goose Up")
+	require.Contains(t, string(content), "-- +goose Down")
+
+	// a second migration of the same second does not overwrite the first
+	_, err = Create(dir, "add task priority", now)
+	require.Error(t, err)
+
+	_, err = Create(dir, "--", now)
+	require.Error(t, err)
+}
+
+func TestNewAddsGoMigrations(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	register(9999, &goose.GoFunc{RunDB: Backfill{Name: "test"}.Run}, nil)
+	defer delete(goMigrations, 9999)
+
+	m, err := New(db, "goose_db_version", FS)
+	require.NoError(t, err)
+
+	sources := m.provider.ListSources()
+	last := sources[len(sources)-1]
+	require.Equal(t, int64(9999), last.Version)
+	require.Equal(t, goose.TypeGo, last.Type)
+
+	require.Panics(t, func() {
+		register(9999, nil, nil)
+	})
+}
+
+func TestLatest(t *testing.T) {
+	migrations := fstest.MapFS{
+		"migrations/000001_tasks.sql": {},
+		"migrations/000003_jobs.sql":  {},
+		"migrations/000002_views.sql": {},
+	}
+
+	latest, err := Latest(migrations)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), latest)
+
+	register(4, nil, nil)
+	defer delete(goMigrations, 4)
+
+	latest, err = Latest(migrations)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), latest)
+}
+
+// TestUpRunsGoMigrationsWithoutTx applies a Go migration run with RunDB on a pool of the size
+// Open connects with, goose refuses to on a single connection held by the advisory lock
+func TestUpRunsGoMigrationsWithoutTx(t *testing.T) {
+	register(9999, &goose.GoFunc{RunDB: func(ctx context.Context, db *sql.DB) error {
+		_, err := db.ExecContext(ctx, `UPDATE tasks.tasks SET title = title`)
+
+		return err
+	}}, nil)
+	defer delete(goMigrations, 9999)
+
+	up := func(size int, expect func(sqlmock.Sqlmock)) error {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		db.SetMaxOpenConns(size)
+
+		m, err := New(db, "goose_db_version", fstest.MapFS{"migrations": {Mode: fs.ModeDir}})
+		require.NoError(t, err)
+
+		applied := func() *sqlmock.Rows {
+			return sqlmock.NewRows([]string{"version_id", "is_applied"}).AddRow(0, true)
+		}
+		mock.ExpectQuery(`SELECT EXISTS`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery(`SELECT version_id, is_applied from goose_db_version`).WillReturnRows(applied())
+		mock.ExpectQuery(`SELECT pg_try_advisory_lock`).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+		mock.ExpectQuery(`SELECT version_id, is_applied from goose_db_version`).WillReturnRows(applied())
+		expect(mock)
+		mock.ExpectQuery(`SELECT pg_advisory_unlock`).WillReturnRows(sqlmock.NewRows([]string{"unlocked"}).AddRow(true))
+
+		_, err = m.Up(context.Background())
+		require.NoError(t, mock.ExpectationsWereMet())
+
+		return err
+	}
+
+	err := up(poolSize, func(mock sqlmock.Sqlmock) {
+		mock.ExpectExec(`UPDATE tasks.tasks`).WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec(`INSERT INTO goose_db_version`).WithArgs(9999, true).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery(`SELECT max\(version_id\)`).WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(9999))
+	})
+	require.NoError(t, err)
+
+	err = up(1, func(sqlmock.Sqlmock) {})
+	require.ErrorContains(t, err, "potential deadlock")
+}
+
+// TestRedoHoldsLockOnce rolls back and applies a migration again under a single advisory lock
+func TestRedoHoldsLockOnce(t *testing.T) {
+	register(9999, &goose.GoFunc{RunDB: func(ctx context.Context, db *sql.DB) error {
+		_, err := db.ExecContext(ctx, `UPDATE tasks.tasks SET title = upper(title)`)
+
+		return err
+	}}, &goose.GoFunc{RunDB: func(ctx context.Context, db *sql.DB) error {
+		_, err := db.ExecContext(ctx, `UPDATE tasks.tasks SET title = lower(title)`)
+
+		return err
+	}})
+	defer delete(goMigrations, 9999)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(poolSize)
+
+	m, err := New(db, "goose_db_version", fstest.MapFS{"migrations": {Mode: fs.ModeDir}})
+	require.NoError(t, err)
+
+	applied := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"version_id", "is_applied"}).AddRow(9999, true).AddRow(0, true)
+	}
+	mock.ExpectQuery(`SELECT pg_try_advisory_lock`).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+	mock.ExpectQuery(`SELECT EXISTS`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(`SELECT version_id, is_applied from goose_db_version`).WillReturnRows(applied())
+	mock.ExpectExec(`UPDATE tasks.tasks SET title = lower`).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(`DELETE FROM goose_db_version`).WithArgs(9999).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT .* FROM goose_db_version WHERE version_id`).WithArgs(9999).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectExec(`UPDATE tasks.tasks SET title = upper`).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(`INSERT INTO goose_db_version`).WithArgs(9999, true).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`SELECT pg_advisory_unlock`).WillReturnRows(sqlmock.NewRows([]string{"unlocked"}).AddRow(true))
+
+	results, err := m.Redo(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"go-tasks-api/internal/enum"
+
+	"github.com/google/uuid"
+)
+
+const (
+	EventTaskCreated = "task.created"
+	EventTaskUpdated = "task.updated"
+	EventTaskDeleted = "task.deleted"
+)
+
+// Event records a change of a task. Data holds the task as returned by the API, or only
+// its ID for deleted tasks. Status is the status of the task after the change, it is
+// unset for deleted tasks. OutboxID is the outbox message the event was published from.
+type Event struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	TaskID    uuid.UUID       `json:"task_id"`
+	Status    enum.StatusType `json:"-"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+	OutboxID  int64           `json:"-"`
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"go-tasks-api/internal/enum"
+	"go-tasks-api/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+type JobCreateRequest struct {
+	Type   string          `json:"type"`
+	Params json.RawMessage `json:"params"`
+}
+
+// Validate checks the request, the params are checked by the job type
+func (a JobCreateRequest) Validate() []utils.FieldError {
+	vErr := make([]utils.FieldError, 0)
+	if strings.TrimSpace(a.Type) == "" {
+		vErr = append(vErr, utils.FieldError{
+			Field:   "type",
+			Message: "field is required",
+		})
+	}
+
+	return vErr
+}
+
+// Job is a long-running operation executed by a worker. Params are kept from the API as they
+// may hold task contents, the result is downloaded separately.
+type Job struct {
+	ID              uuid.UUID       `json:"id"`
+	Type            string          `json:"type"`
+	Params          json.RawMessage `json:"-"`
+	Status          enum.JobStatus  `json:"status"`
+	Progress        int             `json:"progress"`
+	Attempts        int             `json:"attempts"`
+	CancelRequested bool            `json:"cancel_requested"`
+	Error           *string         `json:"error,omitempty"`
+	CreatedAt       time.Time       `json:"created_at"`
+	StartedAt       *time.Time      `json:"started_at,omitempty"`
+	FinishedAt      *time.Time      `json:"finished_at,omitempty"`
+}
+
+// Finished reports whether the job reached a final status
+func (a Job) Finished() bool {
+	return a.Status == enum.JobStatus_Succeeded || a.Status == enum.JobStatus_Failed || a.Status == enum.JobStatus_Cancelled
+}
+
+// JobResult is the downloadable output of a succeeded job
+type JobResult struct {
+	ContentType string
+	Data        []byte
+}
+
+// JobOutcome completes an attempt of a job. Status is succeeded, failed or cancelled, Result is
+// only stored for succeeded jobs.
+type JobOutcome struct {
+	JobID   uuid.UUID
+	Attempt int
+	Status  enum.JobStatus
+	Error   *string
+	Result  *JobResult
+}
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go-tasks-api/internal/enum"
+
+	"github.com/google/uuid"
+)
+
+// AggregateTask is the aggregate type of task events
+const AggregateTask = "task"
+
+// OutboxMessage is a domain event written in the same transaction as the change it describes.
+// Messages of the same aggregate are published in ID order.
+type OutboxMessage struct {
+	ID            int64           `json:"id"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   uuid.UUID       `json:"aggregate_id"`
+	EventType     string          `json:"event_type"`
+	Status        enum.StatusType `json:"-"`
+	Payload       json.RawMessage `json:"payload"`
+	Attempts      int             `json:"-"`
+	// PublishedTo are the publishers which took the message in an earlier attempt
+	PublishedTo []string  `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// NewTaskMessage builds the outbox message for a change of the task, deleted tasks only carry their ID
+func NewTaskMessage(eventType string, task Task) (OutboxMessage, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if eventType == EventTaskDeleted {
+		data, err = json.Marshal(map[string]string{"id": task.ID.String()})
+	} else {
+		data, err = json.Marshal(task)
+	}
+	if err != nil {
+		return OutboxMessage{}, fmt.Errorf("failed to marshal %s event: %w", eventType, err)
+	}
+
+	return OutboxMessage{
+		AggregateType: AggregateTask,
+		AggregateID:   task.ID,
+		EventType:     eventType,
+		Status:        task.Status,
+		Payload:       data,
+	}, nil
+}
+
+// Event returns the event recorded in the event log for the message
+func (m OutboxMessage) Event() Event {
+	return Event{
+		Type:     m.EventType,
+		TaskID:   m.AggregateID,
+		Status:   m.Status,
+		Data:     m.Payload,
+		OutboxID: m.ID,
+	}
+}
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"testing"
+
+	"go-tasks-api/internal/enum"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTaskMessageDeletedOnlyCarriesID(t *testing.T) {
+	id := uuid.New()
+	msg, err := NewTaskMessage(EventTaskDeleted, Task{ID: id, Title: "title"})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"id":"`+id.String()+`"}`, string(msg.Payload))
+	require.False(t, msg.Status.IsAStatusType())
+}
+
+func TestOutboxMessageEvent(t *testing.T) {
+	task := Task{ID: uuid.New(), Title: "title", Status: enum.Status_Done}
+	msg, err := NewTaskMessage(EventTaskUpdated, task)
+	require.NoError(t, err)
+	msg.ID = 5
+
+	event := msg.Event()
+	require.Equal(t, AggregateTask, msg.AggregateType)
+	require.Equal(t, EventTaskUpdated, event.Type)
+	require.Equal(t, task.ID, event.TaskID)
+	require.Equal(t, enum.Status_Done, event.Status)
+	require.Equal(t, int64(5), event.OutboxID)
+	require.Contains(t, string(event.Data), `"title":"title"`)
+}
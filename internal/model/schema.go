@@ -0,0 +1,16 @@
+package model
+
+// SchemaVersion tells which migrations the database runs and which the service expects
+type SchemaVersion struct {
+	// Current is the latest migration version applied to the database
+	Current int64 `json:"current"`
+	// Expected is the version of the newest migration the service was built with
+	Expected int64 `json:"expected"`
+	// Min and Max bound the versions the service supports, a Max of 0 sets no upper bound
+	Min int64 `json:"min"`
+	Max int64 `json:"max,omitempty"`
+	// Compatible reports whether Current lies between Min and Max
+	Compatible bool `json:"compatible"`
+	// Pending reports whether migrations of the service are not applied yet
+	Pending bool `json:"pending"`
+}
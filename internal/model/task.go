@@ -1,6 +1,9 @@
 package model
 
 import (
+	"encoding/json"
+	"slices"
+	"strings"
 	"time"
 
 	"go-tasks-api/internal/enum"
@@ -68,3 +71,112 @@ type Task struct {
 	CreatedAt   time.Time       `json:"created_at"`
 	UpdatedAt   *time.Time      `json:"updated_at,omitempty"`
 }
+
+func (a Task) CSVHeader() []string {
+	return []string{"id", "title", "status", "description", "created_at", "updated_at"}
+}
+
+func (a Task) CSVRecord() []string {
+	updatedAt := ""
+	if a.UpdatedAt != nil {
+		updatedAt = a.UpdatedAt.Format(time.RFC3339Nano)
+	}
+
+	return []string{
+		a.ID.String(),
+		a.Title,
+		a.Status.String(),
+		a.Description,
+		a.CreatedAt.Format(time.RFC3339Nano),
+		updatedAt,
+	}
+}
+
+// TaskFields lists the fields of tasks in the order of their JSON representation
+var TaskFields = []string{"id", "title", "status", "description", "created_at", "updated_at"}
+
+// TaskProjection is a task restricted to the selected fields, it is encoded like a Task
+// without the other fields
+type TaskProjection struct {
+	Task   Task
+	Fields []string
+}
+
+// Project restricts the tasks to the fields, no fields keep the tasks as they are
+func Project(tasks []Task, fields []string) any {
+	if len(fields) == 0 {
+		return tasks
+	}
+
+	projected := make([]TaskProjection, 0, len(tasks))
+	for _, task := range tasks {
+		projected = append(projected, TaskProjection{Task: task, Fields: fields})
+	}
+
+	return projected
+}
+
+func (a TaskProjection) MarshalJSON() ([]byte, error) {
+	b, err := json.Marshal(a.Task)
+	if err != nil {
+		return nil, err
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(b, &all); err != nil {
+		return nil, err
+	}
+
+	// written by hand to keep the order of TaskFields
+	var out strings.Builder
+	out.WriteByte('{')
+	for _, field := range a.selected() {
+		value, ok := all[field]
+		if !ok {
+			continue
+		}
+
+		if out.Len() > 1 {
+			out.WriteByte(',')
+		}
+		key, _ := json.Marshal(field)
+		out.Write(key)
+		out.WriteByte(':')
+		out.Write(value)
+	}
+	out.WriteByte('}')
+
+	return []byte(out.String()), nil
+}
+
+func (a TaskProjection) CSVHeader() []string {
+	return a.selected()
+}
+
+func (a TaskProjection) CSVRecord() []string {
+	record := a.Task.CSVRecord()
+	values := make([]string, 0, len(a.Fields))
+	for i, field := range TaskFields {
+		if a.has(field) {
+			values = append(values, record[i])
+		}
+	}
+
+	return values
+}
+
+// selected returns the selected fields in the order of TaskFields
+func (a TaskProjection) selected() []string {
+	fields := make([]string, 0, len(a.Fields))
+	for _, field := range TaskFields {
+		if a.has(field) {
+			fields = append(fields, field)
+		}
+	}
+
+	return fields
+}
+
+func (a TaskProjection) has(field string) bool {
+	return slices.Contains(a.Fields, field)
+}
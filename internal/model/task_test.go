@@ -1,8 +1,15 @@
 package model
 
 import (
+	"encoding/json"
 	"strings"
 	"testing"
+	"time"
+
+	"go-tasks-api/internal/enum"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
 )
 
 func TestTaskCreateRequest_Validate(t *testing.T) {
@@ -126,3 +133,25 @@ func TestTaskUpdateRequest_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestTaskProjection(t *testing.T) {
+	task := Task{
+		ID:          uuid.MustParse("3b241101-e2bb-4255-8caf-4136c566a962"),
+		Title:       "title",
+		Description: "description",
+		Status:      enum.Status_Todo,
+		CreatedAt:   time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	projection := TaskProjection{Task: task, Fields: []string{"title", "id"}}
+
+	b, err := json.Marshal(projection)
+	require.NoError(t, err)
+	require.Equal(t, `{"id":"3b241101-e2bb-4255-8caf-4136c566a962","title":"title"}`, string(b))
+	require.Equal(t, []string{"id", "title"}, projection.CSVHeader())
+	require.Equal(t, []string{"3b241101-e2bb-4255-8caf-4136c566a962", "title"}, projection.CSVRecord())
+}
+
+func TestProjectWithoutFieldsKeepsTasks(t *testing.T) {
+	tasks := []Task{{Title: "title"}}
+	require.Equal(t, tasks, Project(tasks, nil))
+}
@@ -0,0 +1,74 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"go-tasks-api/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// maxViewNameLength bounds the name of saved views
+const maxViewNameLength = 100
+
+// ViewDefinition is what a saved view stores of a task list: the filter expression, the sort
+// keys such as -created_at and the fields returned. It is stored as JSON.
+type ViewDefinition struct {
+	Filter string   `json:"filter"`
+	Sort   []string `json:"sort"`
+	Fields []string `json:"fields"`
+}
+
+type ViewCreateRequest struct {
+	Name string `json:"name"`
+	ViewDefinition
+	SharedWith []string `json:"shared_with"`
+	Default    bool     `json:"default"`
+}
+
+// Validate checks the request, the definition itself is checked against the filterable and
+// sortable fields by the repository
+func (a ViewCreateRequest) Validate() []utils.FieldError {
+	vErr := make([]utils.FieldError, 0)
+	name := strings.TrimSpace(a.Name)
+	if name == "" {
+		vErr = append(vErr, utils.FieldError{
+			Field:   "name",
+			Message: "field is required",
+		})
+	} else if len(name) > maxViewNameLength {
+		vErr = append(vErr, utils.FieldError{
+			Field:   "name",
+			Message: "must be at most 100 characters",
+		})
+	}
+
+	for _, user := range a.SharedWith {
+		if strings.TrimSpace(user) == "" {
+			vErr = append(vErr, utils.FieldError{
+				Field:   "shared_with",
+				Message: "user IDs cannot be empty",
+			})
+
+			break
+		}
+	}
+
+	return vErr
+}
+
+// ViewUpdateRequest replaces the view
+type ViewUpdateRequest = ViewCreateRequest
+
+// View is a saved task list of its owner, users it is shared with can read and run it
+type View struct {
+	ID      uuid.UUID `json:"id"`
+	OwnerID string    `json:"owner_id"`
+	Name    string    `json:"name"`
+	ViewDefinition
+	SharedWith []string   `json:"shared_with"`
+	Default    bool       `json:"default"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+}
@@ -0,0 +1,141 @@
+package model
+
+import (
+	"net/url"
+	"slices"
+	"time"
+
+	"go-tasks-api/internal/enum"
+	"go-tasks-api/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// minSecretLength is the minimum length of a webhook signing secret
+const minSecretLength = 16
+
+// EventTypes lists the event types webhooks can subscribe to
+var EventTypes = []string{EventTaskCreated, EventTaskUpdated, EventTaskDeleted}
+
+type WebhookCreateRequest struct {
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret"`
+	EventTypes []string `json:"event_types"`
+}
+
+func (a WebhookCreateRequest) Validate() []utils.FieldError {
+	vErr := validateWebhookURL(a.URL)
+	vErr = append(vErr, validateWebhookSecret(a.Secret)...)
+	vErr = append(vErr, validateEventTypes(a.EventTypes)...)
+
+	return vErr
+}
+
+// WebhookUpdateRequest replaces the subscription, the secret is kept when omitted.
+// Enabling a disabled webhook resets its failure count.
+type WebhookUpdateRequest struct {
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret"`
+	EventTypes []string `json:"event_types"`
+	Enabled    bool     `json:"enabled"`
+}
+
+func (a WebhookUpdateRequest) Validate() []utils.FieldError {
+	vErr := validateWebhookURL(a.URL)
+	if a.Secret != "" {
+		vErr = append(vErr, validateWebhookSecret(a.Secret)...)
+	}
+	vErr = append(vErr, validateEventTypes(a.EventTypes)...)
+
+	return vErr
+}
+
+// Webhook is a subscription to task events, the secret is never returned by the API
+type Webhook struct {
+	ID                  uuid.UUID  `json:"id"`
+	URL                 string     `json:"url"`
+	Secret              string     `json:"-"`
+	EventTypes          []string   `json:"event_types"`
+	Enabled             bool       `json:"enabled"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           *time.Time `json:"updated_at,omitempty"`
+}
+
+// WebhookDelivery is one event to be delivered to one webhook
+type WebhookDelivery struct {
+	ID             uuid.UUID           `json:"id"`
+	WebhookID      uuid.UUID           `json:"webhook_id"`
+	EventID        int64               `json:"event_id"`
+	EventType      string              `json:"event_type"`
+	Status         enum.DeliveryStatus `json:"status"`
+	Attempts       int                 `json:"attempts"`
+	NextAttemptAt  *time.Time          `json:"next_attempt_at,omitempty"`
+	LastStatusCode *int                `json:"last_status_code,omitempty"`
+	LastError      *string             `json:"last_error,omitempty"`
+	CreatedAt      time.Time           `json:"created_at"`
+	DeliveredAt    *time.Time          `json:"delivered_at,omitempty"`
+}
+
+// PendingDelivery is a claimed delivery with everything needed to send it
+type PendingDelivery struct {
+	Delivery WebhookDelivery
+	URL      string
+	Secret   string
+	Event    Event
+}
+
+// DeliveryAttempt is the outcome of sending a delivery once. Status is pending when
+// the delivery is retried at NextAttemptAt.
+type DeliveryAttempt struct {
+	DeliveryID    uuid.UUID
+	WebhookID     uuid.UUID
+	Status        enum.DeliveryStatus
+	StatusCode    *int
+	Error         *string
+	NextAttemptAt time.Time
+}
+
+func validateWebhookURL(raw string) []utils.FieldError {
+	u, err := url.Parse(raw)
+	if raw == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return []utils.FieldError{{
+			Field:   "url",
+			Message: "must be an absolute http or https URL",
+		}}
+	}
+
+	return nil
+}
+
+func validateWebhookSecret(secret string) []utils.FieldError {
+	if len(secret) < minSecretLength {
+		return []utils.FieldError{{
+			Field:   "secret",
+			Message: "must be at least 16 characters",
+		}}
+	}
+
+	return nil
+}
+
+func validateEventTypes(eventTypes []string) []utils.FieldError {
+	if len(eventTypes) == 0 {
+		return []utils.FieldError{{
+			Field:   "event_types",
+			Message: "field is required",
+		}}
+	}
+
+	vErr := make([]utils.FieldError, 0)
+	for _, t := range eventTypes {
+		if !slices.Contains(EventTypes, t) {
+			vErr = append(vErr, utils.FieldError{
+				Field:   "event_types",
+				Message: "invalid event type: " + t,
+			})
+		}
+	}
+
+	return vErr
+}
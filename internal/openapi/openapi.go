@@ -0,0 +1,160 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+//go:embed openapi.json
+var spec []byte
+
+// methods lists the HTTP methods an OpenAPI path item can describe
+var methods = []string{
+	http.MethodGet,
+	http.MethodPut,
+	http.MethodPost,
+	http.MethodDelete,
+	http.MethodOptions,
+	http.MethodHead,
+	http.MethodPatch,
+	http.MethodTrace,
+}
+
+type (
+	// Document is the subset of an OpenAPI 3.1 document the service reads back
+	Document struct {
+		OpenAPI    string              `json:"openapi"`
+		Paths      map[string]PathItem `json:"paths"`
+		Components Components          `json:"components"`
+	}
+
+	Components struct {
+		Schemas    map[string]*Schema    `json:"schemas"`
+		Parameters map[string]*Parameter `json:"parameters"`
+	}
+
+	PathItem struct {
+		Parameters []*Parameter `json:"parameters"`
+		Get        *Operation   `json:"get"`
+		Put        *Operation   `json:"put"`
+		Post       *Operation   `json:"post"`
+		Delete     *Operation   `json:"delete"`
+		Options    *Operation   `json:"options"`
+		Head       *Operation   `json:"head"`
+		Patch      *Operation   `json:"patch"`
+		Trace      *Operation   `json:"trace"`
+	}
+
+	Operation struct {
+		OperationID string       `json:"operationId"`
+		Parameters  []*Parameter `json:"parameters"`
+		RequestBody *RequestBody `json:"requestBody"`
+	}
+
+	Parameter struct {
+		Ref      string  `json:"$ref"`
+		Name     string  `json:"name"`
+		In       string  `json:"in"`
+		Required bool    `json:"required"`
+		Schema   *Schema `json:"schema"`
+	}
+
+	RequestBody struct {
+		Required bool                 `json:"required"`
+		Content  map[string]MediaType `json:"content"`
+		// MaxBytes bounds the body of the operation, maxBodyBytes when 0
+		MaxBytes int64 `json:"x-max-body-bytes"`
+	}
+
+	MediaType struct {
+		Schema *Schema `json:"schema"`
+	}
+
+	// Route is a documented method and path pair
+	Route struct {
+		Method string
+		Path   string
+	}
+)
+
+// Spec returns the raw OpenAPI document
+func Spec() []byte {
+	return spec
+}
+
+// Load parses the embedded OpenAPI document
+func Load() (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse openapi document: %w", err)
+	}
+
+	return &doc, nil
+}
+
+// MustLoad parses the embedded OpenAPI document and panics if it is invalid
+func MustLoad() *Document {
+	doc, err := Load()
+	if err != nil {
+		panic(err)
+	}
+
+	return doc
+}
+
+// Routes returns every documented route sorted by path and method
+func (d *Document) Routes() []Route {
+	routes := make([]Route, 0)
+	for path, item := range d.Paths {
+		for _, method := range methods {
+			if item.Operation(method) != nil {
+				routes = append(routes, Route{Method: method, Path: path})
+			}
+		}
+	}
+
+	slices.SortFunc(routes, func(a, b Route) int {
+		if c := strings.Compare(a.Path, b.Path); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a.Method, b.Method)
+	})
+
+	return routes
+}
+
+// Operation returns the operation documented for the given HTTP method, if any
+func (p PathItem) Operation(method string) *Operation {
+	switch method {
+	case http.MethodGet:
+		return p.Get
+	case http.MethodPut:
+		return p.Put
+	case http.MethodPost:
+		return p.Post
+	case http.MethodDelete:
+		return p.Delete
+	case http.MethodOptions:
+		return p.Options
+	case http.MethodHead:
+		return p.Head
+	case http.MethodPatch:
+		return p.Patch
+	case http.MethodTrace:
+		return p.Trace
+	}
+
+	return nil
+}
+
+// Handler serves the OpenAPI document
+func Handler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(spec)
+}
@@ -0,0 +1,65 @@
+package openapi
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReferencesResolve(t *testing.T) {
+	var root any
+	require.NoError(t, json.Unmarshal(Spec(), &root))
+
+	var walk func(node any)
+	walk = func(node any) {
+		switch v := node.(type) {
+		case map[string]any:
+			if ref, ok := v["$ref"].(string); ok {
+				require.Truef(t, strings.HasPrefix(ref, "#/"), "only local references are supported: %s", ref)
+				require.NotNilf(t, resolve(root, ref), "unresolved reference %s", ref)
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(root)
+}
+
+func TestOperations(t *testing.T) {
+	doc, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, "3.1.0", doc.OpenAPI)
+	require.Contains(t, doc.Routes(), Route{Method: "GET", Path: "/api/v1/tasks/{id}"})
+	// path level keys such as parameters are not operations
+	require.NotContains(t, doc.Routes(), Route{Method: "PARAMETERS", Path: "/api/v1/tasks/{id}"})
+}
+
+func TestInvalidPatternFailsToParse(t *testing.T) {
+	var doc Document
+	err := json.Unmarshal([]byte(`{"components": {"schemas": {"Code": {"type": "object", "properties": {"code": {"type": "string", "pattern": "^[0-9+$"}}}}}}`), &doc)
+	require.ErrorContains(t, err, `invalid schema pattern "^[0-9+$"`)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"components": {"schemas": {"Code": {"type": "string", "pattern": "^[0-9]+$"}}}}`), &doc))
+	require.Empty(t, doc.validate(doc.Components.Schemas["Code"], "42", ""))
+	require.Len(t, doc.validate(doc.Components.Schemas["Code"], "4a", ""), 1)
+}
+
+func resolve(root any, ref string) any {
+	node := root
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		m, ok := node.(map[string]any)
+		if !ok {
+			return nil
+		}
+		node = m[token]
+	}
+
+	return node
+}
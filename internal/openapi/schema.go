@@ -0,0 +1,270 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go-tasks-api/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// Schema is the subset of JSON Schema (draft 2020-12, as used by OpenAPI 3.1) the validator understands
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 Types              `json:"type"`
+	Format               string             `json:"format"`
+	Enum                 []any              `json:"enum"`
+	Required             []string           `json:"required"`
+	Properties           map[string]*Schema `json:"properties"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Pattern              string             `json:"pattern"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+
+	// pattern is Pattern compiled once as the document is parsed
+	pattern *regexp.Regexp
+}
+
+// UnmarshalJSON decodes the schema and compiles its pattern, so an invalid pattern fails to load
+// the document instead of being skipped on every request
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	type plain Schema
+	if err := json.Unmarshal(data, (*plain)(s)); err != nil {
+		return err
+	}
+
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid schema pattern %q: %w", s.Pattern, err)
+		}
+		s.pattern = re
+	}
+
+	return nil
+}
+
+// Types holds the allowed JSON types of a schema, which may be written as a single string or a list
+type Types []string
+
+func (t *Types) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = Types{single}
+
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("schema type must be a string or a list of strings: %w", err)
+	}
+	*t = list
+
+	return nil
+}
+
+// validate checks a decoded JSON value (numbers decoded as json.Number) against the schema
+// and returns one error per violation, located by JSON Pointer relative to pointer
+func (d *Document) validate(schema *Schema, value any, pointer string) []utils.FieldError {
+	schema = d.resolveSchema(schema)
+	if schema == nil {
+		return nil
+	}
+
+	if len(schema.Type) > 0 && !slices.ContainsFunc(schema.Type, func(t string) bool { return hasType(value, t) }) {
+		return []utils.FieldError{fieldError(pointer, "must be of type "+strings.Join(schema.Type, " or "))}
+	}
+
+	if len(schema.Enum) > 0 && !slices.ContainsFunc(schema.Enum, func(e any) bool { return enumEqual(e, value) }) {
+		return []utils.FieldError{fieldError(pointer, fmt.Sprintf("must be one of %v", schema.Enum))}
+	}
+
+	errs := make([]utils.FieldError, 0)
+	switch v := value.(type) {
+	case string:
+		errs = append(errs, validateString(schema, v, pointer)...)
+	case json.Number:
+		errs = append(errs, validateNumber(schema, v, pointer)...)
+	case map[string]any:
+		for _, name := range schema.Required {
+			if _, ok := v[name]; !ok {
+				errs = append(errs, fieldError(pointer+"/"+escapePointer(name), "field is required"))
+			}
+		}
+
+		for _, name := range sortedKeys(v) {
+			prop, ok := schema.Properties[name]
+			if !ok {
+				if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+					errs = append(errs, fieldError(pointer+"/"+escapePointer(name), "unknown field"))
+				}
+
+				continue
+			}
+
+			errs = append(errs, d.validate(prop, v[name], pointer+"/"+escapePointer(name))...)
+		}
+	case []any:
+		for i, item := range v {
+			errs = append(errs, d.validate(schema.Items, item, pointer+"/"+strconv.Itoa(i))...)
+		}
+	}
+
+	return errs
+}
+
+func (d *Document) resolveSchema(schema *Schema) *Schema {
+	for schema != nil && schema.Ref != "" {
+		schema = d.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+	}
+
+	return schema
+}
+
+func validateString(schema *Schema, v, pointer string) []utils.FieldError {
+	errs := make([]utils.FieldError, 0)
+	length := utf8.RuneCountInString(v)
+	if schema.MinLength != nil && length < *schema.MinLength {
+		if *schema.MinLength == 1 {
+			errs = append(errs, fieldError(pointer, "must not be empty"))
+		} else {
+			errs = append(errs, fieldError(pointer, fmt.Sprintf("length must be at least %d", *schema.MinLength)))
+		}
+	}
+
+	if schema.MaxLength != nil && length > *schema.MaxLength {
+		errs = append(errs, fieldError(pointer, fmt.Sprintf("length must be at most %d", *schema.MaxLength)))
+	}
+
+	if schema.pattern != nil && !schema.pattern.MatchString(v) {
+		errs = append(errs, fieldError(pointer, "must match pattern "+schema.Pattern))
+	}
+
+	if msg := validateFormat(schema.Format, v); msg != "" {
+		errs = append(errs, fieldError(pointer, msg))
+	}
+
+	return errs
+}
+
+func validateNumber(schema *Schema, v json.Number, pointer string) []utils.FieldError {
+	n, err := v.Float64()
+	if err != nil {
+		return []utils.FieldError{fieldError(pointer, "must be a number")}
+	}
+
+	errs := make([]utils.FieldError, 0)
+	if schema.Minimum != nil && n < *schema.Minimum {
+		errs = append(errs, fieldError(pointer, fmt.Sprintf("must be at least %v", *schema.Minimum)))
+	}
+
+	if schema.Maximum != nil && n > *schema.Maximum {
+		errs = append(errs, fieldError(pointer, fmt.Sprintf("must be at most %v", *schema.Maximum)))
+	}
+
+	return errs
+}
+
+func validateFormat(format, v string) string {
+	switch format {
+	case "uuid":
+		if _, err := uuid.Parse(v); err != nil {
+			return "must be a valid uuid"
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, v); err != nil {
+			return "must be a valid RFC 3339 date-time"
+		}
+	case "date":
+		if _, err := time.Parse(time.DateOnly, v); err != nil {
+			return "must be a valid date (YYYY-MM-DD)"
+		}
+	}
+
+	return ""
+}
+
+func hasType(value any, t string) bool {
+	switch t {
+	case "string":
+		_, ok := value.(string)
+
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+
+		return ok
+	case "object":
+		_, ok := value.(map[string]any)
+
+		return ok
+	case "array":
+		_, ok := value.([]any)
+
+		return ok
+	case "null":
+		return value == nil
+	case "number":
+		_, ok := value.(json.Number)
+
+		return ok
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		_, err := n.Int64()
+
+		return err == nil
+	}
+
+	return false
+}
+
+func enumEqual(e, value any) bool {
+	if n, ok := value.(json.Number); ok {
+		f, err := n.Float64()
+
+		return err == nil && e == f
+	}
+
+	return e == value
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	return keys
+}
+
+// fieldError builds a FieldError whose Field is the dotted form of the JSON Pointer
+func fieldError(pointer, message string) utils.FieldError {
+	field := strings.TrimPrefix(pointer, "/")
+	field = strings.ReplaceAll(field, "/", ".")
+	field = strings.ReplaceAll(strings.ReplaceAll(field, "~1", "/"), "~0", "~")
+
+	return utils.FieldError{
+		Field:   field,
+		Pointer: pointer,
+		Message: message,
+	}
+}
+
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,271 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go-tasks-api/internal/apperr"
+	"go-tasks-api/internal/utils"
+)
+
+// maxBodyBytes bounds the request bodies buffered for validation, operations raise it with the
+// x-max-body-bytes extension of their request body
+const maxBodyBytes = 1 << 20
+
+// Validator checks requests against the operations of an OpenAPI document
+type Validator struct {
+	doc    *Document
+	routes []route
+}
+
+// route is a documented path template split into segments, parameters are kept as "{name}"
+type route struct {
+	segments []string
+	item     PathItem
+}
+
+// NewValidator creates a validator for every path of the document
+func NewValidator(doc *Document) *Validator {
+	v := &Validator{
+		doc: doc,
+	}
+	for path, item := range doc.Paths {
+		v.routes = append(v.routes, route{
+			segments: strings.Split(strings.Trim(path, "/"), "/"),
+			item:     item,
+		})
+	}
+
+	// literal segments take precedence over parameters, e.g. /tasks/search over /tasks/{id}
+	slices.SortFunc(v.routes, func(a, b route) int {
+		return a.params() - b.params()
+	})
+
+	return v
+}
+
+// Middleware validates path and query parameters and JSON bodies of documented operations,
+// answering 400 with one error per violation. Undocumented requests are passed through.
+func (v *Validator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		item, pathParams, ok := v.match(r.URL.Path)
+		if !ok {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		op := item.Operation(r.Method)
+		if op == nil {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		errs := v.validateParameters(slices.Concat(item.Parameters, op.Parameters), pathParams, r)
+
+		if op.RequestBody != nil {
+			limit := int64(maxBodyBytes)
+			if op.RequestBody.MaxBytes > 0 {
+				limit = op.RequestBody.MaxBytes
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				apperr.Write(w, r, apperr.Wrap(apperr.TooLarge, fmt.Sprintf("the request body exceeds %d bytes", limit), err),
+					"request body too large")
+
+				return
+			}
+			if err != nil {
+				apperr.Write(w, r, apperr.Wrap(apperr.Malformed, "failed to read request body", err), "failed to read request body")
+
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			bodyErrs, err := v.validateBody(op.RequestBody, body)
+			if err != nil {
+				apperr.Write(w, r, err, "request body is not valid JSON")
+
+				return
+			}
+			errs = append(errs, bodyErrs...)
+		}
+
+		if len(errs) > 0 {
+			apperr.Write(w, r, apperr.New(apperr.Validation, "request does not match the API schema", errs...),
+				"request validation failed")
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (rt route) params() int {
+	n := 0
+	for _, s := range rt.segments {
+		if isParam(s) {
+			n++
+		}
+	}
+
+	return n
+}
+
+func isParam(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+// match finds the path item whose template matches the request path
+func (v *Validator) match(path string) (PathItem, map[string]string, bool) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	for _, rt := range v.routes {
+		if len(rt.segments) != len(segments) {
+			continue
+		}
+
+		params := make(map[string]string)
+		matched := true
+		for i, s := range rt.segments {
+			if isParam(s) {
+				params[s[1:len(s)-1]] = segments[i]
+
+				continue
+			}
+
+			if s != segments[i] {
+				matched = false
+
+				break
+			}
+		}
+
+		if matched {
+			return rt.item, params, true
+		}
+	}
+
+	return PathItem{}, nil, false
+}
+
+func (v *Validator) validateParameters(params []*Parameter, pathParams map[string]string, r *http.Request) []utils.FieldError {
+	errs := make([]utils.FieldError, 0)
+	query := r.URL.Query()
+
+	for _, p := range params {
+		p = v.resolveParameter(p)
+		if p == nil {
+			continue
+		}
+
+		var (
+			raw     string
+			present bool
+		)
+		switch p.In {
+		case "path":
+			raw, present = pathParams[p.Name]
+		case "query":
+			present = query.Has(p.Name)
+			raw = query.Get(p.Name)
+		case "header":
+			raw = r.Header.Get(p.Name)
+			present = raw != ""
+		default:
+			continue
+		}
+
+		pointer := "/" + p.In + "/" + escapePointer(p.Name)
+		if !present {
+			if p.Required {
+				errs = append(errs, utils.FieldError{Field: p.Name, Pointer: pointer, Message: p.In + " parameter is required"})
+			}
+
+			continue
+		}
+
+		for _, e := range v.doc.validate(p.Schema, coerce(v.doc.resolveSchema(p.Schema), raw), pointer) {
+			e.Field = p.Name
+			errs = append(errs, e)
+		}
+	}
+
+	return errs
+}
+
+func (v *Validator) validateBody(body *RequestBody, raw []byte) ([]utils.FieldError, error) {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		if body.Required {
+			return []utils.FieldError{{Pointer: "", Message: "request body is required"}}, nil
+		}
+
+		return nil, nil
+	}
+
+	media, ok := body.Content["application/json"]
+	if !ok || media.Schema == nil {
+		return nil, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, apperr.FromJSON(err)
+	}
+
+	if decoder.More() {
+		return nil, apperr.New(apperr.Malformed,
+			fmt.Sprintf("unexpected data after the JSON value at byte offset %d", decoder.InputOffset()))
+	}
+
+	return v.doc.validate(media.Schema, value, ""), nil
+}
+
+func (v *Validator) resolveParameter(p *Parameter) *Parameter {
+	for p != nil && p.Ref != "" {
+		p = v.doc.Components.Parameters[strings.TrimPrefix(p.Ref, "#/components/parameters/")]
+	}
+
+	return p
+}
+
+// coerce converts a raw parameter string into the JSON value its schema expects,
+// values which cannot be converted are left as strings so the type check reports them
+func coerce(schema *Schema, raw string) any {
+	if schema == nil {
+		return raw
+	}
+
+	for _, t := range schema.Type {
+		switch t {
+		case "integer":
+			if _, err := strconv.ParseInt(raw, 10, 64); err == nil {
+				return json.Number(raw)
+			}
+		case "number":
+			if _, err := strconv.ParseFloat(raw, 64); err == nil {
+				return json.Number(raw)
+			}
+		case "boolean":
+			if b, err := strconv.ParseBool(raw); err == nil {
+				return b
+			}
+		}
+	}
+
+	return raw
+}
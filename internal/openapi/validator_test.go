@@ -0,0 +1,142 @@
+package openapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-tasks-api/internal/utils"
+
+	"github.com/stretchr/testify/require"
+)
+
+const taskPath = "/api/v1/tasks/0aeb10a0-634d-430f-b63b-703ae226691d"
+
+func serve(t *testing.T, method, target, body string) (*httptest.ResponseRecorder, bool) {
+	t.Helper()
+
+	var reached bool
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+		// the body must still be readable by the handler
+		_, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+
+	rec := httptest.NewRecorder()
+	NewValidator(MustLoad()).Middleware(next).ServeHTTP(rec, httptest.NewRequest(method, target, reader))
+
+	return rec, reached
+}
+
+func fieldErrors(t *testing.T, rec *httptest.ResponseRecorder) []utils.FieldError {
+	t.Helper()
+
+	var resp utils.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+
+	errs := make([]utils.FieldError, 0, len(resp.Errors))
+	for _, e := range resp.Errors {
+		if e.Source != nil {
+			errs = append(errs, *e.Source)
+		}
+	}
+
+	return errs
+}
+
+func TestValidatorAcceptsValidRequests(t *testing.T) {
+	rec, reached := serve(t, http.MethodPost, "/api/v1/tasks", `{"title":"title","description":"description"}`)
+	require.True(t, reached)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec, reached = serve(t, http.MethodPut, taskPath, `{"title":"title","status":"done"}`)
+	require.True(t, reached)
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestValidatorPassesUndocumentedRequests(t *testing.T) {
+	_, reached := serve(t, http.MethodGet, "/not/documented", "")
+	require.True(t, reached)
+
+	_, reached = serve(t, http.MethodPatch, taskPath, "")
+	require.True(t, reached)
+}
+
+func TestValidatorReportsEveryViolation(t *testing.T) {
+	rec, reached := serve(t, http.MethodPut, taskPath,
+		`{"title":"`+strings.Repeat("a", 256)+`","description":1,"status":"doing"}`)
+	require.False(t, reached)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	require.ElementsMatch(t, []utils.FieldError{
+		{Field: "description", Pointer: "/description", Message: "must be of type string"},
+		{Field: "status", Pointer: "/status", Message: "must be one of [todo done]"},
+		{Field: "title", Pointer: "/title", Message: "length must be at most 255"},
+	}, fieldErrors(t, rec))
+}
+
+func TestValidatorRequiredFields(t *testing.T) {
+	rec, reached := serve(t, http.MethodPut, taskPath, `{"description":"description"}`)
+	require.False(t, reached)
+
+	require.ElementsMatch(t, []utils.FieldError{
+		{Field: "title", Pointer: "/title", Message: "field is required"},
+		{Field: "status", Pointer: "/status", Message: "field is required"},
+	}, fieldErrors(t, rec))
+}
+
+func TestValidatorPathParameterFormat(t *testing.T) {
+	rec, reached := serve(t, http.MethodGet, "/api/v1/tasks/not-a-uuid", "")
+	require.False(t, reached)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	require.Equal(t, []utils.FieldError{
+		{Field: "id", Pointer: "/path/id", Message: "must be a valid uuid"},
+	}, fieldErrors(t, rec))
+}
+
+func TestValidatorMalformedBody(t *testing.T) {
+	rec, reached := serve(t, http.MethodPost, "/api/v1/tasks", `{"title":`)
+	require.False(t, reached)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), "bad_request")
+
+	rec, reached = serve(t, http.MethodPost, "/api/v1/tasks", "")
+	require.False(t, reached)
+	require.Contains(t, rec.Body.String(), "request body is required")
+}
+
+func TestValidatorBodyLimit(t *testing.T) {
+	description := strings.Repeat("x", maxBodyBytes)
+
+	rec, reached := serve(t, http.MethodPost, "/api/v1/tasks", `{"title":"big","description":"`+description+`"}`)
+	require.False(t, reached)
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	require.Contains(t, rec.Body.String(), "too_large")
+
+	// jobs carry the tasks of imports
+	rec, reached = serve(t, http.MethodPost, "/api/v1/jobs", `{"type":"tasks.import","params":{"note":"`+description+`"}}`)
+	require.True(t, reached)
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestCoerceQueryParameters(t *testing.T) {
+	doc := MustLoad()
+	integer := &Schema{Type: Types{"integer"}}
+	maximum := float64(100)
+	integer.Maximum = &maximum
+
+	require.Empty(t, doc.validate(integer, coerce(integer, "10"), "/query/limit"))
+	require.Equal(t, "must be at most 100", doc.validate(integer, coerce(integer, "1000"), "/query/limit")[0].Message)
+	require.Equal(t, "must be of type integer", doc.validate(integer, coerce(integer, "ten"), "/query/limit")[0].Message)
+}
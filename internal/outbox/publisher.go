@@ -0,0 +1,194 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"go-tasks-api/internal/encryption"
+	"go-tasks-api/internal/model"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Publisher delivers outbox messages to a destination. Delivery is at-least-once: a message
+// is published again when the relay fails before marking it as sent.
+type Publisher interface {
+	Publish(ctx context.Context, msg model.OutboxMessage) error
+}
+
+// Destination is a publisher of a Fanout, the outbox records the names of the destinations
+// which took a message
+type Destination struct {
+	Name      string
+	Publisher Publisher
+}
+
+// Fanout publishes every message to all destinations, a message is only sent once all of them
+// succeeded. A message published again skips the destinations which already took it.
+type Fanout []Destination
+
+// Publish publishes the message to the destinations missing from its PublishedTo and returns the
+// names of the destinations which have it
+func (f Fanout) Publish(ctx context.Context, msg model.OutboxMessage) ([]string, error) {
+	published := slices.Clone(msg.PublishedTo)
+	errs := make([]error, 0)
+	for _, d := range f {
+		if slices.Contains(published, d.Name) {
+			continue
+		}
+
+		if err := d.Publisher.Publish(ctx, msg); err != nil {
+			errs = append(errs, fmt.Errorf("%s publisher: %w", d.Name, err))
+
+			continue
+		}
+		published = append(published, d.Name)
+	}
+
+	return published, errors.Join(errs...)
+}
+
+// LogPublisher logs every message, the payload is left out as it holds the sensitive fields of
+// the task in plaintext
+type LogPublisher struct{}
+
+func (LogPublisher) Publish(_ context.Context, msg model.OutboxMessage) error {
+	log.Info().
+		Int64("outbox_id", msg.ID).
+		Str("aggregate_type", msg.AggregateType).
+		Str("aggregate_id", msg.AggregateID.String()).
+		Str("event_type", msg.EventType).
+		Msg("domain event published")
+
+	return nil
+}
+
+// FilePublisher appends every message as a JSON line to a file. The payload is encrypted like
+// the payloads of the outbox table, so the file is no weaker than the database.
+type FilePublisher struct {
+	mu     sync.Mutex
+	file   *os.File
+	cipher encryption.Cipher
+}
+
+// fileRecord is a line of the outbox file, Payload is the encrypted JSON payload bound to the
+// message ID
+type fileRecord struct {
+	ID            int64     `json:"id"`
+	AggregateType string    `json:"aggregate_type"`
+	AggregateID   string    `json:"aggregate_id"`
+	EventType     string    `json:"event_type"`
+	Payload       string    `json:"payload"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// filePayloadData returns the additional data the payload of a line of the outbox file is
+// encrypted with
+func filePayloadData(id int64) string {
+	return encryption.AdditionalData("outbox_file", "payload", strconv.FormatInt(id, 10))
+}
+
+// NewFilePublisher opens the file for appending, creating it when it does not exist
+func NewFilePublisher(path string, cipher encryption.Cipher) (*FilePublisher, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open outbox file: %w", err)
+	}
+
+	return &FilePublisher{
+		file:   file,
+		cipher: cipher,
+	}, nil
+}
+
+func (p *FilePublisher) Publish(_ context.Context, msg model.OutboxMessage) error {
+	payload, err := p.cipher.Encrypt(string(msg.Payload), filePayloadData(msg.ID))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt outbox payload: %w", err)
+	}
+
+	line, err := json.Marshal(fileRecord{
+		ID:            msg.ID,
+		AggregateType: msg.AggregateType,
+		AggregateID:   msg.AggregateID.String(),
+		EventType:     msg.EventType,
+		Payload:       payload,
+		CreatedAt:     msg.CreatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox message: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, err := p.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write outbox message: %w", err)
+	}
+
+	// the message is marked as sent right after, so it must be on disk
+	if err := p.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync outbox file: %w", err)
+	}
+
+	return nil
+}
+
+func (p *FilePublisher) Close() error {
+	return p.file.Close()
+}
+
+// HTTPPublisher POSTs every message as JSON to a URL, any status outside 2xx is an error.
+// The Idempotency-Key header carries the message ID so receivers can drop duplicates.
+type HTTPPublisher struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPPublisher creates a new HTTPPublisher
+func NewHTTPPublisher(url string, timeout time.Duration) *HTTPPublisher {
+	return &HTTPPublisher{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (p *HTTPPublisher) Publish(ctx context.Context, msg model.OutboxMessage) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Idempotency-Key", strconv.FormatInt(msg.ID, 10))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// drain so the connection is reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("receiver responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
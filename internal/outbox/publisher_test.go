@@ -0,0 +1,141 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-tasks-api/internal/encryption"
+	"go-tasks-api/internal/model"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func testMessage(id int64) model.OutboxMessage {
+	return model.OutboxMessage{
+		ID:            id,
+		AggregateType: model.AggregateTask,
+		AggregateID:   uuid.New(),
+		EventType:     model.EventTaskCreated,
+		Payload:       []byte(`{"title":"doc"}`),
+	}
+}
+
+func TestFilePublisherAppendsLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.ndjson")
+	p, err := NewFilePublisher(path, encryption.NoopCipher{})
+	require.NoError(t, err)
+
+	require.NoError(t, p.Publish(context.Background(), testMessage(1)))
+	require.NoError(t, p.Publish(context.Background(), testMessage(2)))
+	require.NoError(t, p.Close())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	require.Len(t, lines, 2)
+
+	var record fileRecord
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &record))
+	require.Equal(t, int64(2), record.ID)
+	require.JSONEq(t, `{"title":"doc"}`, record.Payload)
+}
+
+func TestFilePublisherEncryptsPayload(t *testing.T) {
+	keyring, err := encryption.NewKeyring("k1", map[string][]byte{"k1": []byte("0123456789abcdef0123456789abcdef")})
+	require.NoError(t, err)
+	cipher := encryption.NewEnvelope(keyring)
+	path := filepath.Join(t.TempDir(), "outbox.ndjson")
+	p, err := NewFilePublisher(path, cipher)
+	require.NoError(t, err)
+
+	require.NoError(t, p.Publish(context.Background(), testMessage(1)))
+	require.NoError(t, p.Close())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(content), "title")
+
+	var record fileRecord
+	require.NoError(t, json.Unmarshal(content, &record))
+	payload, err := cipher.Decrypt(record.Payload, filePayloadData(record.ID))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"title":"doc"}`, payload)
+
+	_, err = cipher.Decrypt(record.Payload, filePayloadData(record.ID+1))
+	require.Error(t, err)
+}
+
+func TestHTTPPublisher(t *testing.T) {
+	var (
+		gotKey  string
+		gotBody []byte
+	)
+	status := http.StatusAccepted
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotKey = r.Header.Get("Idempotency-Key")
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer receiver.Close()
+
+	p := NewHTTPPublisher(receiver.URL, time.Second)
+	require.NoError(t, p.Publish(context.Background(), testMessage(42)))
+	require.Equal(t, "42", gotKey)
+	require.Contains(t, string(gotBody), `"event_type":"task.created"`)
+
+	status = http.StatusServiceUnavailable
+	require.ErrorContains(t, p.Publish(context.Background(), testMessage(43)), "503")
+}
+
+// publisherFunc adapts a function to the Publisher interface
+type publisherFunc func(ctx context.Context, msg model.OutboxMessage) error
+
+func (f publisherFunc) Publish(ctx context.Context, msg model.OutboxMessage) error {
+	return f(ctx, msg)
+}
+
+func TestFanoutPublishesToAll(t *testing.T) {
+	mockError := errors.New("publish error")
+	calls := 0
+	counting := publisherFunc(func(context.Context, model.OutboxMessage) error {
+		calls++
+
+		return nil
+	})
+	failing := publisherFunc(func(context.Context, model.OutboxMessage) error { return mockError })
+
+	fanout := Fanout{{Name: "http", Publisher: failing}, {Name: "events", Publisher: counting}, {Name: "log", Publisher: LogPublisher{}}}
+	published, err := fanout.Publish(context.Background(), testMessage(1))
+	require.ErrorIs(t, err, mockError)
+	require.Equal(t, []string{"events", "log"}, published)
+	require.Equal(t, 1, calls)
+}
+
+func TestFanoutSkipsPublished(t *testing.T) {
+	calls := 0
+	counting := publisherFunc(func(context.Context, model.OutboxMessage) error {
+		calls++
+
+		return nil
+	})
+
+	msg := testMessage(1)
+	msg.PublishedTo = []string{"events"}
+
+	fanout := Fanout{{Name: "events", Publisher: counting}, {Name: "log", Publisher: LogPublisher{}}}
+	published, err := fanout.Publish(context.Background(), msg)
+	require.NoError(t, err)
+	require.Equal(t, []string{"events", "log"}, published)
+	require.Zero(t, calls)
+}
@@ -0,0 +1,96 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"go-tasks-api/internal/model"
+	"go-tasks-api/internal/repository"
+
+	"github.com/rs/zerolog/log"
+)
+
+type Config struct {
+	// PollInterval is the delay between two batches when no message was sent
+	PollInterval time.Duration
+	BatchSize    int
+	// MaxAttempts is the number of attempts after which a failing message is dead and no longer
+	// holds back the later messages of its aggregate, 0 retries it forever
+	MaxAttempts int
+	// Lease is the time a batch is published in before other relays may claim its messages
+	Lease time.Duration
+	// BackoffBase and BackoffMax shape the exponential delay before a failed message is retried
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+}
+
+// Relay publishes the messages of the outbox
+type Relay struct {
+	outboxRepo repository.OutboxConnector
+	publisher  Fanout
+	cfg        Config
+}
+
+// NewRelay creates a new Relay publishing to the destinations of publisher
+func NewRelay(outboxRepo repository.OutboxConnector, publisher Fanout, cfg Config) *Relay {
+	return &Relay{
+		outboxRepo: outboxRepo,
+		publisher:  publisher,
+		cfg:        cfg,
+	}
+}
+
+// Run relays messages until the context is cancelled
+func (r *Relay) Run(ctx context.Context) {
+	log.Info().Msg("outbox relay started")
+
+	for {
+		n, err := r.RelayBatch(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to relay outbox messages")
+		}
+
+		// a batch holds at most one message per aggregate, keep going while messages are sent
+		if err == nil && n > 0 {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("outbox relay stopped")
+
+			return
+		case <-time.After(r.cfg.PollInterval):
+		}
+	}
+}
+
+// RelayBatch publishes one batch of messages and returns the number of sent messages
+func (r *Relay) RelayBatch(ctx context.Context) (int, error) {
+	return r.outboxRepo.Relay(ctx, repository.RelayOptions{
+		Limit:       r.cfg.BatchSize,
+		MaxAttempts: r.cfg.MaxAttempts,
+		Lease:       r.cfg.Lease,
+		BackoffBase: r.cfg.BackoffBase,
+		BackoffMax:  r.cfg.BackoffMax,
+	}, r.publish)
+}
+
+func (r *Relay) publish(ctx context.Context, msg model.OutboxMessage) ([]string, error) {
+	published, err := r.publisher.Publish(ctx, msg)
+	if err != nil {
+		logger := log.With().Err(err).
+			Int64("outbox_id", msg.ID).
+			Str("aggregate_id", msg.AggregateID.String()).
+			Int("attempt", msg.Attempts).
+			Logger()
+
+		if r.cfg.MaxAttempts > 0 && msg.Attempts >= r.cfg.MaxAttempts {
+			logger.Error().Strs("published_to", published).Msg("outbox message failed its last attempt, it is dead")
+		} else {
+			logger.Warn().Msg("failed to publish outbox message")
+		}
+	}
+
+	return published, err
+}
@@ -0,0 +1,49 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-tasks-api/internal/model"
+	"go-tasks-api/internal/repository"
+	"go-tasks-api/internal/repository/mocks"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRelayBatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	outboxRepo := mocks.NewMockOutboxConnector(ctrl)
+
+	var published []int64
+	publisher := publisherFunc(func(_ context.Context, msg model.OutboxMessage) error {
+		published = append(published, msg.ID)
+		if msg.ID == 2 {
+			return errors.New("publish error")
+		}
+
+		return nil
+	})
+
+	cfg := Config{BatchSize: 25, MaxAttempts: 3, Lease: time.Minute}
+	outboxRepo.EXPECT().Relay(gomock.Any(), repository.RelayOptions{Limit: 25, MaxAttempts: 3, Lease: time.Minute}, gomock.Any()).
+		DoAndReturn(func(ctx context.Context, _ repository.RelayOptions, publish repository.PublishFunc) (int, error) {
+			published, err := publish(ctx, testMessage(1))
+			require.NoError(t, err)
+			require.Equal(t, []string{"test"}, published)
+
+			published, err = publish(ctx, testMessage(2))
+			require.Error(t, err)
+			require.Empty(t, published)
+
+			return 1, nil
+		})
+
+	n, err := NewRelay(outboxRepo, Fanout{{Name: "test", Publisher: publisher}}, cfg).RelayBatch(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, []int64{1, 2}, published)
+}
@@ -0,0 +1,330 @@
+package repository
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-tasks-api/internal/database"
+	"go-tasks-api/internal/filter"
+	"go-tasks-api/internal/model"
+
+	"github.com/google/uuid"
+	"golang.org/x/sync/singleflight"
+)
+
+type CacheConfig struct {
+	// Size bounds the number of cached tasks and task lists together
+	Size int
+	// TTL is the time a result is served from the cache
+	TTL time.Duration
+}
+
+// CacheStats counts the lookups of a cache since it was created
+type CacheStats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+	// Shared are the misses answered by the load of a concurrent miss of the same key
+	Shared    uint64 `json:"shared"`
+	Evictions uint64 `json:"evictions"`
+	Entries   int    `json:"entries"`
+}
+
+//go:generate go run -mod=mod go.uber.org/mock/mockgen -package mocks -destination=./mocks/cache_mock.go -source=cache.go
+type CachedTaskConnector interface {
+	TaskConnector
+	// Forget drops the cached task and all cached lists, for changes made by other instances
+	Forget(id uuid.UUID)
+	// Purge drops every cached result
+	Purge()
+	Stats() CacheStats
+}
+
+// cacheEntry is a cached result, a model.Task or a []model.Task
+type cacheEntry struct {
+	key     string
+	value   any
+	expires time.Time
+	// generation is the generation of the cache the result was loaded in
+	generation uint64
+}
+
+type cachedTaskRepo struct {
+	next TaskConnector
+	cfg  CacheConfig
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// recency orders the entries from the most to the least recently used
+	recency *list.List
+	// generation grows with every change. Lists of an older generation are stale, and results
+	// loaded while a change was made are not stored.
+	generation uint64
+
+	loads singleflight.Group
+
+	hits, misses, shared, evictions atomic.Uint64
+}
+
+// NewCachedTaskRepo wraps a Task repository with a cache of the results of Get and List,
+// expiring after the TTL and evicting the least recently used beyond its size. Concurrent
+// misses of the same key share one load. Changes made through the cache drop the changed task
+// and all lists, changes made elsewhere are seen once the TTL passed or Forget was called.
+// Reads which have to see the latest writes, see database.Fresh, and Stream bypass the cache.
+func NewCachedTaskRepo(next TaskConnector, cfg CacheConfig) CachedTaskConnector {
+	return &cachedTaskRepo{
+		next:    next,
+		cfg:     cfg,
+		entries: make(map[string]*list.Element),
+		recency: list.New(),
+	}
+}
+
+func (a *cachedTaskRepo) Create(ctx context.Context, task model.Task) (bool, error) {
+	defer a.changed(ctx, "")
+
+	return a.next.Create(ctx, task)
+}
+
+func (a *cachedTaskRepo) Get(ctx context.Context, id string) (model.Task, error) {
+	key, err := uuid.Parse(id)
+	if err != nil || database.Fresh(ctx) {
+		return a.next.Get(ctx, id)
+	}
+
+	task, err := load(a, ctx, taskKey(key), func(ctx context.Context) (model.Task, error) {
+		return a.next.Get(ctx, id)
+	})
+	if err != nil {
+		return model.Task{}, err
+	}
+
+	return copyTask(task), nil
+}
+
+func (a *cachedTaskRepo) List(ctx context.Context, opts ListOptions) ([]model.Task, error) {
+	if database.Fresh(ctx) {
+		return a.next.List(ctx, opts)
+	}
+
+	cached, err := load(a, ctx, "list:"+opts.key(), func(ctx context.Context) ([]model.Task, error) {
+		return a.next.List(ctx, opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := make([]model.Task, 0, len(cached))
+	for _, task := range cached {
+		tasks = append(tasks, copyTask(task))
+	}
+
+	return tasks, nil
+}
+
+func (a *cachedTaskRepo) Stream(ctx context.Context, opts ListOptions, fn func(model.Task) error) error {
+	return a.next.Stream(ctx, opts, fn)
+}
+
+func (a *cachedTaskRepo) Update(ctx context.Context, task model.Task) (model.Task, error) {
+	defer a.changed(ctx, taskKey(task.ID))
+
+	return a.next.Update(ctx, task)
+}
+
+func (a *cachedTaskRepo) Delete(ctx context.Context, id string) error {
+	if key, err := uuid.Parse(id); err == nil {
+		defer a.changed(ctx, taskKey(key))
+	}
+
+	return a.next.Delete(ctx, id)
+}
+
+func (a *cachedTaskRepo) Forget(id uuid.UUID) {
+	a.invalidate(taskKey(id))
+}
+
+func (a *cachedTaskRepo) Purge() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.generation++
+	a.entries = make(map[string]*list.Element)
+	a.recency.Init()
+}
+
+func (a *cachedTaskRepo) Stats() CacheStats {
+	a.mu.Lock()
+	entries := a.recency.Len()
+	a.mu.Unlock()
+
+	return CacheStats{
+		Hits:      a.hits.Load(),
+		Misses:    a.misses.Load(),
+		Shared:    a.shared.Load(),
+		Evictions: a.evictions.Load(),
+		Entries:   entries,
+	}
+}
+
+// changed invalidates the cached task, if any, and all lists after a change. A change made in
+// a transaction is invalidated again once committed, results loaded before are not stored.
+func (a *cachedTaskRepo) changed(ctx context.Context, key string) {
+	a.invalidate(key)
+	database.OnCommit(ctx, func() {
+		a.invalidate(key)
+	})
+}
+
+func (a *cachedTaskRepo) invalidate(key string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.generation++
+	if elem, ok := a.entries[key]; ok {
+		a.remove(elem)
+	}
+}
+
+// load returns the cached result of the key, or else loads it with fn, sharing the load with
+// concurrent misses of the key. A caller whose context ends stops waiting for the load.
+func load[T any](a *cachedTaskRepo, ctx context.Context, key string, fn func(ctx context.Context) (T, error)) (T, error) {
+	value, generation, ok := a.lookup(key)
+	if ok {
+		a.hits.Add(1)
+
+		return value.(T), nil
+	}
+	a.misses.Add(1)
+
+	// a miss after a change does not join a load started before it
+	ch := a.loads.DoChan(key+"@"+strconv.FormatUint(generation, 10), func() (any, error) {
+		value, err := fn(context.WithoutCancel(ctx))
+		if err == nil {
+			a.store(key, value, generation)
+		}
+
+		return value, err
+	})
+
+	select {
+	case <-ctx.Done():
+		var zero T
+
+		return zero, ctx.Err()
+	case res := <-ch:
+		if res.Shared {
+			a.shared.Add(1)
+		}
+		if res.Err != nil {
+			var zero T
+
+			return zero, res.Err
+		}
+
+		return res.Val.(T), nil
+	}
+}
+
+// lookup returns the live result of the key and the current generation
+func (a *cachedTaskRepo) lookup(key string) (any, uint64, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	elem, ok := a.entries[key]
+	if !ok {
+		return nil, a.generation, false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	stale := strings.HasPrefix(key, "list:") && entry.generation != a.generation
+	if stale || time.Now().After(entry.expires) {
+		a.remove(elem)
+
+		return nil, a.generation, false
+	}
+
+	a.recency.MoveToFront(elem)
+
+	return entry.value, a.generation, true
+}
+
+// store caches the result loaded in the generation unless a change was made since
+func (a *cachedTaskRepo) store(key string, value any, generation uint64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if generation != a.generation || a.cfg.Size <= 0 {
+		return
+	}
+
+	if elem, ok := a.entries[key]; ok {
+		a.remove(elem)
+	}
+
+	entry := &cacheEntry{key: key, value: value, expires: time.Now().Add(a.cfg.TTL), generation: generation}
+	a.entries[key] = a.recency.PushFront(entry)
+
+	for a.recency.Len() > a.cfg.Size {
+		a.remove(a.recency.Back())
+		a.evictions.Add(1)
+	}
+}
+
+func (a *cachedTaskRepo) remove(elem *list.Element) {
+	a.recency.Remove(elem)
+	delete(a.entries, elem.Value.(*cacheEntry).key)
+}
+
+func taskKey(id uuid.UUID) string {
+	return "task:" + id.String()
+}
+
+// key identifies the options in the cache, options selecting the same tasks may differ in key
+func (o ListOptions) key() string {
+	var b strings.Builder
+
+	writeExpr(&b, o.Filter)
+	b.WriteString("|")
+	for _, key := range o.Sort {
+		if key.Desc {
+			b.WriteString("-")
+		}
+		b.WriteString(key.Field + ",")
+	}
+	b.WriteString("|" + strings.Join(o.Fields, ","))
+
+	return b.String()
+}
+
+func writeExpr(b *strings.Builder, expr filter.Expr) {
+	switch e := expr.(type) {
+	case filter.And:
+		b.WriteString("and(")
+		writeExpr(b, e.Left)
+		b.WriteString(",")
+		writeExpr(b, e.Right)
+		b.WriteString(")")
+	case filter.Or:
+		b.WriteString("or(")
+		writeExpr(b, e.Left)
+		b.WriteString(",")
+		writeExpr(b, e.Right)
+		b.WriteString(")")
+	case filter.Not:
+		b.WriteString("not(")
+		writeExpr(b, e.Expr)
+		b.WriteString(")")
+	case filter.Comparison:
+		value := e.Value
+		if t, ok := value.(time.Time); ok {
+			value = t.UTC().Format(time.RFC3339Nano)
+		}
+		fmt.Fprintf(b, "%s %s %#v", e.Field, e.Op, value)
+	}
+}
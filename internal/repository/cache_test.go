@@ -0,0 +1,257 @@
+package repository_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go-tasks-api/internal/database"
+	"go-tasks-api/internal/enum"
+	"go-tasks-api/internal/filter"
+	"go-tasks-api/internal/model"
+	"go-tasks-api/internal/repository"
+	"go-tasks-api/internal/repository/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/mock/gomock"
+)
+
+type cacheSuite struct {
+	suite.Suite
+	next  *mocks.MockTaskConnector
+	cache repository.CachedTaskConnector
+	ctx   context.Context
+	task  model.Task
+}
+
+func TestCache(t *testing.T) {
+	suite.Run(t, new(cacheSuite))
+}
+
+func (s *cacheSuite) SetupTest() {
+	s.next = mocks.NewMockTaskConnector(gomock.NewController(s.T()))
+	s.cache = repository.NewCachedTaskRepo(s.next, repository.CacheConfig{Size: 2, TTL: time.Minute})
+	s.ctx = context.Background()
+
+	updatedAt := time.Now()
+	s.task = model.Task{ID: uuid.New(), Title: "title", Status: enum.Status_Todo, UpdatedAt: &updatedAt}
+}
+
+func (s *cacheSuite) get(id uuid.UUID) model.Task {
+	task, err := s.cache.Get(s.ctx, id.String())
+	s.Require().NoError(err)
+
+	return task
+}
+
+func (s *cacheSuite) TestServesGetFromCache() {
+	s.next.EXPECT().Get(gomock.Any(), s.task.ID.String()).Return(s.task, nil).Times(1)
+
+	first := s.get(s.task.ID)
+	*first.UpdatedAt = time.Time{}
+	s.Equal(s.task, s.get(s.task.ID))
+
+	s.Equal(repository.CacheStats{Hits: 1, Misses: 1, Entries: 1}, s.cache.Stats())
+}
+
+func (s *cacheSuite) TestServesListFromCache() {
+	opts := repository.ListOptions{Filter: filter.Comparison{Field: "status", Op: filter.OpEq, Value: "todo"}}
+	s.next.EXPECT().List(gomock.Any(), opts).Return([]model.Task{s.task}, nil).Times(1)
+	s.next.EXPECT().List(gomock.Any(), repository.ListOptions{}).Return([]model.Task{}, nil).Times(1)
+
+	for range 2 {
+		tasks, err := s.cache.List(s.ctx, opts)
+		s.Require().NoError(err)
+		s.Equal([]model.Task{s.task}, tasks)
+
+		tasks, err = s.cache.List(s.ctx, repository.ListOptions{})
+		s.Require().NoError(err)
+		s.Empty(tasks)
+	}
+}
+
+func (s *cacheSuite) TestExpires() {
+	s.cache = repository.NewCachedTaskRepo(s.next, repository.CacheConfig{Size: 2, TTL: time.Millisecond})
+	s.next.EXPECT().Get(gomock.Any(), s.task.ID.String()).Return(s.task, nil).Times(2)
+
+	s.get(s.task.ID)
+	time.Sleep(2 * time.Millisecond)
+	s.get(s.task.ID)
+}
+
+func (s *cacheSuite) TestEvictsLeastRecentlyUsed() {
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	for _, id := range ids {
+		s.next.EXPECT().Get(gomock.Any(), id.String()).Return(model.Task{ID: id}, nil).Times(1)
+	}
+	s.next.EXPECT().Get(gomock.Any(), ids[1].String()).Return(model.Task{ID: ids[1]}, nil).Times(1)
+
+	s.get(ids[0])
+	s.get(ids[1])
+	s.get(ids[0])
+	// evicts ids[1], the least recently used
+	s.get(ids[2])
+	s.get(ids[0])
+	s.get(ids[1])
+
+	stats := s.cache.Stats()
+	s.Equal(uint64(2), stats.Evictions)
+	s.Equal(2, stats.Entries)
+}
+
+func (s *cacheSuite) TestChangesInvalidate() {
+	opts := repository.ListOptions{}
+	s.next.EXPECT().Get(gomock.Any(), s.task.ID.String()).Return(s.task, nil).Times(3)
+	s.next.EXPECT().List(gomock.Any(), opts).Return([]model.Task{s.task}, nil).Times(4)
+	s.next.EXPECT().Create(gomock.Any(), gomock.Any()).Return(true, nil)
+	s.next.EXPECT().Update(gomock.Any(), s.task).Return(s.task, nil)
+	s.next.EXPECT().Delete(gomock.Any(), s.task.ID.String()).Return(nil)
+
+	read := func() {
+		s.get(s.task.ID)
+		_, err := s.cache.List(s.ctx, opts)
+		s.Require().NoError(err)
+	}
+
+	read()
+	// a created task only changes lists
+	_, err := s.cache.Create(s.ctx, model.Task{ID: uuid.New()})
+	s.Require().NoError(err)
+	read()
+	_, err = s.cache.Update(s.ctx, s.task)
+	s.Require().NoError(err)
+	read()
+	s.Require().NoError(s.cache.Delete(s.ctx, s.task.ID.String()))
+	read()
+}
+
+func (s *cacheSuite) TestForgetAndPurge() {
+	s.next.EXPECT().Get(gomock.Any(), s.task.ID.String()).Return(s.task, nil).Times(3)
+
+	s.get(s.task.ID)
+	s.cache.Forget(s.task.ID)
+	s.get(s.task.ID)
+	s.cache.Purge()
+	s.Equal(0, s.cache.Stats().Entries)
+	s.get(s.task.ID)
+}
+
+func (s *cacheSuite) TestDoesNotCacheErrors() {
+	s.next.EXPECT().Get(gomock.Any(), s.task.ID.String()).Return(model.Task{}, repository.ErrNoRows).Times(2)
+
+	for range 2 {
+		_, err := s.cache.Get(s.ctx, s.task.ID.String())
+		s.ErrorIs(err, repository.ErrNoRows)
+	}
+}
+
+func (s *cacheSuite) TestFreshReadsBypassCache() {
+	s.next.EXPECT().Get(gomock.Any(), s.task.ID.String()).Return(s.task, nil).Times(2)
+
+	s.ctx = database.WithPrimary(s.ctx)
+	s.get(s.task.ID)
+	s.get(s.task.ID)
+	s.Equal(repository.CacheStats{}, s.cache.Stats())
+}
+
+func (s *cacheSuite) TestCollapsesConcurrentMisses() {
+	release := make(chan struct{})
+	s.next.EXPECT().Get(gomock.Any(), s.task.ID.String()).
+		DoAndReturn(func(context.Context, string) (model.Task, error) {
+			<-release
+
+			return s.task, nil
+		}).Times(1)
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			s.Equal(s.task.ID, s.get(s.task.ID).ID)
+		})
+	}
+
+	// wait for every caller to miss before the load completes
+	for s.cache.Stats().Misses < 10 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	s.Equal(uint64(10), s.cache.Stats().Misses)
+}
+
+func (s *cacheSuite) TestDoesNotStoreLoadRacingChange() {
+	s.next.EXPECT().Get(gomock.Any(), s.task.ID.String()).
+		DoAndReturn(func(context.Context, string) (model.Task, error) {
+			// a change committed while the task was read
+			s.cache.Forget(s.task.ID)
+
+			return s.task, nil
+		}).Times(2)
+
+	s.get(s.task.ID)
+	s.get(s.task.ID)
+}
+
+func (s *cacheSuite) TestCallerStopsWaitingWhenCancelled() {
+	started, release := make(chan struct{}), make(chan struct{})
+	s.next.EXPECT().Get(gomock.Any(), s.task.ID.String()).
+		DoAndReturn(func(context.Context, string) (model.Task, error) {
+			close(started)
+			<-release
+
+			return s.task, nil
+		}).Times(1)
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	result := make(chan error)
+	go func() {
+		_, err := s.cache.Get(ctx, s.task.ID.String())
+		result <- err
+	}()
+
+	<-started
+	cancel()
+	s.ErrorIs(<-result, context.Canceled)
+
+	// the load goes on for the other callers
+	close(release)
+	s.Equal(s.task, s.get(s.task.ID))
+}
+
+func (s *cacheSuite) TestListKeys() {
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	title := filter.Comparison{Field: "title", Op: filter.OpEq, Value: "a"}
+	done := filter.Comparison{Field: "status", Op: filter.OpEq, Value: "done"}
+	options := []repository.ListOptions{
+		{},
+		{Fields: []string{"id"}},
+		{Sort: []repository.SortKey{{Field: "title"}}},
+		{Sort: []repository.SortKey{{Field: "title", Desc: true}}},
+		{Filter: title},
+		{Filter: filter.Not{Expr: title}},
+		{Filter: filter.Comparison{Field: "updated_at", Op: filter.OpEq, Value: nil}},
+		{Filter: filter.Comparison{Field: "created_at", Op: filter.OpGt, Value: at}},
+		{Filter: filter.And{Left: title, Right: done}},
+		{Filter: filter.Or{Left: title, Right: done}},
+	}
+	s.cache = repository.NewCachedTaskRepo(s.next, repository.CacheConfig{Size: len(options), TTL: time.Minute})
+
+	// every options are loaded once, the same instant in another zone has the same key
+	for _, opts := range options {
+		s.next.EXPECT().List(gomock.Any(), opts).Return([]model.Task{}, nil).Times(1)
+	}
+	for range 2 {
+		for _, opts := range options {
+			_, err := s.cache.List(s.ctx, opts)
+			s.Require().NoError(err)
+		}
+	}
+
+	_, err := s.cache.List(s.ctx, repository.ListOptions{
+		Filter: filter.Comparison{Field: "created_at", Op: filter.OpGt, Value: at.In(time.FixedZone("", 3600))},
+	})
+	s.Require().NoError(err)
+}
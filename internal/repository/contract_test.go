@@ -0,0 +1,72 @@
+package repository_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-tasks-api/internal/database"
+	"go-tasks-api/internal/encryption"
+	"go-tasks-api/internal/migrator"
+	"go-tasks-api/internal/repository"
+	"go-tasks-api/internal/repository/repotest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestMemoryTaskContract(t *testing.T) {
+	suite.Run(t, &repotest.TaskSuite{NewRepo: repository.NewMemoryTaskRepo})
+}
+
+func TestCachedTaskContract(t *testing.T) {
+	suite.Run(t, &repotest.TaskSuite{
+		NewRepo: func() repository.TaskConnector {
+			return repository.NewCachedTaskRepo(repository.NewMemoryTaskRepo(), repository.CacheConfig{
+				Size: 100,
+				TTL:  time.Minute,
+			})
+		},
+	})
+}
+
+func TestFileTaskContract(t *testing.T) {
+	suite.Run(t, &repotest.TaskSuite{
+		NewRepo: func() repository.TaskConnector {
+			repo, err := repository.OpenFileTaskRepo(repository.FileConfig{
+				Path: filepath.Join(t.TempDir(), "tasks.log"),
+				Sync: repository.FileSyncAlways,
+			}, encryption.NoopCipher{})
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = repo.Close() })
+
+			return repo
+		},
+	})
+}
+
+// TestPostgresTaskContract runs the contract against the database of TEST_DATABASE_DSN, its
+// tasks schema is migrated and the tasks are removed before every test
+func TestPostgresTaskContract(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	require.NoError(t, migrator.Run(context.Background(), dsn, 0, "goose_db_version", migrator.FS))
+
+	db, err := database.NewConnection(context.Background(), dsn, 10, 0)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	suite.Run(t, &repotest.TaskSuite{
+		NewRepo: func() repository.TaskConnector {
+			_, err := db.Exec(`TRUNCATE tasks.tasks, tasks.outbox;`)
+			require.NoError(t, err)
+
+			return repository.NewTaskRepo(db, encryption.NoopCipher{}, nil)
+		},
+	})
+}
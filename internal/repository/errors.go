@@ -1,5 +1,5 @@
 package repository
 
-import "errors"
+import "go-tasks-api/internal/apperr"
 
-var ErrNoRows = errors.New("no rows found")
+var ErrNoRows = apperr.New(apperr.NotFound, "no rows found")
@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"go-tasks-api/internal/database"
+	"go-tasks-api/internal/encryption"
+	"go-tasks-api/internal/model"
+)
+
+// EventsChannel is the LISTEN/NOTIFY channel carrying the IDs of appended events. Payloads are
+// limited to 8000 bytes, so listeners fetch the event itself from the log.
+const EventsChannel = "task_events"
+
+// appendLockKey is the key of the transaction-level advisory lock serializing appends
+const appendLockKey = 7284201
+
+type eventRepo struct {
+	db     *sql.DB
+	cipher encryption.Cipher
+}
+
+//go:generate go run -mod=mod go.uber.org/mock/mockgen -package mocks -destination=./mocks/event_mock.go -source=event.go
+type EventConnector interface {
+	// Append adds the event to the log and notifies EventsChannel with its ID on commit. Appends
+	// commit in the order of their IDs, so no event shows up behind an ID already read.
+	Append(ctx context.Context, event model.Event) (model.Event, error)
+	Get(ctx context.Context, id int64) (model.Event, error)
+	// ListAfter returns up to limit events with an ID above afterID in ID order
+	ListAfter(ctx context.Context, afterID int64, limit int) ([]model.Event, error)
+	// LastID returns the highest event ID, 0 for an empty log
+	LastID(ctx context.Context) (int64, error)
+}
+
+// NewEventRepo creates a new Event repository, event payloads contain task fields and are
+// encrypted with the same cipher as tasks
+func NewEventRepo(db *sql.DB, cipher encryption.Cipher) EventConnector {
+	return &eventRepo{
+		db:     db,
+		cipher: cipher,
+	}
+}
+
+func (a *eventRepo) Append(ctx context.Context, event model.Event) (model.Event, error) {
+	// an event published again from the outbox returns the existing row, so it keeps its ID
+	insertSQL := `
+		INSERT INTO tasks.events (id, type, task_id, status, payload, outbox_id) values ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (outbox_id) DO UPDATE SET outbox_id = EXCLUDED.outbox_id
+		RETURNING id, created_at;
+	`
+	notifySQL := `SELECT pg_notify($1, $2);`
+	// the lock is held until the commit, IDs are drawn from the sequence in lock order, so an
+	// event is never committed after one with a higher ID that readers resumed from
+	lockSQL := `SELECT pg_advisory_xact_lock($1);`
+	// the ID is drawn ahead of the insert as the payload is encrypted with it
+	nextIDSQL := `SELECT nextval('tasks.events_id_seq');`
+
+	var status any
+	if event.Status.IsAStatusType() {
+		status = event.Status
+	}
+
+	var outboxID any
+	if event.OutboxID != 0 {
+		outboxID = event.OutboxID
+	}
+
+	tx, err := database.Begin(ctx, a.db)
+	if err != nil {
+		return model.Event{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, lockSQL, appendLockKey); err != nil {
+		return model.Event{}, fmt.Errorf("failed to lock event log: %w", err)
+	}
+
+	var id int64
+	if err := tx.QueryRowContext(ctx, nextIDSQL).Scan(&id); err != nil {
+		return model.Event{}, fmt.Errorf("failed to draw event id: %w", err)
+	}
+
+	payload, err := a.cipher.Encrypt(string(event.Data), eventPayloadData(id))
+	if err != nil {
+		return model.Event{}, fmt.Errorf("failed to encrypt event payload: %w", err)
+	}
+
+	if err := tx.QueryRowContext(ctx, insertSQL, id, event.Type, event.TaskID.String(), status, payload, outboxID).
+		Scan(&event.ID, &event.CreatedAt); err != nil {
+		return model.Event{}, fmt.Errorf("failed to insert event: %w", err)
+	}
+
+	// notifications are only delivered on commit, when the event is visible to listeners
+	if _, err := tx.ExecContext(ctx, notifySQL, EventsChannel, strconv.FormatInt(event.ID, 10)); err != nil {
+		return model.Event{}, fmt.Errorf("failed to notify event: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return model.Event{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return event, nil
+}
+
+func (a *eventRepo) Get(ctx context.Context, id int64) (model.Event, error) {
+	getSQL := `SELECT id, type, task_id, status, payload, created_at FROM tasks.events WHERE id = $1;`
+
+	event, err := a.scan(database.Conn(ctx, a.db).QueryRowContext(ctx, getSQL, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Event{}, ErrNoRows
+		}
+
+		return model.Event{}, fmt.Errorf("failed to get event: %w", err)
+	}
+
+	return event, nil
+}
+
+func (a *eventRepo) ListAfter(ctx context.Context, afterID int64, limit int) ([]model.Event, error) {
+	listSQL := `SELECT id, type, task_id, status, payload, created_at FROM tasks.events WHERE id > $1 ORDER BY id LIMIT $2;`
+
+	rows, err := database.Conn(ctx, a.db).QueryContext(ctx, listSQL, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]model.Event, 0)
+	for rows.Next() {
+		event, err := a.scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return events, nil
+}
+
+func (a *eventRepo) LastID(ctx context.Context) (int64, error) {
+	lastSQL := `SELECT COALESCE(max(id), 0) FROM tasks.events;`
+
+	var id int64
+	if err := database.Conn(ctx, a.db).QueryRowContext(ctx, lastSQL).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to get last event id: %w", err)
+	}
+
+	return id, nil
+}
+
+func (a *eventRepo) scan(row scanner) (model.Event, error) {
+	var (
+		event   model.Event
+		payload string
+	)
+	if err := row.Scan(
+		&event.ID,
+		&event.Type,
+		&event.TaskID,
+		&event.Status,
+		&payload,
+		&event.CreatedAt,
+	); err != nil {
+		return model.Event{}, err
+	}
+
+	data, err := a.cipher.Decrypt(payload, eventPayloadData(event.ID))
+	if err != nil {
+		return model.Event{}, fmt.Errorf("failed to decrypt event payload: %w", err)
+	}
+	event.Data = []byte(data)
+
+	return event, nil
+}
+
+// eventPayloadData returns the additional data the payload of the event is encrypted with
+func eventPayloadData(id int64) string {
+	return encryption.AdditionalData("tasks.events", "payload", strconv.FormatInt(id, 10))
+}
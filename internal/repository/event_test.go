@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"go-tasks-api/internal/encryption"
+	"go-tasks-api/internal/enum"
+	"go-tasks-api/internal/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type eventSuite struct {
+	suite.Suite
+	repo EventConnector
+	db   sqlmock.Sqlmock
+}
+
+func TestEvent(t *testing.T) {
+	suite.Run(t, new(eventSuite))
+}
+
+func (s *eventSuite) SetupTest() {
+	db, mock, err := sqlmock.New()
+	require.NoError(s.T(), err)
+
+	s.repo = NewEventRepo(db, encryption.NoopCipher{})
+	s.db = mock
+}
+
+func (s *eventSuite) TearDownTest() {
+	s.NoError(s.db.ExpectationsWereMet())
+}
+
+func (s *eventSuite) TestAppendSuccess() {
+	now := time.Now()
+	event := model.Event{
+		Type:   model.EventTaskCreated,
+		TaskID: uuid.New(),
+		Status: enum.Status_Todo,
+		Data:   []byte(`{"title":"doc"}`),
+	}
+
+	s.db.ExpectBegin()
+	s.db.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_xact_lock($1);`)).
+		WithArgs(appendLockKey).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	s.db.ExpectQuery(regexp.QuoteMeta(`SELECT nextval('tasks.events_id_seq');`)).
+		WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(42))
+	s.db.ExpectQuery(regexp.QuoteMeta(`INSERT INTO tasks.events (id, type, task_id, status, payload, outbox_id) values ($1, $2, $3, $4, $5, $6)`)).
+		WithArgs(int64(42), event.Type, event.TaskID.String(), event.Status, string(event.Data), nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(42, now))
+	s.db.ExpectExec(regexp.QuoteMeta(`SELECT pg_notify($1, $2);`)).
+		WithArgs(EventsChannel, "42").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	s.db.ExpectCommit()
+
+	got, err := s.repo.Append(context.Background(), event)
+	s.NoError(err)
+	s.Equal(int64(42), got.ID)
+	s.Equal(now, got.CreatedAt)
+}
+
+func (s *eventSuite) TestAppendDeletedWithoutStatus() {
+	event := model.Event{
+		Type:   model.EventTaskDeleted,
+		TaskID: uuid.New(),
+		Data:   []byte(`{}`),
+	}
+
+	s.db.ExpectBegin()
+	s.db.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_xact_lock`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	s.db.ExpectQuery(regexp.QuoteMeta(`SELECT nextval('tasks.events_id_seq');`)).
+		WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(1))
+	s.db.ExpectQuery(regexp.QuoteMeta(`INSERT INTO tasks.events`)).
+		WithArgs(int64(1), event.Type, event.TaskID.String(), nil, "{}", nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+	s.db.ExpectExec(regexp.QuoteMeta(`SELECT pg_notify`)).
+		WithArgs(EventsChannel, "1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	s.db.ExpectCommit()
+
+	_, err := s.repo.Append(context.Background(), event)
+	s.NoError(err)
+}
+
+func (s *eventSuite) TestAppendNotifyFailure() {
+	mockError := errors.New("db error")
+
+	s.db.ExpectBegin()
+	s.db.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_xact_lock`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	s.db.ExpectQuery(regexp.QuoteMeta(`SELECT nextval('tasks.events_id_seq');`)).
+		WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(1))
+	s.db.ExpectQuery(regexp.QuoteMeta(`INSERT INTO tasks.events`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+	s.db.ExpectExec(regexp.QuoteMeta(`SELECT pg_notify`)).WillReturnError(mockError)
+	s.db.ExpectRollback()
+
+	_, err := s.repo.Append(context.Background(), model.Event{Type: model.EventTaskDeleted, TaskID: uuid.New()})
+	s.ErrorIs(err, mockError)
+}
+
+func (s *eventSuite) TestGetSuccess() {
+	now := time.Now()
+	taskID := uuid.New()
+
+	s.db.ExpectQuery(regexp.QuoteMeta(`SELECT id, type, task_id, status, payload, created_at FROM tasks.events WHERE id = $1;`)).
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type", "task_id", "status", "payload", "created_at"}).
+			AddRow(7, model.EventTaskCreated, taskID.String(), "todo", `{"id":7}`, now))
+
+	got, err := s.repo.Get(context.Background(), 7)
+	s.NoError(err)
+	s.Equal(model.Event{ID: 7, Type: model.EventTaskCreated, TaskID: taskID, Status: enum.Status_Todo, Data: []byte(`{"id":7}`), CreatedAt: now}, got)
+}
+
+func (s *eventSuite) TestGetNotFound() {
+	s.db.ExpectQuery(regexp.QuoteMeta(`FROM tasks.events WHERE id = $1;`)).
+		WithArgs(int64(7)).
+		WillReturnError(sql.ErrNoRows)
+
+	_, err := s.repo.Get(context.Background(), 7)
+	s.ErrorIs(err, ErrNoRows)
+}
+
+func (s *eventSuite) TestListAfterSuccess() {
+	now := time.Now()
+	taskID := uuid.New()
+
+	s.db.ExpectQuery(regexp.QuoteMeta(`SELECT id, type, task_id, status, payload, created_at FROM tasks.events WHERE id > $1 ORDER BY id LIMIT $2;`)).
+		WithArgs(int64(10), 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type", "task_id", "status", "payload", "created_at"}).
+			AddRow(11, model.EventTaskUpdated, taskID.String(), "done", `{"id":1}`, now).
+			AddRow(12, model.EventTaskDeleted, taskID.String(), nil, `{"id":2}`, now))
+
+	got, err := s.repo.ListAfter(context.Background(), 10, 2)
+	s.NoError(err)
+	s.Equal([]model.Event{
+		{ID: 11, Type: model.EventTaskUpdated, TaskID: taskID, Status: enum.Status_Done, Data: []byte(`{"id":1}`), CreatedAt: now},
+		{ID: 12, Type: model.EventTaskDeleted, TaskID: taskID, Data: []byte(`{"id":2}`), CreatedAt: now},
+	}, got)
+}
+
+func (s *eventSuite) TestListAfterError() {
+	mockError := errors.New("db error")
+	s.db.ExpectQuery(regexp.QuoteMeta(`SELECT id, type, task_id, status, payload, created_at FROM tasks.events`)).
+		WillReturnError(mockError)
+
+	got, err := s.repo.ListAfter(context.Background(), 0, 10)
+	s.True(errors.Is(err, mockError))
+	s.Nil(got)
+}
+
+func (s *eventSuite) TestLastID() {
+	s.db.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(max(id), 0) FROM tasks.events;`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(42)))
+
+	got, err := s.repo.LastID(context.Background())
+	s.NoError(err)
+	s.Equal(int64(42), got)
+}
@@ -0,0 +1,579 @@
+package repository
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go-tasks-api/internal/encryption"
+	"go-tasks-api/internal/model"
+
+	"github.com/rs/zerolog/log"
+)
+
+// FileSync is the policy of flushing appended records to the disk
+type FileSync string
+
+const (
+	// FileSyncAlways syncs every change before it is acknowledged, no acknowledged change is lost
+	FileSyncAlways FileSync = "always"
+	// FileSyncInterval syncs changes in the background, a crash loses at most one interval
+	FileSyncInterval FileSync = "interval"
+	// FileSyncNever leaves flushing to the operating system
+	FileSyncNever FileSync = "never"
+)
+
+// ErrFileClosed is returned by changes made after the file repository was closed
+var ErrFileClosed = errors.New("task log is closed")
+
+// ErrFileLocked is returned when another process, such as a running API, holds the task log
+var ErrFileLocked = errors.New("task log is in use by another process")
+
+// errLockHeld is returned by tryLock when another process holds the lock
+var errLockHeld = errors.New("lock is held")
+
+// errTornRecord is the error of a record a crash left incomplete while it was appended
+var errTornRecord = errors.New("torn task log record")
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+type FileConfig struct {
+	// Path is the log file, it is created when it does not exist
+	Path string
+	Sync FileSync
+	// SyncInterval is the delay between syncs of the interval policy
+	SyncInterval time.Duration
+	// CompactInterval is the delay between checks whether the log is worth compacting
+	CompactInterval time.Duration
+	// CompactMinStale is the number of superseded records from which the log is compacted, once
+	// they also outnumber the tasks
+	CompactMinStale int
+}
+
+// fileRecord is a line of the log, the state of a task after a change. The line is the hex
+// CRC-32C of the JSON record, a space and the record.
+type fileRecord struct {
+	Task   model.Task `json:"task"`
+	Active bool       `json:"active"`
+}
+
+type fileTaskRepo struct {
+	cfg    FileConfig
+	cipher encryption.Cipher
+	// index holds the current state of every task, reads are served from it alone
+	index *memoryTaskRepo
+	// lock holds the exclusive lock on the log until Close, the log itself is replaced when
+	// compacted so the lock is taken on a file next to it
+	lock *os.File
+
+	// mu serializes changes, so records are appended in the order they are applied to the index
+	mu   sync.Mutex
+	file *os.File
+	// size is the length of the log up to its last complete record
+	size    int64
+	records int
+	dirty   bool
+}
+
+//go:generate go run -mod=mod go.uber.org/mock/mockgen -package mocks -destination=./mocks/file_mock.go -source=file.go
+type FileTaskConnector interface {
+	TaskConnector
+	// Run syncs the log with the interval policy and compacts it until the context is cancelled
+	Run(ctx context.Context)
+	// Compact rewrites the log with one record per task
+	Compact() error
+	// Close syncs and closes the log, later changes fail with ErrFileClosed
+	Close() error
+}
+
+// OpenFileTaskRepo creates a Task repository persisting tasks to an append-only log. The log is
+// replayed into memory first, a record torn by a crash at its end is cut off. A record which
+// cannot be decoded or decrypted, such as with a keyring missing its key, fails the replay and
+// leaves the log untouched. The log is locked until Close, opening a log another process holds
+// fails with ErrFileLocked. Descriptions are encrypted with the given cipher.
+func OpenFileTaskRepo(cfg FileConfig, cipher encryption.Cipher) (FileTaskConnector, error) {
+	switch cfg.Sync {
+	case FileSyncAlways, FileSyncInterval, FileSyncNever:
+	default:
+		return nil, fmt.Errorf("unknown file sync policy %q", cfg.Sync)
+	}
+
+	if cfg.Sync == FileSyncInterval && cfg.SyncInterval <= 0 {
+		return nil, errors.New("the interval file sync policy requires a positive sync interval")
+	}
+
+	lock, err := lockFile(cfg.Path + ".lock")
+	if err != nil {
+		return nil, err
+	}
+
+	// records are always appended, whatever the offset the replay read up to
+	file, err := os.OpenFile(cfg.Path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		_ = lock.Close()
+
+		return nil, fmt.Errorf("failed to open task log: %w", err)
+	}
+
+	a := &fileTaskRepo{
+		cfg:    cfg,
+		cipher: cipher,
+		index:  newMemoryTaskRepo(),
+		lock:   lock,
+		file:   file,
+	}
+
+	if err := a.replay(); err != nil {
+		_ = file.Close()
+		_ = lock.Close()
+
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// lockFile takes an exclusive lock on the file at path, which is released when it is closed
+func lockFile(path string) (*os.File, error) {
+	lock, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open task log lock: %w", err)
+	}
+
+	if err := tryLock(lock); err != nil {
+		_ = lock.Close()
+
+		if errors.Is(err, errLockHeld) {
+			return nil, fmt.Errorf("%w: %s", ErrFileLocked, path)
+		}
+
+		return nil, fmt.Errorf("failed to lock task log: %w", err)
+	}
+
+	return lock, nil
+}
+
+// replay loads the log into the index
+func (a *fileTaskRepo) replay() error {
+	reader := bufio.NewReader(a.file)
+
+	var (
+		offset int64
+		// damaged is the offset of a torn record, -1 while there is none
+		damaged int64 = -1
+		line    int
+	)
+	for {
+		data, err := reader.ReadBytes('\n')
+		if len(data) > 0 {
+			line++
+
+			// a crash only tears the last record, a record after a torn one means the log was
+			// corrupted otherwise
+			if damaged >= 0 {
+				return fmt.Errorf("task log %s is corrupt before line %d", a.cfg.Path, line)
+			}
+
+			stored, dErr := a.decode(data)
+			switch {
+			case errors.Is(dErr, errTornRecord):
+				damaged = offset
+			case dErr != nil:
+				return fmt.Errorf("failed to replay line %d of task log %s: %w", line, a.cfg.Path, dErr)
+			default:
+				a.index.put(stored)
+				a.records++
+			}
+			offset += int64(len(data))
+		}
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read task log: %w", err)
+		}
+	}
+
+	end := offset
+	if damaged >= 0 {
+		log.Warn().Str("path", a.cfg.Path).Int64("offset", damaged).Int64("size", offset).
+			Msg("cutting off the damaged end of the task log")
+
+		if err := a.file.Truncate(damaged); err != nil {
+			return fmt.Errorf("failed to truncate task log: %w", err)
+		}
+		if err := a.file.Sync(); err != nil {
+			return fmt.Errorf("failed to sync task log: %w", err)
+		}
+		end = damaged
+	}
+
+	a.size = end
+
+	return nil
+}
+
+// decode returns the task of a line, it returns errTornRecord for a line damaged by a crash
+func (a *fileTaskRepo) decode(line []byte) (memoryTask, error) {
+	// a record without its newline was torn while it was appended
+	line, ok := bytes.CutSuffix(line, []byte("\n"))
+	if !ok {
+		return memoryTask{}, errTornRecord
+	}
+
+	sum, data, ok := bytes.Cut(line, []byte(" "))
+	if !ok || string(sum) != checksum(data) {
+		return memoryTask{}, errTornRecord
+	}
+
+	var rec fileRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return memoryTask{}, fmt.Errorf("failed to decode task: %w", err)
+	}
+
+	description, err := a.cipher.Decrypt(rec.Task.Description, descriptionData(rec.Task.ID))
+	if err != nil {
+		return memoryTask{}, fmt.Errorf("failed to decrypt task description: %w", err)
+	}
+	rec.Task.Description = description
+
+	return memoryTask{task: rec.Task, active: rec.Active}, nil
+}
+
+// encode returns the line of the task
+func (a *fileTaskRepo) encode(stored memoryTask) ([]byte, error) {
+	task := stored.task
+	description, err := a.cipher.Encrypt(task.Description, descriptionData(task.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt task description: %w", err)
+	}
+	task.Description = description
+
+	data, err := json.Marshal(fileRecord{Task: task, Active: stored.active})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode task: %w", err)
+	}
+
+	return append(append([]byte(checksum(data)+" "), data...), '\n'), nil
+}
+
+func checksum(data []byte) string {
+	return fmt.Sprintf("%08x", crc32.Checksum(data, crcTable))
+}
+
+// write appends the task to the log and applies it to the index, the caller holds mu
+func (a *fileTaskRepo) write(stored memoryTask) error {
+	if a.file == nil {
+		return ErrFileClosed
+	}
+
+	line, err := a.encode(stored)
+	if err != nil {
+		return err
+	}
+
+	if _, err := a.file.Write(line); err != nil {
+		// a partial record would hide the records appended after it on replay
+		if tErr := a.file.Truncate(a.size); tErr != nil {
+			a.fail()
+		}
+
+		return fmt.Errorf("failed to append to task log: %w", err)
+	}
+	a.size += int64(len(line))
+
+	if a.cfg.Sync == FileSyncAlways {
+		if err := a.file.Sync(); err != nil {
+			// whether the written pages reach the disk is unknown after a failed sync
+			a.fail()
+
+			return fmt.Errorf("failed to sync task log: %w", err)
+		}
+	} else {
+		a.dirty = true
+	}
+
+	a.index.put(stored)
+	a.records++
+
+	return nil
+}
+
+// fail closes the log after an error that leaves its content unknown, the caller holds mu
+func (a *fileTaskRepo) fail() {
+	log.Error().Str("path", a.cfg.Path).Msg("task log closed after a failed write or compaction, restart to replay it")
+
+	_ = a.file.Close()
+	a.file = nil
+}
+
+func (a *fileTaskRepo) Create(_ context.Context, task model.Task) (bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// an existing ID is left unchanged like the ON CONFLICT DO NOTHING insert
+	if _, ok := a.index.lookup(task.ID); ok {
+		return false, nil
+	}
+
+	if err := a.write(memoryTask{task: createdTask(task), active: true}); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (a *fileTaskRepo) Get(ctx context.Context, id string) (model.Task, error) {
+	return a.index.Get(ctx, id)
+}
+
+func (a *fileTaskRepo) List(ctx context.Context, opts ListOptions) ([]model.Task, error) {
+	return a.index.List(ctx, opts)
+}
+
+func (a *fileTaskRepo) Stream(ctx context.Context, opts ListOptions, fn func(model.Task) error) error {
+	return a.index.Stream(ctx, opts, fn)
+}
+
+func (a *fileTaskRepo) Update(_ context.Context, task model.Task) (model.Task, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	stored, ok := a.index.lookup(task.ID)
+	if !ok || !stored.active {
+		return model.Task{}, ErrNoRows
+	}
+
+	stored.task = updatedTask(stored.task, task)
+	if err := a.write(stored); err != nil {
+		return model.Task{}, err
+	}
+
+	return copyTask(stored.task), nil
+}
+
+func (a *fileTaskRepo) Delete(_ context.Context, id string) error {
+	key, err := parseTaskID(id)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	stored, ok := a.index.lookup(key)
+	if !ok || !stored.active {
+		return ErrNoRows
+	}
+	stored.active = false
+
+	return a.write(stored)
+}
+
+func (a *fileTaskRepo) Run(ctx context.Context) {
+	// a nil channel never fires, so the ticker of a disabled task is left out
+	var syncTick, compactTick <-chan time.Time
+	if a.cfg.Sync == FileSyncInterval {
+		ticker := time.NewTicker(a.cfg.SyncInterval)
+		defer ticker.Stop()
+		syncTick = ticker.C
+	}
+	if a.cfg.CompactInterval > 0 {
+		ticker := time.NewTicker(a.cfg.CompactInterval)
+		defer ticker.Stop()
+		compactTick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-syncTick:
+			if err := a.sync(); err != nil {
+				log.Error().Err(err).Msg("failed to sync task log")
+			}
+		case <-compactTick:
+			if !a.compactable() {
+				continue
+			}
+
+			if err := a.Compact(); err != nil {
+				log.Error().Err(err).Msg("failed to compact task log")
+			}
+		}
+	}
+}
+
+// sync flushes the records appended since the last sync
+func (a *fileTaskRepo) sync() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.file == nil || !a.dirty {
+		return nil
+	}
+
+	if err := a.file.Sync(); err != nil {
+		a.fail()
+
+		return err
+	}
+	a.dirty = false
+
+	return nil
+}
+
+// compactable reports whether enough records were superseded to rewrite the log
+func (a *fileTaskRepo) compactable() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	stale := a.records - a.index.size()
+
+	return a.file != nil && stale >= a.cfg.CompactMinStale && stale >= a.records-stale
+}
+
+func (a *fileTaskRepo) Compact() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.file == nil {
+		return ErrFileClosed
+	}
+
+	// deleted tasks are kept, their IDs cannot be taken again
+	tasks := a.index.all()
+
+	tmpPath := a.cfg.Path + ".compact"
+	tmp, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create compacted task log: %w", err)
+	}
+	// removing fails once the file was renamed, which is fine
+	defer func() { _ = os.Remove(tmpPath) }()
+
+	if err := a.writeAll(tmp, tasks); err != nil {
+		_ = tmp.Close()
+
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close compacted task log: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, a.cfg.Path); err != nil {
+		return fmt.Errorf("failed to replace task log: %w", err)
+	}
+
+	// the open file is no longer linked from here on, changes appended to it would be lost on
+	// restart, so an error closes the log
+	file, err := os.OpenFile(a.cfg.Path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		a.fail()
+
+		return fmt.Errorf("failed to reopen task log: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		a.fail()
+
+		return fmt.Errorf("failed to stat task log: %w", err)
+	}
+
+	_ = a.file.Close()
+	a.file = file
+	a.size = info.Size()
+	a.records = len(tasks)
+	a.dirty = false
+
+	// the rename only survives a crash once the directory is synced, until then the changes
+	// appended to the new log may be lost
+	if err := syncDir(filepath.Dir(a.cfg.Path)); err != nil {
+		a.fail()
+
+		return err
+	}
+
+	log.Info().Str("path", a.cfg.Path).Int("tasks", len(tasks)).Msg("task log compacted")
+
+	return nil
+}
+
+// writeAll writes one record per task and syncs the file
+func (a *fileTaskRepo) writeAll(file *os.File, tasks []memoryTask) error {
+	w := bufio.NewWriter(file)
+	for _, stored := range tasks {
+		line, err := a.encode(stored)
+		if err != nil {
+			return err
+		}
+
+		if _, err := w.Write(line); err != nil {
+			return fmt.Errorf("failed to write compacted task log: %w", err)
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write compacted task log: %w", err)
+	}
+
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync compacted task log: %w", err)
+	}
+
+	return nil
+}
+
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open task log directory: %w", err)
+	}
+	defer dir.Close()
+
+	if err := dir.Sync(); err != nil {
+		return fmt.Errorf("failed to sync task log directory: %w", err)
+	}
+
+	return nil
+}
+
+func (a *fileTaskRepo) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// the lock is held by a log closed after a failure too, its index still serves reads
+	if a.lock != nil {
+		defer func() {
+			_ = a.lock.Close()
+			a.lock = nil
+		}()
+	}
+
+	if a.file == nil {
+		return nil
+	}
+
+	syncErr := a.file.Sync()
+	closeErr := a.file.Close()
+	a.file = nil
+
+	if err := errors.Join(syncErr, closeErr); err != nil {
+		return fmt.Errorf("failed to close task log: %w", err)
+	}
+
+	return nil
+}
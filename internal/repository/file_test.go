@@ -0,0 +1,306 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-tasks-api/internal/encryption"
+	"go-tasks-api/internal/enum"
+	"go-tasks-api/internal/model"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type fileSuite struct {
+	suite.Suite
+	cfg    FileConfig
+	cipher encryption.Cipher
+	repo   FileTaskConnector
+	ctx    context.Context
+}
+
+func TestFile(t *testing.T) {
+	suite.Run(t, new(fileSuite))
+}
+
+func (s *fileSuite) SetupTest() {
+	s.cfg = FileConfig{
+		Path:            filepath.Join(s.T().TempDir(), "tasks.log"),
+		Sync:            FileSyncAlways,
+		CompactMinStale: 3,
+	}
+	s.cipher = encryption.NoopCipher{}
+	s.ctx = context.Background()
+	s.open()
+}
+
+func (s *fileSuite) TearDownTest() {
+	s.NoError(s.repo.Close())
+}
+
+// open replaces the repository with one replaying the log
+func (s *fileSuite) open() {
+	if s.repo != nil {
+		s.Require().NoError(s.repo.Close())
+	}
+
+	repo, err := OpenFileTaskRepo(s.cfg, s.cipher)
+	s.Require().NoError(err)
+	s.repo = repo
+}
+
+func (s *fileSuite) create(title string) model.Task {
+	task := model.Task{ID: uuid.New(), Title: title, Status: enum.Status_Todo, CreatedAt: time.Now()}
+	_, err := s.repo.Create(s.ctx, task)
+	s.Require().NoError(err)
+
+	return task
+}
+
+func (s *fileSuite) lines() []string {
+	data, err := os.ReadFile(s.cfg.Path)
+	s.Require().NoError(err)
+
+	return stringsOf(bytes.SplitAfter(data, []byte("\n")))
+}
+
+func stringsOf(parts [][]byte) []string {
+	lines := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if len(part) > 0 {
+			lines = append(lines, string(part))
+		}
+	}
+
+	return lines
+}
+
+func (s *fileSuite) TestReplaysLog() {
+	kept := s.create("keep")
+	deleted := s.create("delete")
+
+	updatedAt := time.Now()
+	kept.Title = "kept"
+	kept.Status = enum.Status_Done
+	kept.UpdatedAt = &updatedAt
+	_, err := s.repo.Update(s.ctx, kept)
+	s.Require().NoError(err)
+	s.Require().NoError(s.repo.Delete(s.ctx, deleted.ID.String()))
+
+	s.open()
+
+	got, err := s.repo.Get(s.ctx, kept.ID.String())
+	s.Require().NoError(err)
+	s.Equal("kept", got.Title)
+	s.Equal(enum.Status_Done, got.Status)
+	s.True(updatedAt.Round(time.Microsecond).Equal(*got.UpdatedAt))
+
+	_, err = s.repo.Get(s.ctx, deleted.ID.String())
+	s.ErrorIs(err, ErrNoRows)
+
+	// the ID of the deleted task is still taken
+	created, err := s.repo.Create(s.ctx, deleted)
+	s.Require().NoError(err)
+	s.False(created)
+	_, err = s.repo.Get(s.ctx, deleted.ID.String())
+	s.ErrorIs(err, ErrNoRows)
+}
+
+func (s *fileSuite) TestCutsOffTornRecord() {
+	task := s.create("first")
+	s.Require().NoError(s.repo.Close())
+
+	intact, err := os.ReadFile(s.cfg.Path)
+	s.Require().NoError(err)
+
+	// a crash in the middle of an append leaves part of the record
+	s.Require().NoError(os.WriteFile(s.cfg.Path, append(append([]byte{}, intact...), intact[:10]...), 0o600))
+
+	s.open()
+	_, err = s.repo.Get(s.ctx, task.ID.String())
+	s.Require().NoError(err)
+
+	data, err := os.ReadFile(s.cfg.Path)
+	s.Require().NoError(err)
+	s.Equal(intact, data)
+
+	second := s.create("second")
+	s.open()
+	_, err = s.repo.Get(s.ctx, second.ID.String())
+	s.NoError(err)
+}
+
+func (s *fileSuite) TestRejectsCorruptLog() {
+	s.create("first")
+	s.create("second")
+	s.Require().NoError(s.repo.Close())
+
+	data, err := os.ReadFile(s.cfg.Path)
+	s.Require().NoError(err)
+	data[20] ^= 0xff
+	s.Require().NoError(os.WriteFile(s.cfg.Path, data, 0o600))
+
+	_, err = OpenFileTaskRepo(s.cfg, s.cipher)
+	s.ErrorContains(err, "is corrupt before line 2")
+}
+
+func (s *fileSuite) TestCompact() {
+	task := s.create("task")
+	deleted := s.create("deleted")
+	s.Require().NoError(s.repo.Delete(s.ctx, deleted.ID.String()))
+	for _, title := range []string{"a", "b", "c"} {
+		task.Title = title
+		_, err := s.repo.Update(s.ctx, task)
+		s.Require().NoError(err)
+	}
+	s.Len(s.lines(), 6)
+
+	s.Require().NoError(s.repo.Compact())
+	s.Len(s.lines(), 2)
+
+	// appends after the compaction go to the new log
+	s.create("new")
+	s.Len(s.lines(), 3)
+
+	s.open()
+	tasks, err := s.repo.List(s.ctx, ListOptions{Sort: []SortKey{{Field: "title"}}})
+	s.Require().NoError(err)
+	s.Require().Len(tasks, 2)
+	s.Equal("c", tasks[0].Title)
+	s.Equal("new", tasks[1].Title)
+
+	created, err := s.repo.Create(s.ctx, deleted)
+	s.Require().NoError(err)
+	s.False(created)
+	_, err = s.repo.Get(s.ctx, deleted.ID.String())
+	s.ErrorIs(err, ErrNoRows)
+}
+
+func (s *fileSuite) TestCompactable() {
+	task := s.create("task")
+	s.create("other")
+	repo := s.repo.(*fileTaskRepo)
+
+	// two stale records are below the minimum of three
+	for range 2 {
+		_, err := s.repo.Update(s.ctx, task)
+		s.Require().NoError(err)
+	}
+	s.False(repo.compactable())
+
+	_, err := s.repo.Update(s.ctx, task)
+	s.Require().NoError(err)
+	s.True(repo.compactable())
+}
+
+func (s *fileSuite) TestRunCompacts() {
+	s.cfg.CompactInterval = time.Millisecond
+	s.open()
+
+	task := s.create("task")
+	for range 4 {
+		_, err := s.repo.Update(s.ctx, task)
+		s.Require().NoError(err)
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	done := make(chan struct{})
+	go func() {
+		s.repo.Run(ctx)
+		close(done)
+	}()
+
+	s.Eventually(func() bool { return len(s.lines()) == 1 }, time.Second, time.Millisecond)
+	cancel()
+	<-done
+}
+
+func (s *fileSuite) TestEncryptsDescription() {
+	keyring, err := encryption.NewKeyring("k1", map[string][]byte{"k1": []byte("0123456789abcdef0123456789abcdef")})
+	s.Require().NoError(err)
+	s.cipher = encryption.NewEnvelope(keyring)
+	s.open()
+
+	task := model.Task{ID: uuid.New(), Title: "task", Description: "customer data", CreatedAt: time.Now()}
+	_, err = s.repo.Create(s.ctx, task)
+	s.Require().NoError(err)
+	s.NotContains(s.lines()[0], "customer data")
+
+	s.open()
+	got, err := s.repo.Get(s.ctx, task.ID.String())
+	s.Require().NoError(err)
+	s.Equal("customer data", got.Description)
+}
+
+func (s *fileSuite) TestRejectsLogOfOtherKey() {
+	keyring, err := encryption.NewKeyring("k1", map[string][]byte{"k1": []byte("0123456789abcdef0123456789abcdef")})
+	s.Require().NoError(err)
+	s.cipher = encryption.NewEnvelope(keyring)
+	s.open()
+
+	_, err = s.repo.Create(s.ctx, model.Task{ID: uuid.New(), Title: "first", Description: "customer data", CreatedAt: time.Now()})
+	s.Require().NoError(err)
+	_, err = s.repo.Create(s.ctx, model.Task{ID: uuid.New(), Title: "second", Description: "more data", CreatedAt: time.Now()})
+	s.Require().NoError(err)
+	s.Require().NoError(s.repo.Close())
+
+	intact, err := os.ReadFile(s.cfg.Path)
+	s.Require().NoError(err)
+
+	// the keyring lacks the key the log was written with
+	other, err := encryption.NewKeyring("k2", map[string][]byte{"k2": []byte("abcdef0123456789abcdef0123456789")})
+	s.Require().NoError(err)
+
+	_, err = OpenFileTaskRepo(s.cfg, encryption.NewEnvelope(other))
+	s.ErrorContains(err, "failed to replay line 1")
+
+	data, err := os.ReadFile(s.cfg.Path)
+	s.Require().NoError(err)
+	s.Equal(intact, data)
+
+	s.open()
+}
+
+func (s *fileSuite) TestRejectsSeveralTornRecords() {
+	s.create("first")
+	s.Require().NoError(s.repo.Close())
+
+	intact, err := os.ReadFile(s.cfg.Path)
+	s.Require().NoError(err)
+
+	// a damaged record followed by a torn one is no crash at the end of the log
+	damaged := append(append([]byte{}, intact...), []byte("00000000 {}\n")...)
+	s.Require().NoError(os.WriteFile(s.cfg.Path, append(damaged, intact[:10]...), 0o600))
+
+	_, err = OpenFileTaskRepo(s.cfg, s.cipher)
+	s.ErrorContains(err, "is corrupt before line 3")
+
+	s.Require().NoError(os.WriteFile(s.cfg.Path, intact, 0o600))
+	s.open()
+}
+
+func (s *fileSuite) TestLocksLog() {
+	_, err := OpenFileTaskRepo(s.cfg, s.cipher)
+	s.ErrorIs(err, ErrFileLocked)
+
+	// the lock is released on close
+	s.open()
+}
+
+func (s *fileSuite) TestClosed() {
+	s.Require().NoError(s.repo.Close())
+
+	_, err := s.repo.Create(s.ctx, model.Task{ID: uuid.New(), Title: "task"})
+	s.ErrorIs(err, ErrFileClosed)
+	s.ErrorIs(s.repo.Compact(), ErrFileClosed)
+}
+
+func (s *fileSuite) TestUnknownSyncPolicy() {
+	_, err := OpenFileTaskRepo(FileConfig{Path: s.cfg.Path, Sync: "sometimes"}, s.cipher)
+	s.EqualError(err, `unknown file sync policy "sometimes"`)
+}
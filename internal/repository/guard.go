@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+
+	"go-tasks-api/internal/database"
+	"go-tasks-api/internal/model"
+)
+
+type guardedTaskRepo struct {
+	next  TaskConnector
+	guard *database.Guard
+}
+
+// NewGuardedTaskRepo wraps a Task repository so its calls run through the guard, which retries
+// transient failures and rejects calls while the database is down
+func NewGuardedTaskRepo(next TaskConnector, guard *database.Guard) TaskConnector {
+	return &guardedTaskRepo{
+		next:  next,
+		guard: guard,
+	}
+}
+
+func (a *guardedTaskRepo) Create(ctx context.Context, task model.Task) (bool, error) {
+	var created bool
+	err := a.guard.Write(ctx, func(ctx context.Context) error {
+		var err error
+		created, err = a.next.Create(ctx, task)
+
+		return err
+	})
+
+	return created, err
+}
+
+func (a *guardedTaskRepo) Get(ctx context.Context, id string) (model.Task, error) {
+	var task model.Task
+	err := a.guard.Read(ctx, func(ctx context.Context) error {
+		var err error
+		task, err = a.next.Get(ctx, id)
+
+		return err
+	})
+
+	return task, err
+}
+
+func (a *guardedTaskRepo) List(ctx context.Context, opts ListOptions) ([]model.Task, error) {
+	var tasks []model.Task
+	err := a.guard.Read(ctx, func(ctx context.Context) error {
+		var err error
+		tasks, err = a.next.List(ctx, opts)
+
+		return err
+	})
+
+	return tasks, err
+}
+
+// Stream is run again only while no task was handed to fn, the tasks of a second run would
+// repeat those of the first. The errors of fn, such as a client which went away, are returned
+// without going through the guard, they tell nothing about the database.
+func (a *guardedTaskRepo) Stream(ctx context.Context, opts ListOptions, fn func(model.Task) error) error {
+	var (
+		started bool
+		fnErr   error
+	)
+
+	err := a.guard.Read(ctx, func(ctx context.Context) error {
+		err := a.next.Stream(ctx, opts, func(task model.Task) error {
+			started = true
+			fnErr = fn(task)
+
+			return fnErr
+		})
+		if fnErr != nil {
+			return nil
+		}
+		if err != nil && started {
+			return database.Final(err)
+		}
+
+		return err
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+
+	return err
+}
+
+func (a *guardedTaskRepo) Update(ctx context.Context, task model.Task) (model.Task, error) {
+	var updated model.Task
+	err := a.guard.Write(ctx, func(ctx context.Context) error {
+		var err error
+		updated, err = a.next.Update(ctx, task)
+
+		return err
+	})
+
+	return updated, err
+}
+
+func (a *guardedTaskRepo) Delete(ctx context.Context, id string) error {
+	return a.guard.Write(ctx, func(ctx context.Context) error {
+		return a.next.Delete(ctx, id)
+	})
+}
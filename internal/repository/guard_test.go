@@ -0,0 +1,110 @@
+package repository_test
+
+import (
+	"context"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"go-tasks-api/internal/database"
+	"go-tasks-api/internal/model"
+	"go-tasks-api/internal/repository"
+	"go-tasks-api/internal/repository/mocks"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/mock/gomock"
+)
+
+type guardedSuite struct {
+	suite.Suite
+	next *mocks.MockTaskConnector
+	repo repository.TaskConnector
+	ctx  context.Context
+}
+
+func TestGuarded(t *testing.T) {
+	suite.Run(t, new(guardedSuite))
+}
+
+func (s *guardedSuite) SetupTest() {
+	s.next = mocks.NewMockTaskConnector(gomock.NewController(s.T()))
+	s.repo = repository.NewGuardedTaskRepo(s.next, database.NewGuard(database.RetryConfig{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+	}))
+	s.ctx = context.Background()
+}
+
+func (s *guardedSuite) TestRetriesInterruptedGet() {
+	task := model.Task{ID: uuid.New()}
+	gomock.InOrder(
+		s.next.EXPECT().Get(gomock.Any(), task.ID.String()).Return(model.Task{}, &pq.Error{Code: "57P01"}),
+		s.next.EXPECT().Get(gomock.Any(), task.ID.String()).Return(task, nil),
+	)
+
+	got, err := s.repo.Get(s.ctx, task.ID.String())
+	s.Require().NoError(err)
+	s.Equal(task, got)
+}
+
+func (s *guardedSuite) TestDoesNotRetryInterruptedUpdate() {
+	interrupted := &pq.Error{Code: "08006"}
+	s.next.EXPECT().Update(gomock.Any(), gomock.Any()).Return(model.Task{}, interrupted).Times(1)
+
+	_, err := s.repo.Update(s.ctx, model.Task{ID: uuid.New()})
+	s.ErrorIs(err, interrupted)
+}
+
+func (s *guardedSuite) TestRestartsStreamOnlyBeforeFirstTask() {
+	interrupted := &pq.Error{Code: "08006"}
+	stream := func(n int) func(context.Context, repository.ListOptions, func(model.Task) error) error {
+		return func(_ context.Context, _ repository.ListOptions, fn func(model.Task) error) error {
+			for range n {
+				if err := fn(model.Task{ID: uuid.New()}); err != nil {
+					return err
+				}
+			}
+
+			return interrupted
+		}
+	}
+	gomock.InOrder(
+		s.next.EXPECT().Stream(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(stream(0)),
+		s.next.EXPECT().Stream(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(stream(2)),
+	)
+
+	seen := 0
+	err := s.repo.Stream(s.ctx, repository.ListOptions{}, func(model.Task) error {
+		seen++
+
+		return nil
+	})
+	s.ErrorIs(err, interrupted)
+	s.Equal(2, seen)
+}
+
+func (s *guardedSuite) TestStreamCallbackErrorsKeepBreakerClosed() {
+	s.repo = repository.NewGuardedTaskRepo(s.next, database.NewGuard(database.RetryConfig{
+		MaxAttempts:      1,
+		BreakerThreshold: 1,
+		BreakerCooldown:  time.Hour,
+	}))
+	brokenPipe := &net.OpError{Op: "write", Net: "tcp", Err: syscall.EPIPE}
+	s.next.EXPECT().Stream(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ repository.ListOptions, fn func(model.Task) error) error {
+			return fn(model.Task{ID: uuid.New()})
+		})
+	s.next.EXPECT().Get(gomock.Any(), gomock.Any()).Return(model.Task{}, nil)
+
+	err := s.repo.Stream(s.ctx, repository.ListOptions{}, func(model.Task) error {
+		return brokenPipe
+	})
+	s.ErrorIs(err, brokenPipe)
+
+	// the breaker would reject the call had the client counted as a database failure
+	_, err = s.repo.Get(s.ctx, uuid.NewString())
+	s.NoError(err)
+}
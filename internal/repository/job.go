@@ -0,0 +1,276 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-tasks-api/internal/apperr"
+	"go-tasks-api/internal/database"
+	"go-tasks-api/internal/encryption"
+	"go-tasks-api/internal/model"
+
+	"github.com/google/uuid"
+)
+
+// ErrJobFinished is returned when cancelling a job which already reached a final status
+var ErrJobFinished = apperr.New(apperr.Conflict, "job already finished")
+
+type jobRepo struct {
+	db     *sql.DB
+	cipher encryption.Cipher
+}
+
+//go:generate go run -mod=mod go.uber.org/mock/mockgen -package mocks -destination=./mocks/job_mock.go -source=job.go
+type JobConnector interface {
+	Create(ctx context.Context, job model.Job) error
+	Get(ctx context.Context, id string) (model.Job, error)
+	// Claim leases the oldest queued job, or a running job whose lease expired because its
+	// worker stopped, and marks it as running. It returns ErrNoRows when no job is due.
+	Claim(ctx context.Context, lease time.Duration) (model.Job, error)
+	// Heartbeat stores the progress of the claimed job and extends its lease, it reports whether
+	// the cancellation of the job was requested. The attempt of the job fences off workers whose
+	// lease expired, they get ErrNoRows like workers of jobs which are no longer running.
+	Heartbeat(ctx context.Context, job model.Job, lease time.Duration) (bool, error)
+	// Release puts the claimed job back in the queue without counting the attempt
+	Release(ctx context.Context, job model.Job) error
+	// Complete stores the final status of the claimed job, it returns ErrNoRows when the
+	// attempt lost its lease
+	Complete(ctx context.Context, outcome model.JobOutcome) error
+	// Cancel cancels a queued job and requests the cancellation of a running job, it returns
+	// ErrJobFinished for finished jobs
+	Cancel(ctx context.Context, id string) (model.Job, error)
+	GetResult(ctx context.Context, id string) (model.JobResult, error)
+}
+
+// NewJobRepo creates a new Job repository, params and results are encrypted with the given cipher
+// as they hold task contents
+func NewJobRepo(db *sql.DB, cipher encryption.Cipher) JobConnector {
+	return &jobRepo{
+		db:     db,
+		cipher: cipher,
+	}
+}
+
+func (a *jobRepo) Create(ctx context.Context, job model.Job) error {
+	insertSQL := `INSERT INTO tasks.jobs (id, type, params, status, created_at) values ($1, $2, $3, $4, $5);`
+
+	params, err := a.cipher.Encrypt(string(job.Params), jobData("params", job.ID))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt job params: %w", err)
+	}
+
+	if _, err := database.Conn(ctx, a.db).ExecContext(ctx, insertSQL, job.ID.String(), job.Type, params, job.Status, job.CreatedAt); err != nil {
+		return fmt.Errorf("failed to insert job: %w", err)
+	}
+
+	return nil
+}
+
+func (a *jobRepo) Get(ctx context.Context, id string) (model.Job, error) {
+	getSQL := `SELECT id, type, params, status, progress, attempts, cancel_requested, error, created_at, started_at, finished_at FROM tasks.jobs WHERE id = $1;`
+
+	job, err := a.scan(database.Conn(ctx, a.db).QueryRowContext(ctx, getSQL, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Job{}, ErrNoRows
+		}
+
+		return model.Job{}, fmt.Errorf("failed to get job: %w", err)
+	}
+
+	return job, nil
+}
+
+func (a *jobRepo) Claim(ctx context.Context, lease time.Duration) (model.Job, error) {
+	// moving locked_until forward acts as the lease, the jobs of a crashed worker are claimed
+	// again once it expires
+	claimSQL := `
+		WITH next AS (
+			SELECT id
+			FROM tasks.jobs
+			WHERE status = 'queued'
+			   OR (status = 'running' AND locked_until < CURRENT_TIMESTAMP)
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE tasks.jobs j
+		SET status = 'running',
+		    attempts = j.attempts + 1,
+		    locked_until = CURRENT_TIMESTAMP + make_interval(secs => $1),
+		    started_at = COALESCE(j.started_at, CURRENT_TIMESTAMP)
+		FROM next
+		WHERE j.id = next.id
+		RETURNING j.id, j.type, j.params, j.status, j.progress, j.attempts, j.cancel_requested, j.error, j.created_at, j.started_at, j.finished_at;
+	`
+
+	job, err := a.scan(database.Conn(ctx, a.db).QueryRowContext(ctx, claimSQL, lease.Seconds()))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Job{}, ErrNoRows
+		}
+
+		return model.Job{}, fmt.Errorf("failed to claim job: %w", err)
+	}
+
+	return job, nil
+}
+
+func (a *jobRepo) Heartbeat(ctx context.Context, job model.Job, lease time.Duration) (bool, error) {
+	heartbeatSQL := `
+		UPDATE tasks.jobs
+		SET progress = $3,
+		    locked_until = CURRENT_TIMESTAMP + make_interval(secs => $4)
+		WHERE id = $1 AND attempts = $2 AND status = 'running'
+		RETURNING cancel_requested;
+	`
+
+	var cancelRequested bool
+	if err := database.Conn(ctx, a.db).QueryRowContext(ctx, heartbeatSQL, job.ID.String(), job.Attempts, job.Progress, lease.Seconds()).Scan(&cancelRequested); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, ErrNoRows
+		}
+
+		return false, fmt.Errorf("failed to store job heartbeat: %w", err)
+	}
+
+	return cancelRequested, nil
+}
+
+func (a *jobRepo) Release(ctx context.Context, job model.Job) error {
+	releaseSQL := `UPDATE tasks.jobs SET status = 'queued', attempts = attempts - 1, progress = $3, locked_until = NULL WHERE id = $1 AND attempts = $2 AND status = 'running';`
+
+	if _, err := database.Conn(ctx, a.db).ExecContext(ctx, releaseSQL, job.ID.String(), job.Attempts, job.Progress); err != nil {
+		return fmt.Errorf("failed to release job: %w", err)
+	}
+
+	return nil
+}
+
+func (a *jobRepo) Complete(ctx context.Context, outcome model.JobOutcome) error {
+	completeSQL := `
+		UPDATE tasks.jobs
+		SET status = $3,
+		    error = $4,
+		    result = $5,
+		    result_type = $6,
+		    progress = CASE WHEN $3 = 'succeeded' THEN 100 ELSE progress END,
+		    locked_until = NULL,
+		    finished_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND attempts = $2 AND status = 'running';
+	`
+
+	var result, resultType any
+	if outcome.Result != nil {
+		encrypted, err := a.cipher.Encrypt(string(outcome.Result.Data), jobData("result", outcome.JobID))
+		if err != nil {
+			return fmt.Errorf("failed to encrypt job result: %w", err)
+		}
+		result, resultType = encrypted, outcome.Result.ContentType
+	}
+
+	res, err := database.Conn(ctx, a.db).ExecContext(ctx, completeSQL, outcome.JobID.String(), outcome.Attempt, outcome.Status, outcome.Error, result, resultType)
+	if err != nil {
+		return fmt.Errorf("failed to complete job: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if rows == 0 {
+		return ErrNoRows
+	}
+
+	return nil
+}
+
+func (a *jobRepo) Cancel(ctx context.Context, id string) (model.Job, error) {
+	// queued jobs are cancelled right away, running jobs are cancelled by their worker on its next heartbeat
+	cancelSQL := `
+		UPDATE tasks.jobs
+		SET status = CASE WHEN status = 'queued' THEN 'cancelled' ELSE status END,
+		    cancel_requested = true,
+		    finished_at = CASE WHEN status = 'queued' THEN CURRENT_TIMESTAMP ELSE finished_at END
+		WHERE id = $1 AND status IN ('queued', 'running')
+		RETURNING id, type, params, status, progress, attempts, cancel_requested, error, created_at, started_at, finished_at;
+	`
+
+	job, err := a.scan(database.Conn(ctx, a.db).QueryRowContext(ctx, cancelSQL, id))
+	if err == nil {
+		return job, nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return model.Job{}, fmt.Errorf("failed to cancel job: %w", err)
+	}
+
+	// tell finished jobs apart from unknown ones
+	if _, err := a.Get(ctx, id); err != nil {
+		return model.Job{}, err
+	}
+
+	return model.Job{}, ErrJobFinished
+}
+
+func (a *jobRepo) GetResult(ctx context.Context, id string) (model.JobResult, error) {
+	resultSQL := `SELECT id, result, result_type FROM tasks.jobs WHERE id = $1 AND status = 'succeeded' AND result IS NOT NULL;`
+
+	var (
+		jobID             uuid.UUID
+		data, contentType string
+	)
+	if err := database.Conn(ctx, a.db).QueryRowContext(ctx, resultSQL, id).Scan(&jobID, &data, &contentType); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.JobResult{}, ErrNoRows
+		}
+
+		return model.JobResult{}, fmt.Errorf("failed to get job result: %w", err)
+	}
+
+	decrypted, err := a.cipher.Decrypt(data, jobData("result", jobID))
+	if err != nil {
+		return model.JobResult{}, fmt.Errorf("failed to decrypt job result: %w", err)
+	}
+
+	return model.JobResult{ContentType: contentType, Data: []byte(decrypted)}, nil
+}
+
+func (a *jobRepo) scan(row scanner) (model.Job, error) {
+	var (
+		job    model.Job
+		params string
+	)
+	if err := row.Scan(
+		&job.ID,
+		&job.Type,
+		&params,
+		&job.Status,
+		&job.Progress,
+		&job.Attempts,
+		&job.CancelRequested,
+		&job.Error,
+		&job.CreatedAt,
+		&job.StartedAt,
+		&job.FinishedAt,
+	); err != nil {
+		return model.Job{}, err
+	}
+
+	decrypted, err := a.cipher.Decrypt(params, jobData("params", job.ID))
+	if err != nil {
+		return model.Job{}, fmt.Errorf("failed to decrypt job params: %w", err)
+	}
+	job.Params = []byte(decrypted)
+
+	return job, nil
+}
+
+// jobData returns the additional data the column of the job is encrypted with
+func jobData(column string, id uuid.UUID) string {
+	return encryption.AdditionalData("tasks.jobs", column, id.String())
+}
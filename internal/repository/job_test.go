@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"go-tasks-api/internal/encryption"
+	"go-tasks-api/internal/enum"
+	"go-tasks-api/internal/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+var jobColumns = []string{"id", "type", "params", "status", "progress", "attempts", "cancel_requested", "error", "created_at", "started_at", "finished_at"}
+
+type jobSuite struct {
+	suite.Suite
+	repo JobConnector
+	db   sqlmock.Sqlmock
+}
+
+func TestJob(t *testing.T) {
+	suite.Run(t, new(jobSuite))
+}
+
+func (s *jobSuite) SetupTest() {
+	db, mock, err := sqlmock.New()
+	require.NoError(s.T(), err)
+
+	s.repo = NewJobRepo(db, encryption.NoopCipher{})
+	s.db = mock
+}
+
+func (s *jobSuite) TearDownTest() {
+	s.NoError(s.db.ExpectationsWereMet())
+}
+
+func (s *jobSuite) TestClaimTakesOverExpiredLeases() {
+	id := uuid.New()
+	now := time.Now()
+	s.db.ExpectQuery(regexp.QuoteMeta(`OR (status = 'running' AND locked_until < CURRENT_TIMESTAMP)`)).
+		WithArgs(float64(60)).
+		WillReturnRows(sqlmock.NewRows(jobColumns).
+			AddRow(id, "tasks.export", `{"format":"csv"}`, "running", 40, 2, false, nil, now, now, nil))
+
+	job, err := s.repo.Claim(context.Background(), time.Minute)
+	s.NoError(err)
+	s.Equal(id, job.ID)
+	s.Equal(enum.JobStatus_Running, job.Status)
+	s.Equal(2, job.Attempts)
+	s.JSONEq(`{"format":"csv"}`, string(job.Params))
+}
+
+func (s *jobSuite) TestClaimNothingQueued() {
+	s.db.ExpectQuery(regexp.QuoteMeta(`FOR UPDATE SKIP LOCKED`)).
+		WillReturnError(sql.ErrNoRows)
+
+	_, err := s.repo.Claim(context.Background(), time.Minute)
+	s.ErrorIs(err, ErrNoRows)
+}
+
+func (s *jobSuite) TestHeartbeatReportsCancellation() {
+	job := model.Job{ID: uuid.New(), Attempts: 1, Progress: 30}
+	s.db.ExpectQuery(regexp.QuoteMeta(`WHERE id = $1 AND attempts = $2 AND status = 'running'`)).
+		WithArgs(job.ID.String(), 1, 30, float64(60)).
+		WillReturnRows(sqlmock.NewRows([]string{"cancel_requested"}).AddRow(true))
+
+	cancelRequested, err := s.repo.Heartbeat(context.Background(), job, time.Minute)
+	s.NoError(err)
+	s.True(cancelRequested)
+}
+
+func (s *jobSuite) TestCompleteLostLease() {
+	outcome := model.JobOutcome{JobID: uuid.New(), Attempt: 1, Status: enum.JobStatus_Succeeded, Result: &model.JobResult{ContentType: "text/csv", Data: []byte("id\n")}}
+	s.db.ExpectExec(regexp.QuoteMeta(`UPDATE tasks.jobs`)).
+		WithArgs(outcome.JobID.String(), 1, enum.JobStatus_Succeeded, nil, "id\n", "text/csv").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	s.ErrorIs(s.repo.Complete(context.Background(), outcome), ErrNoRows)
+}
+
+func (s *jobSuite) TestCancelFinishedJob() {
+	id := uuid.New()
+	s.db.ExpectQuery(regexp.QuoteMeta(`WHERE id = $1 AND status IN ('queued', 'running')`)).
+		WithArgs(id.String()).
+		WillReturnError(sql.ErrNoRows)
+	s.db.ExpectQuery(regexp.QuoteMeta(`FROM tasks.jobs WHERE id = $1;`)).
+		WithArgs(id.String()).
+		WillReturnRows(sqlmock.NewRows(jobColumns).
+			AddRow(id, "tasks.export", `{}`, "succeeded", 100, 1, false, nil, time.Now(), nil, nil))
+
+	_, err := s.repo.Cancel(context.Background(), id.String())
+	s.ErrorIs(err, ErrJobFinished)
+}
+
+func (s *jobSuite) TestCancelUnknownJob() {
+	s.db.ExpectQuery(regexp.QuoteMeta(`WHERE id = $1 AND status IN ('queued', 'running')`)).
+		WillReturnError(sql.ErrNoRows)
+	s.db.ExpectQuery(regexp.QuoteMeta(`FROM tasks.jobs WHERE id = $1;`)).
+		WillReturnError(sql.ErrNoRows)
+
+	_, err := s.repo.Cancel(context.Background(), "id")
+	s.ErrorIs(err, ErrNoRows)
+}
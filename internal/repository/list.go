@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"go-tasks-api/internal/filter"
+	"go-tasks-api/internal/model"
+	"go-tasks-api/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// TaskSortFields maps the fields tasks can be sorted by to their columns
+var TaskSortFields = map[string]string{
+	"title":      "title",
+	"status":     "status",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+// SortKey orders tasks by a field
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// ListOptions narrows, orders and projects the tasks returned by List and Stream
+type ListOptions struct {
+	// Filter selects the tasks, nil selects all of them
+	Filter filter.Expr
+	// Sort orders the tasks, ties are ordered by creation
+	Sort []SortKey
+	// Fields are the fields read from the database, empty reads all of them
+	Fields []string
+}
+
+// NewListOptions parses a list definition. Errors name the offending member, filter, sort or
+// fields, callers locate them with a pointer.
+func NewListOptions(def model.ViewDefinition) (ListOptions, []utils.FieldError) {
+	var (
+		opts ListOptions
+		err  error
+	)
+	vErr := make([]utils.FieldError, 0)
+
+	if opts.Filter, err = filter.Parse(def.Filter, TaskFilterFields); err != nil {
+		vErr = append(vErr, utils.FieldError{Field: "filter", Message: err.Error()})
+	}
+
+	for _, spec := range def.Sort {
+		field, desc := strings.CutPrefix(strings.TrimSpace(spec), "-")
+		if _, ok := TaskSortFields[field]; !ok {
+			vErr = append(vErr, utils.FieldError{
+				Field:   "sort",
+				Message: fmt.Sprintf("cannot sort by %q, sortable fields are created_at, status, title, updated_at", field),
+			})
+
+			continue
+		}
+		opts.Sort = append(opts.Sort, SortKey{Field: field, Desc: desc})
+	}
+
+	for _, field := range def.Fields {
+		field = strings.TrimSpace(field)
+		if !slices.Contains(model.TaskFields, field) {
+			vErr = append(vErr, utils.FieldError{
+				Field:   "fields",
+				Message: fmt.Sprintf("unknown field %q, fields are %s", field, strings.Join(model.TaskFields, ", ")),
+			})
+
+			continue
+		}
+		if !slices.Contains(opts.Fields, field) {
+			opts.Fields = append(opts.Fields, field)
+		}
+	}
+
+	return opts, vErr
+}
+
+// taskColumn is a column of tasks.tasks read by List
+type taskColumn struct {
+	field  string
+	column string
+	dest   func(task *model.Task) any
+	// clear resets the field of tasks read without the column
+	clear func(task *model.Task)
+}
+
+// taskColumns are in the order of the select list when all fields are read
+var taskColumns = []taskColumn{
+	{"id", "id", func(t *model.Task) any { return &t.ID }, func(t *model.Task) { t.ID = uuid.UUID{} }},
+	{"title", "title", func(t *model.Task) any { return &t.Title }, func(t *model.Task) { t.Title = "" }},
+	{"description", "description", func(t *model.Task) any { return &t.Description }, func(t *model.Task) { t.Description = "" }},
+	{"status", "status", func(t *model.Task) any { return &t.Status }, func(t *model.Task) { t.Status = 0 }},
+	{"created_at", "created_at", func(t *model.Task) any { return &t.CreatedAt }, func(t *model.Task) { t.CreatedAt = time.Time{} }},
+	{"updated_at", "updated_at", func(t *model.Task) any { return &t.UpdatedAt }, func(t *model.Task) { t.UpdatedAt = nil }},
+}
+
+// columns returns the columns read for the options, the ID is read along with the description
+// as it is encrypted with the ID
+func (o ListOptions) columns() []taskColumn {
+	if len(o.Fields) == 0 {
+		return taskColumns
+	}
+
+	columns := make([]taskColumn, 0, len(o.Fields)+1)
+	for _, c := range taskColumns {
+		if slices.Contains(o.Fields, c.field) || c.field == "id" && slices.Contains(o.Fields, "description") {
+			columns = append(columns, c)
+		}
+	}
+
+	return columns
+}
+
+// project returns the task with only the fields read for the options, as List returns it
+func (o ListOptions) project(task model.Task) model.Task {
+	for _, c := range taskColumns {
+		if !o.selects(c.field) {
+			c.clear(&task)
+		}
+	}
+
+	return task
+}
+
+// selects reports whether the field is read
+func (o ListOptions) selects(field string) bool {
+	return len(o.Fields) == 0 || slices.Contains(o.Fields, field)
+}
+
+// orderBy returns the ORDER BY clause, creation and ID break ties so the order is stable
+func (o ListOptions) orderBy() (string, error) {
+	keys := make([]string, 0, len(o.Sort)+2)
+	for _, key := range o.Sort {
+		column, ok := TaskSortFields[key.Field]
+		if !ok {
+			return "", fmt.Errorf("unknown sort field %q", key.Field)
+		}
+
+		if key.Desc {
+			column += " DESC"
+		}
+		keys = append(keys, column)
+	}
+
+	return "ORDER BY " + strings.Join(append(keys, "created_at", "id"), ", "), nil
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"go-tasks-api/internal/encryption"
+	"go-tasks-api/internal/filter"
+	"go-tasks-api/internal/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewListOptions(t *testing.T) {
+	opts, vErr := NewListOptions(model.ViewDefinition{
+		Filter: "status eq 'todo'",
+		Sort:   []string{"-created_at", " title"},
+		Fields: []string{"id", "title", "id"},
+	})
+	require.Empty(t, vErr)
+	require.Equal(t, ListOptions{
+		Filter: filter.Comparison{Field: "status", Op: filter.OpEq, Value: "todo"},
+		Sort:   []SortKey{{Field: "created_at", Desc: true}, {Field: "title"}},
+		Fields: []string{"id", "title"},
+	}, opts)
+}
+
+func TestNewListOptionsReportsEveryMember(t *testing.T) {
+	_, vErr := NewListOptions(model.ViewDefinition{
+		Filter: "description eq 'x'",
+		Sort:   []string{"description"},
+		Fields: []string{"owner"},
+	})
+	require.Len(t, vErr, 3)
+	require.Equal(t, "filter", vErr[0].Field)
+	require.Equal(t, "sort", vErr[1].Field)
+	require.Equal(t, "fields", vErr[2].Field)
+}
+
+// TestListTasksRejectsCopiedDescription reads a description copied from another task, which
+// is bound to the ID of that task, the ID is read for it although only the description is listed
+func TestListTasksRejectsCopiedDescription(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	keyring, err := encryption.NewKeyring("k1", map[string][]byte{"k1": []byte("0123456789abcdef0123456789abcdef")})
+	require.NoError(t, err)
+	cipher := encryption.NewEnvelope(keyring)
+
+	other, copied := uuid.New(), uuid.New()
+	encrypted, err := cipher.Encrypt("customer data", descriptionData(other))
+	require.NoError(t, err)
+
+	listSQL := regexp.QuoteMeta(`SELECT id, description FROM tasks.tasks WHERE is_active = true ORDER BY created_at, id;`)
+	mock.ExpectQuery(listSQL).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "description"}).AddRow(other.String(), encrypted))
+	mock.ExpectQuery(listSQL).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "description"}).AddRow(copied.String(), encrypted))
+
+	repo := NewTaskRepo(db, cipher, nil)
+	got, err := repo.List(context.Background(), ListOptions{Fields: []string{"description"}})
+	require.NoError(t, err)
+	require.Equal(t, []model.Task{{Description: "customer data"}}, got)
+
+	_, err = repo.List(context.Background(), ListOptions{Fields: []string{"description"}})
+	require.ErrorContains(t, err, "failed to decrypt task description")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func (s *taskSuite) TestListTasksSortedAndProjected() {
+	ctx := context.Background()
+	id := uuid.New()
+
+	s.db.ExpectQuery(regexp.QuoteMeta(`SELECT id, title FROM tasks.tasks WHERE is_active = true ORDER BY status DESC, title, created_at, id;`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title"}).AddRow(id, "title"))
+
+	got, err := s.repo.List(ctx, ListOptions{
+		Sort:   []SortKey{{Field: "status", Desc: true}, {Field: "title"}},
+		Fields: []string{"title", "id"},
+	})
+	s.NoError(err)
+	s.Equal([]model.Task{{ID: id, Title: "title"}}, got)
+}
@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package repository
+
+import "os"
+
+// tryLock does nothing where files cannot be locked, the task log must not be shared there
+func tryLock(*os.File) error {
+	return nil
+}
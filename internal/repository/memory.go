@@ -0,0 +1,341 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go-tasks-api/internal/apperr"
+	"go-tasks-api/internal/enum"
+	"go-tasks-api/internal/filter"
+	"go-tasks-api/internal/model"
+
+	"github.com/google/uuid"
+)
+
+// memoryTask is a stored task, deleted tasks are kept inactive like in tasks.tasks
+type memoryTask struct {
+	task   model.Task
+	active bool
+}
+
+type memoryTaskRepo struct {
+	mu    sync.RWMutex
+	tasks map[uuid.UUID]*memoryTask
+}
+
+// NewMemoryTaskRepo creates a Task repository holding tasks in memory. It behaves like the
+// Postgres repository, except that no events are published and strings sort by bytes.
+func NewMemoryTaskRepo() TaskConnector {
+	return newMemoryTaskRepo()
+}
+
+func newMemoryTaskRepo() *memoryTaskRepo {
+	return &memoryTaskRepo{
+		tasks: make(map[uuid.UUID]*memoryTask),
+	}
+}
+
+func (a *memoryTaskRepo) Create(_ context.Context, task model.Task) (bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// an existing ID is left unchanged like the ON CONFLICT DO NOTHING insert
+	if _, ok := a.tasks[task.ID]; ok {
+		return false, nil
+	}
+
+	a.tasks[task.ID] = &memoryTask{task: createdTask(task), active: true}
+
+	return true, nil
+}
+
+func (a *memoryTaskRepo) Get(_ context.Context, id string) (model.Task, error) {
+	key, err := parseTaskID(id)
+	if err != nil {
+		return model.Task{}, err
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	stored, ok := a.tasks[key]
+	if !ok || !stored.active {
+		return model.Task{}, ErrNoRows
+	}
+
+	return copyTask(stored.task), nil
+}
+
+func (a *memoryTaskRepo) List(ctx context.Context, opts ListOptions) ([]model.Task, error) {
+	tasks := make([]model.Task, 0)
+	if err := a.Stream(ctx, opts, func(task model.Task) error {
+		tasks = append(tasks, task)
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+func (a *memoryTaskRepo) Stream(ctx context.Context, opts ListOptions, fn func(model.Task) error) error {
+	// fn runs without the lock, so it may call the repository
+	tasks, err := a.matching(opts)
+	if err != nil {
+		return err
+	}
+
+	if err := sortTasks(tasks, opts.Sort); err != nil {
+		return err
+	}
+
+	for _, task := range tasks {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("failed to list tasks: %w", err)
+		}
+
+		if err := fn(opts.project(task)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// matching returns copies of the active tasks matching the filter of the options
+func (a *memoryTaskRepo) matching(opts ListOptions) ([]model.Task, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	tasks := make([]model.Task, 0, len(a.tasks))
+	for _, stored := range a.tasks {
+		if !stored.active {
+			continue
+		}
+
+		if opts.Filter != nil {
+			ok, err := filter.Eval(opts.Filter, TaskFilterFields, taskRow(stored.task))
+			if err != nil {
+				return nil, fmt.Errorf("failed to evaluate filter: %w", err)
+			}
+
+			if !ok {
+				continue
+			}
+		}
+
+		tasks = append(tasks, copyTask(stored.task))
+	}
+
+	return tasks, nil
+}
+
+func (a *memoryTaskRepo) Update(_ context.Context, task model.Task) (model.Task, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	stored, ok := a.tasks[task.ID]
+	if !ok || !stored.active {
+		return model.Task{}, ErrNoRows
+	}
+
+	stored.task = updatedTask(stored.task, task)
+
+	return copyTask(stored.task), nil
+}
+
+func (a *memoryTaskRepo) Delete(_ context.Context, id string) error {
+	key, err := parseTaskID(id)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	stored, ok := a.tasks[key]
+	if !ok || !stored.active {
+		return ErrNoRows
+	}
+	stored.active = false
+
+	return nil
+}
+
+// lookup returns a copy of the stored task, deleted tasks included
+func (a *memoryTaskRepo) lookup(id uuid.UUID) (memoryTask, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	stored, ok := a.tasks[id]
+	if !ok {
+		return memoryTask{}, false
+	}
+
+	return memoryTask{task: copyTask(stored.task), active: stored.active}, true
+}
+
+// put stores the task as it is, replacing the task of the same ID
+func (a *memoryTaskRepo) put(stored memoryTask) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.tasks[stored.task.ID] = &memoryTask{task: copyTask(stored.task), active: stored.active}
+}
+
+// all returns copies of the stored tasks, deleted tasks included
+func (a *memoryTaskRepo) all() []memoryTask {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	tasks := make([]memoryTask, 0, len(a.tasks))
+	for _, stored := range a.tasks {
+		tasks = append(tasks, memoryTask{task: copyTask(stored.task), active: stored.active})
+	}
+
+	return tasks
+}
+
+// size returns the number of stored tasks, deleted tasks included
+func (a *memoryTaskRepo) size() int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return len(a.tasks)
+}
+
+// createdTask returns the task as Create stores it: only the inserted columns are taken, the
+// others get their column defaults
+func createdTask(task model.Task) model.Task {
+	return model.Task{
+		ID:          task.ID,
+		Title:       task.Title,
+		Description: task.Description,
+		Status:      enum.Status_Todo,
+		CreatedAt:   timestamp(task.CreatedAt),
+	}
+}
+
+// updatedTask returns the stored task with the columns set by Update taken from the change
+func updatedTask(stored, change model.Task) model.Task {
+	stored.Title = change.Title
+	stored.Description = change.Description
+	stored.Status = change.Status
+	stored.UpdatedAt = nil
+	if change.UpdatedAt != nil {
+		updatedAt := timestamp(*change.UpdatedAt)
+		stored.UpdatedAt = &updatedAt
+	}
+
+	return stored
+}
+
+// parseTaskID rejects malformed IDs like the uuid column does
+func parseTaskID(id string) (uuid.UUID, error) {
+	key, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.UUID{}, apperr.Wrap(apperr.Validation, "invalid task id", err)
+	}
+
+	return key, nil
+}
+
+// timestamp rounds to the microsecond precision of TIMESTAMP columns
+func timestamp(t time.Time) time.Time {
+	return t.Round(time.Microsecond)
+}
+
+// copyTask returns a task sharing no memory with the stored one
+func copyTask(task model.Task) model.Task {
+	if task.UpdatedAt != nil {
+		updatedAt := *task.UpdatedAt
+		task.UpdatedAt = &updatedAt
+	}
+
+	return task
+}
+
+// taskRow exposes the filterable fields of a task to filter.Eval
+func taskRow(task model.Task) filter.Row {
+	return func(field string) any {
+		switch field {
+		case "id":
+			return task.ID.String()
+		case "title":
+			return task.Title
+		case "status":
+			return task.Status.String()
+		case "created_at":
+			return task.CreatedAt
+		case "updated_at":
+			if task.UpdatedAt == nil {
+				return nil
+			}
+
+			return *task.UpdatedAt
+		default:
+			return nil
+		}
+	}
+}
+
+// sortTasks orders tasks like the ORDER BY clause of ListOptions: by the sort keys, then by
+// creation and ID. Nulls are larger than any value, as in Postgres.
+func sortTasks(tasks []model.Task, keys []SortKey) error {
+	for _, key := range keys {
+		if _, ok := TaskSortFields[key.Field]; !ok {
+			return fmt.Errorf("unknown sort field %q", key.Field)
+		}
+	}
+
+	slices.SortFunc(tasks, func(x, y model.Task) int {
+		for _, key := range keys {
+			c := compareTaskField(key.Field, x, y)
+			if key.Desc {
+				c = -c
+			}
+
+			if c != 0 {
+				return c
+			}
+		}
+
+		if c := x.CreatedAt.Compare(y.CreatedAt); c != 0 {
+			return c
+		}
+
+		return bytes.Compare(x.ID[:], y.ID[:])
+	})
+
+	return nil
+}
+
+func compareTaskField(field string, x, y model.Task) int {
+	switch field {
+	case "title":
+		return strings.Compare(x.Title, y.Title)
+	case "status":
+		return strings.Compare(x.Status.String(), y.Status.String())
+	case "created_at":
+		return x.CreatedAt.Compare(y.CreatedAt)
+	case "updated_at":
+		switch {
+		case x.UpdatedAt == nil && y.UpdatedAt == nil:
+			return 0
+		case x.UpdatedAt == nil:
+			return 1
+		case y.UpdatedAt == nil:
+			return -1
+		default:
+			return x.UpdatedAt.Compare(*y.UpdatedAt)
+		}
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,170 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: cache.go
+//
+// Generated by this command:
+//
+//	mockgen -package mocks -destination=./mocks/cache_mock.go -source=cache.go
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	model "go-tasks-api/internal/model"
+	repository "go-tasks-api/internal/repository"
+	reflect "reflect"
+
+	uuid "github.com/google/uuid"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockCachedTaskConnector is a mock of CachedTaskConnector interface.
+type MockCachedTaskConnector struct {
+	ctrl     *gomock.Controller
+	recorder *MockCachedTaskConnectorMockRecorder
+	isgomock struct{}
+}
+
+// MockCachedTaskConnectorMockRecorder is the mock recorder for MockCachedTaskConnector.
+type MockCachedTaskConnectorMockRecorder struct {
+	mock *MockCachedTaskConnector
+}
+
+// NewMockCachedTaskConnector creates a new mock instance.
+func NewMockCachedTaskConnector(ctrl *gomock.Controller) *MockCachedTaskConnector {
+	mock := &MockCachedTaskConnector{ctrl: ctrl}
+	mock.recorder = &MockCachedTaskConnectorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCachedTaskConnector) EXPECT() *MockCachedTaskConnectorMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockCachedTaskConnector) Create(ctx context.Context, a model.Task) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, a)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockCachedTaskConnectorMockRecorder) Create(ctx, a any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockCachedTaskConnector)(nil).Create), ctx, a)
+}
+
+// Delete mocks base method.
+func (m *MockCachedTaskConnector) Delete(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockCachedTaskConnectorMockRecorder) Delete(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockCachedTaskConnector)(nil).Delete), ctx, id)
+}
+
+// Forget mocks base method.
+func (m *MockCachedTaskConnector) Forget(id uuid.UUID) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Forget", id)
+}
+
+// Forget indicates an expected call of Forget.
+func (mr *MockCachedTaskConnectorMockRecorder) Forget(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Forget", reflect.TypeOf((*MockCachedTaskConnector)(nil).Forget), id)
+}
+
+// Get mocks base method.
+func (m *MockCachedTaskConnector) Get(ctx context.Context, id string) (model.Task, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, id)
+	ret0, _ := ret[0].(model.Task)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockCachedTaskConnectorMockRecorder) Get(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockCachedTaskConnector)(nil).Get), ctx, id)
+}
+
+// List mocks base method.
+func (m *MockCachedTaskConnector) List(ctx context.Context, opts repository.ListOptions) ([]model.Task, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, opts)
+	ret0, _ := ret[0].([]model.Task)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockCachedTaskConnectorMockRecorder) List(ctx, opts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockCachedTaskConnector)(nil).List), ctx, opts)
+}
+
+// Purge mocks base method.
+func (m *MockCachedTaskConnector) Purge() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Purge")
+}
+
+// Purge indicates an expected call of Purge.
+func (mr *MockCachedTaskConnectorMockRecorder) Purge() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockCachedTaskConnector)(nil).Purge))
+}
+
+// Stats mocks base method.
+func (m *MockCachedTaskConnector) Stats() repository.CacheStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stats")
+	ret0, _ := ret[0].(repository.CacheStats)
+	return ret0
+}
+
+// Stats indicates an expected call of Stats.
+func (mr *MockCachedTaskConnectorMockRecorder) Stats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockCachedTaskConnector)(nil).Stats))
+}
+
+// Stream mocks base method.
+func (m *MockCachedTaskConnector) Stream(ctx context.Context, opts repository.ListOptions, fn func(model.Task) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stream", ctx, opts, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Stream indicates an expected call of Stream.
+func (mr *MockCachedTaskConnectorMockRecorder) Stream(ctx, opts, fn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stream", reflect.TypeOf((*MockCachedTaskConnector)(nil).Stream), ctx, opts, fn)
+}
+
+// Update mocks base method.
+func (m *MockCachedTaskConnector) Update(ctx context.Context, task model.Task) (model.Task, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, task)
+	ret0, _ := ret[0].(model.Task)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockCachedTaskConnectorMockRecorder) Update(ctx, task any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockCachedTaskConnector)(nil).Update), ctx, task)
+}
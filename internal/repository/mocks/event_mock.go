@@ -0,0 +1,102 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: event.go
+//
+// Generated by this command:
+//
+//	mockgen -package mocks -destination=./mocks/event_mock.go -source=event.go
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	model "go-tasks-api/internal/model"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockEventConnector is a mock of EventConnector interface.
+type MockEventConnector struct {
+	ctrl     *gomock.Controller
+	recorder *MockEventConnectorMockRecorder
+	isgomock struct{}
+}
+
+// MockEventConnectorMockRecorder is the mock recorder for MockEventConnector.
+type MockEventConnectorMockRecorder struct {
+	mock *MockEventConnector
+}
+
+// NewMockEventConnector creates a new mock instance.
+func NewMockEventConnector(ctrl *gomock.Controller) *MockEventConnector {
+	mock := &MockEventConnector{ctrl: ctrl}
+	mock.recorder = &MockEventConnectorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockEventConnector) EXPECT() *MockEventConnectorMockRecorder {
+	return m.recorder
+}
+
+// Append mocks base method.
+func (m *MockEventConnector) Append(ctx context.Context, event model.Event) (model.Event, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Append", ctx, event)
+	ret0, _ := ret[0].(model.Event)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Append indicates an expected call of Append.
+func (mr *MockEventConnectorMockRecorder) Append(ctx, event any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Append", reflect.TypeOf((*MockEventConnector)(nil).Append), ctx, event)
+}
+
+// Get mocks base method.
+func (m *MockEventConnector) Get(ctx context.Context, id int64) (model.Event, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, id)
+	ret0, _ := ret[0].(model.Event)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockEventConnectorMockRecorder) Get(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockEventConnector)(nil).Get), ctx, id)
+}
+
+// LastID mocks base method.
+func (m *MockEventConnector) LastID(ctx context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LastID", ctx)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LastID indicates an expected call of LastID.
+func (mr *MockEventConnectorMockRecorder) LastID(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LastID", reflect.TypeOf((*MockEventConnector)(nil).LastID), ctx)
+}
+
+// ListAfter mocks base method.
+func (m *MockEventConnector) ListAfter(ctx context.Context, afterID int64, limit int) ([]model.Event, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAfter", ctx, afterID, limit)
+	ret0, _ := ret[0].([]model.Event)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAfter indicates an expected call of ListAfter.
+func (mr *MockEventConnectorMockRecorder) ListAfter(ctx, afterID, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAfter", reflect.TypeOf((*MockEventConnector)(nil).ListAfter), ctx, afterID, limit)
+}
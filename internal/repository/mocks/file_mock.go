@@ -0,0 +1,170 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: file.go
+//
+// Generated by this command:
+//
+//	mockgen -package mocks -destination=./mocks/file_mock.go -source=file.go
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	model "go-tasks-api/internal/model"
+	repository "go-tasks-api/internal/repository"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockFileTaskConnector is a mock of FileTaskConnector interface.
+type MockFileTaskConnector struct {
+	ctrl     *gomock.Controller
+	recorder *MockFileTaskConnectorMockRecorder
+	isgomock struct{}
+}
+
+// MockFileTaskConnectorMockRecorder is the mock recorder for MockFileTaskConnector.
+type MockFileTaskConnectorMockRecorder struct {
+	mock *MockFileTaskConnector
+}
+
+// NewMockFileTaskConnector creates a new mock instance.
+func NewMockFileTaskConnector(ctrl *gomock.Controller) *MockFileTaskConnector {
+	mock := &MockFileTaskConnector{ctrl: ctrl}
+	mock.recorder = &MockFileTaskConnectorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFileTaskConnector) EXPECT() *MockFileTaskConnectorMockRecorder {
+	return m.recorder
+}
+
+// Close mocks base method.
+func (m *MockFileTaskConnector) Close() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Close")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockFileTaskConnectorMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockFileTaskConnector)(nil).Close))
+}
+
+// Compact mocks base method.
+func (m *MockFileTaskConnector) Compact() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Compact")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Compact indicates an expected call of Compact.
+func (mr *MockFileTaskConnectorMockRecorder) Compact() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Compact", reflect.TypeOf((*MockFileTaskConnector)(nil).Compact))
+}
+
+// Create mocks base method.
+func (m *MockFileTaskConnector) Create(ctx context.Context, a model.Task) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, a)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockFileTaskConnectorMockRecorder) Create(ctx, a any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockFileTaskConnector)(nil).Create), ctx, a)
+}
+
+// Delete mocks base method.
+func (m *MockFileTaskConnector) Delete(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockFileTaskConnectorMockRecorder) Delete(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockFileTaskConnector)(nil).Delete), ctx, id)
+}
+
+// Get mocks base method.
+func (m *MockFileTaskConnector) Get(ctx context.Context, id string) (model.Task, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, id)
+	ret0, _ := ret[0].(model.Task)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockFileTaskConnectorMockRecorder) Get(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockFileTaskConnector)(nil).Get), ctx, id)
+}
+
+// List mocks base method.
+func (m *MockFileTaskConnector) List(ctx context.Context, opts repository.ListOptions) ([]model.Task, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, opts)
+	ret0, _ := ret[0].([]model.Task)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockFileTaskConnectorMockRecorder) List(ctx, opts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockFileTaskConnector)(nil).List), ctx, opts)
+}
+
+// Run mocks base method.
+func (m *MockFileTaskConnector) Run(ctx context.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Run", ctx)
+}
+
+// Run indicates an expected call of Run.
+func (mr *MockFileTaskConnectorMockRecorder) Run(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Run", reflect.TypeOf((*MockFileTaskConnector)(nil).Run), ctx)
+}
+
+// Stream mocks base method.
+func (m *MockFileTaskConnector) Stream(ctx context.Context, opts repository.ListOptions, fn func(model.Task) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stream", ctx, opts, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Stream indicates an expected call of Stream.
+func (mr *MockFileTaskConnectorMockRecorder) Stream(ctx, opts, fn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stream", reflect.TypeOf((*MockFileTaskConnector)(nil).Stream), ctx, opts, fn)
+}
+
+// Update mocks base method.
+func (m *MockFileTaskConnector) Update(ctx context.Context, task model.Task) (model.Task, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, task)
+	ret0, _ := ret[0].(model.Task)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockFileTaskConnectorMockRecorder) Update(ctx, task any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockFileTaskConnector)(nil).Update), ctx, task)
+}
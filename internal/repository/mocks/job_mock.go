@@ -0,0 +1,160 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: job.go
+//
+// Generated by this command:
+//
+//	mockgen -package mocks -destination=./mocks/job_mock.go -source=job.go
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	model "go-tasks-api/internal/model"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockJobConnector is a mock of JobConnector interface.
+type MockJobConnector struct {
+	ctrl     *gomock.Controller
+	recorder *MockJobConnectorMockRecorder
+	isgomock struct{}
+}
+
+// MockJobConnectorMockRecorder is the mock recorder for MockJobConnector.
+type MockJobConnectorMockRecorder struct {
+	mock *MockJobConnector
+}
+
+// NewMockJobConnector creates a new mock instance.
+func NewMockJobConnector(ctrl *gomock.Controller) *MockJobConnector {
+	mock := &MockJobConnector{ctrl: ctrl}
+	mock.recorder = &MockJobConnectorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockJobConnector) EXPECT() *MockJobConnectorMockRecorder {
+	return m.recorder
+}
+
+// Cancel mocks base method.
+func (m *MockJobConnector) Cancel(ctx context.Context, id string) (model.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Cancel", ctx, id)
+	ret0, _ := ret[0].(model.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Cancel indicates an expected call of Cancel.
+func (mr *MockJobConnectorMockRecorder) Cancel(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Cancel", reflect.TypeOf((*MockJobConnector)(nil).Cancel), ctx, id)
+}
+
+// Claim mocks base method.
+func (m *MockJobConnector) Claim(ctx context.Context, lease time.Duration) (model.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Claim", ctx, lease)
+	ret0, _ := ret[0].(model.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Claim indicates an expected call of Claim.
+func (mr *MockJobConnectorMockRecorder) Claim(ctx, lease any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Claim", reflect.TypeOf((*MockJobConnector)(nil).Claim), ctx, lease)
+}
+
+// Complete mocks base method.
+func (m *MockJobConnector) Complete(ctx context.Context, outcome model.JobOutcome) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Complete", ctx, outcome)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Complete indicates an expected call of Complete.
+func (mr *MockJobConnectorMockRecorder) Complete(ctx, outcome any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Complete", reflect.TypeOf((*MockJobConnector)(nil).Complete), ctx, outcome)
+}
+
+// Create mocks base method.
+func (m *MockJobConnector) Create(ctx context.Context, job model.Job) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, job)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockJobConnectorMockRecorder) Create(ctx, job any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockJobConnector)(nil).Create), ctx, job)
+}
+
+// Get mocks base method.
+func (m *MockJobConnector) Get(ctx context.Context, id string) (model.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, id)
+	ret0, _ := ret[0].(model.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockJobConnectorMockRecorder) Get(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockJobConnector)(nil).Get), ctx, id)
+}
+
+// GetResult mocks base method.
+func (m *MockJobConnector) GetResult(ctx context.Context, id string) (model.JobResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetResult", ctx, id)
+	ret0, _ := ret[0].(model.JobResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetResult indicates an expected call of GetResult.
+func (mr *MockJobConnectorMockRecorder) GetResult(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetResult", reflect.TypeOf((*MockJobConnector)(nil).GetResult), ctx, id)
+}
+
+// Heartbeat mocks base method.
+func (m *MockJobConnector) Heartbeat(ctx context.Context, job model.Job, lease time.Duration) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Heartbeat", ctx, job, lease)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Heartbeat indicates an expected call of Heartbeat.
+func (mr *MockJobConnectorMockRecorder) Heartbeat(ctx, job, lease any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Heartbeat", reflect.TypeOf((*MockJobConnector)(nil).Heartbeat), ctx, job, lease)
+}
+
+// Release mocks base method.
+func (m *MockJobConnector) Release(ctx context.Context, job model.Job) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Release", ctx, job)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Release indicates an expected call of Release.
+func (mr *MockJobConnectorMockRecorder) Release(ctx, job any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockJobConnector)(nil).Release), ctx, job)
+}
@@ -0,0 +1,57 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: outbox.go
+//
+// Generated by this command:
+//
+//	mockgen -package mocks -destination=./mocks/outbox_mock.go -source=outbox.go
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	repository "go-tasks-api/internal/repository"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockOutboxConnector is a mock of OutboxConnector interface.
+type MockOutboxConnector struct {
+	ctrl     *gomock.Controller
+	recorder *MockOutboxConnectorMockRecorder
+	isgomock struct{}
+}
+
+// MockOutboxConnectorMockRecorder is the mock recorder for MockOutboxConnector.
+type MockOutboxConnectorMockRecorder struct {
+	mock *MockOutboxConnector
+}
+
+// NewMockOutboxConnector creates a new mock instance.
+func NewMockOutboxConnector(ctrl *gomock.Controller) *MockOutboxConnector {
+	mock := &MockOutboxConnector{ctrl: ctrl}
+	mock.recorder = &MockOutboxConnectorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOutboxConnector) EXPECT() *MockOutboxConnectorMockRecorder {
+	return m.recorder
+}
+
+// Relay mocks base method.
+func (m *MockOutboxConnector) Relay(ctx context.Context, opts repository.RelayOptions, publish repository.PublishFunc) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Relay", ctx, opts, publish)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Relay indicates an expected call of Relay.
+func (mr *MockOutboxConnectorMockRecorder) Relay(ctx, opts, publish any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Relay", reflect.TypeOf((*MockOutboxConnector)(nil).Relay), ctx, opts, publish)
+}
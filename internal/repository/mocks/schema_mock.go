@@ -0,0 +1,56 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: schema.go
+//
+// Generated by this command:
+//
+//	mockgen -package mocks -destination=./mocks/schema_mock.go -source=schema.go
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockSchemaConnector is a mock of SchemaConnector interface.
+type MockSchemaConnector struct {
+	ctrl     *gomock.Controller
+	recorder *MockSchemaConnectorMockRecorder
+	isgomock struct{}
+}
+
+// MockSchemaConnectorMockRecorder is the mock recorder for MockSchemaConnector.
+type MockSchemaConnectorMockRecorder struct {
+	mock *MockSchemaConnector
+}
+
+// NewMockSchemaConnector creates a new mock instance.
+func NewMockSchemaConnector(ctrl *gomock.Controller) *MockSchemaConnector {
+	mock := &MockSchemaConnector{ctrl: ctrl}
+	mock.recorder = &MockSchemaConnectorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSchemaConnector) EXPECT() *MockSchemaConnectorMockRecorder {
+	return m.recorder
+}
+
+// Version mocks base method.
+func (m *MockSchemaConnector) Version(ctx context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Version", ctx)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Version indicates an expected call of Version.
+func (mr *MockSchemaConnectorMockRecorder) Version(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Version", reflect.TypeOf((*MockSchemaConnector)(nil).Version), ctx)
+}
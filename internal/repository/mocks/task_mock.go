@@ -12,6 +12,7 @@ package mocks
 import (
 	context "context"
 	model "go-tasks-api/internal/model"
+	repository "go-tasks-api/internal/repository"
 	reflect "reflect"
 
 	gomock "go.uber.org/mock/gomock"
@@ -42,11 +43,12 @@ func (m *MockTaskConnector) EXPECT() *MockTaskConnectorMockRecorder {
 }
 
 // Create mocks base method.
-func (m *MockTaskConnector) Create(ctx context.Context, a model.Task) error {
+func (m *MockTaskConnector) Create(ctx context.Context, a model.Task) (bool, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Create", ctx, a)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // Create indicates an expected call of Create.
@@ -85,18 +87,32 @@ func (mr *MockTaskConnectorMockRecorder) Get(ctx, id any) *gomock.Call {
 }
 
 // List mocks base method.
-func (m *MockTaskConnector) List(ctx context.Context) ([]model.Task, error) {
+func (m *MockTaskConnector) List(ctx context.Context, opts repository.ListOptions) ([]model.Task, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "List", ctx)
+	ret := m.ctrl.Call(m, "List", ctx, opts)
 	ret0, _ := ret[0].([]model.Task)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // List indicates an expected call of List.
-func (mr *MockTaskConnectorMockRecorder) List(ctx any) *gomock.Call {
+func (mr *MockTaskConnectorMockRecorder) List(ctx, opts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockTaskConnector)(nil).List), ctx, opts)
+}
+
+// Stream mocks base method.
+func (m *MockTaskConnector) Stream(ctx context.Context, opts repository.ListOptions, fn func(model.Task) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stream", ctx, opts, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Stream indicates an expected call of Stream.
+func (mr *MockTaskConnectorMockRecorder) Stream(ctx, opts, fn any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockTaskConnector)(nil).List), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stream", reflect.TypeOf((*MockTaskConnector)(nil).Stream), ctx, opts, fn)
 }
 
 // Update mocks base method.
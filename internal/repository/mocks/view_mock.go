@@ -0,0 +1,130 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: view.go
+//
+// Generated by this command:
+//
+//	mockgen -package mocks -destination=./mocks/view_mock.go -source=view.go
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	model "go-tasks-api/internal/model"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockViewConnector is a mock of ViewConnector interface.
+type MockViewConnector struct {
+	ctrl     *gomock.Controller
+	recorder *MockViewConnectorMockRecorder
+	isgomock struct{}
+}
+
+// MockViewConnectorMockRecorder is the mock recorder for MockViewConnector.
+type MockViewConnectorMockRecorder struct {
+	mock *MockViewConnector
+}
+
+// NewMockViewConnector creates a new mock instance.
+func NewMockViewConnector(ctrl *gomock.Controller) *MockViewConnector {
+	mock := &MockViewConnector{ctrl: ctrl}
+	mock.recorder = &MockViewConnectorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockViewConnector) EXPECT() *MockViewConnectorMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockViewConnector) Create(ctx context.Context, view model.View) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, view)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockViewConnectorMockRecorder) Create(ctx, view any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockViewConnector)(nil).Create), ctx, view)
+}
+
+// Delete mocks base method.
+func (m *MockViewConnector) Delete(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockViewConnectorMockRecorder) Delete(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockViewConnector)(nil).Delete), ctx, id)
+}
+
+// Get mocks base method.
+func (m *MockViewConnector) Get(ctx context.Context, id string) (model.View, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, id)
+	ret0, _ := ret[0].(model.View)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockViewConnectorMockRecorder) Get(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockViewConnector)(nil).Get), ctx, id)
+}
+
+// GetDefault mocks base method.
+func (m *MockViewConnector) GetDefault(ctx context.Context, ownerID string) (model.View, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDefault", ctx, ownerID)
+	ret0, _ := ret[0].(model.View)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDefault indicates an expected call of GetDefault.
+func (mr *MockViewConnectorMockRecorder) GetDefault(ctx, ownerID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDefault", reflect.TypeOf((*MockViewConnector)(nil).GetDefault), ctx, ownerID)
+}
+
+// ListFor mocks base method.
+func (m *MockViewConnector) ListFor(ctx context.Context, userID string) ([]model.View, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListFor", ctx, userID)
+	ret0, _ := ret[0].([]model.View)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListFor indicates an expected call of ListFor.
+func (mr *MockViewConnectorMockRecorder) ListFor(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListFor", reflect.TypeOf((*MockViewConnector)(nil).ListFor), ctx, userID)
+}
+
+// Update mocks base method.
+func (m *MockViewConnector) Update(ctx context.Context, view model.View) (model.View, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, view)
+	ret0, _ := ret[0].(model.View)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockViewConnectorMockRecorder) Update(ctx, view any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockViewConnector)(nil).Update), ctx, view)
+}
@@ -0,0 +1,233 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: webhook.go
+//
+// Generated by this command:
+//
+//	mockgen -package mocks -destination=./mocks/webhook_mock.go -source=webhook.go
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	model "go-tasks-api/internal/model"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockWebhookConnector is a mock of WebhookConnector interface.
+type MockWebhookConnector struct {
+	ctrl     *gomock.Controller
+	recorder *MockWebhookConnectorMockRecorder
+	isgomock struct{}
+}
+
+// MockWebhookConnectorMockRecorder is the mock recorder for MockWebhookConnector.
+type MockWebhookConnectorMockRecorder struct {
+	mock *MockWebhookConnector
+}
+
+// NewMockWebhookConnector creates a new mock instance.
+func NewMockWebhookConnector(ctrl *gomock.Controller) *MockWebhookConnector {
+	mock := &MockWebhookConnector{ctrl: ctrl}
+	mock.recorder = &MockWebhookConnectorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWebhookConnector) EXPECT() *MockWebhookConnectorMockRecorder {
+	return m.recorder
+}
+
+// ClaimDue mocks base method.
+func (m *MockWebhookConnector) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]model.PendingDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimDue", ctx, limit, lease)
+	ret0, _ := ret[0].([]model.PendingDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimDue indicates an expected call of ClaimDue.
+func (mr *MockWebhookConnectorMockRecorder) ClaimDue(ctx, limit, lease any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimDue", reflect.TypeOf((*MockWebhookConnector)(nil).ClaimDue), ctx, limit, lease)
+}
+
+// CompleteAttempt mocks base method.
+func (m *MockWebhookConnector) CompleteAttempt(ctx context.Context, attempt model.DeliveryAttempt, disableAfter int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteAttempt", ctx, attempt, disableAfter)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CompleteAttempt indicates an expected call of CompleteAttempt.
+func (mr *MockWebhookConnectorMockRecorder) CompleteAttempt(ctx, attempt, disableAfter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteAttempt", reflect.TypeOf((*MockWebhookConnector)(nil).CompleteAttempt), ctx, attempt, disableAfter)
+}
+
+// Create mocks base method.
+func (m *MockWebhookConnector) Create(ctx context.Context, webhook model.Webhook) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, webhook)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockWebhookConnectorMockRecorder) Create(ctx, webhook any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockWebhookConnector)(nil).Create), ctx, webhook)
+}
+
+// Delete mocks base method.
+func (m *MockWebhookConnector) Delete(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockWebhookConnectorMockRecorder) Delete(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockWebhookConnector)(nil).Delete), ctx, id)
+}
+
+// Enqueue mocks base method.
+func (m *MockWebhookConnector) Enqueue(ctx context.Context, event model.Event) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Enqueue", ctx, event)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Enqueue indicates an expected call of Enqueue.
+func (mr *MockWebhookConnectorMockRecorder) Enqueue(ctx, event any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Enqueue", reflect.TypeOf((*MockWebhookConnector)(nil).Enqueue), ctx, event)
+}
+
+// Get mocks base method.
+func (m *MockWebhookConnector) Get(ctx context.Context, id string) (model.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, id)
+	ret0, _ := ret[0].(model.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockWebhookConnectorMockRecorder) Get(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockWebhookConnector)(nil).Get), ctx, id)
+}
+
+// List mocks base method.
+func (m *MockWebhookConnector) List(ctx context.Context) ([]model.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx)
+	ret0, _ := ret[0].([]model.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockWebhookConnectorMockRecorder) List(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockWebhookConnector)(nil).List), ctx)
+}
+
+// ListDeliveries mocks base method.
+func (m *MockWebhookConnector) ListDeliveries(ctx context.Context, webhookID string, limit int) ([]model.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDeliveries", ctx, webhookID, limit)
+	ret0, _ := ret[0].([]model.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDeliveries indicates an expected call of ListDeliveries.
+func (mr *MockWebhookConnectorMockRecorder) ListDeliveries(ctx, webhookID, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDeliveries", reflect.TypeOf((*MockWebhookConnector)(nil).ListDeliveries), ctx, webhookID, limit)
+}
+
+// Redeliver mocks base method.
+func (m *MockWebhookConnector) Redeliver(ctx context.Context, webhookID, deliveryID string) (model.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Redeliver", ctx, webhookID, deliveryID)
+	ret0, _ := ret[0].(model.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Redeliver indicates an expected call of Redeliver.
+func (mr *MockWebhookConnectorMockRecorder) Redeliver(ctx, webhookID, deliveryID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Redeliver", reflect.TypeOf((*MockWebhookConnector)(nil).Redeliver), ctx, webhookID, deliveryID)
+}
+
+// Update mocks base method.
+func (m *MockWebhookConnector) Update(ctx context.Context, webhook model.Webhook) (model.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, webhook)
+	ret0, _ := ret[0].(model.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockWebhookConnectorMockRecorder) Update(ctx, webhook any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockWebhookConnector)(nil).Update), ctx, webhook)
+}
+
+// Mockscanner is a mock of scanner interface.
+type Mockscanner struct {
+	ctrl     *gomock.Controller
+	recorder *MockscannerMockRecorder
+	isgomock struct{}
+}
+
+// MockscannerMockRecorder is the mock recorder for Mockscanner.
+type MockscannerMockRecorder struct {
+	mock *Mockscanner
+}
+
+// NewMockscanner creates a new mock instance.
+func NewMockscanner(ctrl *gomock.Controller) *Mockscanner {
+	mock := &Mockscanner{ctrl: ctrl}
+	mock.recorder = &MockscannerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *Mockscanner) EXPECT() *MockscannerMockRecorder {
+	return m.recorder
+}
+
+// Scan mocks base method.
+func (m *Mockscanner) Scan(dest ...any) error {
+	m.ctrl.T.Helper()
+	varargs := []any{}
+	for _, a := range dest {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Scan", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Scan indicates an expected call of Scan.
+func (mr *MockscannerMockRecorder) Scan(dest ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Scan", reflect.TypeOf((*Mockscanner)(nil).Scan), dest...)
+}
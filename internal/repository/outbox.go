@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"go-tasks-api/internal/database"
+	"go-tasks-api/internal/encryption"
+	"go-tasks-api/internal/model"
+	"go-tasks-api/internal/utils"
+
+	"github.com/lib/pq"
+)
+
+// maxOutboxErrorLength bounds the publish error stored per message
+const maxOutboxErrorLength = 512
+
+type outboxRepo struct {
+	db     *sql.DB
+	cipher encryption.Cipher
+}
+
+// PublishFunc publishes one outbox message and returns the publishers which have it, the
+// message stays in the outbox when it fails and is published again to the others
+type PublishFunc func(ctx context.Context, msg model.OutboxMessage) ([]string, error)
+
+// RelayOptions bound a run of Relay
+type RelayOptions struct {
+	// Limit is the maximum number of messages claimed
+	Limit int
+	// MaxAttempts is the number of attempts after which a failing message is dead, 0 retries it
+	// forever
+	MaxAttempts int
+	// Lease is the time the claimed messages are kept from other relays, messages left when it
+	// expired are not published
+	Lease time.Duration
+	// BackoffBase and BackoffMax shape the exponential delay before a failed message is claimed
+	// again, the later messages of its aggregate wait as well
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+}
+
+// backoff returns the delay after the given failed attempt: exponential from BackoffBase,
+// capped at BackoffMax
+func (o RelayOptions) backoff(attempt int) time.Duration {
+	delay := o.BackoffBase
+	for i := 1; i < attempt && delay < o.BackoffMax; i++ {
+		delay *= 2
+	}
+
+	return min(delay, o.BackoffMax)
+}
+
+//go:generate go run -mod=mod go.uber.org/mock/mockgen -package mocks -destination=./mocks/outbox_mock.go -source=outbox.go
+type OutboxConnector interface {
+	// Relay leases unsent messages, at most the oldest one per aggregate, hands them to publish
+	// outside of any transaction and marks the published ones as sent. It returns the number of
+	// sent messages.
+	Relay(ctx context.Context, opts RelayOptions, publish PublishFunc) (int, error)
+}
+
+// NewOutboxRepo creates a new Outbox repository, payloads are encrypted with the same cipher as tasks
+func NewOutboxRepo(db *sql.DB, cipher encryption.Cipher) OutboxConnector {
+	return &outboxRepo{
+		db:     db,
+		cipher: cipher,
+	}
+}
+
+func (a *outboxRepo) Relay(ctx context.Context, opts RelayOptions, publish PublishFunc) (int, error) {
+	// a message is only claimed once all older messages of its aggregate were sent or are dead,
+	// leased ones keep their successors unclaimed so per-aggregate order holds. Moving
+	// locked_until forward acts as the lease, the messages of a crashed relay are claimed again
+	// once it expires, and the attempt counted by the claim fences the updates of its relay.
+	claimSQL := `
+		WITH next AS (
+			SELECT o.id
+			FROM tasks.outbox o
+			WHERE o.sent_at IS NULL AND o.dead_at IS NULL
+			  AND (o.locked_until IS NULL OR o.locked_until < CURRENT_TIMESTAMP)
+			  AND NOT EXISTS (
+				SELECT 1 FROM tasks.outbox p
+				WHERE p.aggregate_id = o.aggregate_id AND p.sent_at IS NULL AND p.dead_at IS NULL AND p.id < o.id
+			  )
+			ORDER BY o.id
+			LIMIT $1
+			FOR UPDATE OF o SKIP LOCKED
+		)
+		UPDATE tasks.outbox o
+		SET attempts = o.attempts + 1,
+		    locked_until = CURRENT_TIMESTAMP + make_interval(secs => $2)
+		FROM next
+		WHERE o.id = next.id
+		RETURNING o.id, o.aggregate_type, o.aggregate_id, o.event_type, o.status, o.payload, o.attempts, o.published_to, o.created_at;
+	`
+	sentSQL := `
+		UPDATE tasks.outbox
+		SET sent_at = CURRENT_TIMESTAMP, last_error = NULL, published_to = $3, locked_until = NULL
+		WHERE id = $1 AND attempts = $2;
+	`
+	// the message keeps its lease until the backoff passed, so it is not claimed again at once
+	failedSQL := `
+		UPDATE tasks.outbox
+		SET last_error = $3, published_to = $4, locked_until = CURRENT_TIMESTAMP + make_interval(secs => $6),
+		    dead_at = CASE WHEN $5 > 0 AND attempts >= $5 THEN CURRENT_TIMESTAMP END
+		WHERE id = $1 AND attempts = $2;
+	`
+
+	messages, err := a.claim(ctx, claimSQL, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	// the messages are published again by another relay once the lease expired
+	leaseCtx, cancel := context.WithTimeout(ctx, opts.Lease)
+	defer cancel()
+
+	sent := 0
+	for _, msg := range messages {
+		if leaseCtx.Err() != nil {
+			break
+		}
+
+		published, pErr := publish(leaseCtx, msg)
+		if published == nil {
+			// published_to is not null
+			published = []string{}
+		}
+
+		if pErr != nil {
+			errMsg := utils.TruncateString(pErr.Error(), maxOutboxErrorLength)
+
+			_, err := a.db.ExecContext(ctx, failedSQL, msg.ID, msg.Attempts, errMsg, pq.Array(published), opts.MaxAttempts,
+				opts.backoff(msg.Attempts).Seconds())
+			if err != nil {
+				return sent, fmt.Errorf("failed to record outbox failure: %w", err)
+			}
+
+			continue
+		}
+
+		if _, err := a.db.ExecContext(ctx, sentSQL, msg.ID, msg.Attempts, pq.Array(published)); err != nil {
+			return sent, fmt.Errorf("failed to mark outbox message as sent: %w", err)
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+func (a *outboxRepo) claim(ctx context.Context, claimSQL string, opts RelayOptions) ([]model.OutboxMessage, error) {
+	rows, err := a.db.QueryContext(ctx, claimSQL, opts.Limit, opts.Lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := make([]model.OutboxMessage, 0)
+	for rows.Next() {
+		var (
+			msg     model.OutboxMessage
+			payload string
+		)
+		if err := rows.Scan(
+			&msg.ID,
+			&msg.AggregateType,
+			&msg.AggregateID,
+			&msg.EventType,
+			&msg.Status,
+			&payload,
+			&msg.Attempts,
+			pq.Array(&msg.PublishedTo),
+			&msg.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox message: %w", err)
+		}
+
+		data, err := a.cipher.Decrypt(payload, outboxPayloadData(msg.ID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt outbox payload: %w", err)
+		}
+		msg.Payload = []byte(data)
+
+		messages = append(messages, msg)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	// RETURNING keeps no order, older messages are published first
+	slices.SortFunc(messages, func(a, b model.OutboxMessage) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return messages, nil
+}
+
+// writeOutbox adds the message for a change of the task to the outbox, within the transaction of the change
+func writeOutbox(ctx context.Context, tx database.Executor, cipher encryption.Cipher, eventType string, task model.Task) error {
+	insertSQL := `INSERT INTO tasks.outbox (id, aggregate_type, aggregate_id, event_type, status, payload) values ($1, $2, $3, $4, $5, $6);`
+	// the ID is drawn ahead of the insert as the payload is encrypted with it
+	nextIDSQL := `SELECT nextval('tasks.outbox_id_seq');`
+
+	msg, err := model.NewTaskMessage(eventType, task)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.QueryRowContext(ctx, nextIDSQL).Scan(&msg.ID); err != nil {
+		return fmt.Errorf("failed to draw outbox message id: %w", err)
+	}
+
+	payload, err := cipher.Encrypt(string(msg.Payload), outboxPayloadData(msg.ID))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt outbox payload: %w", err)
+	}
+
+	var status any
+	if msg.Status.IsAStatusType() {
+		status = msg.Status
+	}
+
+	if _, err := tx.ExecContext(ctx, insertSQL, msg.ID, msg.AggregateType, msg.AggregateID.String(), msg.EventType, status, payload); err != nil {
+		return fmt.Errorf("failed to insert outbox message: %w", err)
+	}
+
+	return nil
+}
+
+// outboxPayloadData returns the additional data the payload of the outbox message is encrypted with
+func outboxPayloadData(id int64) string {
+	return encryption.AdditionalData("tasks.outbox", "payload", strconv.FormatInt(id, 10))
+}
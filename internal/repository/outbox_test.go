@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"go-tasks-api/internal/encryption"
+	"go-tasks-api/internal/enum"
+	"go-tasks-api/internal/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type outboxSuite struct {
+	suite.Suite
+	repo OutboxConnector
+	db   sqlmock.Sqlmock
+}
+
+func TestOutbox(t *testing.T) {
+	suite.Run(t, new(outboxSuite))
+}
+
+func (s *outboxSuite) SetupTest() {
+	db, mock, err := sqlmock.New()
+	require.NoError(s.T(), err)
+
+	s.repo = NewOutboxRepo(db, encryption.NoopCipher{})
+	s.db = mock
+}
+
+func (s *outboxSuite) TearDownTest() {
+	s.NoError(s.db.ExpectationsWereMet())
+}
+
+var claimColumns = []string{"id", "aggregate_type", "aggregate_id", "event_type", "status", "payload", "attempts", "published_to", "created_at"}
+
+func (s *outboxSuite) TestRelayMarksSentAndFailed() {
+	now := time.Now()
+	first, second := uuid.New(), uuid.New()
+
+	s.db.ExpectQuery(regexp.QuoteMeta(`FOR UPDATE OF o SKIP LOCKED`)).
+		WithArgs(10, float64(60)).
+		WillReturnRows(sqlmock.NewRows(claimColumns).
+			AddRow(2, model.AggregateTask, second, model.EventTaskDeleted, nil, `{"id":"x"}`, 4, "{events}", now).
+			AddRow(1, model.AggregateTask, first, model.EventTaskCreated, "todo", `{"title":"doc"}`, 1, "{}", now))
+	s.db.ExpectExec(regexp.QuoteMeta(`SET sent_at = CURRENT_TIMESTAMP`)).
+		WithArgs(int64(1), 1, `{"events","log"}`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.db.ExpectExec(regexp.QuoteMeta(`dead_at = CASE WHEN $5 > 0 AND attempts >= $5 THEN CURRENT_TIMESTAMP END`)).
+		WithArgs(int64(2), 4, "broker unavailable", `{"events"}`, 5, float64(8)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	var published []model.OutboxMessage
+	opts := RelayOptions{Limit: 10, MaxAttempts: 5, Lease: time.Minute, BackoffBase: time.Second, BackoffMax: time.Minute}
+	sent, err := s.repo.Relay(context.Background(), opts, func(_ context.Context, msg model.OutboxMessage) ([]string, error) {
+		published = append(published, msg)
+		if msg.ID == 2 {
+			return msg.PublishedTo, errors.New("broker unavailable")
+		}
+
+		return []string{"events", "log"}, nil
+	})
+	s.NoError(err)
+	s.Equal(1, sent)
+
+	// older messages are published first
+	s.Require().Len(published, 2)
+	s.Equal(first, published[0].AggregateID)
+	s.Equal(enum.Status_Todo, published[0].Status)
+	s.JSONEq(`{"title":"doc"}`, string(published[0].Payload))
+	s.Empty(published[0].PublishedTo)
+	s.Equal(4, published[1].Attempts)
+	s.Equal([]string{"events"}, published[1].PublishedTo)
+	s.False(published[1].Status.IsAStatusType())
+}
+
+// TestRelayBacksOffFailedMessages checks a failed message stays leased for its backoff, the
+// claim skips leased messages and the later messages of their aggregates
+func (s *outboxSuite) TestRelayBacksOffFailedMessages() {
+	s.db.ExpectQuery(regexp.QuoteMeta(`AND (o.locked_until IS NULL OR o.locked_until < CURRENT_TIMESTAMP)`)).
+		WillReturnRows(sqlmock.NewRows(claimColumns).
+			AddRow(1, model.AggregateTask, uuid.New(), model.EventTaskCreated, "todo", `{}`, 12, "{}", time.Now()))
+	s.db.ExpectExec(regexp.QuoteMeta(`locked_until = CURRENT_TIMESTAMP + make_interval(secs => $6)`)).
+		WithArgs(int64(1), 12, "broker unavailable", "{}", 25, float64(300)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// nothing can be claimed until the backoff passed
+	s.db.ExpectQuery(regexp.QuoteMeta(`FOR UPDATE OF o SKIP LOCKED`)).WillReturnRows(sqlmock.NewRows(claimColumns))
+
+	opts := RelayOptions{Limit: 10, MaxAttempts: 25, Lease: time.Minute, BackoffBase: time.Second, BackoffMax: 5 * time.Minute}
+	failing := func(context.Context, model.OutboxMessage) ([]string, error) {
+		return nil, errors.New("broker unavailable")
+	}
+	sent, err := s.repo.Relay(context.Background(), opts, failing)
+	s.NoError(err)
+	s.Zero(sent)
+
+	sent, err = s.repo.Relay(context.Background(), opts, func(context.Context, model.OutboxMessage) ([]string, error) {
+		s.Fail("the failed message must not be claimed again before its backoff")
+
+		return nil, nil
+	})
+	s.NoError(err)
+	s.Zero(sent)
+}
+
+func (s *outboxSuite) TestRelayTruncatesErrorOnRuneBoundary() {
+	s.db.ExpectQuery(regexp.QuoteMeta(`FOR UPDATE OF o SKIP LOCKED`)).
+		WillReturnRows(sqlmock.NewRows(claimColumns).
+			AddRow(1, model.AggregateTask, uuid.New(), model.EventTaskCreated, "todo", `{}`, 1, "{}", time.Now()))
+	// the cut at maxOutboxErrorLength falls into the two bytes of é
+	s.db.ExpectExec(regexp.QuoteMeta(`SET last_error = $3`)).
+		WithArgs(int64(1), 1, strings.Repeat("a", maxOutboxErrorLength-1), "{}", 0, float64(0)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	_, err := s.repo.Relay(context.Background(), RelayOptions{Limit: 10, Lease: time.Minute},
+		func(context.Context, model.OutboxMessage) ([]string, error) {
+			return nil, errors.New(strings.Repeat("a", maxOutboxErrorLength-1) + "é task title")
+		})
+	s.NoError(err)
+}
+
+func TestRelayBackoff(t *testing.T) {
+	opts := RelayOptions{BackoffBase: time.Second, BackoffMax: time.Minute}
+
+	require.Equal(t, time.Second, opts.backoff(1))
+	require.Equal(t, 8*time.Second, opts.backoff(4))
+	require.Equal(t, time.Minute, opts.backoff(7))
+	require.Equal(t, time.Minute, opts.backoff(1000))
+}
+
+func (s *outboxSuite) TestRelayStopsWhenLeaseExpired() {
+	now := time.Now()
+
+	s.db.ExpectQuery(regexp.QuoteMeta(`FOR UPDATE OF o SKIP LOCKED`)).
+		WillReturnRows(sqlmock.NewRows(claimColumns).
+			AddRow(1, model.AggregateTask, uuid.New(), model.EventTaskCreated, "todo", `{}`, 1, "{}", now).
+			AddRow(2, model.AggregateTask, uuid.New(), model.EventTaskCreated, "todo", `{}`, 1, "{}", now))
+	s.db.ExpectExec(regexp.QuoteMeta(`SET sent_at = CURRENT_TIMESTAMP`)).
+		WithArgs(int64(1), 1, `{"events"}`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	calls := 0
+	sent, err := s.repo.Relay(context.Background(), RelayOptions{Limit: 10, Lease: 10 * time.Millisecond},
+		func(context.Context, model.OutboxMessage) ([]string, error) {
+			calls++
+			time.Sleep(20 * time.Millisecond)
+
+			return []string{"events"}, nil
+		})
+	s.NoError(err)
+	s.Equal(1, sent)
+	// the second message is left to the relay claiming it once the lease expired
+	s.Equal(1, calls)
+}
+
+func (s *outboxSuite) TestRelayClaimError() {
+	mockError := errors.New("db error")
+
+	s.db.ExpectQuery(regexp.QuoteMeta(`FROM tasks.outbox o`)).WillReturnError(mockError)
+
+	_, err := s.repo.Relay(context.Background(), RelayOptions{Limit: 10, Lease: time.Minute}, func(context.Context, model.OutboxMessage) ([]string, error) {
+		s.Fail("nothing should be published")
+
+		return nil, nil
+	})
+	s.ErrorIs(err, mockError)
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"go-tasks-api/internal/encryption"
+)
+
+// encryptedColumn is a column of sensitive values, encrypted with the ID of their row as
+// additional data
+type encryptedColumn struct {
+	table  string
+	column string
+	// firstID sorts before every ID of the table
+	firstID string
+}
+
+var encryptedColumns = []encryptedColumn{
+	{"tasks.tasks", "description", "00000000-0000-0000-0000-000000000000"},
+	{"tasks.events", "payload", "0"},
+	{"tasks.outbox", "payload", "0"},
+	{"tasks.webhooks", "secret", "00000000-0000-0000-0000-000000000000"},
+	{"tasks.jobs", "params", "00000000-0000-0000-0000-000000000000"},
+	{"tasks.jobs", "result", "00000000-0000-0000-0000-000000000000"},
+}
+
+// Reencrypt walks every row of the tables with sensitive fields, including soft deleted ones,
+// in batches ordered by ID and re-encrypts the values which are stored in plaintext, sealed
+// without their additional data or sealed with a key other than the primary key. It returns
+// the number of rewritten values.
+func Reencrypt(ctx context.Context, db *sql.DB, cipher *encryption.Envelope, batchSize int) (int, error) {
+	var rewritten int
+	for _, c := range encryptedColumns {
+		n, err := reencryptColumn(ctx, db, cipher, c, batchSize)
+		rewritten += n
+		if err != nil {
+			return rewritten, err
+		}
+	}
+
+	return rewritten, nil
+}
+
+func reencryptColumn(ctx context.Context, db *sql.DB, cipher *encryption.Envelope, c encryptedColumn, batchSize int) (int, error) {
+	selectSQL := `SELECT id, ` + c.column + ` FROM ` + c.table + ` WHERE id > $1 ORDER BY id LIMIT $2;`
+	// the current value is part of the predicate so concurrent writes are never overwritten
+	updateSQL := `UPDATE ` + c.table + ` SET ` + c.column + ` = $2 WHERE id = $1 AND ` + c.column + ` = $3;`
+
+	type row struct {
+		id    string
+		value sql.NullString
+	}
+
+	var (
+		rewritten int
+		lastID    = c.firstID
+	)
+	for {
+		rows, err := db.QueryContext(ctx, selectSQL, lastID, batchSize)
+		if err != nil {
+			return rewritten, fmt.Errorf("failed to list %s: %w", c.table, err)
+		}
+
+		batch := make([]row, 0, batchSize)
+		for rows.Next() {
+			var r row
+			if err := rows.Scan(&r.id, &r.value); err != nil {
+				rows.Close()
+
+				return rewritten, fmt.Errorf("failed to scan %s: %w", c.table, err)
+			}
+			batch = append(batch, r)
+		}
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return rewritten, fmt.Errorf("row iteration error: %w", err)
+		}
+
+		for _, r := range batch {
+			// a job without a result has nothing to encrypt
+			if !r.value.Valid || !cipher.NeedsRotation(r.value.String) {
+				continue
+			}
+
+			additionalData := encryption.AdditionalData(c.table, c.column, r.id)
+			plaintext, err := cipher.Decrypt(r.value.String, additionalData)
+			if err != nil {
+				return rewritten, fmt.Errorf("failed to decrypt %s.%s of %s: %w", c.table, c.column, r.id, err)
+			}
+
+			ciphertext, err := cipher.Encrypt(plaintext, additionalData)
+			if err != nil {
+				return rewritten, fmt.Errorf("failed to encrypt %s.%s of %s: %w", c.table, c.column, r.id, err)
+			}
+
+			res, err := db.ExecContext(ctx, updateSQL, r.id, ciphertext, r.value.String)
+			if err != nil {
+				return rewritten, fmt.Errorf("failed to update %s.%s of %s: %w", c.table, c.column, r.id, err)
+			}
+
+			if n, err := res.RowsAffected(); err == nil && n > 0 {
+				rewritten++
+			}
+		}
+
+		if len(batch) < batchSize {
+			return rewritten, nil
+		}
+		lastID = batch[len(batch)-1].id
+	}
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"regexp"
+	"testing"
+
+	"go-tasks-api/internal/encryption"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReencrypt(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	oldKeyring, err := encryption.NewKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)})
+	require.NoError(t, err)
+	keyring, err := encryption.NewKeyring("k2", map[string][]byte{
+		"k1": bytes.Repeat([]byte{1}, 32),
+		"k2": bytes.Repeat([]byte{2}, 32),
+	})
+	require.NoError(t, err)
+
+	cipher := encryption.NewEnvelope(keyring)
+	taskData := encryption.AdditionalData("tasks.tasks", "description", "00000000-0000-0000-0000-000000000002")
+	sealedWithOld, err := encryption.NewEnvelope(oldKeyring).Encrypt("old secret", taskData)
+	require.NoError(t, err)
+	sealedWithPrimary, err := cipher.Encrypt("current secret", encryption.AdditionalData("tasks.tasks", "description", "00000000-0000-0000-0000-000000000003"))
+	require.NoError(t, err)
+	jobData := encryption.AdditionalData("tasks.jobs", "result", "00000000-0000-0000-0000-000000000005")
+	resultWithOld, err := encryption.NewEnvelope(oldKeyring).Encrypt("result", jobData)
+	require.NoError(t, err)
+
+	selectSQL := regexp.QuoteMeta(`SELECT id, description FROM tasks.tasks WHERE id > $1 ORDER BY id LIMIT $2;`)
+	updateSQL := regexp.QuoteMeta(`UPDATE tasks.tasks SET description = $2 WHERE id = $1 AND description = $3;`)
+
+	var rewritten string
+	mock.ExpectQuery(selectSQL).
+		WithArgs("00000000-0000-0000-0000-000000000000", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "description"}).
+			AddRow("00000000-0000-0000-0000-000000000001", "legacy plaintext").
+			AddRow("00000000-0000-0000-0000-000000000002", sealedWithOld))
+	mock.ExpectExec(updateSQL).
+		WithArgs("00000000-0000-0000-0000-000000000001", sqlmock.AnyArg(), "legacy plaintext").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(updateSQL).
+		WithArgs("00000000-0000-0000-0000-000000000002", capture{&rewritten}, sealedWithOld).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(selectSQL).
+		WithArgs("00000000-0000-0000-0000-000000000002", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "description"}).
+			AddRow("00000000-0000-0000-0000-000000000003", sealedWithPrimary))
+
+	for _, c := range []struct{ table, column, firstID string }{
+		{"tasks.events", "payload", "0"},
+		{"tasks.outbox", "payload", "0"},
+		{"tasks.webhooks", "secret", "00000000-0000-0000-0000-000000000000"},
+		{"tasks.jobs", "params", "00000000-0000-0000-0000-000000000000"},
+	} {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, `+c.column+` FROM `+c.table+` WHERE id > $1 ORDER BY id LIMIT $2;`)).
+			WithArgs(c.firstID, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", c.column}))
+	}
+
+	// a job without a result is left alone
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, result FROM tasks.jobs WHERE id > $1 ORDER BY id LIMIT $2;`)).
+		WithArgs("00000000-0000-0000-0000-000000000000", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "result"}).
+			AddRow("00000000-0000-0000-0000-000000000004", nil).
+			AddRow("00000000-0000-0000-0000-000000000005", resultWithOld))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE tasks.jobs SET result = $2 WHERE id = $1 AND result = $3;`)).
+		WithArgs("00000000-0000-0000-0000-000000000005", sqlmock.AnyArg(), resultWithOld).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, result FROM tasks.jobs WHERE id > $1 ORDER BY id LIMIT $2;`)).
+		WithArgs("00000000-0000-0000-0000-000000000005", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "result"}))
+
+	n, err := Reencrypt(context.Background(), db, cipher, 2)
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// the value is sealed again for the same row
+	require.False(t, cipher.NeedsRotation(rewritten))
+	plaintext, err := cipher.Decrypt(rewritten, taskData)
+	require.NoError(t, err)
+	require.Equal(t, "old secret", plaintext)
+}
+
+// capture is an argument matcher recording the argument
+type capture struct {
+	value *string
+}
+
+func (c capture) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	*c.value = s
+
+	return ok
+}
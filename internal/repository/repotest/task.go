@@ -0,0 +1,347 @@
+// Package repotest holds the contract test suites every implementation of a repository
+// interface has to pass, so the behaviour of the storage backends cannot drift apart
+package repotest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go-tasks-api/internal/apperr"
+	"go-tasks-api/internal/enum"
+	"go-tasks-api/internal/filter"
+	"go-tasks-api/internal/model"
+	"go-tasks-api/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+// epoch is the creation time of the first task of a test, times are in UTC and microseconds
+// like the TIMESTAMP columns of tasks.tasks
+var epoch = time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+
+// TaskSuite is the contract of repository.TaskConnector. NewRepo is called before every test
+// and has to return a repository without tasks.
+type TaskSuite struct {
+	suite.Suite
+	NewRepo func() repository.TaskConnector
+
+	repo repository.TaskConnector
+	ctx  context.Context
+}
+
+func (s *TaskSuite) SetupTest() {
+	s.repo = s.NewRepo()
+	s.ctx = context.Background()
+}
+
+// create stores a task created the given number of minutes after epoch
+func (s *TaskSuite) create(title string, minutes int) model.Task {
+	task := model.Task{
+		ID:          uuid.New(),
+		Title:       title,
+		Description: "description of " + title,
+		CreatedAt:   epoch.Add(time.Duration(minutes) * time.Minute),
+	}
+	created, err := s.repo.Create(s.ctx, task)
+	s.Require().NoError(err)
+	s.Require().True(created)
+	task.Status = enum.Status_Todo
+
+	return task
+}
+
+// complete marks the task as done the given number of minutes after epoch
+func (s *TaskSuite) complete(task model.Task, minutes int) model.Task {
+	updatedAt := epoch.Add(time.Duration(minutes) * time.Minute)
+	task.Status = enum.Status_Done
+	task.UpdatedAt = &updatedAt
+
+	updated, err := s.repo.Update(s.ctx, task)
+	s.Require().NoError(err)
+
+	return updated
+}
+
+// equalTask compares tasks field by field, times are equal when they denote the same instant
+func (s *TaskSuite) equalTask(expected, actual model.Task) {
+	s.Equal(expected.ID, actual.ID)
+	s.Equal(expected.Title, actual.Title)
+	s.Equal(expected.Description, actual.Description)
+	s.Equal(expected.Status, actual.Status)
+	s.Truef(expected.CreatedAt.Equal(actual.CreatedAt), "created_at %s, expected %s", actual.CreatedAt, expected.CreatedAt)
+
+	if expected.UpdatedAt == nil {
+		s.Nil(actual.UpdatedAt)
+
+		return
+	}
+	if s.NotNil(actual.UpdatedAt) {
+		s.Truef(expected.UpdatedAt.Equal(*actual.UpdatedAt), "updated_at %s, expected %s", *actual.UpdatedAt, *expected.UpdatedAt)
+	}
+}
+
+// ids lists the tasks matching the options by ID
+func (s *TaskSuite) ids(opts repository.ListOptions) []uuid.UUID {
+	tasks, err := s.repo.List(s.ctx, opts)
+	s.Require().NoError(err)
+
+	ids := make([]uuid.UUID, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+
+	return ids
+}
+
+func (s *TaskSuite) TestCreateAndGet() {
+	task := s.create("write report", 0)
+
+	got, err := s.repo.Get(s.ctx, task.ID.String())
+	s.Require().NoError(err)
+	s.equalTask(task, got)
+}
+
+func (s *TaskSuite) TestCreateTakesDefaultsOfStatusAndUpdatedAt() {
+	updatedAt := epoch.Add(time.Hour)
+	task := model.Task{
+		ID:        uuid.New(),
+		Title:     "write report",
+		Status:    enum.Status_Done,
+		CreatedAt: epoch,
+		UpdatedAt: &updatedAt,
+	}
+	_, err := s.repo.Create(s.ctx, task)
+	s.Require().NoError(err)
+
+	got, err := s.repo.Get(s.ctx, task.ID.String())
+	s.Require().NoError(err)
+	s.Equal(enum.Status_Todo, got.Status)
+	s.Nil(got.UpdatedAt)
+}
+
+func (s *TaskSuite) TestCreateExistingIDKeepsTask() {
+	task := s.create("write report", 0)
+
+	again := task
+	again.Title = "another title"
+	created, err := s.repo.Create(s.ctx, again)
+	s.Require().NoError(err)
+	s.False(created)
+
+	got, err := s.repo.Get(s.ctx, task.ID.String())
+	s.Require().NoError(err)
+	s.equalTask(task, got)
+}
+
+func (s *TaskSuite) TestCreateConcurrently() {
+	const tasks = 50
+
+	var wg sync.WaitGroup
+	errs := make(chan error, tasks)
+	for i := range tasks {
+		wg.Go(func() {
+			_, err := s.repo.Create(s.ctx, model.Task{
+				ID:        uuid.New(),
+				Title:     "task",
+				CreatedAt: epoch.Add(time.Duration(i) * time.Second),
+			})
+			errs <- err
+		})
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		s.Require().NoError(err)
+	}
+	s.Len(s.ids(repository.ListOptions{}), tasks)
+}
+
+func (s *TaskSuite) TestGetUnknownTask() {
+	_, err := s.repo.Get(s.ctx, uuid.NewString())
+	s.ErrorIs(err, repository.ErrNoRows)
+}
+
+func (s *TaskSuite) TestGetMalformedID() {
+	_, err := s.repo.Get(s.ctx, "not-a-uuid")
+	s.Equal(apperr.Validation, apperr.KindOf(err))
+}
+
+func (s *TaskSuite) TestUpdate() {
+	task := s.create("write report", 0)
+
+	updatedAt := epoch.Add(time.Hour)
+	task.Title = "write the report"
+	task.Description = "with figures"
+	task.Status = enum.Status_Done
+	task.UpdatedAt = &updatedAt
+
+	// the creation time cannot be changed
+	change := task
+	change.CreatedAt = epoch.Add(time.Minute)
+
+	updated, err := s.repo.Update(s.ctx, change)
+	s.Require().NoError(err)
+	s.equalTask(task, updated)
+
+	got, err := s.repo.Get(s.ctx, task.ID.String())
+	s.Require().NoError(err)
+	s.equalTask(task, got)
+}
+
+func (s *TaskSuite) TestUpdateUnknownTask() {
+	_, err := s.repo.Update(s.ctx, model.Task{ID: uuid.New(), Title: "write report"})
+	s.ErrorIs(err, repository.ErrNoRows)
+}
+
+func (s *TaskSuite) TestDeleteIsSoft() {
+	task := s.create("write report", 0)
+	kept := s.create("keep", 1)
+
+	s.Require().NoError(s.repo.Delete(s.ctx, task.ID.String()))
+
+	_, err := s.repo.Get(s.ctx, task.ID.String())
+	s.ErrorIs(err, repository.ErrNoRows)
+
+	_, err = s.repo.Update(s.ctx, task)
+	s.ErrorIs(err, repository.ErrNoRows)
+
+	s.ErrorIs(s.repo.Delete(s.ctx, task.ID.String()), repository.ErrNoRows)
+	s.Equal([]uuid.UUID{kept.ID}, s.ids(repository.ListOptions{}))
+
+	// the deleted row still holds the ID
+	created, err := s.repo.Create(s.ctx, task)
+	s.Require().NoError(err)
+	s.False(created)
+	_, err = s.repo.Get(s.ctx, task.ID.String())
+	s.ErrorIs(err, repository.ErrNoRows)
+}
+
+func (s *TaskSuite) TestDeleteUnknownTask() {
+	s.ErrorIs(s.repo.Delete(s.ctx, uuid.NewString()), repository.ErrNoRows)
+}
+
+func (s *TaskSuite) TestDeleteMalformedID() {
+	s.Equal(apperr.Validation, apperr.KindOf(s.repo.Delete(s.ctx, "not-a-uuid")))
+}
+
+func (s *TaskSuite) TestListEmpty() {
+	tasks, err := s.repo.List(s.ctx, repository.ListOptions{})
+	s.Require().NoError(err)
+	s.NotNil(tasks)
+	s.Empty(tasks)
+}
+
+func (s *TaskSuite) TestListOrdersByCreationAndID() {
+	later := s.create("later", 2)
+	first := s.create("first", 0)
+	// tasks created at the same time are ordered by ID
+	tie := []model.Task{s.create("tie", 1), s.create("tie", 1)}
+	if tie[0].ID.String() > tie[1].ID.String() {
+		tie[0], tie[1] = tie[1], tie[0]
+	}
+
+	s.Equal([]uuid.UUID{first.ID, tie[0].ID, tie[1].ID, later.ID}, s.ids(repository.ListOptions{}))
+}
+
+func (s *TaskSuite) TestListReturnsFullTasks() {
+	task := s.complete(s.create("write report", 0), 5)
+
+	tasks, err := s.repo.List(s.ctx, repository.ListOptions{})
+	s.Require().NoError(err)
+	s.Require().Len(tasks, 1)
+	s.equalTask(task, tasks[0])
+}
+
+func (s *TaskSuite) TestListSort() {
+	b := s.create("b", 0)
+	a := s.create("a", 1)
+	c := s.create("c", 2)
+	s.complete(c, 10)
+	s.complete(b, 20)
+
+	cases := []struct {
+		sort     []repository.SortKey
+		expected []uuid.UUID
+	}{
+		{[]repository.SortKey{{Field: "title"}}, []uuid.UUID{a.ID, b.ID, c.ID}},
+		{[]repository.SortKey{{Field: "title", Desc: true}}, []uuid.UUID{c.ID, b.ID, a.ID}},
+		{[]repository.SortKey{{Field: "created_at", Desc: true}}, []uuid.UUID{c.ID, a.ID, b.ID}},
+		// null is larger than any value
+		{[]repository.SortKey{{Field: "updated_at"}}, []uuid.UUID{c.ID, b.ID, a.ID}},
+		{[]repository.SortKey{{Field: "updated_at", Desc: true}}, []uuid.UUID{a.ID, b.ID, c.ID}},
+		// ties keep the order of creation
+		{[]repository.SortKey{{Field: "status"}}, []uuid.UUID{b.ID, c.ID, a.ID}},
+		{[]repository.SortKey{{Field: "status", Desc: true}, {Field: "title", Desc: true}}, []uuid.UUID{a.ID, c.ID, b.ID}},
+	}
+
+	for _, tc := range cases {
+		s.Equalf(tc.expected, s.ids(repository.ListOptions{Sort: tc.sort}), "sort %v", tc.sort)
+	}
+}
+
+func (s *TaskSuite) TestListFilter() {
+	report := s.create("Write report", 0)
+	invoice := s.create("pay invoice", 60)
+	call := s.create("call back", 120)
+	s.complete(invoice, 180)
+	deleted := s.create("deleted report", 30)
+	s.Require().NoError(s.repo.Delete(s.ctx, deleted.ID.String()))
+
+	cases := []struct {
+		filter   string
+		expected []uuid.UUID
+	}{
+		{"title eq 'pay invoice'", []uuid.UUID{invoice.ID}},
+		{"title contains 'REPORT'", []uuid.UUID{report.ID}},
+		{"title ne 'call back'", []uuid.UUID{report.ID, invoice.ID}},
+		{"status eq 'done'", []uuid.UUID{invoice.ID}},
+		{"id eq '" + call.ID.String() + "'", []uuid.UUID{call.ID}},
+		{"created_at ge 2026-01-01T10:00:00Z", []uuid.UUID{invoice.ID, call.ID}},
+		{"created_at lt 2026-01-01T10:00:00Z or status eq 'done'", []uuid.UUID{report.ID, invoice.ID}},
+		{"not (title contains 'a' and created_at gt 2026-01-01)", []uuid.UUID{report.ID}},
+		// comparisons with null are unknown, not leaves them unknown
+		{"updated_at lt 2026-01-02", []uuid.UUID{invoice.ID}},
+		{"not (updated_at lt 2026-01-02)", []uuid.UUID{}},
+		{"updated_at eq null", []uuid.UUID{report.ID, call.ID}},
+		{"updated_at ne null", []uuid.UUID{invoice.ID}},
+		{"updated_at ne 2026-01-01T12:00:00Z", []uuid.UUID{report.ID, call.ID}},
+		// timestamps with an offset compare as the same instant in UTC
+		{"created_at ge 2026-01-01T12:00:00+02:00", []uuid.UUID{invoice.ID, call.ID}},
+		{"updated_at eq 2026-01-01T07:00:00-05:00", []uuid.UUID{invoice.ID}},
+		{"updated_at eq null or updated_at gt 2026-01-02", []uuid.UUID{report.ID, call.ID}},
+	}
+
+	for _, tc := range cases {
+		expr, err := filter.Parse(tc.filter, repository.TaskFilterFields)
+		s.Require().NoError(err, tc.filter)
+		s.Equalf(tc.expected, s.ids(repository.ListOptions{Filter: expr}), "filter %s", tc.filter)
+	}
+}
+
+func (s *TaskSuite) TestListFields() {
+	task := s.create("write report", 0)
+
+	tasks, err := s.repo.List(s.ctx, repository.ListOptions{Fields: []string{"title", "status"}})
+	s.Require().NoError(err)
+	s.Require().Len(tasks, 1)
+	s.equalTask(model.Task{Title: task.Title, Status: task.Status}, tasks[0])
+}
+
+func (s *TaskSuite) TestStreamStopsAtError() {
+	s.create("first", 0)
+	s.create("second", 1)
+
+	stop := errors.New("stop")
+	calls := 0
+	err := s.repo.Stream(s.ctx, repository.ListOptions{}, func(model.Task) error {
+		calls++
+
+		return stop
+	})
+	s.ErrorIs(err, stop)
+	s.Equal(1, calls)
+}
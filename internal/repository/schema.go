@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"go-tasks-api/internal/database"
+)
+
+type schemaRepo struct {
+	db        *sql.DB
+	tableName string
+}
+
+//go:generate go run -mod=mod go.uber.org/mock/mockgen -package mocks -destination=./mocks/schema_mock.go -source=schema.go
+type SchemaConnector interface {
+	// Version returns the latest migration version applied to the database
+	Version(ctx context.Context) (int64, error)
+}
+
+// NewSchemaRepo creates a new Schema repository reading the goose version table tableName
+func NewSchemaRepo(db *sql.DB, tableName string) SchemaConnector {
+	return &schemaRepo{
+		db:        db,
+		tableName: tableName,
+	}
+}
+
+func (a *schemaRepo) Version(ctx context.Context) (int64, error) {
+	return database.SchemaVersion(ctx, a.db, a.tableName)
+}
@@ -5,45 +5,123 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 
+	"go-tasks-api/internal/database"
+	"go-tasks-api/internal/encryption"
+	"go-tasks-api/internal/enum"
+	"go-tasks-api/internal/filter"
 	"go-tasks-api/internal/model"
+
+	"github.com/google/uuid"
 )
 
+// TaskFilterFields are the fields tasks can be filtered by, the description is encrypted and
+// cannot be compared in SQL
+var TaskFilterFields = filter.Fields{
+	"id": {
+		Column: "id",
+		Type:   filter.UUID,
+		Ops:    []filter.Op{filter.OpEq, filter.OpNe},
+	},
+	"title": {
+		Column: "title",
+		Type:   filter.String,
+		Ops:    []filter.Op{filter.OpEq, filter.OpNe, filter.OpContains},
+	},
+	"status": {
+		Column: "status",
+		Type:   filter.Enum,
+		Ops:    []filter.Op{filter.OpEq, filter.OpNe},
+		Values: enum.StatusTypeStrings(),
+	},
+	"created_at": {
+		Column: "created_at",
+		Type:   filter.Time,
+		Ops:    []filter.Op{filter.OpEq, filter.OpNe, filter.OpGt, filter.OpGe, filter.OpLt, filter.OpLe},
+	},
+	"updated_at": {
+		Column:   "updated_at",
+		Type:     filter.Time,
+		Ops:      []filter.Op{filter.OpEq, filter.OpNe, filter.OpGt, filter.OpGe, filter.OpLt, filter.OpLe},
+		Nullable: true,
+	},
+}
+
 type taskRepo struct {
-	db *sql.DB
+	db       *sql.DB
+	cipher   encryption.Cipher
+	replicas *database.Replicas
 }
 
+// TaskConnector methods run on the transaction of their context, see database.TxManager
+//
 //go:generate go run -mod=mod go.uber.org/mock/mockgen -package mocks -destination=./mocks/task_mock.go -source=task.go
 type TaskConnector interface {
-	Create(ctx context.Context, a model.Task) error
+	// Create inserts the task and reports whether it did, an existing task of the same ID, even
+	// a deleted one, is left unchanged
+	Create(ctx context.Context, a model.Task) (bool, error)
 	Get(ctx context.Context, id string) (model.Task, error)
-	List(ctx context.Context) ([]model.Task, error)
+	List(ctx context.Context, opts ListOptions) ([]model.Task, error)
+	// Stream calls fn for every task in the order of List while reading them from the cursor,
+	// it stops at and returns the first error of fn
+	Stream(ctx context.Context, opts ListOptions, fn func(model.Task) error) error
 	Update(ctx context.Context, task model.Task) (model.Task, error)
 	Delete(ctx context.Context, id string) error
 }
 
-// NewTaskRepo creates a new Task repository
-func NewTaskRepo(db *sql.DB) TaskConnector {
+// NewTaskRepo creates a new Task repository, sensitive fields are encrypted with the given cipher.
+// Get, List and Stream read from the replicas when they are in sync, replicas may be nil.
+func NewTaskRepo(db *sql.DB, cipher encryption.Cipher, replicas *database.Replicas) TaskConnector {
 	return &taskRepo{
-		db,
+		db:       db,
+		cipher:   cipher,
+		replicas: replicas,
 	}
 }
 
-func (a *taskRepo) Create(ctx context.Context, task model.Task) error {
+func (a *taskRepo) Create(ctx context.Context, task model.Task) (bool, error) {
 	insertSQL := `INSERT INTO tasks.tasks (id, title, description, created_at) values ($1, $2, $3, $4) ON CONFLICT (id) DO NOTHING;`
 
-	_, err := a.db.ExecContext(ctx, insertSQL, task.ID.String(), task.Title, task.Description, task.CreatedAt)
+	encrypted, err := a.encrypt(task)
 	if err != nil {
-		return fmt.Errorf("failed to insert task: %w", err)
+		return false, err
 	}
 
-	return nil
+	tx, err := database.Begin(ctx, a.db)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx, insertSQL, encrypted.ID.String(), encrypted.Title, encrypted.Description, encrypted.CreatedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to insert task: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	// a conflicting insert changed nothing, so there is nothing to publish
+	if rows > 0 {
+		if err := writeOutbox(ctx, tx, a.cipher, model.EventTaskCreated, task); err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return rows > 0, nil
 }
 
 func (a *taskRepo) Get(ctx context.Context, id string) (model.Task, error) {
 	getTaskSQL := `SELECT id, title, description, status, created_at, updated_at FROM tasks.tasks where id = $1 AND is_active = true;`
 
-	rows := a.db.QueryRowContext(ctx, getTaskSQL, id)
+	rows := a.replicas.Reader(ctx, a.db).QueryRowContext(ctx, getTaskSQL, id)
 	if rows.Err() != nil {
 		return model.Task{}, fmt.Errorf("failed to query task: %w", rows.Err())
 	}
@@ -63,40 +141,86 @@ func (a *taskRepo) Get(ctx context.Context, id string) (model.Task, error) {
 		return model.Task{}, fmt.Errorf("failed to scan task: %w", err)
 	}
 
+	if err := a.decrypt(&task); err != nil {
+		return model.Task{}, err
+	}
+
 	return task, nil
 }
 
-func (a *taskRepo) List(ctx context.Context) ([]model.Task, error) {
-	listSQL := `SELECT id, title, description, status, created_at, updated_at FROM tasks.tasks WHERE is_active = true;`
+func (a *taskRepo) List(ctx context.Context, opts ListOptions) ([]model.Task, error) {
+	tasks := make([]model.Task, 0)
+	if err := a.Stream(ctx, opts, func(task model.Task) error {
+		tasks = append(tasks, task)
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+func (a *taskRepo) Stream(ctx context.Context, opts ListOptions, fn func(model.Task) error) error {
+	columns := opts.columns()
+	names := make([]string, 0, len(columns))
+	for _, c := range columns {
+		names = append(names, c.column)
+	}
 
-	rows, err := a.db.QueryContext(ctx, listSQL)
+	listSQL := `SELECT ` + strings.Join(names, ", ") + ` FROM tasks.tasks WHERE is_active = true`
+
+	var args []any
+	if opts.Filter != nil {
+		cond, filterArgs, err := filter.SQL(opts.Filter, TaskFilterFields, args)
+		if err != nil {
+			return fmt.Errorf("failed to compile filter: %w", err)
+		}
+		listSQL += " AND " + cond
+		args = filterArgs
+	}
+
+	// a stable order keeps the ETag of unchanged lists stable
+	orderBy, err := opts.orderBy()
 	if err != nil {
-		return nil, fmt.Errorf("failed to list tasks: %w", err)
+		return err
+	}
+	listSQL += " " + orderBy + ";"
+
+	rows, err := a.replicas.Reader(ctx, a.db).QueryContext(ctx, listSQL, args...)
+	if err != nil {
+		return fmt.Errorf("failed to list tasks: %w", err)
 	}
 	defer rows.Close()
 
-	tasks := make([]model.Task, 0)
 	for rows.Next() {
 		var task model.Task
-		if err := rows.Scan(
-			&task.ID,
-			&task.Title,
-			&task.Description,
-			&task.Status,
-			&task.CreatedAt,
-			&task.UpdatedAt,
-		); err != nil {
-			return nil, fmt.Errorf("failed to scan task: %w", err)
+		dest := make([]any, 0, len(columns))
+		for _, c := range columns {
+			dest = append(dest, c.dest(&task))
 		}
 
-		tasks = append(tasks, task)
+		if err := rows.Scan(dest...); err != nil {
+			return fmt.Errorf("failed to scan task: %w", err)
+		}
+
+		if opts.selects("description") {
+			if err := a.decrypt(&task); err != nil {
+				return err
+			}
+			task = opts.project(task)
+		}
+
+		if err := fn(task); err != nil {
+			return err
+		}
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("row iteration error: %w", err)
+		return fmt.Errorf("row iteration error: %w", err)
 	}
 
-	return tasks, nil
+	return nil
 }
 
 func (a *taskRepo) Update(ctx context.Context, task model.Task) (model.Task, error) {
@@ -106,12 +230,23 @@ func (a *taskRepo) Update(ctx context.Context, task model.Task) (model.Task, err
 		    description = $3,
 		    status = $4,
 		    updated_at = $5
-		WHERE id = $1
+		WHERE id = $1 AND is_active = true
 		RETURNING id, title, description, status, created_at, updated_at;
 	`
 
+	task, err := a.encrypt(task)
+	if err != nil {
+		return model.Task{}, err
+	}
+
+	tx, err := database.Begin(ctx, a.db)
+	if err != nil {
+		return model.Task{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
 	var updated model.Task
-	err := a.db.QueryRowContext(
+	err = tx.QueryRowContext(
 		ctx,
 		updateSQL,
 		task.ID.String(),
@@ -134,25 +269,73 @@ func (a *taskRepo) Update(ctx context.Context, task model.Task) (model.Task, err
 		return model.Task{}, fmt.Errorf("failed to update task: %w", err)
 	}
 
+	if err := a.decrypt(&updated); err != nil {
+		return model.Task{}, err
+	}
+
+	if err := writeOutbox(ctx, tx, a.cipher, model.EventTaskUpdated, updated); err != nil {
+		return model.Task{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return model.Task{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return updated, nil
 }
 
 func (a *taskRepo) Delete(ctx context.Context, id string) error {
-	deleteSQL := `UPDATE tasks.tasks SET is_active = false WHERE id = $1;`
+	deleteSQL := `UPDATE tasks.tasks SET is_active = false WHERE id = $1 AND is_active = true RETURNING id;`
 
-	res, err := a.db.ExecContext(ctx, deleteSQL, id)
+	tx, err := database.Begin(ctx, a.db)
 	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var deleted model.Task
+	if err := tx.QueryRowContext(ctx, deleteSQL, id).Scan(&deleted.ID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRows
+		}
+
 		return fmt.Errorf("failed to delete task: %w", err)
 	}
 
-	rows, err := res.RowsAffected()
+	if err := writeOutbox(ctx, tx, a.cipher, model.EventTaskDeleted, deleted); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// encrypt returns a copy of the task with all sensitive fields encrypted
+func (a *taskRepo) encrypt(task model.Task) (model.Task, error) {
+	description, err := a.cipher.Encrypt(task.Description, descriptionData(task.ID))
 	if err != nil {
-		return fmt.Errorf("failed to get affected rows: %w", err)
+		return model.Task{}, fmt.Errorf("failed to encrypt task description: %w", err)
 	}
+	task.Description = description
 
-	if rows == 0 {
-		return ErrNoRows
+	return task, nil
+}
+
+// decrypt decrypts all sensitive fields of the task in place
+func (a *taskRepo) decrypt(task *model.Task) error {
+	description, err := a.cipher.Decrypt(task.Description, descriptionData(task.ID))
+	if err != nil {
+		return fmt.Errorf("failed to decrypt task description: %w", err)
 	}
+	task.Description = description
 
 	return nil
 }
+
+// descriptionData returns the additional data the description of the task is encrypted with
+func descriptionData(id uuid.UUID) string {
+	return encryption.AdditionalData("tasks.tasks", "description", id.String())
+}
@@ -8,7 +8,10 @@ import (
 	"testing"
 	"time"
 
+	"go-tasks-api/internal/database"
+	"go-tasks-api/internal/encryption"
 	"go-tasks-api/internal/enum"
+	"go-tasks-api/internal/filter"
 	"go-tasks-api/internal/model"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -31,7 +34,7 @@ func (s *taskSuite) SetupTest() {
 	db, mock, err := sqlmock.New()
 	require.NoError(s.T(), err)
 
-	s.repo = NewTaskRepo(db)
+	s.repo = NewTaskRepo(db, encryption.NoopCipher{}, nil)
 	s.db = mock
 }
 
@@ -49,6 +52,7 @@ func (s *taskSuite) TestCreateSuccess() {
 		CreatedAt: now,
 	}
 
+	s.db.ExpectBegin()
 	s.db.ExpectExec(regexp.QuoteMeta(`INSERT INTO tasks.tasks (id, title, description, created_at) 
 										values ($1, $2, $3, $4) ON CONFLICT (id) DO NOTHING;`)).
 		WithArgs(
@@ -57,9 +61,16 @@ func (s *taskSuite) TestCreateSuccess() {
 			request.Description,
 			request.CreatedAt,
 		).WillReturnResult(sqlmock.NewResult(1, 1))
+	s.db.ExpectQuery(regexp.QuoteMeta(`SELECT nextval('tasks.outbox_id_seq');`)).
+		WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(1))
+	s.db.ExpectExec(regexp.QuoteMeta(`INSERT INTO tasks.outbox (id, aggregate_type, aggregate_id, event_type, status, payload) values ($1, $2, $3, $4, $5, $6);`)).
+		WithArgs(int64(1), model.AggregateTask, request.ID.String(), model.EventTaskCreated, nil, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	s.db.ExpectCommit()
 
-	err := s.repo.Create(ctx, request)
+	created, err := s.repo.Create(ctx, request)
 	s.NoError(err)
+	s.True(created)
 }
 
 func (s *taskSuite) TestCreateError() {
@@ -74,6 +85,7 @@ func (s *taskSuite) TestCreateError() {
 		CreatedAt: now,
 	}
 
+	s.db.ExpectBegin()
 	s.db.ExpectExec(regexp.QuoteMeta(`INSERT INTO tasks.tasks (id, title, description, created_at) 
 										values ($1, $2, $3, $4) ON CONFLICT (id) DO NOTHING;`)).
 		WithArgs(
@@ -82,12 +94,30 @@ func (s *taskSuite) TestCreateError() {
 			request.Description,
 			request.CreatedAt,
 		).WillReturnError(mockError)
+	s.db.ExpectRollback()
 
-	err := s.repo.Create(ctx, request)
+	_, err := s.repo.Create(ctx, request)
 	s.Error(err)
 	s.True(errors.Is(err, mockError))
 }
 
+func (s *taskSuite) TestCreateConflictWritesNoOutbox() {
+	request := model.Task{
+		ID:        uuid.New(),
+		Title:     "doc",
+		CreatedAt: time.Now(),
+	}
+
+	s.db.ExpectBegin()
+	s.db.ExpectExec(regexp.QuoteMeta(`INSERT INTO tasks.tasks`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	s.db.ExpectCommit()
+
+	created, err := s.repo.Create(context.Background(), request)
+	s.NoError(err)
+	s.False(created)
+}
+
 func (s *taskSuite) TestGetTaskSuccess() {
 	ctx := context.Background()
 	now := time.Now()
@@ -164,7 +194,7 @@ func (s *taskSuite) TestListTasksSuccess() {
 			UpdatedAt:   &now,
 		},
 	}
-	s.db.ExpectQuery(regexp.QuoteMeta(`SELECT id, title, description, status, created_at, updated_at FROM tasks.tasks WHERE is_active = true;`)).
+	s.db.ExpectQuery(regexp.QuoteMeta(`SELECT id, title, description, status, created_at, updated_at FROM tasks.tasks WHERE is_active = true ORDER BY created_at, id;`)).
 		WillReturnRows(
 			sqlmock.NewRows(
 				[]string{
@@ -193,7 +223,7 @@ func (s *taskSuite) TestListTasksSuccess() {
 				),
 		)
 
-	got, err := s.repo.List(ctx)
+	got, err := s.repo.List(ctx, ListOptions{})
 	s.NoError(err)
 	s.Equal(got, expected)
 }
@@ -202,10 +232,10 @@ func (s *taskSuite) TestListTasksError() {
 	ctx := context.Background()
 	mockError := errors.New("db error")
 
-	s.db.ExpectQuery(regexp.QuoteMeta(`SELECT id, title, description, status, created_at, updated_at FROM tasks.tasks WHERE is_active = true;`)).
+	s.db.ExpectQuery(regexp.QuoteMeta(`SELECT id, title, description, status, created_at, updated_at FROM tasks.tasks WHERE is_active = true ORDER BY created_at, id;`)).
 		WillReturnError(mockError)
 
-	got, err := s.repo.List(ctx)
+	got, err := s.repo.List(ctx, ListOptions{})
 	s.Error(err)
 	s.True(errors.Is(err, mockError))
 	s.Nil(got)
@@ -214,7 +244,31 @@ func (s *taskSuite) TestListTasksError() {
 func (s *taskSuite) TestListTasksEmpty() {
 	ctx := context.Background()
 
-	s.db.ExpectQuery(regexp.QuoteMeta(`SELECT id, title, description, status, created_at, updated_at FROM tasks.tasks WHERE is_active = true;`)).
+	s.db.ExpectQuery(regexp.QuoteMeta(`SELECT id, title, description, status, created_at, updated_at FROM tasks.tasks WHERE is_active = true ORDER BY created_at, id;`)).
+		WillReturnRows(
+			sqlmock.NewRows(
+				[]string{
+					"id",
+					"title",
+					"description",
+					"status",
+					"created_at",
+					"updated_at",
+				}))
+
+	got, err := s.repo.List(ctx, ListOptions{})
+	s.NoError(err)
+	s.Empty(got)
+}
+
+func (s *taskSuite) TestListTasksFiltered() {
+	ctx := context.Background()
+
+	expr, err := filter.Parse(`status eq 'done' or title contains 'invoice'`, TaskFilterFields)
+	s.Require().NoError(err)
+
+	s.db.ExpectQuery(regexp.QuoteMeta(`SELECT id, title, description, status, created_at, updated_at FROM tasks.tasks WHERE is_active = true AND (status = $1 OR strpos(lower(title), lower($2)) > 0) ORDER BY created_at, id;`)).
+		WithArgs("done", "invoice").
 		WillReturnRows(
 			sqlmock.NewRows(
 				[]string{
@@ -226,11 +280,41 @@ func (s *taskSuite) TestListTasksEmpty() {
 					"updated_at",
 				}))
 
-	got, err := s.repo.List(ctx)
+	got, err := s.repo.List(ctx, ListOptions{Filter: expr})
 	s.NoError(err)
 	s.Empty(got)
 }
 
+func (s *taskSuite) TestStreamTasksStopsOnCallbackError() {
+	ctx := context.Background()
+	now := time.Now()
+	mockError := errors.New("client gone")
+
+	s.db.ExpectQuery(regexp.QuoteMeta(`SELECT id, title, description, status, created_at, updated_at FROM tasks.tasks WHERE is_active = true ORDER BY created_at, id;`)).
+		WillReturnRows(
+			sqlmock.NewRows(
+				[]string{
+					"id",
+					"title",
+					"description",
+					"status",
+					"created_at",
+					"updated_at",
+				}).
+				AddRow(uuid.New().String(), "test title 1", "test description 1", enum.Status_Todo, now, nil).
+				AddRow(uuid.New().String(), "test title 2", "test description 2", enum.Status_Todo, now, nil),
+		)
+
+	calls := 0
+	err := s.repo.Stream(ctx, ListOptions{}, func(model.Task) error {
+		calls++
+
+		return mockError
+	})
+	s.True(errors.Is(err, mockError))
+	s.Equal(1, calls)
+}
+
 func (s *taskSuite) TestGetTaskNoRows() {
 	ctx := context.Background()
 	mockUUID := uuid.New()
@@ -258,12 +342,13 @@ func (s *taskSuite) TestUpdateTaskSuccess() {
 		UpdatedAt:   &now,
 	}
 
+	s.db.ExpectBegin()
 	s.db.ExpectQuery(regexp.QuoteMeta(`UPDATE tasks.tasks
 		SET title = $2,
 		    description = $3,
 		    status = $4,
 		    updated_at = $5
-		WHERE id = $1
+		WHERE id = $1 AND is_active = true
 		RETURNING id, title, description, status, created_at, updated_at;`)).
 		WithArgs(
 			mockUUID.String(),
@@ -289,6 +374,12 @@ func (s *taskSuite) TestUpdateTaskSuccess() {
 				mockTask.CreatedAt,
 				mockTask.UpdatedAt,
 			))
+	s.db.ExpectQuery(regexp.QuoteMeta(`SELECT nextval('tasks.outbox_id_seq');`)).
+		WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(1))
+	s.db.ExpectExec(regexp.QuoteMeta(`INSERT INTO tasks.outbox (id, aggregate_type, aggregate_id, event_type, status, payload) values ($1, $2, $3, $4, $5, $6);`)).
+		WithArgs(int64(1), model.AggregateTask, mockUUID.String(), model.EventTaskUpdated, mockTask.Status, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	s.db.ExpectCommit()
 
 	task, err := s.repo.Update(ctx, mockTask)
 	s.NoError(err)
@@ -308,12 +399,13 @@ func (s *taskSuite) TestUpdateTaskError() {
 		UpdatedAt:   &now,
 	}
 
+	s.db.ExpectBegin()
 	s.db.ExpectQuery(regexp.QuoteMeta(`UPDATE tasks.tasks
 		SET title = $2,
 		    description = $3,
 		    status = $4,
 		    updated_at = $5
-		WHERE id = $1
+		WHERE id = $1 AND is_active = true
 		RETURNING id, title, description, status, created_at, updated_at;`)).
 		WithArgs(
 			mockUUID.String(),
@@ -323,6 +415,7 @@ func (s *taskSuite) TestUpdateTaskError() {
 			mockTask.UpdatedAt,
 		).
 		WillReturnError(errors.New("db error"))
+	s.db.ExpectRollback()
 
 	task, err := s.repo.Update(ctx, mockTask)
 	s.Error(err)
@@ -333,9 +426,16 @@ func (s *taskSuite) TestDeleteTaskSuccess() {
 	ctx := context.Background()
 	mockUUID := uuid.New()
 
-	s.db.ExpectExec(regexp.QuoteMeta(`UPDATE tasks.tasks SET is_active = false WHERE id = $1;`)).
+	s.db.ExpectBegin()
+	s.db.ExpectQuery(regexp.QuoteMeta(`UPDATE tasks.tasks SET is_active = false WHERE id = $1 AND is_active = true RETURNING id;`)).
 		WithArgs(mockUUID.String()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(mockUUID.String()))
+	s.db.ExpectQuery(regexp.QuoteMeta(`SELECT nextval('tasks.outbox_id_seq');`)).
+		WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(1))
+	s.db.ExpectExec(regexp.QuoteMeta(`INSERT INTO tasks.outbox (id, aggregate_type, aggregate_id, event_type, status, payload) values ($1, $2, $3, $4, $5, $6);`)).
+		WithArgs(int64(1), model.AggregateTask, mockUUID.String(), model.EventTaskDeleted, nil, sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	s.db.ExpectCommit()
 
 	err := s.repo.Delete(ctx, mockUUID.String())
 	s.NoError(err)
@@ -345,10 +445,113 @@ func (s *taskSuite) TestDeleteTaskError() {
 	ctx := context.Background()
 	mockUUID := uuid.New()
 
-	s.db.ExpectExec(regexp.QuoteMeta(`UPDATE tasks.tasks SET is_active = false WHERE id = $1;`)).
+	s.db.ExpectBegin()
+	s.db.ExpectQuery(regexp.QuoteMeta(`UPDATE tasks.tasks SET is_active = false WHERE id = $1 AND is_active = true RETURNING id;`)).
 		WithArgs(mockUUID.String()).
 		WillReturnError(errors.New("db error"))
+	s.db.ExpectRollback()
 
 	err := s.repo.Delete(ctx, mockUUID.String())
 	s.Error(err)
 }
+
+func (s *taskSuite) TestDeleteTaskNotFound() {
+	mockUUID := uuid.New()
+
+	s.db.ExpectBegin()
+	s.db.ExpectQuery(regexp.QuoteMeta(`UPDATE tasks.tasks SET is_active = false WHERE id = $1 AND is_active = true RETURNING id;`)).
+		WithArgs(mockUUID.String()).
+		WillReturnError(sql.ErrNoRows)
+	s.db.ExpectRollback()
+
+	err := s.repo.Delete(context.Background(), mockUUID.String())
+	s.ErrorIs(err, ErrNoRows)
+}
+
+func TestGetTaskDecryptsDescription(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	keyring, err := encryption.NewKeyring("k1", map[string][]byte{"k1": []byte("0123456789abcdef0123456789abcdef")})
+	require.NoError(t, err)
+	cipher := encryption.NewEnvelope(keyring)
+
+	mockUUID := uuid.New()
+	encrypted, err := cipher.Encrypt("customer data", descriptionData(mockUUID))
+	require.NoError(t, err)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, title, description, status, created_at, updated_at FROM tasks.tasks where id = $1 AND is_active = true;`)).
+		WithArgs(mockUUID.String()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "description", "status", "created_at", "updated_at"}).
+			AddRow(mockUUID.String(), "title", encrypted, enum.Status_Todo, time.Now(), nil))
+
+	got, err := NewTaskRepo(db, cipher, nil).Get(context.Background(), mockUUID.String())
+	require.NoError(t, err)
+	require.Equal(t, "customer data", got.Description)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTaskChangesJoinTransaction(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	repo := NewTaskRepo(db, encryption.NoopCipher{}, nil)
+	id := uuid.New()
+
+	// one transaction holds the read, the update and its outbox message
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM tasks.tasks where id = $1 AND is_active = true;`)).
+		WithArgs(id.String()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "description", "status", "created_at", "updated_at"}).
+			AddRow(id.String(), "title", "", enum.Status_Todo, time.Now(), nil))
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE tasks.tasks`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "description", "status", "created_at", "updated_at"}).
+			AddRow(id.String(), "title", "", enum.Status_Done, time.Now(), time.Now()))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT nextval('tasks.outbox_id_seq');`)).
+		WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO tasks.outbox`)).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err = database.NewTxManager(db, database.TxConfig{MaxAttempts: 1}).InTx(context.Background(), func(ctx context.Context) error {
+		task, err := repo.Get(ctx, id.String())
+		if err != nil {
+			return err
+		}
+
+		task.Status = enum.Status_Done
+		_, err = repo.Update(ctx, task)
+
+		return err
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTaskReadsFromReplica(t *testing.T) {
+	primary, primaryMock, err := sqlmock.New()
+	require.NoError(t, err)
+	replica, replicaMock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	replicas := database.NewReplicas([]*sql.DB{replica}, database.ReplicaConfig{MaxLag: time.Second, CheckInterval: time.Second})
+	replicaMock.ExpectQuery("pg_last_xact_replay_timestamp").WillReturnRows(sqlmock.NewRows([]string{"lag"}).AddRow(0))
+	replicas.Check(context.Background())
+
+	repo := NewTaskRepo(primary, encryption.NoopCipher{}, replicas)
+	id := uuid.New()
+	columns := []string{"id", "title", "description", "status", "created_at", "updated_at"}
+
+	replicaMock.ExpectQuery(regexp.QuoteMeta(`FROM tasks.tasks where id = $1`)).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(id.String(), "title", "", enum.Status_Todo, time.Now(), nil))
+	_, err = repo.Get(context.Background(), id.String())
+	require.NoError(t, err)
+
+	// reads which have to see the latest writes go to the primary
+	primaryMock.ExpectQuery(regexp.QuoteMeta(`FROM tasks.tasks where id = $1`)).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(id.String(), "title", "", enum.Status_Todo, time.Now(), nil))
+	_, err = repo.Get(database.WithPrimary(context.Background()), id.String())
+	require.NoError(t, err)
+
+	require.NoError(t, replicaMock.ExpectationsWereMet())
+	require.NoError(t, primaryMock.ExpectationsWereMet())
+}
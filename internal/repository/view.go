@@ -0,0 +1,242 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"go-tasks-api/internal/database"
+	"go-tasks-api/internal/model"
+
+	"github.com/lib/pq"
+)
+
+type viewRepo struct {
+	db *sql.DB
+}
+
+//go:generate go run -mod=mod go.uber.org/mock/mockgen -package mocks -destination=./mocks/view_mock.go -source=view.go
+type ViewConnector interface {
+	// Create stores the view, a default view replaces the previous default of its owner
+	Create(ctx context.Context, view model.View) error
+	Get(ctx context.Context, id string) (model.View, error)
+	// GetDefault returns the default view of the owner
+	GetDefault(ctx context.Context, ownerID string) (model.View, error)
+	// ListFor returns the views owned by or shared with the user
+	ListFor(ctx context.Context, userID string) ([]model.View, error)
+	// Update replaces the view, a default view replaces the previous default of its owner
+	Update(ctx context.Context, view model.View) (model.View, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// NewViewRepo creates a new View repository
+func NewViewRepo(db *sql.DB) ViewConnector {
+	return &viewRepo{
+		db: db,
+	}
+}
+
+func (a *viewRepo) Create(ctx context.Context, view model.View) error {
+	insertSQL := `INSERT INTO tasks.views (id, owner_id, name, definition, shared_with, is_default, created_at) values ($1, $2, $3, $4, $5, $6, $7);`
+
+	definition, err := json.Marshal(view.ViewDefinition)
+	if err != nil {
+		return fmt.Errorf("failed to marshal view definition: %w", err)
+	}
+
+	tx, err := database.Begin(ctx, a.db)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if view.Default {
+		if err := clearDefault(ctx, tx, view); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, insertSQL,
+		view.ID.String(),
+		view.OwnerID,
+		view.Name,
+		definition,
+		pq.Array(view.SharedWith),
+		view.Default,
+		view.CreatedAt,
+	); err != nil {
+		return fmt.Errorf("failed to insert view: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (a *viewRepo) Get(ctx context.Context, id string) (model.View, error) {
+	getSQL := `SELECT id, owner_id, name, definition, shared_with, is_default, created_at, updated_at FROM tasks.views WHERE id = $1;`
+
+	view, err := scanView(database.Conn(ctx, a.db).QueryRowContext(ctx, getSQL, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.View{}, ErrNoRows
+		}
+
+		return model.View{}, fmt.Errorf("failed to get view: %w", err)
+	}
+
+	return view, nil
+}
+
+func (a *viewRepo) GetDefault(ctx context.Context, ownerID string) (model.View, error) {
+	getSQL := `SELECT id, owner_id, name, definition, shared_with, is_default, created_at, updated_at FROM tasks.views WHERE owner_id = $1 AND is_default = true;`
+
+	view, err := scanView(database.Conn(ctx, a.db).QueryRowContext(ctx, getSQL, ownerID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.View{}, ErrNoRows
+		}
+
+		return model.View{}, fmt.Errorf("failed to get default view: %w", err)
+	}
+
+	return view, nil
+}
+
+func (a *viewRepo) ListFor(ctx context.Context, userID string) ([]model.View, error) {
+	listSQL := `SELECT id, owner_id, name, definition, shared_with, is_default, created_at, updated_at FROM tasks.views WHERE owner_id = $1 OR $1 = ANY(shared_with) ORDER BY name, id;`
+
+	rows, err := database.Conn(ctx, a.db).QueryContext(ctx, listSQL, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list views: %w", err)
+	}
+	defer rows.Close()
+
+	views := make([]model.View, 0)
+	for rows.Next() {
+		view, err := scanView(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan view: %w", err)
+		}
+
+		views = append(views, view)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return views, nil
+}
+
+func (a *viewRepo) Update(ctx context.Context, view model.View) (model.View, error) {
+	updateSQL := `
+		UPDATE tasks.views
+		SET name = $2,
+		    definition = $3,
+		    shared_with = $4,
+		    is_default = $5,
+		    updated_at = $6
+		WHERE id = $1
+		RETURNING id, owner_id, name, definition, shared_with, is_default, created_at, updated_at;
+	`
+
+	definition, err := json.Marshal(view.ViewDefinition)
+	if err != nil {
+		return model.View{}, fmt.Errorf("failed to marshal view definition: %w", err)
+	}
+
+	tx, err := database.Begin(ctx, a.db)
+	if err != nil {
+		return model.View{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if view.Default {
+		if err := clearDefault(ctx, tx, view); err != nil {
+			return model.View{}, err
+		}
+	}
+
+	updated, err := scanView(tx.QueryRowContext(ctx, updateSQL,
+		view.ID.String(),
+		view.Name,
+		definition,
+		pq.Array(view.SharedWith),
+		view.Default,
+		view.UpdatedAt,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.View{}, ErrNoRows
+		}
+
+		return model.View{}, fmt.Errorf("failed to update view: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return model.View{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return updated, nil
+}
+
+func (a *viewRepo) Delete(ctx context.Context, id string) error {
+	deleteSQL := `DELETE FROM tasks.views WHERE id = $1;`
+
+	res, err := database.Conn(ctx, a.db).ExecContext(ctx, deleteSQL, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete view: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if rows == 0 {
+		return ErrNoRows
+	}
+
+	return nil
+}
+
+// clearDefault unsets the current default view of the owner of view
+func clearDefault(ctx context.Context, tx database.Executor, view model.View) error {
+	clearSQL := `UPDATE tasks.views SET is_default = false WHERE owner_id = $1 AND is_default = true AND id <> $2;`
+
+	if _, err := tx.ExecContext(ctx, clearSQL, view.OwnerID, view.ID.String()); err != nil {
+		return fmt.Errorf("failed to clear default view: %w", err)
+	}
+
+	return nil
+}
+
+func scanView(row scanner) (model.View, error) {
+	var (
+		view       model.View
+		definition []byte
+	)
+	if err := row.Scan(
+		&view.ID,
+		&view.OwnerID,
+		&view.Name,
+		&definition,
+		pq.Array(&view.SharedWith),
+		&view.Default,
+		&view.CreatedAt,
+		&view.UpdatedAt,
+	); err != nil {
+		return model.View{}, err
+	}
+
+	if err := json.Unmarshal(definition, &view.ViewDefinition); err != nil {
+		return model.View{}, fmt.Errorf("failed to unmarshal view definition: %w", err)
+	}
+
+	return view, nil
+}
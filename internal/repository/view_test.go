@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"go-tasks-api/internal/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type viewSuite struct {
+	suite.Suite
+	repo ViewConnector
+	db   sqlmock.Sqlmock
+}
+
+func TestView(t *testing.T) {
+	suite.Run(t, new(viewSuite))
+}
+
+func (s *viewSuite) SetupTest() {
+	db, mock, err := sqlmock.New()
+	require.NoError(s.T(), err)
+
+	s.repo = NewViewRepo(db)
+	s.db = mock
+}
+
+func (s *viewSuite) TearDownTest() {
+	s.NoError(s.db.ExpectationsWereMet())
+}
+
+func (s *viewSuite) TestCreateDefaultClearsPreviousDefault() {
+	view := model.View{
+		ID:      uuid.New(),
+		OwnerID: "alice",
+		Name:    "open",
+		ViewDefinition: model.ViewDefinition{
+			Filter: "status eq 'todo'",
+			Sort:   []string{"-created_at"},
+		},
+		SharedWith: []string{"bob"},
+		Default:    true,
+		CreatedAt:  time.Now(),
+	}
+
+	s.db.ExpectBegin()
+	s.db.ExpectExec(regexp.QuoteMeta(`UPDATE tasks.views SET is_default = false WHERE owner_id = $1 AND is_default = true AND id <> $2;`)).
+		WithArgs("alice", view.ID.String()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.db.ExpectExec(regexp.QuoteMeta(`INSERT INTO tasks.views`)).
+		WithArgs(view.ID.String(), "alice", "open",
+			[]byte(`{"filter":"status eq 'todo'","sort":["-created_at"],"fields":null}`),
+			pq.Array([]string{"bob"}), true, view.CreatedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.db.ExpectCommit()
+
+	s.NoError(s.repo.Create(context.Background(), view))
+}
+
+func (s *viewSuite) TestGetDecodesDefinition() {
+	id := uuid.New()
+	s.db.ExpectQuery(regexp.QuoteMeta(`FROM tasks.views WHERE id = $1;`)).
+		WithArgs(id.String()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id", "name", "definition", "shared_with", "is_default", "created_at", "updated_at"}).
+			AddRow(id, "alice", "open", []byte(`{"filter":"status eq 'todo'","fields":["id","title"]}`), "{bob,carol}", false, time.Now(), nil))
+
+	view, err := s.repo.Get(context.Background(), id.String())
+	s.NoError(err)
+	s.Equal("status eq 'todo'", view.Filter)
+	s.Equal([]string{"id", "title"}, view.Fields)
+	s.Equal([]string{"bob", "carol"}, view.SharedWith)
+}
+
+func (s *viewSuite) TestGetDefaultNotFound() {
+	s.db.ExpectQuery(regexp.QuoteMeta(`WHERE owner_id = $1 AND is_default = true;`)).
+		WithArgs("alice").
+		WillReturnError(sql.ErrNoRows)
+
+	_, err := s.repo.GetDefault(context.Background(), "alice")
+	s.ErrorIs(err, ErrNoRows)
+}
+
+func (s *viewSuite) TestListForIncludesSharedViews() {
+	s.db.ExpectQuery(regexp.QuoteMeta(`WHERE owner_id = $1 OR $1 = ANY(shared_with) ORDER BY name, id;`)).
+		WithArgs("bob").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id", "name", "definition", "shared_with", "is_default", "created_at", "updated_at"}).
+			AddRow(uuid.New(), "alice", "open", []byte(`{}`), "{bob}", true, time.Now(), nil).
+			AddRow(uuid.New(), "bob", "mine", []byte(`{}`), "{}", false, time.Now(), nil))
+
+	views, err := s.repo.ListFor(context.Background(), "bob")
+	s.NoError(err)
+	s.Len(views, 2)
+	s.Equal("alice", views[0].OwnerID)
+}
+
+func (s *viewSuite) TestUpdateNotFound() {
+	view := model.View{ID: uuid.New(), OwnerID: "alice", Name: "open"}
+
+	s.db.ExpectBegin()
+	s.db.ExpectQuery(regexp.QuoteMeta(`UPDATE tasks.views`)).
+		WillReturnError(sql.ErrNoRows)
+	s.db.ExpectRollback()
+
+	_, err := s.repo.Update(context.Background(), view)
+	s.ErrorIs(err, ErrNoRows)
+}
+
+func (s *viewSuite) TestDeleteNotFound() {
+	s.db.ExpectExec(regexp.QuoteMeta(`DELETE FROM tasks.views WHERE id = $1;`)).
+		WithArgs("id").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	s.ErrorIs(s.repo.Delete(context.Background(), "id"), ErrNoRows)
+}
@@ -0,0 +1,443 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-tasks-api/internal/database"
+	"go-tasks-api/internal/encryption"
+	"go-tasks-api/internal/enum"
+	"go-tasks-api/internal/model"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type webhookRepo struct {
+	db     *sql.DB
+	cipher encryption.Cipher
+}
+
+//go:generate go run -mod=mod go.uber.org/mock/mockgen -package mocks -destination=./mocks/webhook_mock.go -source=webhook.go
+type WebhookConnector interface {
+	Create(ctx context.Context, webhook model.Webhook) error
+	Get(ctx context.Context, id string) (model.Webhook, error)
+	List(ctx context.Context) ([]model.Webhook, error)
+	Update(ctx context.Context, webhook model.Webhook) (model.Webhook, error)
+	Delete(ctx context.Context, id string) error
+
+	// Enqueue creates a pending delivery of the event for every enabled webhook subscribed to its
+	// type, webhooks which already have a delivery of the event are skipped
+	Enqueue(ctx context.Context, event model.Event) (int, error)
+	// ClaimDue leases up to limit due deliveries of enabled webhooks, a lease which is not
+	// completed expires after the given duration and the delivery is attempted again
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]model.PendingDelivery, error)
+	// CompleteAttempt stores the outcome of an attempt and disables the webhook once it
+	// failed disableAfter times in a row, it reports whether the webhook was disabled
+	CompleteAttempt(ctx context.Context, attempt model.DeliveryAttempt, disableAfter int) (bool, error)
+	ListDeliveries(ctx context.Context, webhookID string, limit int) ([]model.WebhookDelivery, error)
+	Redeliver(ctx context.Context, webhookID, deliveryID string) (model.WebhookDelivery, error)
+}
+
+// NewWebhookRepo creates a new Webhook repository, signing secrets are encrypted with the given cipher
+func NewWebhookRepo(db *sql.DB, cipher encryption.Cipher) WebhookConnector {
+	return &webhookRepo{
+		db:     db,
+		cipher: cipher,
+	}
+}
+
+func (a *webhookRepo) Create(ctx context.Context, webhook model.Webhook) error {
+	insertSQL := `INSERT INTO tasks.webhooks (id, url, secret, event_types, created_at) values ($1, $2, $3, $4, $5);`
+
+	secret, err := a.cipher.Encrypt(webhook.Secret, secretData(webhook.ID))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt webhook secret: %w", err)
+	}
+
+	_, err = database.Conn(ctx, a.db).ExecContext(ctx, insertSQL,
+		webhook.ID.String(),
+		webhook.URL,
+		secret,
+		pq.Array(webhook.EventTypes),
+		webhook.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert webhook: %w", err)
+	}
+
+	return nil
+}
+
+func (a *webhookRepo) Get(ctx context.Context, id string) (model.Webhook, error) {
+	getSQL := `SELECT id, url, secret, event_types, enabled, consecutive_failures, created_at, updated_at FROM tasks.webhooks WHERE id = $1 AND is_active = true;`
+
+	webhook, err := a.scan(database.Conn(ctx, a.db).QueryRowContext(ctx, getSQL, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Webhook{}, ErrNoRows
+		}
+
+		return model.Webhook{}, fmt.Errorf("failed to get webhook: %w", err)
+	}
+
+	return webhook, nil
+}
+
+func (a *webhookRepo) List(ctx context.Context) ([]model.Webhook, error) {
+	listSQL := `SELECT id, url, secret, event_types, enabled, consecutive_failures, created_at, updated_at FROM tasks.webhooks WHERE is_active = true ORDER BY created_at;`
+
+	rows, err := database.Conn(ctx, a.db).QueryContext(ctx, listSQL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := make([]model.Webhook, 0)
+	for rows.Next() {
+		webhook, err := a.scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+func (a *webhookRepo) Update(ctx context.Context, webhook model.Webhook) (model.Webhook, error) {
+	updateSQL := `
+		UPDATE tasks.webhooks
+		SET url = $2,
+		    secret = $3,
+		    event_types = $4,
+		    enabled = $5,
+		    consecutive_failures = $6,
+		    updated_at = $7
+		WHERE id = $1 AND is_active = true
+		RETURNING id, url, secret, event_types, enabled, consecutive_failures, created_at, updated_at;
+	`
+
+	secret, err := a.cipher.Encrypt(webhook.Secret, secretData(webhook.ID))
+	if err != nil {
+		return model.Webhook{}, fmt.Errorf("failed to encrypt webhook secret: %w", err)
+	}
+
+	updated, err := a.scan(database.Conn(ctx, a.db).QueryRowContext(ctx, updateSQL,
+		webhook.ID.String(),
+		webhook.URL,
+		secret,
+		pq.Array(webhook.EventTypes),
+		webhook.Enabled,
+		webhook.ConsecutiveFailures,
+		webhook.UpdatedAt,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Webhook{}, ErrNoRows
+		}
+
+		return model.Webhook{}, fmt.Errorf("failed to update webhook: %w", err)
+	}
+
+	return updated, nil
+}
+
+func (a *webhookRepo) Delete(ctx context.Context, id string) error {
+	deleteSQL := `UPDATE tasks.webhooks SET is_active = false WHERE id = $1 AND is_active = true;`
+
+	res, err := database.Conn(ctx, a.db).ExecContext(ctx, deleteSQL, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if rows == 0 {
+		return ErrNoRows
+	}
+
+	return nil
+}
+
+func (a *webhookRepo) Enqueue(ctx context.Context, event model.Event) (int, error) {
+	enqueueSQL := `
+		INSERT INTO tasks.webhook_deliveries (id, webhook_id, event_id)
+		SELECT gen_random_uuid(), w.id, $1
+		FROM tasks.webhooks w
+		WHERE w.is_active = true AND w.enabled = true AND $2 = ANY(w.event_types)
+		ON CONFLICT (webhook_id, event_id) WHERE redelivery_of IS NULL DO NOTHING;
+	`
+
+	res, err := database.Conn(ctx, a.db).ExecContext(ctx, enqueueSQL, event.ID, event.Type)
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue webhook deliveries: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return int(rows), nil
+}
+
+func (a *webhookRepo) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]model.PendingDelivery, error) {
+	// moving next_attempt_at forward acts as the lease, a crashed worker's deliveries become due again
+	claimSQL := `
+		WITH due AS (
+			SELECT d.id
+			FROM tasks.webhook_deliveries d
+			JOIN tasks.webhooks w ON w.id = d.webhook_id
+			WHERE d.status = 'pending'
+			  AND d.next_attempt_at <= CURRENT_TIMESTAMP
+			  AND w.is_active = true
+			  AND w.enabled = true
+			ORDER BY d.next_attempt_at
+			LIMIT $1
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		UPDATE tasks.webhook_deliveries d
+		SET next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $2)
+		FROM due, tasks.webhooks w, tasks.events e
+		WHERE d.id = due.id AND w.id = d.webhook_id AND e.id = d.event_id
+		RETURNING d.id, d.webhook_id, d.event_id, d.status, d.attempts, d.created_at,
+		          w.url, w.secret, e.type, e.task_id, e.payload, e.created_at;
+	`
+
+	rows, err := database.Conn(ctx, a.db).QueryContext(ctx, claimSQL, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	pending := make([]model.PendingDelivery, 0)
+	for rows.Next() {
+		var (
+			p       model.PendingDelivery
+			payload string
+		)
+		if err := rows.Scan(
+			&p.Delivery.ID,
+			&p.Delivery.WebhookID,
+			&p.Delivery.EventID,
+			&p.Delivery.Status,
+			&p.Delivery.Attempts,
+			&p.Delivery.CreatedAt,
+			&p.URL,
+			&p.Secret,
+			&p.Event.Type,
+			&p.Event.TaskID,
+			&payload,
+			&p.Event.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+
+		if p.Secret, err = a.cipher.Decrypt(p.Secret, secretData(p.Delivery.WebhookID)); err != nil {
+			return nil, fmt.Errorf("failed to decrypt webhook secret: %w", err)
+		}
+
+		data, err := a.cipher.Decrypt(payload, eventPayloadData(p.Delivery.EventID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt event payload: %w", err)
+		}
+
+		p.Event.ID = p.Delivery.EventID
+		p.Event.Data = []byte(data)
+		p.Delivery.EventType = p.Event.Type
+		pending = append(pending, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return pending, nil
+}
+
+func (a *webhookRepo) CompleteAttempt(ctx context.Context, attempt model.DeliveryAttempt, disableAfter int) (bool, error) {
+	deliverySQL := `
+		UPDATE tasks.webhook_deliveries
+		SET status = $2,
+		    attempts = attempts + 1,
+		    next_attempt_at = $3,
+		    last_status_code = $4,
+		    last_error = $5,
+		    delivered_at = CASE WHEN $2 = 'succeeded' THEN CURRENT_TIMESTAMP ELSE NULL END
+		WHERE id = $1;
+	`
+	succeededSQL := `UPDATE tasks.webhooks SET consecutive_failures = 0 WHERE id = $1;`
+	failedSQL := `
+		UPDATE tasks.webhooks
+		SET consecutive_failures = consecutive_failures + 1,
+		    enabled = enabled AND consecutive_failures + 1 < $2
+		WHERE id = $1
+		RETURNING enabled;
+	`
+
+	tx, err := database.Begin(ctx, a.db)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, deliverySQL,
+		attempt.DeliveryID.String(),
+		attempt.Status,
+		attempt.NextAttemptAt,
+		attempt.StatusCode,
+		attempt.Error,
+	); err != nil {
+		return false, fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+
+	disabled := false
+	if attempt.Status == enum.DeliveryStatus_Succeeded {
+		if _, err := tx.ExecContext(ctx, succeededSQL, attempt.WebhookID.String()); err != nil {
+			return false, fmt.Errorf("failed to reset webhook failures: %w", err)
+		}
+	} else {
+		var enabled bool
+		if err := tx.QueryRowContext(ctx, failedSQL, attempt.WebhookID.String(), disableAfter).Scan(&enabled); err != nil {
+			return false, fmt.Errorf("failed to count webhook failure: %w", err)
+		}
+		disabled = !enabled
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return disabled, nil
+}
+
+func (a *webhookRepo) ListDeliveries(ctx context.Context, webhookID string, limit int) ([]model.WebhookDelivery, error) {
+	listSQL := `
+		SELECT d.id, d.webhook_id, d.event_id, e.type, d.status, d.attempts, d.next_attempt_at,
+		       d.last_status_code, d.last_error, d.created_at, d.delivered_at
+		FROM tasks.webhook_deliveries d
+		JOIN tasks.events e ON e.id = d.event_id
+		WHERE d.webhook_id = $1
+		ORDER BY d.created_at DESC
+		LIMIT $2;
+	`
+
+	rows, err := database.Conn(ctx, a.db).QueryContext(ctx, listSQL, webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]model.WebhookDelivery, 0)
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+func (a *webhookRepo) Redeliver(ctx context.Context, webhookID, deliveryID string) (model.WebhookDelivery, error) {
+	redeliverSQL := `
+		WITH redelivery AS (
+			INSERT INTO tasks.webhook_deliveries (id, webhook_id, event_id, redelivery_of)
+			SELECT $3, d.webhook_id, d.event_id, d.id
+			FROM tasks.webhook_deliveries d
+			JOIN tasks.webhooks w ON w.id = d.webhook_id
+			WHERE d.id = $2 AND d.webhook_id = $1 AND w.is_active = true
+			RETURNING *
+		)
+		SELECT r.id, r.webhook_id, r.event_id, e.type, r.status, r.attempts, r.next_attempt_at,
+		       r.last_status_code, r.last_error, r.created_at, r.delivered_at
+		FROM redelivery r
+		JOIN tasks.events e ON e.id = r.event_id;
+	`
+
+	delivery, err := scanDelivery(database.Conn(ctx, a.db).QueryRowContext(ctx, redeliverSQL, webhookID, deliveryID, uuid.New().String()))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.WebhookDelivery{}, ErrNoRows
+		}
+
+		return model.WebhookDelivery{}, fmt.Errorf("failed to redeliver webhook delivery: %w", err)
+	}
+
+	return delivery, nil
+}
+
+// scanner is implemented by both *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func (a *webhookRepo) scan(row scanner) (model.Webhook, error) {
+	var webhook model.Webhook
+	if err := row.Scan(
+		&webhook.ID,
+		&webhook.URL,
+		&webhook.Secret,
+		pq.Array(&webhook.EventTypes),
+		&webhook.Enabled,
+		&webhook.ConsecutiveFailures,
+		&webhook.CreatedAt,
+		&webhook.UpdatedAt,
+	); err != nil {
+		return model.Webhook{}, err
+	}
+
+	secret, err := a.cipher.Decrypt(webhook.Secret, secretData(webhook.ID))
+	if err != nil {
+		return model.Webhook{}, fmt.Errorf("failed to decrypt webhook secret: %w", err)
+	}
+	webhook.Secret = secret
+
+	return webhook, nil
+}
+
+func scanDelivery(row scanner) (model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	err := row.Scan(
+		&delivery.ID,
+		&delivery.WebhookID,
+		&delivery.EventID,
+		&delivery.EventType,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.NextAttemptAt,
+		&delivery.LastStatusCode,
+		&delivery.LastError,
+		&delivery.CreatedAt,
+		&delivery.DeliveredAt,
+	)
+	if delivery.Status != enum.DeliveryStatus_Pending {
+		// only pending deliveries are attempted again
+		delivery.NextAttemptAt = nil
+	}
+
+	return delivery, err
+}
+
+// secretData returns the additional data the signing secret of the webhook is encrypted with
+func secretData(id uuid.UUID) string {
+	return encryption.AdditionalData("tasks.webhooks", "secret", id.String())
+}
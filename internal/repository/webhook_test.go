@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"go-tasks-api/internal/encryption"
+	"go-tasks-api/internal/enum"
+	"go-tasks-api/internal/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type webhookSuite struct {
+	suite.Suite
+	repo WebhookConnector
+	db   sqlmock.Sqlmock
+}
+
+func TestWebhook(t *testing.T) {
+	suite.Run(t, new(webhookSuite))
+}
+
+func (s *webhookSuite) SetupTest() {
+	db, mock, err := sqlmock.New()
+	require.NoError(s.T(), err)
+
+	s.repo = NewWebhookRepo(db, encryption.NoopCipher{})
+	s.db = mock
+}
+
+func (s *webhookSuite) TearDownTest() {
+	s.NoError(s.db.ExpectationsWereMet())
+}
+
+func (s *webhookSuite) TestGetNotFound() {
+	s.db.ExpectQuery(regexp.QuoteMeta(`SELECT id, url, secret, event_types, enabled, consecutive_failures, created_at, updated_at FROM tasks.webhooks WHERE id = $1 AND is_active = true;`)).
+		WithArgs("id").
+		WillReturnError(sql.ErrNoRows)
+
+	_, err := s.repo.Get(context.Background(), "id")
+	s.ErrorIs(err, ErrNoRows)
+}
+
+func (s *webhookSuite) TestGetSuccess() {
+	id := uuid.New()
+	s.db.ExpectQuery(regexp.QuoteMeta(`FROM tasks.webhooks WHERE id = $1`)).
+		WithArgs(id.String()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "url", "secret", "event_types", "enabled", "consecutive_failures", "created_at", "updated_at"}).
+			AddRow(id, "https://example.com", "0123456789abcdef", "{task.created,task.deleted}", true, 0, time.Now(), nil))
+
+	webhook, err := s.repo.Get(context.Background(), id.String())
+	s.NoError(err)
+	s.Equal(id, webhook.ID)
+	s.Equal("0123456789abcdef", webhook.Secret)
+	s.Equal([]string{model.EventTaskCreated, model.EventTaskDeleted}, webhook.EventTypes)
+}
+
+func (s *webhookSuite) TestDeleteNotFound() {
+	s.db.ExpectExec(regexp.QuoteMeta(`UPDATE tasks.webhooks SET is_active = false WHERE id = $1 AND is_active = true;`)).
+		WithArgs("id").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	s.ErrorIs(s.repo.Delete(context.Background(), "id"), ErrNoRows)
+}
+
+func (s *webhookSuite) TestEnqueue() {
+	// an event published again by the relay keeps its deliveries
+	s.db.ExpectExec(`INSERT INTO tasks.webhook_deliveries \(id, webhook_id, event_id\)(?s:.*)ON CONFLICT \(webhook_id, event_id\) WHERE redelivery_of IS NULL DO NOTHING`).
+		WithArgs(int64(3), model.EventTaskUpdated).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	n, err := s.repo.Enqueue(context.Background(), model.Event{ID: 3, Type: model.EventTaskUpdated})
+	s.NoError(err)
+	s.Equal(2, n)
+}
+
+func (s *webhookSuite) TestCompleteAttemptDisablesWebhook() {
+	msg := "receiver responded with status 500"
+	attempt := model.DeliveryAttempt{
+		DeliveryID:    uuid.New(),
+		WebhookID:     uuid.New(),
+		Status:        enum.DeliveryStatus_Failed,
+		Error:         &msg,
+		NextAttemptAt: time.Now(),
+	}
+
+	s.db.ExpectBegin()
+	s.db.ExpectExec(regexp.QuoteMeta(`UPDATE tasks.webhook_deliveries`)).
+		WithArgs(attempt.DeliveryID.String(), attempt.Status, attempt.NextAttemptAt, nil, msg).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.db.ExpectQuery(regexp.QuoteMeta(`SET consecutive_failures = consecutive_failures + 1`)).
+		WithArgs(attempt.WebhookID.String(), 25).
+		WillReturnRows(sqlmock.NewRows([]string{"enabled"}).AddRow(false))
+	s.db.ExpectCommit()
+
+	disabled, err := s.repo.CompleteAttempt(context.Background(), attempt, 25)
+	s.NoError(err)
+	s.True(disabled)
+}
+
+func (s *webhookSuite) TestCompleteAttemptSucceeded() {
+	code := 200
+	attempt := model.DeliveryAttempt{
+		DeliveryID:    uuid.New(),
+		WebhookID:     uuid.New(),
+		Status:        enum.DeliveryStatus_Succeeded,
+		StatusCode:    &code,
+		NextAttemptAt: time.Now(),
+	}
+
+	s.db.ExpectBegin()
+	s.db.ExpectExec(regexp.QuoteMeta(`UPDATE tasks.webhook_deliveries`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.db.ExpectExec(regexp.QuoteMeta(`UPDATE tasks.webhooks SET consecutive_failures = 0 WHERE id = $1;`)).
+		WithArgs(attempt.WebhookID.String()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.db.ExpectCommit()
+
+	disabled, err := s.repo.CompleteAttempt(context.Background(), attempt, 25)
+	s.NoError(err)
+	s.False(disabled)
+}
+
+func (s *webhookSuite) TestRedeliverNotFound() {
+	s.db.ExpectQuery(regexp.QuoteMeta(`WITH redelivery AS`)).
+		WithArgs("webhook", "delivery", sqlmock.AnyArg()).
+		WillReturnError(sql.ErrNoRows)
+
+	_, err := s.repo.Redeliver(context.Background(), "webhook", "delivery")
+	s.ErrorIs(err, ErrNoRows)
+}
@@ -0,0 +1,111 @@
+package seed
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go-tasks-api/internal/enum"
+	"go-tasks-api/internal/model"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+// fixtureNamespace derives the IDs of fixture tasks without one from their title, so loading a
+// fixture again finds the tasks it created before
+var fixtureNamespace = uuid.MustParse("6f1c0d4e-8a53-4f0b-9d6e-2b7c1f3a9e41")
+
+// Fixture is the content of a fixture file
+type Fixture struct {
+	Tasks []FixtureTask `json:"tasks" yaml:"tasks"`
+}
+
+// FixtureTask is a task of a fixture file, only the title is required. The status defaults to
+// todo and the creation time to the time of loading.
+type FixtureTask struct {
+	ID          *uuid.UUID      `json:"id" yaml:"id"`
+	Title       string          `json:"title" yaml:"title"`
+	Description string          `json:"description" yaml:"description"`
+	Status      enum.StatusType `json:"status" yaml:"status"`
+	CreatedAt   *time.Time      `json:"created_at" yaml:"created_at"`
+}
+
+// Load reads the tasks of a YAML or JSON fixture file, told apart by the file extension
+func Load(path string, now time.Time) ([]model.Task, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+
+	var fixture Fixture
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&fixture)
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&fixture)
+	default:
+		return nil, fmt.Errorf("unknown fixture format %q, use .yaml, .yml or .json", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode fixture %s: %w", path, err)
+	}
+
+	tasks, err := fixture.tasks(now)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %w", path, err)
+	}
+
+	return tasks, nil
+}
+
+// tasks converts the fixture tasks, checking that every ID is used once
+func (f Fixture) tasks(now time.Time) ([]model.Task, error) {
+	tasks := make([]model.Task, 0, len(f.Tasks))
+	seen := make(map[uuid.UUID]int, len(f.Tasks))
+	for i, ft := range f.Tasks {
+		title := strings.TrimSpace(ft.Title)
+		if title == "" {
+			return nil, fmt.Errorf("task %d: title is required", i+1)
+		}
+
+		task := model.Task{
+			ID:          uuid.NewSHA1(fixtureNamespace, []byte(title)),
+			Title:       title,
+			Description: strings.TrimSpace(ft.Description),
+			Status:      ft.Status,
+			CreatedAt:   now,
+		}
+		if ft.ID != nil {
+			task.ID = *ft.ID
+		}
+		if task.Status == 0 {
+			task.Status = enum.Status_Todo
+		}
+		if ft.CreatedAt != nil {
+			task.CreatedAt = *ft.CreatedAt
+		}
+
+		if first, ok := seen[task.ID]; ok {
+			return nil, fmt.Errorf("task %d: id %s is already used by task %d, set distinct ids or titles", i+1, task.ID, first)
+		}
+		seen[task.ID] = i + 1
+
+		tasks = append(tasks, task)
+	}
+
+	if len(tasks) == 0 {
+		return nil, errors.New("no tasks")
+	}
+
+	return tasks, nil
+}
@@ -0,0 +1,98 @@
+package seed
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"go-tasks-api/internal/enum"
+	"go-tasks-api/internal/model"
+
+	"github.com/google/uuid"
+)
+
+// generatedSpan is the time before the end of generation the synthetic tasks were created in
+const generatedSpan = 90 * 24 * time.Hour
+
+var (
+	verbs = []string{
+		"Review", "Write", "Update", "Fix", "Plan", "Prepare", "Test", "Document", "Refactor", "Migrate",
+		"Schedule", "Draft", "Clean up", "Investigate", "Deploy",
+	}
+	subjects = []string{
+		"the quarterly report", "the onboarding guide", "the billing service", "the release notes",
+		"the login page", "the database backups", "the team offsite", "the API rate limits",
+		"the customer survey", "the search index", "the hiring pipeline", "the error dashboards",
+		"the mobile checkout", "the vendor contracts", "the CI pipeline",
+	}
+	contexts = []string{
+		"", "", "", " before Friday", " for the next sprint", " with the design team", " after the audit",
+		" for the board meeting", " on staging",
+	}
+	details = []string{
+		"Check the open comments first.",
+		"Ask the owners for a final review.",
+		"Link the ticket in the changelog.",
+		"Keep the previous version around until the rollout finished.",
+		"Share the outcome in the weekly update.",
+		"Estimate the remaining work afterwards.",
+		"Coordinate with support before making changes.",
+	}
+)
+
+// Generate returns n synthetic tasks, the same seed and end always return the same tasks. The
+// tasks were created in the 90 days before end, about a third of them is done.
+func Generate(n int, seed uint64, end time.Time) []model.Task {
+	var key [32]byte
+	binary.LittleEndian.PutUint64(key[:], seed)
+	source := rand.NewChaCha8(key)
+	rng := rand.New(source)
+
+	end = end.UTC().Truncate(time.Second)
+	tasks := make([]model.Task, 0, n)
+	for range n {
+		// the source reads as a stream of random bytes, which keeps the IDs deterministic too
+		id, err := uuid.NewRandomFromReader(source)
+		if err != nil {
+			panic(fmt.Sprintf("seed: failed to generate task id: %v", err))
+		}
+
+		createdAt := end.Add(-time.Duration(rng.Int64N(int64(generatedSpan)))).Truncate(time.Second)
+		task := model.Task{
+			ID:          id,
+			Title:       pick(rng, verbs) + " " + pick(rng, subjects) + pick(rng, contexts),
+			Description: description(rng),
+			Status:      enum.Status_Todo,
+			CreatedAt:   createdAt,
+		}
+
+		if rng.IntN(3) == 0 {
+			updatedAt := createdAt.Add(time.Duration(rng.Int64N(int64(end.Sub(createdAt)) + 1))).Truncate(time.Second)
+			task.Status = enum.Status_Done
+			task.UpdatedAt = &updatedAt
+		}
+
+		tasks = append(tasks, task)
+	}
+
+	return tasks
+}
+
+// description returns up to three sentences, a fifth of the tasks has none
+func description(rng *rand.Rand) string {
+	if rng.IntN(5) == 0 {
+		return ""
+	}
+
+	text := pick(rng, details)
+	for range rng.IntN(3) {
+		text += " " + pick(rng, details)
+	}
+
+	return text
+}
+
+func pick(rng *rand.Rand, values []string) string {
+	return values[rng.IntN(len(values))]
+}
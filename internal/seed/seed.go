@@ -0,0 +1,84 @@
+package seed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-tasks-api/internal/enum"
+	"go-tasks-api/internal/model"
+	"go-tasks-api/internal/repository"
+)
+
+// Result counts what seeding did to the tasks
+type Result struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	// Skipped are the tasks deleted since they were seeded
+	Skipped int `json:"skipped"`
+}
+
+// Seed stores the tasks through the repository, tasks are matched by ID so seeding again only
+// changes what differs. Missing tasks are created, tasks whose title, description or status
+// differ are updated and the others are left alone. Deleted tasks are not brought back.
+func Seed(ctx context.Context, repo repository.TaskConnector, tasks []model.Task, now time.Time) (Result, error) {
+	var result Result
+	for _, task := range tasks {
+		existing, err := repo.Get(ctx, task.ID.String())
+		switch {
+		case errors.Is(err, repository.ErrNoRows):
+			created, err := create(ctx, repo, task, now)
+			if err != nil {
+				return result, err
+			}
+			if created {
+				result.Created++
+			} else {
+				result.Skipped++
+			}
+		case err != nil:
+			return result, fmt.Errorf("failed to get task %s: %w", task.ID, err)
+		case existing.Title == task.Title && existing.Description == task.Description && existing.Status == task.Status:
+			result.Unchanged++
+		default:
+			if err := update(ctx, repo, task, now); err != nil {
+				return result, err
+			}
+			result.Updated++
+		}
+	}
+
+	return result, nil
+}
+
+// create creates the task, tasks are created as todo so others get their status by an update.
+// It returns false for a deleted task, which keeps its ID and is left alone.
+func create(ctx context.Context, repo repository.TaskConnector, task model.Task, now time.Time) (bool, error) {
+	created, err := repo.Create(ctx, task)
+	if err != nil {
+		return false, fmt.Errorf("failed to create task %s: %w", task.ID, err)
+	}
+	if !created {
+		return false, nil
+	}
+
+	if task.Status != enum.Status_Todo {
+		return true, update(ctx, repo, task, now)
+	}
+
+	return true, nil
+}
+
+func update(ctx context.Context, repo repository.TaskConnector, task model.Task, now time.Time) error {
+	if task.UpdatedAt == nil {
+		task.UpdatedAt = &now
+	}
+
+	if _, err := repo.Update(ctx, task); err != nil {
+		return fmt.Errorf("failed to update task %s: %w", task.ID, err)
+	}
+
+	return nil
+}
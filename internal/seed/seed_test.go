@@ -0,0 +1,133 @@
+package seed
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-tasks-api/internal/enum"
+	"go-tasks-api/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+
+func TestLoad(t *testing.T) {
+	for _, path := range []string{"testdata/tasks.yaml", "testdata/tasks.json"} {
+		t.Run(filepath.Ext(path), func(t *testing.T) {
+			tasks, err := Load(path, now)
+			require.NoError(t, err)
+			require.Len(t, tasks, 2)
+
+			// without an ID the task gets one derived from its title
+			require.Equal(t, uuid.NewSHA1(fixtureNamespace, []byte("Write the quarterly report")), tasks[0].ID)
+			require.Equal(t, "Include the churn numbers.", tasks[0].Description)
+			require.Equal(t, enum.Status_Todo, tasks[0].Status)
+			require.Equal(t, now, tasks[0].CreatedAt)
+
+			require.Equal(t, uuid.MustParse("0b6a3c1e-2f4d-4e5a-8b9c-1d2e3f4a5b6c"), tasks[1].ID)
+			require.Equal(t, enum.Status_Done, tasks[1].Status)
+			require.Equal(t, time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC), tasks[1].CreatedAt)
+		})
+	}
+}
+
+func TestLoadRejectsInvalidFixtures(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{"unknown format", "tasks.toml", ``},
+		{"unknown field", "tasks.yaml", "tasks:\n  - title: a\n    priority: 1\n"},
+		{"missing title", "tasks.json", `{"tasks": [{"description": "no title"}]}`},
+		{"invalid status", "tasks.json", `{"tasks": [{"title": "a", "status": "later"}]}`},
+		{"duplicate title", "tasks.yaml", "tasks:\n  - title: a\n  - title: a\n"},
+		{"no tasks", "tasks.yaml", "tasks: []\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o600))
+
+			_, err := Load(path, now)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestGenerateIsDeterministic(t *testing.T) {
+	tasks := Generate(50, 42, now)
+	require.Len(t, tasks, 50)
+	require.Equal(t, tasks, Generate(50, 42, now))
+	require.NotEqual(t, tasks[0].ID, Generate(1, 43, now)[0].ID)
+
+	ids := make(map[uuid.UUID]bool)
+	for _, task := range tasks {
+		require.False(t, ids[task.ID])
+		ids[task.ID] = true
+
+		require.NotEmpty(t, task.Title)
+		require.False(t, task.CreatedAt.After(now))
+		require.True(t, task.CreatedAt.After(now.Add(-generatedSpan)))
+		if task.Status == enum.Status_Done {
+			require.NotNil(t, task.UpdatedAt)
+			require.False(t, task.UpdatedAt.Before(task.CreatedAt))
+		}
+	}
+}
+
+func TestSeedIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryTaskRepo()
+	tasks, err := Load("testdata/tasks.yaml", now)
+	require.NoError(t, err)
+
+	result, err := Seed(ctx, repo, tasks, now)
+	require.NoError(t, err)
+	require.Equal(t, Result{Created: 2}, result)
+
+	done, err := repo.Get(ctx, tasks[1].ID.String())
+	require.NoError(t, err)
+	require.Equal(t, enum.Status_Done, done.Status)
+
+	result, err = Seed(ctx, repo, tasks, now)
+	require.NoError(t, err)
+	require.Equal(t, Result{Unchanged: 2}, result)
+
+	// a changed fixture updates the task it created before
+	tasks[0].Description = "Include the churn and growth numbers."
+	result, err = Seed(ctx, repo, tasks, now)
+	require.NoError(t, err)
+	require.Equal(t, Result{Updated: 1, Unchanged: 1}, result)
+
+	updated, err := repo.Get(ctx, tasks[0].ID.String())
+	require.NoError(t, err)
+	require.Equal(t, "Include the churn and growth numbers.", updated.Description)
+}
+
+func TestSeedSkipsDeletedTasks(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryTaskRepo()
+	tasks, err := Load("testdata/tasks.yaml", now)
+	require.NoError(t, err)
+
+	_, err = Seed(ctx, repo, tasks, now)
+	require.NoError(t, err)
+
+	// the done task is deleted through the API
+	require.Equal(t, enum.Status_Done, tasks[1].Status)
+	require.NoError(t, repo.Delete(ctx, tasks[1].ID.String()))
+
+	result, err := Seed(ctx, repo, tasks, now)
+	require.NoError(t, err)
+	require.Equal(t, Result{Unchanged: 1, Skipped: 1}, result)
+
+	_, err = repo.Get(ctx, tasks[1].ID.String())
+	require.ErrorIs(t, err, repository.ErrNoRows)
+}
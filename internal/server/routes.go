@@ -1,26 +1,95 @@
 package server
 
 import (
+	"go-tasks-api/internal/database"
 	"go-tasks-api/internal/handler"
+	"go-tasks-api/internal/httpcache"
+	"go-tasks-api/internal/openapi"
+	"go-tasks-api/internal/utils"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
-// NewRouter sets up the router with all routes and middleware
-func NewRouter(a *handler.Task) *chi.Mux {
+// Handlers groups the HTTP handlers served by the router, the routes of nil handlers are not
+// registered
+type Handlers struct {
+	Task    *handler.Task
+	Events  *handler.Events
+	Webhook *handler.Webhook
+	View    *handler.View
+	Job     *handler.Job
+	Admin   *handler.Admin
+}
+
+// NewRouter sets up the router with all routes and middleware, cache holds the Cache-Control
+// policies of the conditional GET routes, errs the format of error responses and replicas,
+// which may be nil, the read replicas reads of a client avoid after it wrote
+func NewRouter(h Handlers, cache httpcache.Policies, errs utils.ErrorOptions, replicas *database.Replicas) *chi.Mux {
 	router := chi.NewRouter()
 
 	router.Use(middleware.Logger)
+	router.Use(errs.Middleware)
+	router.Use(replicas.Middleware)
+	router.Use(openapi.NewValidator(openapi.MustLoad()).Middleware)
+
+	router.Get("/openapi.json", openapi.Handler)
 
 	// tasks routes
 	router.Route("/api/v1/tasks", func(r chi.Router) {
-		r.Post("/", a.Create)
-		r.Get("/", a.List)
-		r.Get("/{id}", a.Get)
-		r.Put("/{id}", a.Update)
-		r.Delete("/{id}", a.Delete)
+		r.Use(cache.Middleware)
+
+		r.Post("/", h.Task.Create)
+		r.Get("/", h.Task.List)
+		r.Get("/{id}", h.Task.Get)
+		r.Put("/{id}", h.Task.Update)
+		r.Delete("/{id}", h.Task.Delete)
 	})
 
+	// events routes
+	if h.Events != nil {
+		router.Get("/api/v1/events", h.Events.Stream)
+	}
+
+	// webhooks routes
+	if h.Webhook != nil {
+		router.Route("/api/v1/webhooks", func(r chi.Router) {
+			r.Post("/", h.Webhook.Create)
+			r.Get("/", h.Webhook.List)
+			r.Get("/{id}", h.Webhook.Get)
+			r.Put("/{id}", h.Webhook.Update)
+			r.Delete("/{id}", h.Webhook.Delete)
+			r.Get("/{id}/deliveries", h.Webhook.Deliveries)
+			r.Post("/{id}/deliveries/{deliveryID}/redeliver", h.Webhook.Redeliver)
+		})
+	}
+
+	// views routes
+	if h.View != nil {
+		router.Route("/api/v1/views", func(r chi.Router) {
+			r.Post("/", h.View.Create)
+			r.Get("/", h.View.List)
+			r.Get("/{id}", h.View.Get)
+			r.Put("/{id}", h.View.Update)
+			r.Delete("/{id}", h.View.Delete)
+			r.Get("/{id}/tasks", h.View.Tasks)
+		})
+	}
+
+	// jobs routes
+	if h.Job != nil {
+		router.Route("/api/v1/jobs", func(r chi.Router) {
+			r.Post("/", h.Job.Create)
+			r.Get("/{id}", h.Job.Get)
+			r.Post("/{id}/cancel", h.Job.Cancel)
+			r.Get("/{id}/result", h.Job.Result)
+		})
+	}
+
+	// admin routes
+	if h.Admin != nil {
+		router.Get("/api/v1/admin/schema", h.Admin.Schema)
+	}
+
 	return router
 }
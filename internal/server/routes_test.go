@@ -0,0 +1,100 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-tasks-api/internal/database"
+	"go-tasks-api/internal/handler"
+	"go-tasks-api/internal/openapi"
+	"go-tasks-api/internal/repository"
+	"go-tasks-api/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRoutesAreDocumented fails when a route is registered without being described in
+// the OpenAPI document, or when the document describes a route that does not exist
+func TestRoutesAreDocumented(t *testing.T) {
+	doc, err := openapi.Load()
+	require.NoError(t, err)
+
+	documented := make(map[openapi.Route]bool)
+	for _, op := range doc.Routes() {
+		documented[op] = true
+	}
+
+	registered := make(map[openapi.Route]bool)
+	err = chi.Walk(NewRouter(testHandlers(), nil, utils.ErrorOptions{}, nil),
+		func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+			if len(route) > 1 {
+				route = strings.TrimSuffix(route, "/")
+			}
+			registered[openapi.Route{Method: method, Path: route}] = true
+
+			return nil
+		})
+	require.NoError(t, err)
+
+	for op := range registered {
+		require.Truef(t, documented[op], "route %s %s is not documented in openapi.json", op.Method, op.Path)
+	}
+
+	for op := range documented {
+		require.Truef(t, registered[op], "documented operation %s %s is not registered", op.Method, op.Path)
+	}
+}
+
+func TestServeOpenAPI(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	rec := httptest.NewRecorder()
+
+	NewRouter(testHandlers(), nil, utils.ErrorOptions{}, nil).ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	require.Equal(t, "3.1.0", doc["openapi"])
+}
+
+// TestRouterServesTasksFromMemory runs the tasks routes without Postgres, as with the memory
+// storage backend, the routes of missing handlers are not registered
+func TestRouterServesTasksFromMemory(t *testing.T) {
+	router := NewRouter(Handlers{Task: handler.NewTaskHandler(repository.NewMemoryTaskRepo(), database.NoopTransactor{})}, nil, utils.ErrorOptions{}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks", strings.NewReader(`{"title":"write report"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	var created map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/tasks/"+created["id"].(string), nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `"title":"write report"`)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/webhooks", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func testHandlers() Handlers {
+	return Handlers{
+		Task:    handler.NewTaskHandler(nil, nil),
+		Events:  handler.NewEventsHandler(nil, nil, time.Second),
+		Webhook: handler.NewWebhookHandler(nil),
+		View:    handler.NewViewHandler(nil, nil),
+		Job:     handler.NewJobHandler(nil, nil),
+		Admin:   handler.NewAdminHandler(nil, database.SchemaWindow{}, 0),
+	}
+}
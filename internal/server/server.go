@@ -3,15 +3,23 @@ package server
 import (
 	"net/http"
 
-	"go-tasks-api/internal/handler"
+	"go-tasks-api/internal/database"
+	"go-tasks-api/internal/httpcache"
+	"go-tasks-api/internal/utils"
 )
 
 // NewServer creates and configures a new HTTP server
-func NewServer(a *handler.Task) *http.Server {
-	r := NewRouter(a)
+func NewServer(h Handlers, cache httpcache.Policies, errs utils.ErrorOptions, replicas *database.Replicas) *http.Server {
+	r := NewRouter(h, cache, errs, replicas)
 
-	return &http.Server{
+	server := &http.Server{
 		Addr:    ":3000",
 		Handler: r,
 	}
+	// event streams never complete on their own, end them so shutdown does not wait on them
+	if h.Events != nil {
+		server.RegisterOnShutdown(h.Events.Close)
+	}
+
+	return server
 }
@@ -0,0 +1,283 @@
+package utils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	MediaTypeJSON   = "application/json"
+	MediaTypeYAML   = "application/yaml"
+	MediaTypeCSV    = "text/csv"
+	MediaTypeNDJSON = "application/x-ndjson"
+)
+
+// Encoder writes response bodies in one media type
+type Encoder interface {
+	// ContentType is the Content-Type header of the encoded body
+	ContentType() string
+	Encode(w io.Writer, data any) error
+}
+
+// CSVRecorder is implemented by types that can be written as CSV rows
+type CSVRecorder interface {
+	CSVHeader() []string
+	CSVRecord() []string
+}
+
+// Encoders is the registry of media types responses can be negotiated to, in order of
+// preference when the Accept header allows several of them
+var Encoders = []struct {
+	MediaType string
+	Encoder   Encoder
+}{
+	{MediaTypeJSON, JSONEncoder{}},
+	{MediaTypeYAML, YAMLEncoder{}},
+	{MediaTypeCSV, CSVEncoder{}},
+	{MediaTypeNDJSON, NDJSONEncoder{}},
+}
+
+// Negotiate returns the encoder of the most preferred media type accepted by the request, the
+// JSON encoder when no Accept header is sent. It returns false when no media type is acceptable.
+func Negotiate(r *http.Request) (string, Encoder, bool) {
+	accept := r.Header.Values("Accept")
+	if len(accept) == 0 {
+		return MediaTypeJSON, JSONEncoder{}, true
+	}
+
+	// the highest quality wins, then the earliest range of the header and the registry order
+	ranges := parseAccept(strings.Join(accept, ","))
+	best, bestQ, bestPos := -1, 0.0, 0
+	for i, e := range Encoders {
+		q, pos := quality(ranges, e.MediaType)
+		if q > bestQ || (q > 0 && q == bestQ && pos < bestPos) {
+			best, bestQ, bestPos = i, q, pos
+		}
+	}
+
+	if best < 0 {
+		return "", nil, false
+	}
+
+	return Encoders[best].MediaType, Encoders[best].Encoder, true
+}
+
+// WriteEncoded encodes the provided data with the given encoder and writes it into the given reader
+func WriteEncoded(w http.ResponseWriter, enc Encoder, status int, data any) {
+	w.Header().Set("Content-Type", enc.ContentType())
+	w.WriteHeader(status)
+	if status != http.StatusNoContent {
+		if err := enc.Encode(w, data); err != nil {
+			log.Error().Err(err).Msg("failed to write response")
+		}
+	}
+}
+
+// mediaRange is one entry of an Accept header
+type mediaRange struct {
+	mediaType string
+	q         float64
+	// pos is the position of the range in the header
+	pos int
+}
+
+func (m mediaRange) matches(mediaType string) bool {
+	if m.mediaType == "*/*" || m.mediaType == mediaType {
+		return true
+	}
+
+	prefix, ok := strings.CutSuffix(m.mediaType, "/*")
+
+	return ok && strings.HasPrefix(mediaType, prefix+"/")
+}
+
+// specificity ranks exact types above type/* and type/* above */*
+func (m mediaRange) specificity() int {
+	switch {
+	case m.mediaType == "*/*":
+		return 0
+	case strings.HasSuffix(m.mediaType, "/*"):
+		return 1
+	default:
+		return 2
+	}
+}
+
+// quality returns the quality of the media type and the position of the range it is taken from.
+// The most specific matching range applies, so "*/*, application/yaml;q=0" excludes YAML.
+func quality(ranges []mediaRange, mediaType string) (float64, int) {
+	var (
+		match mediaRange
+		found bool
+	)
+	for _, rng := range ranges {
+		if rng.matches(mediaType) && (!found || rng.specificity() > match.specificity()) {
+			match, found = rng, true
+		}
+	}
+
+	if !found {
+		return 0, 0
+	}
+
+	return match.q, match.pos
+}
+
+// parseAccept returns the media ranges of the header ordered by quality, malformed ranges are
+// left out. Ranges with q=0 are kept last, they exclude the types they match.
+func parseAccept(header string) []mediaRange {
+	ranges := make([]mediaRange, 0)
+	for pos, part := range strings.Split(header, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+
+		rng := mediaRange{mediaType: mediaType, q: 1, pos: pos}
+		if q, ok := params["q"]; ok {
+			if rng.q, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+
+		ranges = append(ranges, rng)
+	}
+
+	// the order of the header breaks ties
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+
+	return ranges
+}
+
+type JSONEncoder struct{}
+
+func (JSONEncoder) ContentType() string {
+	return MediaTypeJSON + "; charset=utf-8"
+}
+
+func (JSONEncoder) Encode(w io.Writer, data any) error {
+	return json.NewEncoder(w).Encode(data)
+}
+
+// NDJSONEncoder writes slices one element per line, any other value as a single line
+type NDJSONEncoder struct{}
+
+func (NDJSONEncoder) ContentType() string {
+	return MediaTypeNDJSON
+}
+
+func (NDJSONEncoder) Encode(w io.Writer, data any) error {
+	enc := json.NewEncoder(w)
+
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Slice {
+		return enc.Encode(data)
+	}
+
+	for i := range v.Len() {
+		if err := enc.Encode(v.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// YAMLEncoder writes the JSON representation of the data as YAML, so field names and the
+// order of fields match the JSON responses
+type YAMLEncoder struct{}
+
+func (YAMLEncoder) ContentType() string {
+	return MediaTypeYAML + "; charset=utf-8"
+}
+
+func (YAMLEncoder) Encode(w io.Writer, data any) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal data: %w", err)
+	}
+
+	// JSON is valid YAML, decoding into a node keeps the order of the keys
+	var node yaml.Node
+	if err := yaml.Unmarshal(b, &node); err != nil {
+		return fmt.Errorf("failed to convert data to yaml: %w", err)
+	}
+	blockStyle(&node)
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
+
+// blockStyle drops the flow and quoting styles of the decoded JSON, the encoder still quotes
+// strings that would otherwise be read back as another type
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// CSVEncoder writes a CSVRecorder, or a slice of them, as a header row followed by one row per value
+type CSVEncoder struct{}
+
+func (CSVEncoder) ContentType() string {
+	return MediaTypeCSV + "; charset=utf-8"
+}
+
+func (CSVEncoder) Encode(w io.Writer, data any) error {
+	var (
+		header  []string
+		records [][]string
+	)
+
+	if rec, ok := data.(CSVRecorder); ok {
+		header = rec.CSVHeader()
+		records = [][]string{rec.CSVRecord()}
+	} else {
+		v := reflect.ValueOf(data)
+		if v.Kind() != reflect.Slice {
+			return fmt.Errorf("cannot encode %T as csv", data)
+		}
+
+		// the zero element provides the header of empty slices
+		zero, ok := reflect.Zero(v.Type().Elem()).Interface().(CSVRecorder)
+		if !ok {
+			return fmt.Errorf("cannot encode %T as csv", data)
+		}
+		header = zero.CSVHeader()
+
+		records = make([][]string, 0, v.Len())
+		for i := range v.Len() {
+			records = append(records, v.Index(i).Interface().(CSVRecorder).CSVRecord())
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	return cw.WriteAll(records)
+}
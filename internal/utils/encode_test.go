@@ -0,0 +1,93 @@
+package utils_test
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"go-tasks-api/internal/utils"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name     string
+		accept   string
+		expected string
+		ok       bool
+	}{
+		{"no header", "", utils.MediaTypeJSON, true},
+		{"exact", "text/csv", utils.MediaTypeCSV, true},
+		{"parameters are ignored", "application/json; charset=utf-8", utils.MediaTypeJSON, true},
+		{"highest quality wins", "application/json;q=0.5, application/yaml", utils.MediaTypeYAML, true},
+		{"header order breaks ties", "application/x-ndjson, text/csv", utils.MediaTypeNDJSON, true},
+		{"wildcard prefers json", "*/*", utils.MediaTypeJSON, true},
+		{"subtype wildcard", "text/*", utils.MediaTypeCSV, true},
+		{"unsupported", "application/xml", "", false},
+		{"excluded", "application/xml, application/json;q=0", "", false},
+		{"wildcard with exclusion", "*/*, application/yaml;q=0", utils.MediaTypeJSON, true},
+		{"exclusion of the preferred type", "*/*, application/json;q=0", utils.MediaTypeYAML, true},
+		{"specific range overrides wildcard", "*/*;q=0.5, application/json;q=0.1", utils.MediaTypeYAML, true},
+		{"subtype wildcard with exclusion", "text/*, text/csv;q=0", "", false},
+		{"everything excluded", "*/*;q=0", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+
+			got, _, ok := utils.Negotiate(r)
+			if got != tt.expected || ok != tt.ok {
+				t.Errorf("Negotiate(%q) = %q, %v; want %q, %v", tt.accept, got, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+type record struct {
+	name string
+}
+
+func (r record) CSVHeader() []string {
+	return []string{"name"}
+}
+
+func (r record) CSVRecord() []string {
+	return []string{r.name}
+}
+
+func TestEncoders(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoder  utils.Encoder
+		data     any
+		expected string
+	}{
+		{"csv slice", utils.CSVEncoder{}, []record{{"a"}, {"b"}}, "name\na\nb\n"},
+		{"csv empty slice keeps header", utils.CSVEncoder{}, []record{}, "name\n"},
+		{"csv single value", utils.CSVEncoder{}, record{"a"}, "name\na\n"},
+		{"ndjson slice", utils.NDJSONEncoder{}, []map[string]int{{"a": 1}, {"b": 2}}, "{\"a\":1}\n{\"b\":2}\n"},
+		{"yaml keeps json names", utils.YAMLEncoder{}, struct {
+			Name string   `json:"name"`
+			Tags []string `json:"tags"`
+		}{"a", []string{"1"}}, "name: a\ntags:\n  - \"1\"\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.encoder.Encode(&buf, tt.data); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Encode() = %q; want %q", buf.String(), tt.expected)
+			}
+		})
+	}
+
+	if err := (utils.CSVEncoder{}).Encode(&bytes.Buffer{}, map[string]string{}); err == nil {
+		t.Error("expected an error encoding a type without CSV records")
+	}
+}
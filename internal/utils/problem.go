@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const MediaTypeProblem = "application/problem+json"
+
+// Problem is an error response as defined by RFC 9457, the code and errors members are extensions
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// ErrorOptions selects the format WriteJSONError renders errors in
+type ErrorOptions struct {
+	// ProblemDetails makes application/problem+json the default, requests can still select
+	// either format through the Accept header
+	ProblemDetails bool
+	// ProblemTypeBaseURI is prefixed to the error code to build the type of problems
+	ProblemTypeBaseURI string
+	// RedactDetails keeps the text of unexpected errors, such as driver errors, out of responses
+	RedactDetails bool
+}
+
+type errorOptionsKey struct{}
+
+// Middleware makes the options available to WriteJSONError for the request
+func (o ErrorOptions) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), errorOptionsKey{}, o)))
+	})
+}
+
+// ErrorOptionsFrom returns the error options of the request, set by ErrorOptions.Middleware
+func ErrorOptionsFrom(r *http.Request) ErrorOptions {
+	opts, _ := r.Context().Value(errorOptionsKey{}).(ErrorOptions)
+
+	return opts
+}
+
+// prefersProblem returns the error options of the request and whether its errors are written
+// as problem details. The first of application/problem+json and application/json named in the
+// Accept header wins, wildcards fall back to the configured default.
+func prefersProblem(r *http.Request) (ErrorOptions, bool) {
+	if r == nil {
+		return ErrorOptions{}, false
+	}
+
+	opts := ErrorOptionsFrom(r)
+	for _, rng := range parseAccept(strings.Join(r.Header.Values("Accept"), ",")) {
+		// a type excluded with q=0 selects the other one
+		switch rng.mediaType {
+		case MediaTypeProblem:
+			return opts, rng.q > 0
+		case MediaTypeJSON:
+			return opts, rng.q == 0
+		}
+	}
+
+	return opts, opts.ProblemDetails
+}
+
+// writeProblem writes a single problem, field errors are listed in its errors member
+func writeProblem(w http.ResponseWriter, opts ErrorOptions, status int, errDesc ErrorDescription, sources []FieldError) {
+	if errDesc.ID == "" {
+		errDesc.ID = uuid.New().String()
+	}
+
+	problem := Problem{
+		Type:     opts.ProblemTypeBaseURI + errDesc.Code,
+		Title:    errDesc.Title,
+		Status:   status,
+		Detail:   errDesc.Details,
+		Instance: "urn:uuid:" + errDesc.ID,
+		Code:     errDesc.Code,
+		Errors:   sources,
+	}
+
+	w.Header().Set("Content-Type", MediaTypeProblem)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		log.Error().Err(err).Msg("failed to write error response")
+	}
+}
@@ -0,0 +1,82 @@
+package utils_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-tasks-api/internal/utils"
+)
+
+func writeError(opts utils.ErrorOptions, accept string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler := opts.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		utils.WriteJSONError(w, r, http.StatusBadRequest, utils.ErrorDescription{
+			ID:      "0b6f2a4e-8d1c-4c57-9d0b-0f4a4b1f6b55",
+			Code:    "validation_error",
+			Status:  http.StatusBadRequest,
+			Title:   "failed to create task",
+			Details: "failed to validate request body",
+		}, utils.FieldError{Field: "title", Pointer: "/title", Message: "field is required"})
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/tasks", nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	handler.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestWriteJSONErrorFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		problem     bool
+		accept      string
+		contentType string
+	}{
+		{"default json", false, "", "application/json; charset=utf-8"},
+		{"default problem", true, "", utils.MediaTypeProblem},
+		{"accept problem", false, "application/problem+json", utils.MediaTypeProblem},
+		{"accept json over configured problem", true, "application/json", "application/json; charset=utf-8"},
+		{"first named type wins", false, "application/json;q=0.5, application/problem+json", utils.MediaTypeProblem},
+		{"wildcard keeps default", true, "*/*", utils.MediaTypeProblem},
+		{"excluded problem", true, "*/*, application/problem+json;q=0", "application/json; charset=utf-8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := writeError(utils.ErrorOptions{ProblemDetails: tt.problem}, tt.accept)
+			if got := rec.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Content-Type = %q; want %q", got, tt.contentType)
+			}
+		})
+	}
+}
+
+func TestWriteJSONErrorProblem(t *testing.T) {
+	rec := writeError(utils.ErrorOptions{ProblemTypeBaseURI: "https://example.com/problems/"}, "application/problem+json")
+
+	var problem utils.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("failed to decode problem: %v", err)
+	}
+
+	if rec.Code != http.StatusBadRequest || problem.Status != http.StatusBadRequest {
+		t.Errorf("status = %d, %d; want %d", rec.Code, problem.Status, http.StatusBadRequest)
+	}
+	if problem.Type != "https://example.com/problems/validation_error" {
+		t.Errorf("type = %q", problem.Type)
+	}
+	if problem.Instance != "urn:uuid:0b6f2a4e-8d1c-4c57-9d0b-0f4a4b1f6b55" {
+		t.Errorf("instance = %q", problem.Instance)
+	}
+	if problem.Code != "validation_error" || problem.Detail != "failed to validate request body" {
+		t.Errorf("code, detail = %q, %q", problem.Code, problem.Detail)
+	}
+	if len(problem.Errors) != 1 || problem.Errors[0].Pointer != "/title" {
+		t.Errorf("errors = %+v", problem.Errors)
+	}
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"strings"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
@@ -15,3 +16,16 @@ func GetMockUUID() uuid.UUID {
 func TrimString(s string) string {
 	return strings.Trim(s, " ")
 }
+
+// TruncateString cuts s to at most n bytes without splitting a multi-byte rune
+func TruncateString(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+
+	return s[:n]
+}
@@ -36,3 +36,27 @@ func TestTrimString(t *testing.T) {
 		})
 	}
 }
+
+func TestTruncateString(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		n        int
+		expected string
+	}{
+		{"short", "hello", 10, "hello"},
+		{"cut", "hello", 3, "hel"},
+		{"rune boundary", "añb", 2, "a"},
+		{"after rune", "añb", 3, "añ"},
+		{"empty", "", 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := utils.TruncateString(tt.input, tt.n)
+			if got != tt.expected {
+				t.Errorf("TruncateString(%q, %d) = %q; want %q", tt.input, tt.n, got, tt.expected)
+			}
+		})
+	}
+}
@@ -1,11 +1,9 @@
 package utils
 
 import (
-	"encoding/json"
 	"net/http"
 
 	"github.com/google/uuid"
-	"github.com/rs/zerolog/log"
 )
 
 type (
@@ -22,9 +20,12 @@ type (
 		Source  *FieldError `json:"source,omitempty"`
 	}
 
-	// FieldError describes an error for a specific field, usually provided upon the request
+	// FieldError describes an error for a specific field, usually provided upon the request.
+	// Pointer locates the value as a JSON Pointer into the request body, or as /path/<name>
+	// and /query/<name> for parameters.
 	FieldError struct {
 		Field   string `json:"field"`
+		Pointer string `json:"pointer,omitempty"`
 		Message string `json:"message"`
 	}
 )
@@ -32,19 +33,15 @@ type (
 // WriteJSON encodes the provided data into JSON format and writes it into the given reader with
 // Content-Type set to "application/json; charset=utf-8"
 func WriteJSON(w http.ResponseWriter, status int, data any) {
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	w.WriteHeader(status)
-	if status != http.StatusNoContent {
-		if err := json.NewEncoder(w).Encode(data); err != nil {
-			log.Error().Err(err).Msg("failed to write error response")
-		}
-	}
+	WriteEncoded(w, JSONEncoder{}, status, data)
 }
 
 // WriteJSONError sets the fields and writes them into the given reader as JSON with
-// Content-Type set to "application/json; charset=utf-8"
+// Content-Type set to "application/json; charset=utf-8", or as problem details when the
+// request prefers them, see ErrorOptions
 func WriteJSONError(
 	w http.ResponseWriter,
+	r *http.Request,
 	status int,
 	errDesc ErrorDescription,
 	sources ...FieldError,
@@ -53,6 +50,12 @@ func WriteJSONError(
 		errDesc.Title = "an error occurred"
 	}
 
+	if opts, ok := prefersProblem(r); ok {
+		writeProblem(w, opts, status, errDesc, sources)
+
+		return
+	}
+
 	var errResps []ErrorDescription
 	if len(sources) > 0 {
 		errResps = make([]ErrorDescription, 0, len(sources))
@@ -0,0 +1,215 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go-tasks-api/internal/enum"
+	"go-tasks-api/internal/model"
+	"go-tasks-api/internal/repository"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sethvargo/go-retry"
+	"golang.org/x/sync/errgroup"
+)
+
+// maxErrorLength bounds the error message stored per attempt
+const maxErrorLength = 512
+
+type Config struct {
+	// PollInterval is the delay between two claims when no delivery was due
+	PollInterval time.Duration
+	BatchSize    int
+	Concurrency  int
+	// Timeout bounds a single POST to a receiver
+	Timeout time.Duration
+	// MaxAttempts is the number of attempts after which a delivery is marked as failed
+	MaxAttempts int
+	// BackoffBase and BackoffMax shape the exponential delay between attempts
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// DisableAfter is the number of consecutive failed attempts which disables a webhook
+	DisableAfter int
+}
+
+// Dispatcher POSTs pending deliveries to their webhook and schedules retries
+type Dispatcher struct {
+	webhookRepo repository.WebhookConnector
+	client      *http.Client
+	cfg         Config
+	now         func() time.Time
+}
+
+// payload is the body POSTed to receivers
+type payload struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	TaskID    string          `json:"task_id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// NewDispatcher creates a new Dispatcher
+func NewDispatcher(webhookRepo repository.WebhookConnector, cfg Config) *Dispatcher {
+	return &Dispatcher{
+		webhookRepo: webhookRepo,
+		client:      &http.Client{Timeout: cfg.Timeout},
+		cfg:         cfg,
+		now:         time.Now,
+	}
+}
+
+// Run delivers due webhooks until the context is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	log.Info().Msg("webhook dispatcher started")
+
+	for {
+		n, err := d.DispatchDue(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to dispatch webhook deliveries")
+		}
+
+		// keep draining while full batches are claimed
+		if err == nil && n == d.cfg.BatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("webhook dispatcher stopped")
+
+			return
+		case <-time.After(d.cfg.PollInterval):
+		}
+	}
+}
+
+// DispatchDue claims one batch of due deliveries and attempts each of them once,
+// it returns the number of claimed deliveries
+func (d *Dispatcher) DispatchDue(ctx context.Context) (int, error) {
+	// the lease outlives all attempts of the batch, an expired lease means the worker crashed
+	lease := d.cfg.Timeout*time.Duration(d.cfg.BatchSize/max(d.cfg.Concurrency, 1)+1) + time.Minute
+
+	pending, err := d.webhookRepo.ClaimDue(ctx, d.cfg.BatchSize, lease)
+	if err != nil {
+		return 0, err
+	}
+
+	// a failure to store one outcome must not cancel the other attempts
+	var group errgroup.Group
+	group.SetLimit(max(d.cfg.Concurrency, 1))
+	for _, p := range pending {
+		group.Go(func() error {
+			return d.attempt(ctx, p)
+		})
+	}
+
+	return len(pending), group.Wait()
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, p model.PendingDelivery) error {
+	statusCode, sendErr := d.send(ctx, p)
+
+	attempt := model.DeliveryAttempt{
+		DeliveryID:    p.Delivery.ID,
+		WebhookID:     p.Delivery.WebhookID,
+		Status:        enum.DeliveryStatus_Succeeded,
+		NextAttemptAt: d.now(),
+	}
+	if statusCode != 0 {
+		attempt.StatusCode = &statusCode
+	}
+
+	logger := log.With().
+		Str("delivery_id", p.Delivery.ID.String()).
+		Str("webhook_id", p.Delivery.WebhookID.String()).
+		Int("attempt", p.Delivery.Attempts+1).
+		Logger()
+
+	if sendErr != nil {
+		msg := sendErr.Error()
+		if len(msg) > maxErrorLength {
+			msg = msg[:maxErrorLength]
+		}
+		attempt.Error = &msg
+
+		if p.Delivery.Attempts+1 >= d.cfg.MaxAttempts {
+			attempt.Status = enum.DeliveryStatus_Failed
+		} else {
+			attempt.Status = enum.DeliveryStatus_Pending
+			attempt.NextAttemptAt = d.now().Add(d.backoff(p.Delivery.Attempts + 1))
+		}
+
+		logger.Warn().Err(sendErr).Str("status", attempt.Status.String()).Msg("webhook delivery attempt failed")
+	}
+
+	disabled, err := d.webhookRepo.CompleteAttempt(ctx, attempt, d.cfg.DisableAfter)
+	if err != nil {
+		return fmt.Errorf("failed to complete delivery %s: %w", p.Delivery.ID, err)
+	}
+
+	if disabled {
+		logger.Warn().Int("disable_after", d.cfg.DisableAfter).Msg("webhook disabled after repeated failures")
+	}
+
+	return nil
+}
+
+// send POSTs the delivery and returns the response status code, any status outside 2xx is an error
+func (d *Dispatcher) send(ctx context.Context, p model.PendingDelivery) (int, error) {
+	body, err := json.Marshal(payload{
+		ID:        p.Event.ID,
+		Type:      p.Event.Type,
+		TaskID:    p.Event.TaskID.String(),
+		CreatedAt: p.Event.CreatedAt,
+		Data:      p.Event.Data,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("User-Agent", "go-tasks-api-webhooks")
+	req.Header.Set("X-Webhook-ID", p.Delivery.WebhookID.String())
+	req.Header.Set("X-Delivery-ID", p.Delivery.ID.String())
+	req.Header.Set("X-Event-Type", p.Event.Type)
+	req.Header.Set(SignatureHeader, Sign(p.Secret, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// drain so the connection is reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// backoff returns the delay before the attempt following the given one: exponential from
+// BackoffBase, capped at BackoffMax, with 10% jitter
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	b := retry.WithJitterPercent(10, retry.WithCappedDuration(d.cfg.BackoffMax, retry.NewExponential(d.cfg.BackoffBase)))
+
+	var delay time.Duration
+	for range attempt {
+		delay, _ = b.Next()
+	}
+
+	return delay
+}
@@ -0,0 +1,200 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-tasks-api/internal/enum"
+	"go-tasks-api/internal/model"
+	"go-tasks-api/internal/repository/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/mock/gomock"
+)
+
+const testSecret = "0123456789abcdef"
+
+type dispatcherTestSuite struct {
+	suite.Suite
+	ctrl         *gomock.Controller
+	mockWebhooks *mocks.MockWebhookConnector
+	dispatcher   *Dispatcher
+	now          time.Time
+}
+
+func TestDispatcher(t *testing.T) {
+	suite.Run(t, new(dispatcherTestSuite))
+}
+
+func (s *dispatcherTestSuite) SetupTest() {
+	s.ctrl = gomock.NewController(s.T())
+	s.mockWebhooks = mocks.NewMockWebhookConnector(s.ctrl)
+	s.now = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	s.dispatcher = NewDispatcher(s.mockWebhooks, Config{
+		BatchSize:    10,
+		Concurrency:  2,
+		Timeout:      time.Second,
+		MaxAttempts:  3,
+		BackoffBase:  time.Second,
+		BackoffMax:   time.Minute,
+		DisableAfter: 5,
+	})
+	s.dispatcher.now = func() time.Time { return s.now }
+}
+
+func (s *dispatcherTestSuite) TearDownTest() {
+	s.ctrl.Finish()
+}
+
+func (s *dispatcherTestSuite) pending(url string, attempts int) model.PendingDelivery {
+	return model.PendingDelivery{
+		Delivery: model.WebhookDelivery{
+			ID:        uuid.New(),
+			WebhookID: uuid.New(),
+			EventID:   7,
+			Status:    enum.DeliveryStatus_Pending,
+			Attempts:  attempts,
+		},
+		URL:    url,
+		Secret: testSecret,
+		Event: model.Event{
+			ID:     7,
+			Type:   model.EventTaskCreated,
+			TaskID: uuid.New(),
+			Data:   []byte(`{"title":"doc"}`),
+		},
+	}
+}
+
+// Success: The receiver gets a signed payload and the delivery succeeds
+func (s *dispatcherTestSuite) TestDispatchSigned() {
+	var (
+		gotBody   []byte
+		gotHeader http.Header
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotHeader = r.Header.Clone()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	p := s.pending(receiver.URL, 0)
+	s.mockWebhooks.EXPECT().ClaimDue(gomock.Any(), 10, gomock.Any()).Return([]model.PendingDelivery{p}, nil)
+	s.mockWebhooks.EXPECT().CompleteAttempt(gomock.Any(), gomock.Any(), 5).
+		DoAndReturn(func(ctx context.Context, a model.DeliveryAttempt, disableAfter int) (bool, error) {
+			s.Equal(p.Delivery.ID, a.DeliveryID)
+			s.Equal(enum.DeliveryStatus_Succeeded, a.Status)
+			s.Require().NotNil(a.StatusCode)
+			s.Equal(http.StatusNoContent, *a.StatusCode)
+			s.Nil(a.Error)
+
+			return false, nil
+		})
+
+	n, err := s.dispatcher.DispatchDue(context.Background())
+	s.NoError(err)
+	s.Equal(1, n)
+
+	s.True(Verify(testSecret, gotBody, gotHeader.Get(SignatureHeader)))
+	s.Equal(p.Delivery.ID.String(), gotHeader.Get("X-Delivery-ID"))
+	s.Equal(model.EventTaskCreated, gotHeader.Get("X-Event-Type"))
+	s.JSONEq(`{"id":7,"type":"task.created","task_id":"`+p.Event.TaskID.String()+`","created_at":"0001-01-01T00:00:00Z","data":{"title":"doc"}}`, string(gotBody))
+}
+
+// Retry: A 5xx response schedules the next attempt with backoff
+func (s *dispatcherTestSuite) TestDispatchRetried() {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	s.mockWebhooks.EXPECT().ClaimDue(gomock.Any(), 10, gomock.Any()).
+		Return([]model.PendingDelivery{s.pending(receiver.URL, 1)}, nil)
+	s.mockWebhooks.EXPECT().CompleteAttempt(gomock.Any(), gomock.Any(), 5).
+		DoAndReturn(func(ctx context.Context, a model.DeliveryAttempt, disableAfter int) (bool, error) {
+			s.Equal(enum.DeliveryStatus_Pending, a.Status)
+			s.Require().NotNil(a.StatusCode)
+			s.Equal(http.StatusInternalServerError, *a.StatusCode)
+			s.Require().NotNil(a.Error)
+			s.Contains(*a.Error, "500")
+
+			// second attempt: 2s +/- 10%
+			delay := a.NextAttemptAt.Sub(s.now)
+			s.GreaterOrEqual(delay, 1800*time.Millisecond)
+			s.LessOrEqual(delay, 2200*time.Millisecond)
+
+			return false, nil
+		})
+
+	_, err := s.dispatcher.DispatchDue(context.Background())
+	s.NoError(err)
+}
+
+// Failure: The last attempt marks the delivery as failed
+func (s *dispatcherTestSuite) TestDispatchFailedAfterMaxAttempts() {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer receiver.Close()
+
+	s.mockWebhooks.EXPECT().ClaimDue(gomock.Any(), 10, gomock.Any()).
+		Return([]model.PendingDelivery{s.pending(receiver.URL, 2)}, nil)
+	s.mockWebhooks.EXPECT().CompleteAttempt(gomock.Any(), gomock.Any(), 5).
+		DoAndReturn(func(ctx context.Context, a model.DeliveryAttempt, disableAfter int) (bool, error) {
+			s.Equal(enum.DeliveryStatus_Failed, a.Status)
+
+			return true, nil
+		})
+
+	_, err := s.dispatcher.DispatchDue(context.Background())
+	s.NoError(err)
+}
+
+// Failure: An unreachable receiver is a failed attempt without status code
+func (s *dispatcherTestSuite) TestDispatchUnreachable() {
+	receiver := httptest.NewServer(http.NotFoundHandler())
+	url := receiver.URL
+	receiver.Close()
+
+	s.mockWebhooks.EXPECT().ClaimDue(gomock.Any(), 10, gomock.Any()).
+		Return([]model.PendingDelivery{s.pending(url, 0)}, nil)
+	s.mockWebhooks.EXPECT().CompleteAttempt(gomock.Any(), gomock.Any(), 5).
+		DoAndReturn(func(ctx context.Context, a model.DeliveryAttempt, disableAfter int) (bool, error) {
+			s.Equal(enum.DeliveryStatus_Pending, a.Status)
+			s.Nil(a.StatusCode)
+			s.NotNil(a.Error)
+
+			return false, nil
+		})
+
+	_, err := s.dispatcher.DispatchDue(context.Background())
+	s.NoError(err)
+}
+
+func (s *dispatcherTestSuite) TestBackoffCapped() {
+	for attempt := 1; attempt <= 20; attempt++ {
+		s.LessOrEqual(s.dispatcher.backoff(attempt), time.Minute+6*time.Second)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"id":1}`)
+	signature := Sign(testSecret, body)
+
+	if !Verify(testSecret, body, signature) {
+		t.Fatal("expected signature to verify")
+	}
+	if Verify("another secret!!", body, signature) {
+		t.Fatal("expected signature with another secret to fail")
+	}
+	if Verify(testSecret, []byte(`{"id":2}`), signature) {
+		t.Fatal("expected signature of another body to fail")
+	}
+}
@@ -0,0 +1,26 @@
+package webhook
+
+import (
+	"context"
+
+	"go-tasks-api/internal/model"
+	"go-tasks-api/internal/repository"
+)
+
+// Enqueuer creates webhook deliveries for recorded events
+type Enqueuer struct {
+	webhookRepo repository.WebhookConnector
+}
+
+// NewEnqueuer creates a new Enqueuer, it is registered as a sink of the event recorder
+func NewEnqueuer(webhookRepo repository.WebhookConnector) *Enqueuer {
+	return &Enqueuer{
+		webhookRepo: webhookRepo,
+	}
+}
+
+func (e *Enqueuer) Handle(ctx context.Context, event model.Event) error {
+	_, err := e.webhookRepo.Enqueue(ctx, event)
+
+	return err
+}
@@ -0,0 +1,33 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	// SignatureHeader carries the HMAC-SHA256 of the request body, keyed with the webhook secret
+	SignatureHeader = "X-Signature"
+
+	signaturePrefix = "sha256="
+)
+
+// Sign returns the X-Signature header value for the body, in the form sha256=<hex digest>
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether the signature matches the body, in constant time. Receivers
+// written in Go can use it to authenticate deliveries.
+func Verify(secret string, body []byte, signature string) bool {
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return false
+	}
+
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}